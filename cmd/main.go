@@ -26,7 +26,9 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 	mount "k8s.io/mount-utils"
+	clientset "sigs.k8s.io/gcp-filestore-csi-driver/pkg/client/clientset/versioned"
 	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
 	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/metadata"
 	metadataservice "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/metadata"
 	driver "sigs.k8s.io/gcp-filestore-csi-driver/pkg/csi_driver"
@@ -70,6 +72,40 @@ var (
 	featureNFSExportOptionsOnCreate = flag.Bool("feature-nfs-export-options", false, "if set to true, the driver will accpet nfs-export-options-on-create parameter and configure IP Access rules")
 	featureSharePools               = flag.Bool("feature-share-pools", false, "if set to true, the driver will support Filestore Share Pools provisioning")
 
+	// Feature Filestore inventory cache specific parameters, only take effect when feature-inventory-cache is set to true.
+	featureInventoryCache = flag.Bool("feature-inventory-cache", false, "if set to true, the controller will serve the Filestore instance and share lists from a cache which is resynced periodically.")
+	inventoryResyncPeriod = flag.Duration("inventory-resync-period", file.DefaultInventoryResyncPeriod, "Duration, in seconds, the resync period of the Filestore inventory cache. Defaults to 30 seconds.")
+	inventoryMaxStaleness = flag.Duration("inventory-max-staleness", file.DefaultInventoryMaxStaleness, "Duration, in seconds, after which a cached Filestore list is listed again on use. Defaults to 2 minutes.")
+
+	// Feature persistent IP reservations specific parameters, only take effect when feature-persistent-ip-reservations is set to true.
+	featurePersistentIPReservations = flag.Bool("feature-persistent-ip-reservations", false, "if set to true, the controller will persist the IP ranges reserved for reserved-ipv4-cidr provisioning in a configmap, so that they are shared between controller replicas and survive restarts.")
+	ipReservationNamespace          = flag.String("ip-reservation-namespace", util.ManagedFilestoreCSINamespace, "The namespace of the configmap holding the IP range reservations.")
+	ipReservationTTL                = flag.Duration("ip-reservation-ttl", util.DefaultIPReservationTTL, "Duration after which an IP range reservation which was not released expires. Defaults to 10 minutes.")
+
+	// Feature backup garbage collection
+	featureBackupGC  = flag.Bool("feature-backup-gc", false, "if set to true, the controller will periodically delete the backups created by the driver once they expire, according to their retention labels and the backup-gc-max-age and backup-gc-keep-last flags.")
+	backupGCPeriod   = flag.Duration("backup-gc-period", time.Hour, "Duration, in seconds, the period of the backup garbage collection. Defaults to 1 hour.")
+	backupGCMaxAge   = flag.Duration("backup-gc-max-age", 0, "Default maximum age of the backups created by the driver in this cluster, overridden by the storage_gke_io_backup_retention backup label. Zero disables it.")
+	backupGCKeepLast = flag.Int("backup-gc-keep-last", 0, "Default number of ready backups kept per source volume for the backups created by the driver in this cluster, overridden by the storage_gke_io_backup_keep-last backup label. Zero disables it.")
+	backupGCDryRun   = flag.Bool("backup-gc-dry-run", true, "if set to true, the backup garbage collector only logs the backups it would delete. Set it to false to delete them.")
+
+	// Feature multishare janitor
+	featureMultishareJanitor     = flag.Bool("feature-multishare-janitor", false, "if set to true, the controller will periodically delete the multishare instances of this cluster which have had no shares and no running operations for the multishare-janitor-grace-period. Not supported with feature-stateful-multishare.")
+	multishareJanitorPeriod      = flag.Duration("multishare-janitor-period", 10*time.Minute, "Duration, in seconds, the period of the multishare janitor. Defaults to 10 minutes.")
+	multishareJanitorGracePeriod = flag.Duration("multishare-janitor-grace-period", time.Hour, "Duration a multishare instance stays empty before the multishare janitor deletes it. Defaults to 1 hour.")
+	multishareJanitorDryRun      = flag.Bool("multishare-janitor-dry-run", false, "if set to true, the multishare janitor only logs the instances it would delete.")
+
+	// Feature multishare warm pool
+	featureMultishareWarmPool    = flag.Bool("feature-multishare-warm-pool", false, "if set to true, the controller will keep the number of empty multishare instances set by the warm-pool-size parameter of the multishare StorageClasses.")
+	multishareWarmPoolSyncPeriod = flag.Duration("multishare-warm-pool-sync-period", time.Minute, "Duration, in seconds, the sync period of the multishare warm pools, without feature-stateful-multishare. Defaults to 1 minute.")
+
+	featureVolumeGroupSnapshots = flag.Bool("feature-volume-group-snapshots", false, "if set to true, the controller will serve the CSI GroupController service to back up the volumes of a VolumeGroupSnapshot. Filestore has no backup spanning several instances, the backups of the member volumes are only started concurrently, so the group snapshots are not crash-consistent.")
+
+	featureTopologyRegion = flag.Bool("feature-topology-region", false, "if set to true, the node plugin will publish the topology.gke.io/region topology key, and the controller will report regional and enterprise tier volumes as accessible from the whole region. Enable it on the nodes before the controller.")
+
+	featureBackupSchedules   = flag.Bool("feature-backup-schedules", false, "if set to true, the controller will take the backups declared by FilestoreBackupSchedule objects.")
+	backupScheduleSyncPeriod = flag.Duration("backup-schedule-sync-period", time.Minute, "Duration, in seconds, the sync period of the FilestoreBackupSchedule objects. Defaults to 1 minute.")
+
 	// Feature stateful CSI driver specific parameters
 	featureStateful      = flag.Bool("feature-stateful-multishare", false, "if set to true, the controller will run stateful multishare controller, if set to true, enable-multishare must be set to true as well")
 	statefulResyncPeriod = flag.Duration("stateful-resync-period", 15*time.Minute, "Resync interval of the stateful driver.")
@@ -77,7 +113,7 @@ var (
 	kubeAPIBurst         = flag.Int("kube-api-burst", 10, "Burst to use while communicating with the kubernetes apiserver. Defaults to 10.")
 	kubeconfig           = flag.String("kubeconfig", "", "Absolute path to the kubeconfig file. Required only when running out of cluster.")
 
-	leaderElection              = flag.Bool("leader-election", false, "Enables leader election for stateful driver.")
+	leaderElection              = flag.Bool("leader-election", false, "Enables leader election for the stateful driver and the backup garbage collector.")
 	leaderElectionNamespace     = flag.String("leader-election-namespace", "", "The namespace where the leader election resource exists. Defaults to the pod namespace if not set.")
 	leaderElectionLeaseDuration = flag.Duration("leader-election-lease-duration", 15*time.Second, "Duration, in seconds, that non-leader candidates will wait to force acquire leadership. Defaults to 15 seconds.")
 	leaderElectionRenewDeadline = flag.Duration("leader-election-renew-deadline", 10*time.Second, "Duration, in seconds, that the acting leader will retry refreshing leadership before giving up. Defaults to 10 seconds.")
@@ -153,13 +189,68 @@ func main() {
 		klog.Fatalf("Failed to initialize cloud provider: %v", err)
 	}
 
+	if *featureInventoryCache && *runController {
+		inventoryConfig := file.InventoryConfig{
+			ResyncPeriod: *inventoryResyncPeriod,
+			MaxStaleness: *inventoryMaxStaleness,
+		}
+		if mm != nil {
+			mm.RegisterInventoryMetrics()
+			inventoryConfig.Recorder = mm
+		}
+		inventory := file.NewInventoryService(provider.File, inventoryConfig)
+		go inventory.Run(ctx.Done())
+		provider.File = inventory
+	}
+
+	if *featureBackupGC && *runController {
+		if *backupGCPeriod <= 0 || *backupGCMaxAge < 0 || *backupGCKeepLast < 0 {
+			klog.Fatalf("backup-gc-period must be positive, backup-gc-max-age and backup-gc-keep-last must not be negative")
+		}
+		if mm != nil {
+			mm.RegisterBackupGCMetrics()
+		}
+	}
+
+	if *featureMultishareJanitor && *runController && *enableMultishare {
+		if *multishareJanitorPeriod <= 0 || *multishareJanitorGracePeriod < 0 {
+			klog.Fatalf("multishare-janitor-period must be positive and multishare-janitor-grace-period must not be negative")
+		}
+		if mm != nil {
+			mm.RegisterMultishareJanitorMetrics()
+		}
+	}
+
+	warmPoolEnabled := *featureMultishareWarmPool && *runController && *enableMultishare && !*featureStateful
+	if warmPoolEnabled && *multishareWarmPoolSyncPeriod <= 0 {
+		klog.Fatalf("multishare-warm-pool-sync-period must be positive")
+	}
+
+	backupSchedulesEnabled := *featureBackupSchedules && *runController
+	if backupSchedulesEnabled && *backupScheduleSyncPeriod <= 0 {
+		klog.Fatalf("backup-schedule-sync-period must be positive")
+	}
+
+	// The controller features which talk to the API server share the same clients.
 	var kubeClient *kubernetes.Clientset
-	if *featureMaxSharePerInstance && *runController && *enableMultishare {
+	var driverClientSet *clientset.Clientset
+	maxSharesEnabled := *featureMaxSharePerInstance && *runController && *enableMultishare
+	ipReservationsEnabled := *featurePersistentIPReservations && *runController
+	backupGCEnabled := *featureBackupGC && *runController
+	if maxSharesEnabled || warmPoolEnabled || ipReservationsEnabled || backupGCEnabled || backupSchedulesEnabled {
 		clusterConfig, err := util.BuildConfig(*kubeconfig)
 		if err != nil {
 			klog.Error(err.Error())
 			os.Exit(1)
 		}
+		if backupSchedulesEnabled {
+			// The custom resources are not served as protobuf.
+			driverClientSet, err = clientset.NewForConfig(clusterConfig)
+			if err != nil {
+				klog.Error(err.Error())
+				os.Exit(1)
+			}
+		}
 		clusterConfig.ContentType = runtime.ContentTypeProtobuf
 		klog.Infof("cluster config created")
 
@@ -214,6 +305,55 @@ func main() {
 		FeatureSharePools: &driver.FeatureSharePools{
 			Enabled: *featureSharePools,
 		},
+		FeaturePersistentIPReservations: &driver.FeaturePersistentIPReservations{
+			Enabled:    *featurePersistentIPReservations,
+			Namespace:  *ipReservationNamespace,
+			TTL:        *ipReservationTTL,
+			KubeClient: kubeClient,
+		},
+		FeatureBackupGC: &driver.FeatureBackupGC{
+			Enabled:                     *featureBackupGC,
+			Period:                      *backupGCPeriod,
+			MaxAge:                      *backupGCMaxAge,
+			KeepLast:                    *backupGCKeepLast,
+			DryRun:                      *backupGCDryRun,
+			KubeClient:                  kubeClient,
+			LeaderElection:              *leaderElection,
+			LeaderElectionNamespace:     *leaderElectionNamespace,
+			LeaderElectionLeaseDuration: *leaderElectionLeaseDuration,
+			LeaderElectionRenewDeadline: *leaderElectionRenewDeadline,
+			LeaderElectionRetryPeriod:   *leaderElectionRetryPeriod,
+		},
+		FeatureBackupSchedules: &driver.FeatureBackupSchedules{
+			Enabled:                     *featureBackupSchedules,
+			SyncPeriod:                  *backupScheduleSyncPeriod,
+			KubeClient:                  kubeClient,
+			ClientSet:                   driverClientSet,
+			LeaderElection:              *leaderElection,
+			LeaderElectionNamespace:     *leaderElectionNamespace,
+			LeaderElectionLeaseDuration: *leaderElectionLeaseDuration,
+			LeaderElectionRenewDeadline: *leaderElectionRenewDeadline,
+			LeaderElectionRetryPeriod:   *leaderElectionRetryPeriod,
+		},
+		FeatureMultishareJanitor: &driver.FeatureMultishareJanitor{
+			Enabled:     *featureMultishareJanitor,
+			Period:      *multishareJanitorPeriod,
+			GracePeriod: *multishareJanitorGracePeriod,
+			DryRun:      *multishareJanitorDryRun,
+		},
+		FeatureMultishareWarmPool: &driver.FeatureMultishareWarmPool{
+			Enabled:    *featureMultishareWarmPool,
+			SyncPeriod: *multishareWarmPoolSyncPeriod,
+		},
+		FeatureTopologyRegion: &driver.FeatureTopologyRegion{
+			Enabled: *featureTopologyRegion,
+		},
+		FeatureVolumeGroupSnapshots: &driver.FeatureVolumeGroupSnapshots{
+			Enabled: *featureVolumeGroupSnapshots,
+		},
+	}
+	if warmPoolEnabled {
+		featureOptions.FeatureMultishareWarmPool.KubeClient = kubeClient
 	}
 
 	mounter := mount.New("")
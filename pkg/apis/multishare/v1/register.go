@@ -47,6 +47,8 @@ var (
 // Adds the list of known types to Scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&FilestoreBackupSchedule{},
+		&FilestoreBackupScheduleList{},
 		&ShareInfo{},
 		&ShareInfoList{},
 		&InstanceInfo{},
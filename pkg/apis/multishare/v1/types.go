@@ -110,3 +110,78 @@ type InstanceInfoList struct {
 
 	Items []InstanceInfo `json:"items"`
 }
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FilestoreBackupSchedule periodically backs up the Filestore volumes of the selected PVCs.
+type FilestoreBackupSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec FilestoreBackupScheduleSpec `json:"spec"`
+	// +optional
+	Status *FilestoreBackupScheduleStatus `json:"status"`
+}
+
+// FilestoreBackupScheduleSpec is the spec for a FilestoreBackupSchedule resource
+type FilestoreBackupScheduleSpec struct {
+	// Schedule is a cron expression in the standard five field format, e.g. "0 2 * * *", or
+	// one of @hourly, @daily, @weekly and @monthly. It is evaluated in UTC.
+	Schedule string `json:"schedule"`
+	// Selector selects the PVCs to back up in the namespace of the schedule.
+	Selector *metav1.LabelSelector `json:"selector"`
+	// BackupLocation is the region of the backups, the region of the volume if empty.
+	// +optional
+	BackupLocation string `json:"backupLocation,omitempty"`
+	// Labels are added to the labels of the backups.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Retention *FilestoreBackupRetention `json:"retention,omitempty"`
+	// Suspend stops taking new backups, existing backups are still pruned.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// FilestoreBackupRetention is the retention of the backups taken by a schedule
+type FilestoreBackupRetention struct {
+	// KeepLast is the number of ready backups kept per PVC, zero keeps all of them.
+	// +optional
+	KeepLast int32 `json:"keepLast,omitempty"`
+	// MaxAge is how long backups are kept, zero keeps them forever.
+	// +optional
+	MaxAge metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// FilestoreBackupScheduleStatus is the status for a FilestoreBackupSchedule resource
+type FilestoreBackupScheduleStatus struct {
+	// LastScheduleTime is the last time backups were scheduled.
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// Backups is the history of the backups taken by the schedule which were not pruned yet.
+	Backups []FilestoreBackupRecord `json:"backups,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+// FilestoreBackupRecord is a backup taken by a schedule
+type FilestoreBackupRecord struct {
+	// Name is the name of the CSI snapshot request of the backup.
+	Name         string `json:"name"`
+	PVCName      string `json:"pvcName"`
+	VolumeHandle string `json:"volumeHandle"`
+	// SnapshotHandle is the CSI snapshot id of the backup, set once the backup is created.
+	SnapshotHandle string      `json:"snapshotHandle,omitempty"`
+	ScheduleTime   metav1.Time `json:"scheduleTime"`
+	ReadyToUse     bool        `json:"readyToUse"`
+	Error          string      `json:"error,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FilestoreBackupScheduleList is a list of FilestoreBackupSchedule resources
+type FilestoreBackupScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []FilestoreBackupSchedule `json:"items"`
+}
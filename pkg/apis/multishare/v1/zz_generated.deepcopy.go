@@ -22,9 +22,169 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilestoreBackupRecord) DeepCopyInto(out *FilestoreBackupRecord) {
+	*out = *in
+	in.ScheduleTime.DeepCopyInto(&out.ScheduleTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilestoreBackupRecord.
+func (in *FilestoreBackupRecord) DeepCopy() *FilestoreBackupRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(FilestoreBackupRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilestoreBackupRetention) DeepCopyInto(out *FilestoreBackupRetention) {
+	*out = *in
+	out.MaxAge = in.MaxAge
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilestoreBackupRetention.
+func (in *FilestoreBackupRetention) DeepCopy() *FilestoreBackupRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(FilestoreBackupRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilestoreBackupSchedule) DeepCopyInto(out *FilestoreBackupSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(FilestoreBackupScheduleStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilestoreBackupSchedule.
+func (in *FilestoreBackupSchedule) DeepCopy() *FilestoreBackupSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(FilestoreBackupSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FilestoreBackupSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilestoreBackupScheduleList) DeepCopyInto(out *FilestoreBackupScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FilestoreBackupSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilestoreBackupScheduleList.
+func (in *FilestoreBackupScheduleList) DeepCopy() *FilestoreBackupScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(FilestoreBackupScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FilestoreBackupScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilestoreBackupScheduleSpec) DeepCopyInto(out *FilestoreBackupScheduleSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(FilestoreBackupRetention)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilestoreBackupScheduleSpec.
+func (in *FilestoreBackupScheduleSpec) DeepCopy() *FilestoreBackupScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FilestoreBackupScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilestoreBackupScheduleStatus) DeepCopyInto(out *FilestoreBackupScheduleStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Backups != nil {
+		in, out := &in.Backups, &out.Backups
+		*out = make([]FilestoreBackupRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilestoreBackupScheduleStatus.
+func (in *FilestoreBackupScheduleStatus) DeepCopy() *FilestoreBackupScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FilestoreBackupScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstanceInfo) DeepCopyInto(out *InstanceInfo) {
 	*out = *in
@@ -28,6 +28,10 @@ type FakeMultishareV1 struct {
 	*testing.Fake
 }
 
+func (c *FakeMultishareV1) FilestoreBackupSchedules(namespace string) v1.FilestoreBackupScheduleInterface {
+	return &FakeFilestoreBackupSchedules{c, namespace}
+}
+
 func (c *FakeMultishareV1) InstanceInfos(namespace string) v1.InstanceInfoInterface {
 	return &FakeInstanceInfos{c, namespace}
 }
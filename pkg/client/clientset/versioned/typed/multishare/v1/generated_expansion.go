@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1
 
+type FilestoreBackupScheduleExpansion interface{}
+
 type InstanceInfoExpansion interface{}
 
 type ShareInfoExpansion interface{}
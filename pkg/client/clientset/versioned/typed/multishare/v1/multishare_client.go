@@ -28,6 +28,7 @@ import (
 
 type MultishareV1Interface interface {
 	RESTClient() rest.Interface
+	FilestoreBackupSchedulesGetter
 	InstanceInfosGetter
 	ShareInfosGetter
 }
@@ -37,6 +38,10 @@ type MultishareV1Client struct {
 	restClient rest.Interface
 }
 
+func (c *MultishareV1Client) FilestoreBackupSchedules(namespace string) FilestoreBackupScheduleInterface {
+	return newFilestoreBackupSchedules(c, namespace)
+}
+
 func (c *MultishareV1Client) InstanceInfos(namespace string) InstanceInfoInterface {
 	return newInstanceInfos(c, namespace)
 }
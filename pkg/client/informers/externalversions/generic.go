@@ -53,6 +53,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=multishare.filestore.csi.storage.gke.io, Version=v1
+	case v1.SchemeGroupVersion.WithResource("filestorebackupschedules"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Multishare().V1().FilestoreBackupSchedules().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("instanceinfos"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Multishare().V1().InstanceInfos().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("shareinfos"):
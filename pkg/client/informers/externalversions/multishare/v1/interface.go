@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// FilestoreBackupSchedules returns a FilestoreBackupScheduleInformer.
+	FilestoreBackupSchedules() FilestoreBackupScheduleInformer
 	// InstanceInfos returns a InstanceInfoInformer.
 	InstanceInfos() InstanceInfoInformer
 	// ShareInfos returns a ShareInfoInformer.
@@ -41,6 +43,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// FilestoreBackupSchedules returns a FilestoreBackupScheduleInformer.
+func (v *version) FilestoreBackupSchedules() FilestoreBackupScheduleInformer {
+	return &filestoreBackupScheduleInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // InstanceInfos returns a InstanceInfoInformer.
 func (v *version) InstanceInfos() InstanceInfoInformer {
 	return &instanceInfoInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
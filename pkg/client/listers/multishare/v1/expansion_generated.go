@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1
 
+// FilestoreBackupScheduleListerExpansion allows custom methods to be added to
+// FilestoreBackupScheduleLister.
+type FilestoreBackupScheduleListerExpansion interface{}
+
+// FilestoreBackupScheduleNamespaceListerExpansion allows custom methods to be added to
+// FilestoreBackupScheduleNamespaceLister.
+type FilestoreBackupScheduleNamespaceListerExpansion interface{}
+
 // InstanceInfoListerExpansion allows custom methods to be added to
 // InstanceInfoLister.
 type InstanceInfoListerExpansion interface{}
@@ -73,6 +73,7 @@ func NewFakeTagManagerForSanityTests() *FakeTagServiceManager {
 	tagMgr.On("AttachResourceTags",
 		mock.MatchedBy(func(ctx context.Context) bool { return true }),
 		mock.MatchedBy(func(rscType resourceType) bool { return true }),
+		mock.MatchedBy(func(rscProject string) bool { return true }),
 		mock.MatchedBy(func(rscName string) bool { return true }),
 		mock.MatchedBy(func(rscLocation string) bool { return true }),
 		mock.MatchedBy(func(reqName string) bool { return true }),
@@ -95,8 +96,8 @@ func (f *FakeTagServiceManager) ValidateResourceTags(ctx context.Context, tagsSo
 	return t, e
 }
 
-func (f *FakeTagServiceManager) AttachResourceTags(ctx context.Context, rscType resourceType, rscName, rscLocation, reqName string, reqParameters map[string]string) error {
-	rets := f.Called(ctx, rscType, rscName, rscLocation, reqName, reqParameters)
+func (f *FakeTagServiceManager) AttachResourceTags(ctx context.Context, rscType resourceType, rscProject, rscName, rscLocation, reqName string, reqParameters map[string]string) error {
+	rets := f.Called(ctx, rscType, rscProject, rscName, rscLocation, reqName, reqParameters)
 	e, ok := rets[0].(error)
 	if !ok {
 		return nil
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"github.com/google/uuid"
 	filev1beta1 "google.golang.org/api/file/v1beta1"
@@ -42,6 +43,8 @@ const (
 type fakeServiceManager struct {
 	createdInstances          map[string]*ServiceInstance
 	backups                   map[string]*Backup
+	snapshots                 map[string]*filev1beta1.Snapshot
+	instanceOps               map[string]*filev1beta1.Operation
 	createdMultishareInstance map[string]*MultishareInstance
 	createdMultishares        map[string]*Share
 	multishareops             []*filev1beta1multishare.Operation
@@ -54,6 +57,8 @@ func NewFakeService() (Service, error) {
 	return &fakeServiceManager{
 		createdInstances:          map[string]*ServiceInstance{},
 		backups:                   map[string]*Backup{},
+		snapshots:                 map[string]*filev1beta1.Snapshot{},
+		instanceOps:               map[string]*filev1beta1.Operation{},
 		createdMultishareInstance: make(map[string]*MultishareInstance),
 		createdMultishares:        make(map[string]*Share),
 		allocatedShares:           make(map[string]*PoolShare),
@@ -64,6 +69,8 @@ func NewFakeServiceForMultishare(instances []*MultishareInstance, shares []*Shar
 	s := &fakeServiceManager{
 		createdInstances:          map[string]*ServiceInstance{},
 		backups:                   map[string]*Backup{},
+		snapshots:                 map[string]*filev1beta1.Snapshot{},
+		instanceOps:               map[string]*filev1beta1.Operation{},
 		createdMultishareInstance: make(map[string]*MultishareInstance),
 		createdMultishares:        make(map[string]*Share),
 		multishareops:             make([]*filev1beta1multishare.Operation, 0),
@@ -81,8 +88,12 @@ func NewFakeServiceForMultishare(instances []*MultishareInstance, shares []*Shar
 }
 
 func (manager *fakeServiceManager) CreateInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error) {
+	project := defaultProject
+	if obj.Project != "" {
+		project = obj.Project
+	}
 	instance := &ServiceInstance{
-		Project:  defaultProject,
+		Project:  project,
 		Location: defaultZone,
 		Name:     obj.Name,
 		Tier:     obj.Tier,
@@ -95,18 +106,99 @@ func (manager *fakeServiceManager) CreateInstance(ctx context.Context, obj *Serv
 			Ip:              "1.1.1.1",
 			ReservedIpRange: obj.Network.ReservedIpRange,
 		},
-		Labels:           obj.Labels,
-		State:            "READY",
-		BackupSource:     obj.BackupSource,
-		NfsExportOptions: obj.NfsExportOptions,
-		Protocol:         obj.Protocol,
+		Labels:             obj.Labels,
+		State:              "READY",
+		BackupSource:       obj.BackupSource,
+		NfsExportOptions:   obj.NfsExportOptions,
+		Protocol:           obj.Protocol,
+		DeletionProtection: obj.DeletionProtection,
 	}
 
 	manager.createdInstances[obj.Name] = instance
 	return instance, nil
 }
 
+// StartCreateInstanceOp creates the instance right away and returns a done operation.
+func (manager *fakeServiceManager) StartCreateInstanceOp(ctx context.Context, obj *ServiceInstance) (*filev1beta1.Operation, error) {
+	instance, err := manager.CreateInstance(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	meta := &filev1beta1.OperationMetadata{
+		Target: instanceURI(instance.Project, instance.Location, instance.Name),
+		Verb:   "create",
+	}
+	metaBytes, _ := json.Marshal(meta)
+	op := &filev1beta1.Operation{
+		Name:     "operation-" + uuid.New().String(),
+		Metadata: metaBytes,
+		Done:     true,
+	}
+	manager.instanceOps[op.Name] = op
+	return op, nil
+}
+
+// StartRestoreInstanceOp records the restore of the instance and returns a done operation.
+func (manager *fakeServiceManager) StartRestoreInstanceOp(ctx context.Context, obj *ServiceInstance, snapshotUri string) (*filev1beta1.Operation, error) {
+	instance, ok := manager.createdInstances[obj.Name]
+	if !ok {
+		return nil, notFoundError()
+	}
+	if _, ok := manager.snapshots[snapshotUri]; !ok {
+		return nil, notFoundError()
+	}
+	meta := &filev1beta1.OperationMetadata{
+		Target: instanceURI(instance.Project, instance.Location, instance.Name),
+		Verb:   "restore",
+	}
+	metaBytes, _ := json.Marshal(meta)
+	op := &filev1beta1.Operation{
+		Name:     "operation-" + uuid.New().String(),
+		Metadata: metaBytes,
+		Done:     true,
+	}
+	manager.instanceOps[op.Name] = op
+	return op, nil
+}
+
+func (manager *fakeServiceManager) WaitForInstanceOp(ctx context.Context, opName string) (*filev1beta1.Operation, error) {
+	op, ok := manager.instanceOps[opName]
+	if !ok {
+		return nil, notFoundError()
+	}
+	if !op.Done {
+		return nil, fmt.Errorf("operation %v is not done", opName)
+	}
+	return op, nil
+}
+
+func (manager *fakeServiceManager) ListInstanceOps(ctx context.Context, obj *ServiceInstance, operationType string) ([]*filev1beta1.Operation, error) {
+	var ops []*filev1beta1.Operation
+	for _, op := range manager.instanceOps {
+		ops = append(ops, op)
+	}
+	uri := instanceURI(obj.Project, obj.Location, obj.Name)
+	running, err := ApplyFilter(ops, uri, operationType, false)
+	if err != nil {
+		return nil, err
+	}
+	done, err := ApplyFilter(ops, uri, operationType, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(running, done...), nil
+}
+
 func (manager *fakeServiceManager) DeleteInstance(ctx context.Context, obj *ServiceInstance) error {
+	if instance, ok := manager.createdInstances[obj.Name]; ok && instance.DeletionProtection != nil && instance.DeletionProtection.Enabled {
+		msg := fmt.Sprintf("instance %s cannot be deleted while deletion protection is enabled", obj.Name)
+		return deleteInstanceError(&googleapi.Error{
+			Code:    http.StatusBadRequest,
+			Message: msg,
+			Body:    fmt.Sprintf(`{"error": {"code": %d, "message": %q, "status": "FAILED_PRECONDITION"}}`, http.StatusBadRequest, msg),
+		})
+	}
+	delete(manager.createdInstances, obj.Name)
 	return nil
 }
 
@@ -149,6 +241,9 @@ func (manager *fakeServiceManager) ListInstances(ctx context.Context, obj *Servi
 			State: "READY",
 		},
 	}
+	for _, instance := range manager.createdInstances {
+		instances = append(instances, instance)
+	}
 	return instances, nil
 }
 
@@ -159,6 +254,8 @@ func (manager *fakeServiceManager) ResizeInstance(ctx context.Context, obj *Serv
 	}
 
 	instance.Volume.SizeBytes = obj.Volume.SizeBytes
+	// Like the file_shares update mask, the resize replaces the NFS export options too.
+	instance.NfsExportOptions = obj.NfsExportOptions
 	manager.createdInstances[obj.Name] = instance
 	return instance, nil
 }
@@ -173,6 +270,30 @@ func (manager *fakeServiceManager) UpdateInstancePerformance(ctx context.Context
 	return nil
 }
 
+func (manager *fakeServiceManager) UpdateInstanceNfsExportOptions(ctx context.Context, obj *ServiceInstance, options []*NfsExportOptions) error {
+	if len(options) == 0 {
+		return fmt.Errorf("nfs export options cannot be empty")
+	}
+	instance, ok := manager.createdInstances[obj.Name]
+	if !ok {
+		return notFoundError()
+	}
+	instance.NfsExportOptions = options
+	return nil
+}
+
+func (manager *fakeServiceManager) UpdateInstanceDeletionProtection(ctx context.Context, obj *ServiceInstance, protection *DeletionProtection) error {
+	if protection == nil {
+		return fmt.Errorf("deletion protection cannot be nil")
+	}
+	instance, ok := manager.createdInstances[obj.Name]
+	if !ok {
+		return notFoundError()
+	}
+	instance.DeletionProtection = &DeletionProtection{Enabled: protection.Enabled, Reason: protection.Reason}
+	return nil
+}
+
 func (manager *fakeServiceManager) CreateBackup(ctx context.Context, backupInfo *BackupInfo) (*filev1beta1.Backup, error) {
 	if backupInfo.SourceInstanceName == "" || backupInfo.SourceShare == "" || backupInfo.SourceVolumeId == "" || backupInfo.BackupURI == "" {
 		return nil, fmt.Errorf("BackupInfo fields are not set %+v", backupInfo)
@@ -207,6 +328,23 @@ func (manager *fakeServiceManager) CreateBackup(ctx context.Context, backupInfo
 	return backupToCreate, nil
 }
 
+// StartCreateBackupOp creates the backup right away and returns a done operation.
+func (manager *fakeServiceManager) StartCreateBackupOp(ctx context.Context, backupInfo *BackupInfo) (*filev1beta1.Operation, error) {
+	if _, err := manager.CreateBackup(ctx, backupInfo); err != nil {
+		return nil, err
+	}
+	meta := &filev1beta1.OperationMetadata{
+		Target: backupInfo.BackupURI,
+		Verb:   "create",
+	}
+	metaBytes, _ := json.Marshal(meta)
+	return &filev1beta1.Operation{
+		Name:     "operation-" + uuid.New().String(),
+		Metadata: metaBytes,
+		Done:     true,
+	}, nil
+}
+
 func (manager *fakeServiceManager) DeleteBackup(ctx context.Context, backupName string) error {
 	delete(manager.backups, backupName)
 	return nil
@@ -221,6 +359,60 @@ func (manager *fakeServiceManager) GetBackup(ctx context.Context, backupUri stri
 	return backupInfo, nil
 }
 
+func (manager *fakeServiceManager) ListBackups(ctx context.Context, filter *ListFilter) ([]*Backup, error) {
+	var backups []*Backup
+	for _, backup := range manager.backups {
+		if backup.Backup == nil {
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
+
+func (manager *fakeServiceManager) GetInstanceSnapshot(ctx context.Context, snapshotUri string) (*filev1beta1.Snapshot, error) {
+	snapshot, ok := manager.snapshots[snapshotUri]
+	if !ok {
+		return nil, notFoundError()
+	}
+	return snapshot, nil
+}
+
+func (manager *fakeServiceManager) ListInstanceSnapshots(ctx context.Context, obj *ServiceInstance) ([]*filev1beta1.Snapshot, error) {
+	prefix := instanceURI(obj.Project, obj.Location, obj.Name) + "/snapshots/"
+	var snapshots []*filev1beta1.Snapshot
+	for uri, snapshot := range manager.snapshots {
+		if strings.HasPrefix(uri, prefix) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+func (manager *fakeServiceManager) CreateInstanceSnapshot(ctx context.Context, snapshotInfo *SnapshotInfo) (*filev1beta1.Snapshot, error) {
+	if snapshotInfo.SourceInstanceName == "" || snapshotInfo.SnapshotURI == "" {
+		return nil, fmt.Errorf("SnapshotInfo fields are not set %+v", snapshotInfo)
+	}
+	if snapshot, ok := manager.snapshots[snapshotInfo.SnapshotURI]; ok {
+		return snapshot, nil
+	}
+
+	snapshot := &filev1beta1.Snapshot{
+		Name:                snapshotInfo.SnapshotURI,
+		CreateTime:          "2020-10-02T15:01:23Z",
+		State:               "READY",
+		FilesystemUsedBytes: 1024,
+		Labels:              snapshotInfo.Labels,
+	}
+	manager.snapshots[snapshotInfo.SnapshotURI] = snapshot
+	return snapshot, nil
+}
+
+func (manager *fakeServiceManager) DeleteInstanceSnapshot(ctx context.Context, snapshotUri string) error {
+	delete(manager.snapshots, snapshotUri)
+	return nil
+}
+
 func (m *fakeServiceManager) HasOperations(ctx context.Context, obj *ServiceInstance, operationType string, done bool) (bool, error) {
 	return false, nil
 }
@@ -247,6 +439,8 @@ func NewFakeBlockingService(operationUnblocker chan chan struct{}) (Service, err
 		fakeServiceManager: &fakeServiceManager{
 			createdInstances: map[string]*ServiceInstance{},
 			backups:          map[string]*Backup{},
+			snapshots:        map[string]*filev1beta1.Snapshot{},
+			instanceOps:      map[string]*filev1beta1.Operation{},
 		},
 		OperationUnblocker: operationUnblocker,
 	}, nil
@@ -259,6 +453,13 @@ func (m *fakeBlockingServiceManager) CreateInstance(ctx context.Context, obj *Se
 	return m.fakeServiceManager.CreateInstance(ctx, obj)
 }
 
+func (m *fakeBlockingServiceManager) StartCreateInstanceOp(ctx context.Context, obj *ServiceInstance) (*filev1beta1.Operation, error) {
+	execute := make(chan struct{})
+	m.OperationUnblocker <- execute
+	<-execute
+	return m.fakeServiceManager.StartCreateInstanceOp(ctx, obj)
+}
+
 func (m *fakeBlockingServiceManager) DeleteInstance(ctx context.Context, obj *ServiceInstance) error {
 	execute := make(chan struct{})
 	m.OperationUnblocker <- execute
@@ -312,6 +513,8 @@ func (manager *fakeServiceManager) StartCreateMultishareInstanceOp(ctx context.C
 		Network:       obj.Network,
 		KmsKeyName:    obj.KmsKeyName,
 		Labels:        obj.Labels,
+		Protocol:      obj.Protocol,
+		MaxShareCount: obj.MaxShareCount,
 		State:         "READY",
 	}
 	manager.createdMultishareInstance[obj.Name] = instance
@@ -437,6 +640,25 @@ func (manager *fakeServiceManager) StartResizeShareOp(ctx context.Context, obj *
 	return op, nil
 }
 
+func (manager *fakeServiceManager) StartUpdateShareNfsExportOptionsOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error) {
+	share, ok := manager.createdMultishares[obj.Name]
+	if !ok {
+		return nil, notFoundError()
+	}
+	share.NfsExportOptions = obj.NfsExportOptions
+	meta := &filev1beta1multishare.OperationMetadata{
+		Target: fmt.Sprintf(shareURIFmt, obj.Parent.Project, obj.Parent.Location, obj.Parent.Name, obj.Name),
+		Verb:   "update",
+	}
+	metaBytes, _ := json.Marshal(meta)
+	op := &filev1beta1multishare.Operation{
+		Name:     "operation-" + uuid.New().String(),
+		Metadata: metaBytes,
+	}
+
+	return op, nil
+}
+
 func (manager *fakeServiceManager) WaitForOpWithOpts(ctx context.Context, op string, opts PollOpts) error {
 	return nil
 }
@@ -464,6 +686,9 @@ func (manager *fakeServiceManager) GetShare(ctx context.Context, obj *Share) (*S
 func (manager *fakeServiceManager) ListShares(ctx context.Context, filter *ListFilter) ([]*Share, error) {
 	var slist []*Share
 	for _, v := range manager.createdMultishares {
+		if filter != nil && filter.InstanceName != "" && filter.InstanceName != "-" && v.Parent != nil && v.Parent.Name != filter.InstanceName {
+			continue
+		}
 		slist = append(slist, v)
 	}
 	return slist, nil
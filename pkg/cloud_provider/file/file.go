@@ -124,6 +124,13 @@ type ServiceInstance struct {
 	NfsExportOptions  []*NfsExportOptions
 	Protocol          string
 	PerformanceConfig *PerformanceConfig
+	// DeletionProtection, if set, prevents the instance from being deleted.
+	DeletionProtection *DeletionProtection
+}
+
+type DeletionProtection struct {
+	Enabled bool
+	Reason  string
 }
 
 type Volume struct {
@@ -158,10 +165,24 @@ type BackupInfo struct {
 	Labels             map[string]string
 }
 
+// SnapshotInfo describes an in-place snapshot of a single share instance.
+type SnapshotInfo struct {
+	Name               string
+	SourceVolumeId     string
+	SnapshotURI        string
+	SourceInstanceName string
+	Project            string
+	Location           string
+	Labels             map[string]string
+}
+
 func (bi *BackupInfo) SourceVolumeLocation() string {
 	splitId := strings.Split(bi.SourceVolumeId, "/")
+	if len(splitId) == util.MultishareCSIVolIdSplitLen {
+		// Format: "modeMultishare/scprefix/myproject/us-central1/myinstance/myshare",
+		return splitId[3]
+	}
 	// Format: "modeInstance/us-central1/myinstance/myshare",
-
 	return splitId[1]
 }
 
@@ -175,14 +196,26 @@ func (bi *BackupInfo) BackupSource() string {
 
 type Service interface {
 	CreateInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error)
+	StartCreateInstanceOp(ctx context.Context, obj *ServiceInstance) (*filev1beta1.Operation, error)
+	WaitForInstanceOp(ctx context.Context, opName string) (*filev1beta1.Operation, error)
+	ListInstanceOps(ctx context.Context, obj *ServiceInstance, operationType string) ([]*filev1beta1.Operation, error)
 	DeleteInstance(ctx context.Context, obj *ServiceInstance) error
 	GetInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error)
 	ListInstances(ctx context.Context, obj *ServiceInstance) ([]*ServiceInstance, error)
 	ResizeInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error)
+	StartRestoreInstanceOp(ctx context.Context, obj *ServiceInstance, snapshotUri string) (*filev1beta1.Operation, error)
 	UpdateInstancePerformance(ctx context.Context, obj *ServiceInstance, perfConfig *PerformanceConfig) error
+	UpdateInstanceNfsExportOptions(ctx context.Context, obj *ServiceInstance, options []*NfsExportOptions) error
+	UpdateInstanceDeletionProtection(ctx context.Context, obj *ServiceInstance, protection *DeletionProtection) error
 	GetBackup(ctx context.Context, backupUri string) (*Backup, error)
 	CreateBackup(ctx context.Context, backupInfo *BackupInfo) (*filev1beta1.Backup, error)
+	StartCreateBackupOp(ctx context.Context, backupInfo *BackupInfo) (*filev1beta1.Operation, error)
 	DeleteBackup(ctx context.Context, backupId string) error
+	ListBackups(ctx context.Context, filter *ListFilter) ([]*Backup, error)
+	GetInstanceSnapshot(ctx context.Context, snapshotUri string) (*filev1beta1.Snapshot, error)
+	ListInstanceSnapshots(ctx context.Context, obj *ServiceInstance) ([]*filev1beta1.Snapshot, error)
+	CreateInstanceSnapshot(ctx context.Context, snapshotInfo *SnapshotInfo) (*filev1beta1.Snapshot, error)
+	DeleteInstanceSnapshot(ctx context.Context, snapshotUri string) error
 	HasOperations(ctx context.Context, obj *ServiceInstance, operationType string, done bool) (bool, error)
 	// Multishare ops
 	GetMultishareInstance(ctx context.Context, obj *MultishareInstance) (*MultishareInstance, error)
@@ -195,6 +228,7 @@ type Service interface {
 	StartCreateShareOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error)
 	StartDeleteShareOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error)
 	StartResizeShareOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error)
+	StartUpdateShareNfsExportOptionsOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error)
 	WaitForOpWithOpts(ctx context.Context, op string, opts PollOpts) error
 	GetOp(ctx context.Context, op string) (*filev1beta1multishare.Operation, error)
 	IsOpDone(op *filev1beta1multishare.Operation) (bool, error)
@@ -210,6 +244,7 @@ type gcfsServiceManager struct {
 	instancesService  *filev1beta1.ProjectsLocationsInstancesService
 	operationsService *filev1beta1.ProjectsLocationsOperationsService
 	backupService     *filev1beta1.ProjectsLocationsBackupsService
+	snapshotsService  *filev1beta1.ProjectsLocationsInstancesSnapshotsService
 
 	// multishare definitions
 	fileMultishareService            *filev1beta1multishare.Service
@@ -223,12 +258,15 @@ const (
 	instanceURIFmt  = locationURIFmt + "/instances/%s"
 	operationURIFmt = locationURIFmt + "/operations/%s"
 	backupURIFmt    = locationURIFmt + "/backups/%s"
+	snapshotURIFmt  = instanceURIFmt + "/snapshots/%s"
 	shareSuffixFmt  = "/shares/%s"
 	shareURIFmt     = instanceURIFmt + shareSuffixFmt
 	// Patch update masks
-	fileShareUpdateMask          = "file_shares"
-	multishareCapacityUpdateMask = "capacity_gb"
-	prodBasePath                 = "https://file.googleapis.com/"
+	fileShareUpdateMask                  = "file_shares"
+	multishareCapacityUpdateMask         = "capacity_gb"
+	multishareNfsExportOptionsUpdateMask = "nfs_export_options"
+	deletionProtectionUpdateMask         = "deletion_protection_enabled,deletion_protection_reason"
+	prodBasePath                         = "https://file.googleapis.com/"
 )
 
 var _ Service = &gcfsServiceManager{}
@@ -284,6 +322,7 @@ func NewGCFSService(version string, client *http.Client, primaryFilestoreService
 		instancesService:                 filev1beta1.NewProjectsLocationsInstancesService(fileService),
 		operationsService:                filev1beta1.NewProjectsLocationsOperationsService(fileService),
 		backupService:                    filev1beta1.NewProjectsLocationsBackupsService(fileService),
+		snapshotsService:                 filev1beta1.NewProjectsLocationsInstancesSnapshotsService(fileService),
 		fileMultishareService:            fileMultishareService,
 		multishareInstancesService:       filev1beta1multishare.NewProjectsLocationsInstancesService(fileMultishareService),
 		multishareInstancesSharesService: filev1beta1multishare.NewProjectsLocationsInstancesSharesService(fileMultishareService),
@@ -292,6 +331,28 @@ func NewGCFSService(version string, client *http.Client, primaryFilestoreService
 }
 
 func (manager *gcfsServiceManager) CreateInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error) {
+	op, err := manager.StartCreateInstanceOp(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.V(4).Infof("For instance %v, waiting for create instance op %v to complete", obj.Name, op.Name)
+	err = manager.waitForOp(ctx, op)
+	if err != nil {
+		klog.Errorf("WaitFor CreateInstance op %s failed: %v", op.Name, err)
+		return nil, common.NewTemporaryError(codes.Unavailable, fmt.Errorf("unknown error when polling the operation: %w", err))
+	}
+	serviceInstance, err := manager.GetInstance(ctx, obj)
+	if err != nil {
+		klog.Errorf("failed to get instance after creation: %v", err)
+		return nil, err
+	}
+	return serviceInstance, nil
+}
+
+// StartCreateInstanceOp starts the creation of a Filestore instance and returns the
+// long-running operation without waiting for it.
+func (manager *gcfsServiceManager) StartCreateInstanceOp(ctx context.Context, obj *ServiceInstance) (*filev1beta1.Operation, error) {
 	instance := &filev1beta1.Instance{
 		Tier: obj.Tier,
 		FileShares: []*filev1beta1.FileShareConfig{
@@ -315,6 +376,10 @@ func (manager *gcfsServiceManager) CreateInstance(ctx context.Context, obj *Serv
 		State:      obj.State,
 		Protocol:   obj.Protocol,
 	}
+	if obj.DeletionProtection != nil {
+		instance.DeletionProtectionEnabled = obj.DeletionProtection.Enabled
+		instance.DeletionProtectionReason = obj.DeletionProtection.Reason
+	}
 
 	// Add performance config if provided. Only one of FixedIOPS or IOPSPerTB
 	// may be set at a time.
@@ -353,19 +418,8 @@ func (manager *gcfsServiceManager) CreateInstance(ctx context.Context, obj *Serv
 		klog.Errorf("CreateInstance operation failed for instance %v: %v", obj.Name, err)
 		return nil, err
 	}
-
-	klog.V(4).Infof("For instance %v, waiting for create instance op %v to complete", obj.Name, op.Name)
-	err = manager.waitForOp(ctx, op)
-	if err != nil {
-		klog.Errorf("WaitFor CreateInstance op %s failed: %v", op.Name, err)
-		return nil, common.NewTemporaryError(codes.Unavailable, fmt.Errorf("unknown error when polling the operation: %w", err))
-	}
-	serviceInstance, err := manager.GetInstance(ctx, obj)
-	if err != nil {
-		klog.Errorf("failed to get instance after creation: %v", err)
-		return nil, err
-	}
-	return serviceInstance, nil
+	klog.Infof("Started create instance op %s for instance %q", op.Name, obj.Name)
+	return op, nil
 }
 
 func (manager *gcfsServiceManager) GetInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error) {
@@ -407,6 +461,13 @@ func cloudInstanceToServiceInstance(instance *filev1beta1.Instance) (*ServiceIns
 		}
 		perfCfg = perf
 	}
+	var deletionProtection *DeletionProtection
+	if instance.DeletionProtectionEnabled || instance.DeletionProtectionReason != "" {
+		deletionProtection = &DeletionProtection{
+			Enabled: instance.DeletionProtectionEnabled,
+			Reason:  instance.DeletionProtectionReason,
+		}
+	}
 
 	return &ServiceInstance{
 		Project:  project,
@@ -423,12 +484,14 @@ func cloudInstanceToServiceInstance(instance *filev1beta1.Instance) (*ServiceIns
 			ReservedIpRange: instance.Networks[0].ReservedIpRange,
 			ConnectMode:     instance.Networks[0].ConnectMode,
 		},
-		KmsKeyName:        instance.KmsKeyName,
-		Labels:            instance.Labels,
-		State:             instance.State,
-		BackupSource:      instance.FileShares[0].SourceBackup,
-		Protocol:          instance.Protocol,
-		PerformanceConfig: perfCfg,
+		KmsKeyName:         instance.KmsKeyName,
+		Labels:             instance.Labels,
+		State:              instance.State,
+		BackupSource:       instance.FileShares[0].SourceBackup,
+		NfsExportOptions:   cloudNfsExportOptionsToNfsExportOptions(instance.FileShares[0].NfsExportOptions),
+		Protocol:           instance.Protocol,
+		PerformanceConfig:  perfCfg,
+		DeletionProtection: deletionProtection,
 	}, nil
 }
 
@@ -462,7 +525,7 @@ func (manager *gcfsServiceManager) DeleteInstance(ctx context.Context, obj *Serv
 	klog.V(4).Infof("Starting DeleteInstance cloud operation for instance %s", uri)
 	op, err := manager.instancesService.Delete(uri).Context(ctx).Do()
 	if err != nil {
-		return fmt.Errorf("DeleteInstance operation failed: %w", err)
+		return deleteInstanceError(err)
 	}
 
 	klog.V(4).Infof("For instance %s, waiting for delete op %v to complete", uri, op.Name)
@@ -514,16 +577,35 @@ func (manager *gcfsServiceManager) ListInstances(ctx context.Context, obj *Servi
 	return activeInstances, nil
 }
 
+// StartRestoreInstanceOp starts restoring the file share of the instance from the given
+// instance snapshot and returns the long-running operation without waiting for it.
+func (manager *gcfsServiceManager) StartRestoreInstanceOp(ctx context.Context, obj *ServiceInstance, snapshotUri string) (*filev1beta1.Operation, error) {
+	instanceuri := instanceURI(obj.Project, obj.Location, obj.Name)
+	req := &filev1beta1.RestoreInstanceRequest{
+		FileShare:      obj.Volume.Name,
+		SourceSnapshot: snapshotUri,
+	}
+	klog.V(4).Infof("Restoring file share %q of instance %q from snapshot %q", obj.Volume.Name, instanceuri, snapshotUri)
+	op, err := manager.instancesService.Restore(instanceuri, req).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("restore operation failed: %w", err)
+	}
+	return op, nil
+}
+
 func (manager *gcfsServiceManager) ResizeInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error) {
 	instanceuri := instanceURI(obj.Project, obj.Location, obj.Name)
 	// Create a file instance for the Patch request.
 	betaObj := &filev1beta1.Instance{
 		Tier: obj.Tier,
+		// The file_shares update mask replaces the whole file share config, so the current
+		// NFS export options are sent along with the new size to keep them.
 		FileShares: []*filev1beta1.FileShareConfig{
 			{
 				Name: obj.Volume.Name,
 				// This is the updated instance size requested.
-				CapacityGb: util.BytesToGb(obj.Volume.SizeBytes),
+				CapacityGb:       util.BytesToGb(obj.Volume.SizeBytes),
+				NfsExportOptions: extractNfsShareExportOptions(obj.NfsExportOptions),
 			},
 		},
 		Networks: []*filev1beta1.NetworkConfig{
@@ -616,6 +698,71 @@ func (manager *gcfsServiceManager) UpdateInstancePerformance(ctx context.Context
 	return nil
 }
 
+// UpdateInstanceNfsExportOptions replaces the NFS export options of the file share of a Filestore instance.
+func (manager *gcfsServiceManager) UpdateInstanceNfsExportOptions(ctx context.Context, obj *ServiceInstance, options []*NfsExportOptions) error {
+	if len(options) == 0 {
+		return fmt.Errorf("nfs export options cannot be empty")
+	}
+
+	instanceuri := instanceURI(obj.Project, obj.Location, obj.Name)
+	// The file_shares update mask replaces the whole file share config, so the
+	// current share name and capacity are sent along with the new options.
+	betaObj := &filev1beta1.Instance{
+		FileShares: []*filev1beta1.FileShareConfig{
+			{
+				Name:             obj.Volume.Name,
+				CapacityGb:       util.BytesToGb(obj.Volume.SizeBytes),
+				NfsExportOptions: extractNfsShareExportOptions(options),
+			},
+		},
+	}
+
+	klog.V(4).Infof("Patching instance %q with nfs export options %+v", obj.Name, betaObj.FileShares[0].NfsExportOptions)
+	op, err := manager.instancesService.Patch(instanceuri, betaObj).UpdateMask(fileShareUpdateMask).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("patch operation failed for nfs export options update: %w", err)
+	}
+
+	klog.V(4).Infof("For instance %s, waiting for nfs export options update op %v to complete", instanceuri, op.Name)
+	err = manager.waitForOp(ctx, op)
+	if err != nil {
+		return fmt.Errorf("WaitFor nfs export options update op %s failed: %w", op.Name, err)
+	}
+
+	klog.Infof("Successfully updated nfs export options for instance %q", obj.Name)
+	return nil
+}
+
+// UpdateInstanceDeletionProtection enables or disables the deletion protection of the instance.
+func (manager *gcfsServiceManager) UpdateInstanceDeletionProtection(ctx context.Context, obj *ServiceInstance, protection *DeletionProtection) error {
+	if protection == nil {
+		return fmt.Errorf("deletion protection cannot be nil")
+	}
+
+	instanceuri := instanceURI(obj.Project, obj.Location, obj.Name)
+	betaObj := &filev1beta1.Instance{
+		DeletionProtectionEnabled: protection.Enabled,
+		DeletionProtectionReason:  protection.Reason,
+		// Disabling the protection clears the reason.
+		ForceSendFields: []string{"DeletionProtectionEnabled", "DeletionProtectionReason"},
+	}
+
+	klog.V(4).Infof("Patching instance %q with deletion protection enabled %v, reason %q", obj.Name, protection.Enabled, protection.Reason)
+	op, err := manager.instancesService.Patch(instanceuri, betaObj).UpdateMask(deletionProtectionUpdateMask).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("patch operation failed for deletion protection update: %w", err)
+	}
+
+	klog.V(4).Infof("For instance %s, waiting for deletion protection update op %v to complete", instanceuri, op.Name)
+	err = manager.waitForOp(ctx, op)
+	if err != nil {
+		return fmt.Errorf("WaitFor deletion protection update op %s failed: %w", op.Name, err)
+	}
+
+	klog.Infof("Successfully updated deletion protection for instance %q", obj.Name)
+	return nil
+}
+
 func (manager *gcfsServiceManager) GetBackup(ctx context.Context, backupUri string) (*Backup, error) {
 	backup, err := manager.backupService.Get(backupUri).Context(ctx).Do()
 	if err != nil {
@@ -630,17 +777,8 @@ func (manager *gcfsServiceManager) GetBackup(ctx context.Context, backupUri stri
 }
 
 func (manager *gcfsServiceManager) CreateBackup(ctx context.Context, backupInfo *BackupInfo) (*filev1beta1.Backup, error) {
-
-	backupobj := &filev1beta1.Backup{
-		SourceInstance:  backupInfo.BackupSource(),
-		SourceFileShare: backupInfo.SourceShare,
-		Labels:          backupInfo.Labels,
-	}
-	klog.V(4).Infof("Creating backup object %+v for the URI %v", *backupobj, backupInfo.BackupURI)
-	opbackup, err := manager.backupService.Create(locationURI(backupInfo.Project, backupInfo.Location), backupobj).BackupId(backupInfo.Name).Context(ctx).Do()
-
+	opbackup, err := manager.StartCreateBackupOp(ctx, backupInfo)
 	if err != nil {
-		klog.Errorf("Create Backup operation failed: %v", err)
 		return nil, err
 	}
 
@@ -661,6 +799,23 @@ func (manager *gcfsServiceManager) CreateBackup(ctx context.Context, backupInfo
 	return backupObj, nil
 }
 
+// StartCreateBackupOp starts the creation of a Filestore backup and returns the long-running
+// operation without waiting for it. The backup is in the CREATING state until it completes.
+func (manager *gcfsServiceManager) StartCreateBackupOp(ctx context.Context, backupInfo *BackupInfo) (*filev1beta1.Operation, error) {
+	backupobj := &filev1beta1.Backup{
+		SourceInstance:  backupInfo.BackupSource(),
+		SourceFileShare: backupInfo.SourceShare,
+		Labels:          backupInfo.Labels,
+	}
+	klog.V(4).Infof("Creating backup object %+v for the URI %v", *backupobj, backupInfo.BackupURI)
+	opbackup, err := manager.backupService.Create(locationURI(backupInfo.Project, backupInfo.Location), backupobj).BackupId(backupInfo.Name).Context(ctx).Do()
+	if err != nil {
+		klog.Errorf("Create Backup operation failed: %v", err)
+		return nil, err
+	}
+	return opbackup, nil
+}
+
 func (manager *gcfsServiceManager) DeleteBackup(ctx context.Context, backupId string) error {
 	opbackup, err := manager.backupService.Delete(backupId).Context(ctx).Do()
 	if err != nil {
@@ -677,6 +832,101 @@ func (manager *gcfsServiceManager) DeleteBackup(ctx context.Context, backupId st
 	return nil
 }
 
+// ListBackups lists the backups in the filter location, "-" indicates all the locations of the project.
+func (manager *gcfsServiceManager) ListBackups(ctx context.Context, filter *ListFilter) ([]*Backup, error) {
+	lCall := manager.backupService.List(locationURI(filter.Project, filter.Location)).Context(ctx)
+	nextPageToken := "pageToken"
+	var backups []*Backup
+
+	for nextPageToken != "" {
+		resp, err := lCall.Do()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, backup := range resp.Backups {
+			backups = append(backups, &Backup{
+				Backup:            backup,
+				SourceInstance:    backup.SourceInstance,
+				SourceShare:       backup.SourceFileShare,
+				FileSystemProtocl: backup.FileSystemProtocol,
+			})
+		}
+
+		nextPageToken = resp.NextPageToken
+		lCall.PageToken(nextPageToken)
+	}
+	return backups, nil
+}
+
+func (manager *gcfsServiceManager) GetInstanceSnapshot(ctx context.Context, snapshotUri string) (*filev1beta1.Snapshot, error) {
+	return manager.snapshotsService.Get(snapshotUri).Context(ctx).Do()
+}
+
+// ListInstanceSnapshots lists the snapshots of the instance.
+func (manager *gcfsServiceManager) ListInstanceSnapshots(ctx context.Context, obj *ServiceInstance) ([]*filev1beta1.Snapshot, error) {
+	lCall := manager.snapshotsService.List(instanceURI(obj.Project, obj.Location, obj.Name)).Context(ctx)
+	nextPageToken := "pageToken"
+	var snapshots []*filev1beta1.Snapshot
+
+	for nextPageToken != "" {
+		resp, err := lCall.Do()
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, resp.Snapshots...)
+
+		nextPageToken = resp.NextPageToken
+		lCall.PageToken(nextPageToken)
+	}
+	return snapshots, nil
+}
+
+func (manager *gcfsServiceManager) CreateInstanceSnapshot(ctx context.Context, snapshotInfo *SnapshotInfo) (*filev1beta1.Snapshot, error) {
+	snapshotobj := &filev1beta1.Snapshot{
+		Labels: snapshotInfo.Labels,
+	}
+	instanceuri := instanceURI(snapshotInfo.Project, snapshotInfo.Location, snapshotInfo.SourceInstanceName)
+	klog.V(4).Infof("Creating snapshot object %+v for the URI %v", *snapshotobj, snapshotInfo.SnapshotURI)
+	opsnapshot, err := manager.snapshotsService.Create(instanceuri, snapshotobj).SnapshotId(snapshotInfo.Name).Context(ctx).Do()
+	if err != nil {
+		klog.Errorf("Create Snapshot operation failed: %v", err)
+		return nil, err
+	}
+
+	klog.V(4).Infof("For snapshot uri %s, waiting for snapshot op %v to complete", snapshotInfo.SnapshotURI, opsnapshot.Name)
+	err = manager.waitForOp(ctx, opsnapshot)
+	if err != nil {
+		return nil, fmt.Errorf("WaitFor CreateSnapshot op %s for source instance %v, snapshot uri: %v, operation failed: %w", opsnapshot.Name, instanceuri, snapshotInfo.SnapshotURI, err)
+	}
+
+	snapshotObj, err := manager.snapshotsService.Get(snapshotInfo.SnapshotURI).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if snapshotObj.State != "READY" {
+		return nil, fmt.Errorf("snapshot %v for source %v is not ready, current state: %v", snapshotInfo.SnapshotURI, instanceuri, snapshotObj.State)
+	}
+	klog.Infof("Successfully created snapshot %+v for source instance %v", snapshotObj, instanceuri)
+	return snapshotObj, nil
+}
+
+func (manager *gcfsServiceManager) DeleteInstanceSnapshot(ctx context.Context, snapshotUri string) error {
+	opsnapshot, err := manager.snapshotsService.Delete(snapshotUri).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("delete snapshot operation for snapshot %s failed: %w", snapshotUri, err)
+	}
+
+	klog.V(4).Infof("For snapshot %s, waiting for snapshot op %v to complete", snapshotUri, opsnapshot.Name)
+	err = manager.waitForOp(ctx, opsnapshot)
+	if err != nil {
+		return fmt.Errorf("delete snapshot: %v, op %s failed: %w", snapshotUri, opsnapshot.Name, err)
+	}
+
+	klog.Infof("Snapshot %v successfully deleted", snapshotUri)
+	return nil
+}
+
 func (manager *gcfsServiceManager) waitForOp(ctx context.Context, op *filev1beta1.Operation) error {
 	return wait.Poll(5*time.Second, 5*time.Minute, func() (bool, error) {
 		pollOp, err := manager.operationsService.Get(op.Name).Context(ctx).Do()
@@ -687,6 +937,35 @@ func (manager *gcfsServiceManager) waitForOp(ctx context.Context, op *filev1beta
 	})
 }
 
+// WaitForInstanceOp polls the named operation until it is done and returns its final state.
+// A failed operation is not returned as an error, use OperationError to get its error.
+func (manager *gcfsServiceManager) WaitForInstanceOp(ctx context.Context, opName string) (*filev1beta1.Operation, error) {
+	err := manager.waitForOp(ctx, &filev1beta1.Operation{Name: opName})
+	op, getErr := manager.operationsService.Get(opName).Context(ctx).Do()
+	if getErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, getErr
+	}
+	if !op.Done {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("operation %v is not done", opName)
+	}
+	return op, nil
+}
+
+// OperationError returns the error of a failed operation as a status error carrying the
+// operation's error code, or nil if the operation did not fail.
+func OperationError(op *filev1beta1.Operation) error {
+	if op == nil || op.Error == nil {
+		return nil
+	}
+	return status.Errorf(codes.Code(op.Error.Code), "operation %v failed (%v): %v", op.Name, op.Error.Code, op.Error.Message)
+}
+
 // TODO: unify this function behavior with IsOpDone
 func isOpDone(op *filev1beta1.Operation) (bool, error) {
 	if op == nil {
@@ -796,9 +1075,46 @@ func isFilestoreLimitError(err error) *codes.Code {
 	if strings.Contains(err.Error(), "System limit for internal resources has been reached") {
 		return util.ErrCodePtr(codes.ResourceExhausted)
 	}
+	// Stockouts are reported as failed operations.
+	if strings.Contains(err.Error(), "does not have enough resources available to fulfill the request") {
+		return util.ErrCodePtr(codes.ResourceExhausted)
+	}
 	return nil
 }
 
+// deleteInstanceError returns the error of a rejected DeleteInstance request. Filestore
+// rejects the deletion of an instance with deletion protection enabled with the http code 400
+// and the FAILED_PRECONDITION status, which is returned as FailedPrecondition rather than
+// InvalidArgument so that the CO does not treat the request itself as invalid.
+func deleteInstanceError(err error) error {
+	if isFailedPreconditionError(err) {
+		return status.Errorf(codes.FailedPrecondition, "DeleteInstance operation failed: %v", err)
+	}
+	return fmt.Errorf("DeleteInstance operation failed: %w", err)
+}
+
+// isFailedPreconditionError returns true if the googleapi error has the FAILED_PRECONDITION status.
+func isFailedPreconditionError(err error) bool {
+	var googleErr *googleapi.Error
+	if !errors.As(err, &googleErr) {
+		return false
+	}
+	if googleErr.Code == http.StatusPreconditionFailed {
+		return true
+	}
+	for _, item := range googleErr.Errors {
+		if item.Reason == "failedPrecondition" {
+			return true
+		}
+	}
+	var reply struct {
+		Error struct {
+			Status string `json:"status"`
+		} `json:"error"`
+	}
+	return json.Unmarshal([]byte(googleErr.Body), &reply) == nil && reply.Error.Status == "FAILED_PRECONDITION"
+}
+
 // isContextError returns a pointer to the grpc error code DeadlineExceeded
 // if the passed in error contains the "context deadline exceeded" string and returns
 // the grpc error code Canceled if the error contains the "context canceled" string.
@@ -916,20 +1232,45 @@ func StatusError(err error) error {
 	return status.Error(*codeForError(err), err.Error())
 }
 
-// This function will process an existing backup
+// IsCapacityError returns true if the error means that the location ran out of capacity
+// for the requested instance, e.g. a stockout or a Filestore limit being reached.
+func IsCapacityError(err error) bool {
+	if err == nil {
+		return false
+	}
+	// Rate limited requests should be retried as is, another location would not help.
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+		return false
+	}
+	return *codeForError(err) == codes.ResourceExhausted
+}
+
+// This function will process an existing backup. A backup which is still being created is
+// returned as not ready to use, and a failed backup as an error.
 func ProcessExistingBackup(ctx context.Context, backup *Backup, volumeID string, mode string) (*csi.Snapshot, error) {
 	backupSourceCSIHandle, err := util.BackupVolumeSourceToCSIVolumeHandle(mode, backup.SourceInstance, backup.SourceShare)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "Cannot determine volume handle from back source instance %s, share %s", backup.SourceInstance, backup.SourceShare)
 	}
+	if backupSourceCSIHandle != volumeID && mode == "modeInstance" {
+		// The volume handle of an instance in another project includes the project.
+		if projectHandle, err := util.BackupVolumeSourceToProjectCSIVolumeHandle(backup.SourceInstance, backup.SourceShare); err == nil && projectHandle == volumeID {
+			backupSourceCSIHandle = projectHandle
+		}
+	}
 	if backupSourceCSIHandle != volumeID {
 		return nil, status.Errorf(codes.AlreadyExists, "Backup already exists with a different source volume %s, input source volume %s", backupSourceCSIHandle, volumeID)
 	}
-	// Check if backup is in the process of getting created.
-	if backup.Backup.State == "CREATING" || backup.Backup.State == "FINALIZING" {
-		return nil, status.Errorf(codes.DeadlineExceeded, "Backup %v not yet ready, current state %s", backup.Backup.Name, backup.Backup.State)
-	}
-	if backup.Backup.State != "READY" {
+	// A backup in the process of getting created is returned as not ready to use.
+	var readyToUse bool
+	switch backup.Backup.State {
+	case "READY":
+		readyToUse = true
+	case "CREATING", "FINALIZING":
+	case "FAILED":
+		return nil, status.Errorf(codes.Internal, "Backup %v failed", backup.Backup.Name)
+	default:
 		return nil, status.Errorf(codes.Internal, "Backup %v not yet ready, current state %s", backup.Backup.Name, backup.Backup.State)
 	}
 	tp, err := util.ParseTimestamp(backup.Backup.CreateTime)
@@ -937,16 +1278,22 @@ func ProcessExistingBackup(ctx context.Context, backup *Backup, volumeID string,
 		err = fmt.Errorf("failed to parse create timestamp for backup %v: %w", backup.Backup.Name, err)
 		return nil, StatusError(err)
 	}
-	klog.V(4).Infof("CreateSnapshot success for volume %v, Backup Id: %v", volumeID, backup.Backup.Name)
+	klog.V(4).Infof("CreateSnapshot success for volume %v, Backup Id: %v, ready %v", volumeID, backup.Backup.Name, readyToUse)
 	return &csi.Snapshot{
 		SizeBytes:      util.GbToBytes(backup.Backup.CapacityGb),
 		SnapshotId:     backup.Backup.Name,
 		SourceVolumeId: volumeID,
 		CreationTime:   tp,
-		ReadyToUse:     true,
+		ReadyToUse:     readyToUse,
 	}, nil
 }
 
+// BackupNotReadyError returns a DeadlineExceeded error for a backup which is not ready to use
+// yet, for the callers which must wait for the backup to complete.
+func BackupNotReadyError(snapshot *csi.Snapshot) error {
+	return status.Errorf(codes.DeadlineExceeded, "Backup %v not yet ready", snapshot.GetSnapshotId())
+}
+
 func CheckBackupExists(backupInfo *Backup, err error) (bool, error) {
 	if err != nil {
 		if !IsNotFoundErr(err) {
@@ -973,6 +1320,12 @@ func CreateBackupURI(serviceLocation, project, backupName, backupLocation string
 	return backupURI(project, region, backupName), region, nil
 }
 
+// CreateInstanceSnapshotURI returns the URI of an in-place snapshot of the given instance.
+// Instance snapshots always live in the location of their instance.
+func CreateInstanceSnapshotURI(project, location, instanceName, snapshotName string) string {
+	return fmt.Sprintf(snapshotURIFmt, project, location, instanceName, snapshotName)
+}
+
 // deduceRegion will either return the provided backupLocation region or deduce
 // from the ServiceInstance
 func deduceRegion(serviceLocation, backupLocation string) (string, error) {
@@ -1024,6 +1377,33 @@ func (manager *gcfsServiceManager) HasOperations(ctx context.Context, obj *Servi
 	return len(totalFilteredOps) > 0, nil
 }
 
+// ListInstanceOps returns the running and finished operations of the given type for the instance.
+func (manager *gcfsServiceManager) ListInstanceOps(ctx context.Context, obj *ServiceInstance, operationType string) ([]*filev1beta1.Operation, error) {
+	uri := instanceURI(obj.Project, obj.Location, obj.Name)
+	var totalFilteredOps []*filev1beta1.Operation
+	var nextToken string
+	for {
+		resp, err := manager.operationsService.List(locationURI(obj.Project, obj.Location)).PageToken(nextToken).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("list operations for instance %q, token %q failed: %w", uri, nextToken, err)
+		}
+
+		for _, done := range []bool{false, true} {
+			filteredOps, err := ApplyFilter(resp.Operations, uri, operationType, done)
+			if err != nil {
+				return nil, err
+			}
+			totalFilteredOps = append(totalFilteredOps, filteredOps...)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		nextToken = resp.NextPageToken
+	}
+
+	return totalFilteredOps, nil
+}
+
 func ApplyFilter(ops []*filev1beta1.Operation, uri string, opType string, done bool) ([]*filev1beta1.Operation, error) {
 	var res []*filev1beta1.Operation
 	for _, op := range ops {
@@ -1184,6 +1564,19 @@ func (manager *gcfsServiceManager) StartResizeShareOp(ctx context.Context, share
 	return op, nil
 }
 
+func (manager *gcfsServiceManager) StartUpdateShareNfsExportOptionsOp(ctx context.Context, share *Share) (*filev1beta1multishare.Operation, error) {
+	uri := shareURI(share.Parent.Project, share.Parent.Location, share.Parent.Name, share.Name)
+	targetShare := &filev1beta1multishare.Share{
+		NfsExportOptions: extractNfsShareExportOptions(share.NfsExportOptions),
+	}
+	op, err := manager.multishareInstancesSharesService.Patch(uri, targetShare).UpdateMask(multishareNfsExportOptionsUpdateMask).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("UpdateShare operation failed: %w", err)
+	}
+	klog.Infof("Started Update Share nfs export options op %s for share uri %q ", op.Name, uri)
+	return op, nil
+}
+
 func (manager *gcfsServiceManager) WaitForOpWithOpts(ctx context.Context, op string, opts PollOpts) error {
 	return wait.Poll(opts.Interval, opts.Timeout, func() (bool, error) {
 		pollOp, err := manager.multishareOperationsServices.Get(op).Context(ctx).Do()
@@ -1218,12 +1611,13 @@ func (manager *gcfsServiceManager) GetShare(ctx context.Context, obj *Share) (*S
 	}
 
 	return &Share{
-		Name:           shareName,
-		Parent:         instance,
-		MountPointName: sobj.MountName,
-		CapacityBytes:  sobj.CapacityGb * util.Gb,
-		State:          sobj.State,
-		Labels:         sobj.Labels,
+		Name:             shareName,
+		Parent:           instance,
+		MountPointName:   sobj.MountName,
+		CapacityBytes:    sobj.CapacityGb * util.Gb,
+		State:            sobj.State,
+		Labels:           sobj.Labels,
+		NfsExportOptions: cloudNfsExportOptionsToNfsExportOptions(sobj.NfsExportOptions),
 	}, nil
 }
 
@@ -1481,6 +1875,21 @@ func extractNfsShareExportOptions(options []*NfsExportOptions) []*filev1beta1mul
 	return filerOpts
 }
 
+func cloudNfsExportOptionsToNfsExportOptions(filerOpts []*filev1beta1multishare.NfsExportOptions) []*NfsExportOptions {
+	var options []*NfsExportOptions
+	for _, opt := range filerOpts {
+		options = append(options,
+			&NfsExportOptions{
+				AccessMode: opt.AccessMode,
+				AnonGid:    opt.AnonGid,
+				AnonUid:    opt.AnonUid,
+				IpRanges:   opt.IpRanges,
+				SquashMode: opt.SquashMode,
+			})
+	}
+	return options
+}
+
 const (
 	betaBasePath = "v1beta1"
 )
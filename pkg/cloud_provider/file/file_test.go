@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -194,6 +195,261 @@ func TestUpdateInstancePerformance(t *testing.T) {
 	}
 }
 
+func TestUpdateInstanceNfsExportOptions(t *testing.T) {
+	ctx := context.Background()
+	mgr := &gcfsServiceManager{}
+
+	// empty options -> error
+	if err := mgr.UpdateInstanceNfsExportOptions(ctx, &ServiceInstance{}, nil); err == nil {
+		t.Fatalf("expected error for empty nfs export options")
+	}
+
+	var captured filev1beta1.Instance
+	var updateMask string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" && strings.Contains(r.URL.Path, "/instances/") {
+			if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			updateMask = r.URL.Query().Get("updateMask")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"projects/proj/locations/loc/operations/op1","done":false}`)
+			return
+		}
+		if r.Method == "GET" && strings.Contains(r.URL.Path, "/operations/") {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"projects/proj/locations/loc/operations/op1","done":true}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	svc, err := filev1beta1.NewService(ctx, option.WithEndpoint(ts.URL+"/"), option.WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("failed to create file service: %v", err)
+	}
+	mgr.instancesService = filev1beta1.NewProjectsLocationsInstancesService(svc)
+	mgr.operationsService = filev1beta1.NewProjectsLocationsOperationsService(svc)
+
+	si := &ServiceInstance{Project: "proj", Location: "loc", Name: "name", Volume: Volume{Name: "vol1", SizeBytes: 1 * util.Tb}}
+	options := []*NfsExportOptions{{AccessMode: "READ_ONLY", IpRanges: []string{"10.0.0.0/24"}}}
+	if err := mgr.UpdateInstanceNfsExportOptions(ctx, si, options); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+
+	if updateMask != fileShareUpdateMask {
+		t.Errorf("expected update mask %q, got %q", fileShareUpdateMask, updateMask)
+	}
+	if len(captured.FileShares) != 1 {
+		t.Fatalf("expected 1 file share in patch request, got %d", len(captured.FileShares))
+	}
+	fs := captured.FileShares[0]
+	if fs.Name != "vol1" || fs.CapacityGb != 1024 {
+		t.Errorf("unexpected file share in patch request: %+v", fs)
+	}
+	if len(fs.NfsExportOptions) != 1 || fs.NfsExportOptions[0].AccessMode != "READ_ONLY" || !reflect.DeepEqual(fs.NfsExportOptions[0].IpRanges, []string{"10.0.0.0/24"}) {
+		t.Errorf("unexpected nfs export options in patch request: %+v", fs.NfsExportOptions)
+	}
+}
+
+func TestResizeInstanceKeepsNfsExportOptions(t *testing.T) {
+	ctx := context.Background()
+	mgr := &gcfsServiceManager{}
+
+	var captured filev1beta1.Instance
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" && strings.Contains(r.URL.Path, "/instances/") {
+			if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"projects/proj/locations/loc/operations/op1","done":false}`)
+			return
+		}
+		if r.Method == "GET" && strings.Contains(r.URL.Path, "/operations/") {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"projects/proj/locations/loc/operations/op1","done":true}`)
+			return
+		}
+		if r.Method == "GET" && strings.Contains(r.URL.Path, "/instances/") {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"projects/proj/locations/loc/instances/name","state":"READY","fileShares":[{"name":"vol1","capacityGb":"2048"}],"networks":[{"network":"default","ipAddresses":["1.1.1.1"]}]}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	svc, err := filev1beta1.NewService(ctx, option.WithEndpoint(ts.URL+"/"), option.WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("failed to create file service: %v", err)
+	}
+	mgr.instancesService = filev1beta1.NewProjectsLocationsInstancesService(svc)
+	mgr.operationsService = filev1beta1.NewProjectsLocationsOperationsService(svc)
+
+	si := &ServiceInstance{
+		Project:          "proj",
+		Location:         "loc",
+		Name:             "name",
+		Volume:           Volume{Name: "vol1", SizeBytes: 2 * util.Tb},
+		NfsExportOptions: []*NfsExportOptions{{AccessMode: "READ_ONLY", IpRanges: []string{"10.0.0.0/24"}, SquashMode: "ROOT_SQUASH"}},
+	}
+	if _, err := mgr.ResizeInstance(ctx, si); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(captured.FileShares) != 1 {
+		t.Fatalf("expected 1 file share in patch request, got %d", len(captured.FileShares))
+	}
+	fs := captured.FileShares[0]
+	if fs.Name != "vol1" || fs.CapacityGb != 2048 {
+		t.Errorf("unexpected file share in patch request: %+v", fs)
+	}
+	// The update mask replaces the whole file share config, the options must be sent along.
+	if len(fs.NfsExportOptions) != 1 || fs.NfsExportOptions[0].AccessMode != "READ_ONLY" || fs.NfsExportOptions[0].SquashMode != "ROOT_SQUASH" || !reflect.DeepEqual(fs.NfsExportOptions[0].IpRanges, []string{"10.0.0.0/24"}) {
+		t.Errorf("unexpected nfs export options in patch request: %+v", fs.NfsExportOptions)
+	}
+}
+
+func TestDeleteInstanceDeletionProtection(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name         string
+		body         string
+		expectedCode codes.Code
+	}{
+		{
+			name:         "deletion protection enabled",
+			body:         `{"error": {"code": 400, "message": "Instance cannot be deleted because Deletion Protection is enabled", "status": "FAILED_PRECONDITION"}}`,
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name:         "invalid argument",
+			body:         `{"error": {"code": 400, "message": "Invalid instance name", "status": "INVALID_ARGUMENT"}}`,
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer ts.Close()
+
+			svc, err := filev1beta1.NewService(ctx, option.WithEndpoint(ts.URL+"/"), option.WithHTTPClient(ts.Client()))
+			if err != nil {
+				t.Fatalf("failed to create file service: %v", err)
+			}
+			mgr := &gcfsServiceManager{instancesService: filev1beta1.NewProjectsLocationsInstancesService(svc)}
+
+			err = mgr.DeleteInstance(ctx, &ServiceInstance{Project: "proj", Location: "loc", Name: "name"})
+			if code := status.Code(StatusError(err)); code != tc.expectedCode {
+				t.Errorf("got error %v with code %v, expected %v", err, code, tc.expectedCode)
+			}
+		})
+	}
+}
+
+func TestUpdateInstanceDeletionProtection(t *testing.T) {
+	ctx := context.Background()
+	mgr := &gcfsServiceManager{}
+
+	if err := mgr.UpdateInstanceDeletionProtection(ctx, &ServiceInstance{}, nil); err == nil {
+		t.Fatalf("expected error for nil deletion protection")
+	}
+
+	var body map[string]interface{}
+	var updateMask string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" && strings.Contains(r.URL.Path, "/instances/") {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			updateMask = r.URL.Query().Get("updateMask")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"projects/proj/locations/loc/operations/op1","done":false}`)
+			return
+		}
+		if r.Method == "GET" && strings.Contains(r.URL.Path, "/operations/") {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"projects/proj/locations/loc/operations/op1","done":true}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	svc, err := filev1beta1.NewService(ctx, option.WithEndpoint(ts.URL+"/"), option.WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("failed to create file service: %v", err)
+	}
+	mgr.instancesService = filev1beta1.NewProjectsLocationsInstancesService(svc)
+	mgr.operationsService = filev1beta1.NewProjectsLocationsOperationsService(svc)
+
+	si := &ServiceInstance{Project: "proj", Location: "loc", Name: "name"}
+	if err := mgr.UpdateInstanceDeletionProtection(ctx, si, &DeletionProtection{}); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if updateMask != deletionProtectionUpdateMask {
+		t.Errorf("expected update mask %q, got %q", deletionProtectionUpdateMask, updateMask)
+	}
+	// Disabling the protection must be sent explicitly.
+	if enabled, ok := body["deletionProtectionEnabled"]; !ok || enabled != false {
+		t.Errorf("expected deletionProtectionEnabled false in patch request, got %+v", body)
+	}
+	if reason, ok := body["deletionProtectionReason"]; !ok || reason != "" {
+		t.Errorf("expected empty deletionProtectionReason in patch request, got %+v", body)
+	}
+}
+
+func TestOperationError(t *testing.T) {
+	cases := []struct {
+		name         string
+		op           *filev1beta1.Operation
+		expectedCode codes.Code
+	}{
+		{
+			name: "nil operation",
+		},
+		{
+			name: "succeeded operation",
+			op:   &filev1beta1.Operation{Name: "op", Done: true},
+		},
+		{
+			name: "failed operation",
+			op: &filev1beta1.Operation{
+				Name:  "op",
+				Done:  true,
+				Error: &filev1beta1.Status{Code: int64(codes.ResourceExhausted), Message: "out of capacity"},
+			},
+			expectedCode: codes.ResourceExhausted,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := OperationError(tc.op)
+			if tc.expectedCode == codes.OK {
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if status.Code(err) != tc.expectedCode {
+				t.Fatalf("expected error code %v, got: %v", tc.expectedCode, err)
+			}
+			// The operation error code is kept when the error is wrapped.
+			if code := status.Code(StatusError(fmt.Errorf("create failed: %w", err))); code != tc.expectedCode {
+				t.Errorf("expected wrapped error code %v, got %v", tc.expectedCode, code)
+			}
+		})
+	}
+}
+
 func TestCreateInstance_PerformanceConfig(t *testing.T) {
 	ctx := context.Background()
 	mgr := &gcfsServiceManager{}
@@ -805,6 +1061,38 @@ func TestCodeForError(t *testing.T) {
 			err:             fmt.Errorf("got error: System limit for internal resources has been reached"),
 			expectedErrCode: util.ErrCodePtr(codes.ResourceExhausted),
 		},
+		{
+			name:            "Filestore stockout error",
+			err:             fmt.Errorf("operation failed: The zone 'us-central1-a' does not have enough resources available to fulfill the request"),
+			expectedErrCode: util.ErrCodePtr(codes.ResourceExhausted),
+		},
+		{
+			// Only DeleteInstance returns FailedPrecondition, see TestDeleteInstanceDeletionProtection.
+			name: "failed precondition status",
+			err: &googleapi.Error{
+				Code:    http.StatusBadRequest,
+				Message: "Instance is being updated",
+				Body:    `{"error": {"code": 400, "message": "Instance is being updated", "status": "FAILED_PRECONDITION"}}`,
+			},
+			expectedErrCode: util.ErrCodePtr(codes.InvalidArgument),
+		},
+		{
+			name: "failed precondition reason",
+			err: fmt.Errorf("got error: %w", &googleapi.Error{
+				Code:   http.StatusBadRequest,
+				Errors: []googleapi.ErrorItem{{Reason: "failedPrecondition"}},
+			}),
+			expectedErrCode: util.ErrCodePtr(codes.InvalidArgument),
+		},
+		{
+			name: "invalid deletion protection reason",
+			err: &googleapi.Error{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid deletion protection reason",
+				Body:    `{"error": {"code": 400, "message": "Invalid deletion protection reason", "status": "INVALID_ARGUMENT"}}`,
+			},
+			expectedErrCode: util.ErrCodePtr(codes.InvalidArgument),
+		},
 	}
 
 	for _, test := range cases {
@@ -846,6 +1134,48 @@ func TestStatusError(t *testing.T) {
 	}
 }
 
+func TestIsCapacityError(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name: "nil error",
+		},
+		{
+			name:     "resource exhausted status error",
+			err:      status.Error(codes.ResourceExhausted, "stockout"),
+			expected: true,
+		},
+		{
+			name: "429 googleapi error",
+			err:  &googleapi.Error{Code: http.StatusTooManyRequests},
+		},
+		{
+			name:     "stockout operation error",
+			err:      OperationError(&filev1beta1.Operation{Name: "op", Done: true, Error: &filev1beta1.Status{Code: int64(codes.ResourceExhausted), Message: "stockout"}}),
+			expected: true,
+		},
+		{
+			name: "internal error",
+			err:  status.Error(codes.Internal, "boom"),
+		},
+		{
+			name: "not found googleapi error",
+			err:  &googleapi.Error{Code: http.StatusNotFound},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsCapacityError(test.err); got != test.expected {
+				t.Errorf("got %v, expected %v", got, test.expected)
+			}
+		})
+	}
+}
+
 func TestIsUserError(t *testing.T) {
 	cases := []struct {
 		name            string
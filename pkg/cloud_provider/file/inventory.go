@@ -0,0 +1,666 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	filev1beta1 "google.golang.org/api/file/v1beta1"
+	filev1beta1multishare "google.golang.org/api/file/v1beta1"
+	"k8s.io/klog/v2"
+)
+
+// The controller lists the instances and shares of the project on the hot path of CreateVolume,
+// multishare provisioning and reconciliation. InventoryService serves these lists from a cache
+// which is resynced periodically and updated with the effect of the operations started and
+// waited for through it, so that large projects do not exhaust the Filestore list quota. A
+// cached list is never served once it is older than the staleness bound. Operations are always
+// listed from Filestore: the running operations are used to serialize the multishare
+// operations, so missing one started by another controller is not an option.
+
+const (
+	// Inventory resources, used as metric label values.
+	InventoryInstances           = "instances"
+	InventoryMultishareInstances = "multishare_instances"
+	InventoryShares              = "shares"
+
+	// DefaultInventoryResyncPeriod is how often the cached lists are refreshed.
+	DefaultInventoryResyncPeriod = 30 * time.Second
+	// DefaultInventoryMaxStaleness is the age after which a cached list is listed again on use.
+	DefaultInventoryMaxStaleness = 2 * time.Minute
+
+	// inventoryIdleResyncs is the number of resync periods after which a list which was not
+	// used is dropped from the cache.
+	inventoryIdleResyncs = 10
+
+	stateCreating  = "CREATING"
+	stateDeleting  = "DELETING"
+	stateRestoring = "RESTORING"
+)
+
+var opTargetRegex = regexp.MustCompile(`^projects/([^/]+)/locations/([^/]+)/`)
+
+// InventoryRecorder records the metrics of the inventory cache.
+type InventoryRecorder interface {
+	// RecordInventoryLookup records whether a list of the resource was served from the cache.
+	RecordInventoryLookup(resource string, hit bool)
+	// RecordInventoryList records a list of the resource from Filestore.
+	RecordInventoryList(resource string, err error, duration time.Duration)
+}
+
+type InventoryConfig struct {
+	// ResyncPeriod is how often the cached lists are refreshed.
+	ResyncPeriod time.Duration
+	// MaxStaleness is the age after which a cached list is listed again on use.
+	MaxStaleness time.Duration
+	// Recorder records the cache metrics, if set.
+	Recorder InventoryRecorder
+}
+
+// InventoryService is a Service which caches the instance and share lists.
+type InventoryService struct {
+	Service
+	config InventoryConfig
+
+	instances           *inventoryCache[ServiceInstance]
+	multishareInstances *inventoryCache[MultishareInstance]
+	shares              *inventoryCache[Share]
+}
+
+var _ Service = &InventoryService{}
+
+// NewInventoryService returns an InventoryService caching the lists of the given service. Run
+// has to be called to resync the cache periodically.
+func NewInventoryService(service Service, config InventoryConfig) *InventoryService {
+	if config.ResyncPeriod <= 0 {
+		config.ResyncPeriod = DefaultInventoryResyncPeriod
+	}
+	if config.MaxStaleness <= 0 {
+		config.MaxStaleness = DefaultInventoryMaxStaleness
+	}
+	return &InventoryService{
+		Service:             service,
+		config:              config,
+		instances:           newInventoryCache(InventoryInstances, config, cloneServiceInstance),
+		multishareInstances: newInventoryCache(InventoryMultishareInstances, config, cloneMultishareInstance),
+		shares:              newInventoryCache(InventoryShares, config, cloneShare),
+	}
+}
+
+// Run resyncs the cache every resync period until the stop channel is closed.
+func (s *InventoryService) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(s.config.ResyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+			s.instances.resync(ctx)
+			s.multishareInstances.resync(ctx)
+			s.shares.resync(ctx)
+		}
+	}
+}
+
+func (s *InventoryService) ListInstances(ctx context.Context, obj *ServiceInstance) ([]*ServiceInstance, error) {
+	// Instances are listed in all the locations of the project.
+	filter := ListFilter{Project: obj.Project}
+	return s.instances.list(ctx, filter, func(ctx context.Context) ([]*ServiceInstance, error) {
+		return s.Service.ListInstances(ctx, &ServiceInstance{Project: filter.Project})
+	})
+}
+
+func (s *InventoryService) ListMultishareInstances(ctx context.Context, filter *ListFilter) ([]*MultishareInstance, error) {
+	f := *filter
+	return s.multishareInstances.list(ctx, f, func(ctx context.Context) ([]*MultishareInstance, error) {
+		return s.Service.ListMultishareInstances(ctx, &f)
+	})
+}
+
+func (s *InventoryService) ListShares(ctx context.Context, filter *ListFilter) ([]*Share, error) {
+	f := *filter
+	return s.shares.list(ctx, f, func(ctx context.Context) ([]*Share, error) {
+		return s.Service.ListShares(ctx, &f)
+	})
+}
+
+func (s *InventoryService) CreateInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error) {
+	instance, err := s.Service.CreateInstance(ctx, obj)
+	if err != nil {
+		// The instance may have been created even though the creation failed.
+		s.instances.invalidate(instanceFilterMatcher(obj.Project))
+		return nil, err
+	}
+	s.instances.upsert(instanceFilterMatcher(instance.Project), instance, sameInstance)
+	return instance, nil
+}
+
+func (s *InventoryService) StartCreateInstanceOp(ctx context.Context, obj *ServiceInstance) (*filev1beta1.Operation, error) {
+	op, err := s.Service.StartCreateInstanceOp(ctx, obj)
+	if err != nil {
+		s.instances.invalidate(instanceFilterMatcher(obj.Project))
+		return nil, err
+	}
+	// The instance holds its reserved IP range from now on.
+	instance := *obj
+	instance.State = stateCreating
+	s.instances.upsert(instanceFilterMatcher(obj.Project), &instance, sameInstance)
+	return op, nil
+}
+
+func (s *InventoryService) DeleteInstance(ctx context.Context, obj *ServiceInstance) error {
+	if err := s.Service.DeleteInstance(ctx, obj); err != nil {
+		s.instances.invalidate(instanceFilterMatcher(obj.Project))
+		return err
+	}
+	s.instances.remove(instanceFilterMatcher(obj.Project), obj, sameInstance)
+	return nil
+}
+
+func (s *InventoryService) ResizeInstance(ctx context.Context, obj *ServiceInstance) (*ServiceInstance, error) {
+	instance, err := s.Service.ResizeInstance(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	s.instances.upsert(instanceFilterMatcher(instance.Project), instance, sameInstance)
+	return instance, nil
+}
+
+func (s *InventoryService) StartRestoreInstanceOp(ctx context.Context, obj *ServiceInstance, snapshotUri string) (*filev1beta1.Operation, error) {
+	op, err := s.Service.StartRestoreInstanceOp(ctx, obj, snapshotUri)
+	if err != nil {
+		return nil, err
+	}
+	s.instances.modify(instanceFilterMatcher(obj.Project), obj, sameInstance, func(i *ServiceInstance) {
+		i.State = stateRestoring
+	})
+	return op, nil
+}
+
+func (s *InventoryService) WaitForInstanceOp(ctx context.Context, opName string) (*filev1beta1.Operation, error) {
+	op, err := s.Service.WaitForInstanceOp(ctx, opName)
+	if err != nil {
+		return nil, err
+	}
+	// The instance left the state set when the operation started.
+	if project, _, ok := opProjectAndLocation(op); ok {
+		s.instances.invalidate(instanceFilterMatcher(project))
+	} else {
+		s.instances.invalidate(func(ListFilter) bool { return true })
+	}
+	return op, nil
+}
+
+func (s *InventoryService) UpdateInstancePerformance(ctx context.Context, obj *ServiceInstance, perfConfig *PerformanceConfig) error {
+	err := s.Service.UpdateInstancePerformance(ctx, obj, perfConfig)
+	s.instanceUpdated(obj, err, func(i *ServiceInstance) {
+		config := *perfConfig
+		i.PerformanceConfig = &config
+	})
+	return err
+}
+
+func (s *InventoryService) UpdateInstanceNfsExportOptions(ctx context.Context, obj *ServiceInstance, options []*NfsExportOptions) error {
+	err := s.Service.UpdateInstanceNfsExportOptions(ctx, obj, options)
+	s.instanceUpdated(obj, err, func(i *ServiceInstance) {
+		i.NfsExportOptions = cloneNfsExportOptions(options)
+	})
+	return err
+}
+
+func (s *InventoryService) UpdateInstanceDeletionProtection(ctx context.Context, obj *ServiceInstance, protection *DeletionProtection) error {
+	err := s.Service.UpdateInstanceDeletionProtection(ctx, obj, protection)
+	s.instanceUpdated(obj, err, func(i *ServiceInstance) {
+		p := *protection
+		i.DeletionProtection = &p
+	})
+	return err
+}
+
+// instanceUpdated applies the update of an instance, which waited for its operation, to the
+// cached instance lists.
+func (s *InventoryService) instanceUpdated(obj *ServiceInstance, err error, update func(*ServiceInstance)) {
+	if err != nil {
+		s.instances.invalidate(instanceFilterMatcher(obj.Project))
+		return
+	}
+	s.instances.modify(instanceFilterMatcher(obj.Project), obj, sameInstance, update)
+}
+
+func (s *InventoryService) StartCreateMultishareInstanceOp(ctx context.Context, obj *MultishareInstance) (*filev1beta1multishare.Operation, error) {
+	op, err := s.Service.StartCreateMultishareInstanceOp(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	instance := *obj
+	instance.State = stateCreating
+	s.multishareInstances.upsert(filterMatcher(obj.Project, obj.Location, ""), &instance, sameMultishareInstance)
+	return op, nil
+}
+
+func (s *InventoryService) StartDeleteMultishareInstanceOp(ctx context.Context, obj *MultishareInstance) (*filev1beta1multishare.Operation, error) {
+	op, err := s.Service.StartDeleteMultishareInstanceOp(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	s.multishareInstances.modify(filterMatcher(obj.Project, obj.Location, ""), obj, sameMultishareInstance, func(i *MultishareInstance) {
+		i.State = stateDeleting
+	})
+	return op, nil
+}
+
+func (s *InventoryService) StartResizeMultishareInstanceOp(ctx context.Context, obj *MultishareInstance) (*filev1beta1multishare.Operation, error) {
+	op, err := s.Service.StartResizeMultishareInstanceOp(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	s.multishareInstances.modify(filterMatcher(obj.Project, obj.Location, ""), obj, sameMultishareInstance, func(i *MultishareInstance) {
+		i.CapacityBytes = obj.CapacityBytes
+	})
+	return op, nil
+}
+
+func (s *InventoryService) StartCreateShareOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error) {
+	op, err := s.Service.StartCreateShareOp(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	share := *obj
+	share.State = stateCreating
+	s.shares.upsert(shareFilterMatcher(obj), &share, sameShare)
+	return op, nil
+}
+
+func (s *InventoryService) StartDeleteShareOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error) {
+	op, err := s.Service.StartDeleteShareOp(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	s.shares.modify(shareFilterMatcher(obj), obj, sameShare, func(share *Share) {
+		share.State = stateDeleting
+	})
+	return op, nil
+}
+
+func (s *InventoryService) StartResizeShareOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error) {
+	op, err := s.Service.StartResizeShareOp(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	s.shares.modify(shareFilterMatcher(obj), obj, sameShare, func(share *Share) {
+		share.CapacityBytes = obj.CapacityBytes
+	})
+	return op, nil
+}
+
+func (s *InventoryService) StartUpdateShareNfsExportOptionsOp(ctx context.Context, obj *Share) (*filev1beta1multishare.Operation, error) {
+	op, err := s.Service.StartUpdateShareNfsExportOptionsOp(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	s.shares.modify(shareFilterMatcher(obj), obj, sameShare, func(share *Share) {
+		share.NfsExportOptions = obj.NfsExportOptions
+	})
+	return op, nil
+}
+
+func (s *InventoryService) GetOp(ctx context.Context, opName string) (*filev1beta1multishare.Operation, error) {
+	op, err := s.Service.GetOp(ctx, opName)
+	if err != nil {
+		return nil, err
+	}
+	if op.Done {
+		s.opDone(op)
+	}
+	return op, nil
+}
+
+func (s *InventoryService) WaitForOpWithOpts(ctx context.Context, opName string, opts PollOpts) error {
+	err := s.Service.WaitForOpWithOpts(ctx, opName, opts)
+	// Update the cached lists affected by the operation, which is done unless the wait timed out.
+	if _, getErr := s.GetOp(ctx, opName); getErr != nil {
+		s.multishareInstances.invalidate(func(ListFilter) bool { return true })
+		s.shares.invalidate(func(ListFilter) bool { return true })
+	}
+	return err
+}
+
+// opDone makes the instance and share lists affected by a finished operation be listed again,
+// since the cached items hold the state set when the operation started.
+func (s *InventoryService) opDone(op *filev1beta1multishare.Operation) {
+	project, location, ok := opProjectAndLocation(op)
+	if !ok {
+		s.multishareInstances.invalidate(func(ListFilter) bool { return true })
+		s.shares.invalidate(func(ListFilter) bool { return true })
+		return
+	}
+	s.multishareInstances.invalidate(filterMatcher(project, location, ""))
+	s.shares.invalidate(filterMatcher(project, location, ""))
+}
+
+// opProjectAndLocation returns the project and location of the target of the operation.
+func opProjectAndLocation(op *filev1beta1multishare.Operation) (string, string, bool) {
+	var meta filev1beta1multishare.OperationMetadata
+	if err := json.Unmarshal(op.Metadata, &meta); err != nil {
+		return "", "", false
+	}
+	substrings := opTargetRegex.FindStringSubmatch(meta.Target)
+	if substrings == nil {
+		return "", "", false
+	}
+	return substrings[1], substrings[2], true
+}
+
+// filterMatcher returns a function telling whether a list with the given filter contains the
+// resources of the given project, location and instance. An empty or "-" location or instance
+// name of the filter matches all of them.
+func filterMatcher(project, location, instanceName string) func(ListFilter) bool {
+	return func(f ListFilter) bool {
+		return f.Project == project &&
+			(f.Location == "" || f.Location == "-" || f.Location == location) &&
+			(f.InstanceName == "" || f.InstanceName == "-" || f.InstanceName == instanceName)
+	}
+}
+
+func instanceFilterMatcher(project string) func(ListFilter) bool {
+	return func(f ListFilter) bool {
+		return f.Project == project
+	}
+}
+
+func shareFilterMatcher(share *Share) func(ListFilter) bool {
+	if share.Parent == nil {
+		return func(ListFilter) bool { return true }
+	}
+	return filterMatcher(share.Parent.Project, share.Parent.Location, share.Parent.Name)
+}
+
+// The clone functions copy the maps and pointers of the cached items, which are mutated by
+// the callers of the service.
+
+func cloneServiceInstance(i *ServiceInstance) *ServiceInstance {
+	c := *i
+	c.Labels = maps.Clone(i.Labels)
+	c.NfsExportOptions = cloneNfsExportOptions(i.NfsExportOptions)
+	if i.PerformanceConfig != nil {
+		config := *i.PerformanceConfig
+		c.PerformanceConfig = &config
+	}
+	if i.DeletionProtection != nil {
+		protection := *i.DeletionProtection
+		c.DeletionProtection = &protection
+	}
+	return &c
+}
+
+func cloneMultishareInstance(i *MultishareInstance) *MultishareInstance {
+	c := *i
+	c.Labels = maps.Clone(i.Labels)
+	return &c
+}
+
+func cloneShare(s *Share) *Share {
+	c := *s
+	if s.Parent != nil {
+		c.Parent = cloneMultishareInstance(s.Parent)
+	}
+	c.Labels = maps.Clone(s.Labels)
+	c.NfsExportOptions = cloneNfsExportOptions(s.NfsExportOptions)
+	return &c
+}
+
+func cloneNfsExportOptions(options []*NfsExportOptions) []*NfsExportOptions {
+	if options == nil {
+		return nil
+	}
+	c := make([]*NfsExportOptions, 0, len(options))
+	for _, o := range options {
+		option := *o
+		option.IpRanges = slices.Clone(o.IpRanges)
+		c = append(c, &option)
+	}
+	return c
+}
+
+func sameInstance(a, b *ServiceInstance) bool {
+	return a.Project == b.Project && a.Location == b.Location && a.Name == b.Name
+}
+
+func sameMultishareInstance(a, b *MultishareInstance) bool {
+	return a.Project == b.Project && a.Location == b.Location && a.Name == b.Name
+}
+
+func sameShare(a, b *Share) bool {
+	if a.Name != b.Name {
+		return false
+	}
+	if a.Parent == nil || b.Parent == nil {
+		return a.Parent == b.Parent
+	}
+	return sameMultishareInstance(a.Parent, b.Parent)
+}
+
+// inventoryCache caches the lists of one resource, keyed by their filter.
+type inventoryCache[T any] struct {
+	resource string
+	config   InventoryConfig
+	clone    func(*T) *T
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[ListFilter]*inventoryEntry[T]
+}
+
+type inventoryEntry[T any] struct {
+	// listMu serializes the lists of the entry, so that concurrent misses list only once.
+	listMu sync.Mutex
+	list   func(ctx context.Context) ([]*T, error)
+
+	// The fields below are guarded by the mutex of the cache.
+	items    []*T
+	synced   time.Time
+	lastUsed time.Time
+	// generation is incremented by every update of the items, to detect updates racing with a list.
+	generation int
+}
+
+func newInventoryCache[T any](resource string, config InventoryConfig, clone func(*T) *T) *inventoryCache[T] {
+	return &inventoryCache[T]{
+		resource: resource,
+		config:   config,
+		clone:    clone,
+		now:      time.Now,
+		entries:  make(map[ListFilter]*inventoryEntry[T]),
+	}
+}
+
+// list returns the cached list for the filter, listing it first if it is missing or stale.
+func (c *inventoryCache[T]) list(ctx context.Context, filter ListFilter, list func(ctx context.Context) ([]*T, error)) ([]*T, error) {
+	c.mu.Lock()
+	e, ok := c.entries[filter]
+	if !ok {
+		e = &inventoryEntry[T]{list: list}
+		c.entries[filter] = e
+	}
+	e.lastUsed = c.now()
+	c.mu.Unlock()
+
+	e.listMu.Lock()
+	defer e.listMu.Unlock()
+	c.mu.Lock()
+	if !e.synced.IsZero() && c.now().Sub(e.synced) < c.config.MaxStaleness {
+		items := c.copyItems(e.items)
+		c.mu.Unlock()
+		c.recordLookup(true)
+		return items, nil
+	}
+	c.mu.Unlock()
+	c.recordLookup(false)
+
+	items, err := c.sync(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	return c.copyItems(items), nil
+}
+
+// sync lists the items of the entry and stores them. The caller must hold the list mutex of the entry.
+func (c *inventoryCache[T]) sync(ctx context.Context, e *inventoryEntry[T]) ([]*T, error) {
+	c.mu.Lock()
+	generation := e.generation
+	c.mu.Unlock()
+
+	start := c.now()
+	items, err := e.list(ctx)
+	if c.config.Recorder != nil {
+		c.config.Recorder.RecordInventoryList(c.resource, err, c.now().Sub(start))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The listed items are returned to the caller, so the cache keeps its own copies.
+	e.items = c.copyItems(items)
+	e.synced = start
+	if e.generation != generation {
+		// The items were updated while listing, the list may not reflect the update.
+		e.synced = time.Time{}
+	}
+	return items, nil
+}
+
+// resync lists all the entries used recently again and drops the others.
+func (c *inventoryCache[T]) resync(ctx context.Context) {
+	c.mu.Lock()
+	entries := make(map[ListFilter]*inventoryEntry[T])
+	for filter, e := range c.entries {
+		if c.now().Sub(e.lastUsed) > inventoryIdleResyncs*c.config.ResyncPeriod {
+			delete(c.entries, filter)
+			continue
+		}
+		entries[filter] = e
+	}
+	c.mu.Unlock()
+
+	for filter, e := range entries {
+		e.listMu.Lock()
+		if _, err := c.sync(ctx, e); err != nil {
+			klog.Warningf("Failed to resync %s with filter %+v: %v", c.resource, filter, err)
+		}
+		e.listMu.Unlock()
+	}
+}
+
+// upsert replaces the item in the lists matched by the filter matcher, or adds it if missing.
+func (c *inventoryCache[T]) upsert(match func(ListFilter) bool, item *T, same func(a, b *T) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for filter, e := range c.entries {
+		if !match(filter) {
+			continue
+		}
+		e.generation++
+		replaced := false
+		for i, cached := range e.items {
+			if same(cached, item) {
+				e.items[i] = c.clone(item)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			e.items = append(e.items, c.clone(item))
+		}
+	}
+}
+
+// modify updates the item in the lists matched by the filter matcher, if present.
+func (c *inventoryCache[T]) modify(match func(ListFilter) bool, item *T, same func(a, b *T) bool, update func(*T)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for filter, e := range c.entries {
+		if !match(filter) {
+			continue
+		}
+		for i, cached := range e.items {
+			if same(cached, item) {
+				// Cached items may have been handed out before, so they are replaced rather than updated.
+				updated := c.clone(cached)
+				update(updated)
+				e.items[i] = updated
+				e.generation++
+			}
+		}
+	}
+}
+
+// remove removes the item from the lists matched by the filter matcher.
+func (c *inventoryCache[T]) remove(match func(ListFilter) bool, item *T, same func(a, b *T) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for filter, e := range c.entries {
+		if !match(filter) {
+			continue
+		}
+		items := []*T{}
+		for _, cached := range e.items {
+			if !same(cached, item) {
+				items = append(items, cached)
+			}
+		}
+		if len(items) != len(e.items) {
+			e.generation++
+		}
+		e.items = items
+	}
+}
+
+// invalidate makes the lists matched by the filter matcher be listed again on next use.
+func (c *inventoryCache[T]) invalidate(match func(ListFilter) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for filter, e := range c.entries {
+		if match(filter) {
+			e.generation++
+			e.synced = time.Time{}
+		}
+	}
+}
+
+func (c *inventoryCache[T]) copyItems(items []*T) []*T {
+	var copied []*T
+	for _, item := range items {
+		copied = append(copied, c.clone(item))
+	}
+	return copied
+}
+
+func (c *inventoryCache[T]) recordLookup(hit bool) {
+	if c.config.Recorder != nil {
+		c.config.Recorder.RecordInventoryLookup(c.resource, hit)
+	}
+}
@@ -0,0 +1,505 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	filev1beta1multishare "google.golang.org/api/file/v1beta1"
+)
+
+// listCountingService counts the lists made on the wrapped service.
+type listCountingService struct {
+	Service
+	lists map[string]int
+	err   error
+}
+
+func (s *listCountingService) ListInstances(ctx context.Context, obj *ServiceInstance) ([]*ServiceInstance, error) {
+	s.lists[InventoryInstances]++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.Service.ListInstances(ctx, obj)
+}
+
+func (s *listCountingService) ListMultishareInstances(ctx context.Context, filter *ListFilter) ([]*MultishareInstance, error) {
+	s.lists[InventoryMultishareInstances]++
+	return s.Service.ListMultishareInstances(ctx, filter)
+}
+
+func (s *listCountingService) ListShares(ctx context.Context, filter *ListFilter) ([]*Share, error) {
+	s.lists[InventoryShares]++
+	return s.Service.ListShares(ctx, filter)
+}
+
+func (s *listCountingService) ListOps(ctx context.Context, filter *ListFilter) ([]*filev1beta1multishare.Operation, error) {
+	s.lists["operations"]++
+	return s.Service.ListOps(ctx, filter)
+}
+
+type fakeInventoryRecorder struct {
+	hits, misses, lists int
+}
+
+func (r *fakeInventoryRecorder) RecordInventoryLookup(resource string, hit bool) {
+	if hit {
+		r.hits++
+	} else {
+		r.misses++
+	}
+}
+
+func (r *fakeInventoryRecorder) RecordInventoryList(resource string, err error, duration time.Duration) {
+	r.lists++
+}
+
+func newTestInventoryService(t *testing.T, now *time.Time) (*InventoryService, *listCountingService, *fakeInventoryRecorder) {
+	instances := []*MultishareInstance{
+		{Project: defaultProject, Location: "us-central1", Name: "instance-1", State: "READY"},
+	}
+	shares := []*Share{
+		{Name: "share-1", Parent: instances[0], State: "READY"},
+	}
+	fake, err := NewFakeServiceForMultishare(instances, shares, nil)
+	if err != nil {
+		t.Fatalf("failed to create fake service: %v", err)
+	}
+	counting := &listCountingService{Service: fake, lists: make(map[string]int)}
+	recorder := &fakeInventoryRecorder{}
+	s := NewInventoryService(counting, InventoryConfig{
+		ResyncPeriod: time.Minute,
+		MaxStaleness: 2 * time.Minute,
+		Recorder:     recorder,
+	})
+	clock := func() time.Time { return *now }
+	s.instances.now = clock
+	s.multishareInstances.now = clock
+	s.shares.now = clock
+	return s, counting, recorder
+}
+
+func TestInventoryList(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s, counting, recorder := newTestInventoryService(t, &now)
+
+	for i := 0; i < 3; i++ {
+		instances, err := s.ListInstances(ctx, &ServiceInstance{Project: defaultProject})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(instances) != 2 {
+			t.Errorf("got %d instances, expected 2", len(instances))
+		}
+	}
+	if counting.lists[InventoryInstances] != 1 {
+		t.Errorf("got %d instance lists, expected 1", counting.lists[InventoryInstances])
+	}
+	if recorder.hits != 2 || recorder.misses != 1 || recorder.lists != 1 {
+		t.Errorf("got %d hits, %d misses and %d lists, expected 2, 1 and 1", recorder.hits, recorder.misses, recorder.lists)
+	}
+
+	// Lists of another project are cached separately.
+	if _, err := s.ListInstances(ctx, &ServiceInstance{Project: "other-project"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counting.lists[InventoryInstances] != 2 {
+		t.Errorf("got %d instance lists, expected 2", counting.lists[InventoryInstances])
+	}
+
+	// Stale lists are listed again.
+	now = now.Add(3 * time.Minute)
+	if _, err := s.ListInstances(ctx, &ServiceInstance{Project: defaultProject}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counting.lists[InventoryInstances] != 3 {
+		t.Errorf("got %d instance lists, expected 3", counting.lists[InventoryInstances])
+	}
+
+	// Errors are not cached.
+	now = now.Add(3 * time.Minute)
+	counting.err = fmt.Errorf("quota exceeded")
+	if _, err := s.ListInstances(ctx, &ServiceInstance{Project: defaultProject}); err == nil {
+		t.Errorf("expected error")
+	}
+	counting.err = nil
+	if _, err := s.ListInstances(ctx, &ServiceInstance{Project: defaultProject}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counting.lists[InventoryInstances] != 5 {
+		t.Errorf("got %d instance lists, expected 5", counting.lists[InventoryInstances])
+	}
+
+	// Returned items are copies of the cached ones.
+	instances, _ := s.ListInstances(ctx, &ServiceInstance{Project: defaultProject})
+	instances[0].State = "MODIFIED"
+	instances, _ = s.ListInstances(ctx, &ServiceInstance{Project: defaultProject})
+	if instances[0].State == "MODIFIED" {
+		t.Errorf("cached instance was modified through a returned instance")
+	}
+}
+
+func TestInventoryWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	instanceFilter := &ServiceInstance{Project: defaultProject}
+	regionFilter := &ListFilter{Project: defaultProject, Location: "us-central1"}
+	allFilter := &ListFilter{Project: defaultProject, Location: "-"}
+	sharesFilter := &ListFilter{Project: defaultProject, Location: "-", InstanceName: "-"}
+	parent := &MultishareInstance{Project: defaultProject, Location: "us-central1", Name: "instance-1"}
+
+	cases := []struct {
+		name  string
+		write func(s *InventoryService) error
+		check func(s *InventoryService) error
+	}{
+		{
+			name: "start create instance",
+			write: func(s *InventoryService) error {
+				_, err := s.StartCreateInstanceOp(ctx, &ServiceInstance{Project: defaultProject, Location: defaultZone, Name: "new", Network: Network{ReservedIpRange: "10.0.0.0/29"}})
+				return err
+			},
+			check: func(s *InventoryService) error {
+				instances, err := s.ListInstances(ctx, instanceFilter)
+				if err != nil {
+					return err
+				}
+				for _, i := range instances {
+					if i.Name == "new" && i.Network.ReservedIpRange == "10.0.0.0/29" {
+						return nil
+					}
+				}
+				return fmt.Errorf("created instance missing from %+v", instances)
+			},
+		},
+		{
+			name: "delete instance",
+			write: func(s *InventoryService) error {
+				if _, err := s.Service.CreateInstance(ctx, &ServiceInstance{Name: "old"}); err != nil {
+					return err
+				}
+				// Cache the list which contains the instance.
+				s.instances.invalidate(instanceFilterMatcher(defaultProject))
+				if _, err := s.ListInstances(ctx, instanceFilter); err != nil {
+					return err
+				}
+				return s.DeleteInstance(ctx, &ServiceInstance{Project: defaultProject, Location: defaultZone, Name: "old"})
+			},
+			check: func(s *InventoryService) error {
+				instances, err := s.ListInstances(ctx, instanceFilter)
+				if err != nil {
+					return err
+				}
+				for _, i := range instances {
+					if i.Name == "old" {
+						return fmt.Errorf("deleted instance found in %+v", instances)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "update instance nfs export options and deletion protection",
+			write: func(s *InventoryService) error {
+				obj := &ServiceInstance{Project: defaultProject, Location: defaultZone, Name: "updated"}
+				if _, err := s.Service.CreateInstance(ctx, obj); err != nil {
+					return err
+				}
+				// Cache the list which contains the instance.
+				s.instances.invalidate(instanceFilterMatcher(defaultProject))
+				if _, err := s.ListInstances(ctx, instanceFilter); err != nil {
+					return err
+				}
+				if err := s.UpdateInstanceNfsExportOptions(ctx, obj, []*NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}}}); err != nil {
+					return err
+				}
+				return s.UpdateInstanceDeletionProtection(ctx, obj, &DeletionProtection{Enabled: true})
+			},
+			check: func(s *InventoryService) error {
+				instances, err := s.ListInstances(ctx, instanceFilter)
+				if err != nil {
+					return err
+				}
+				for _, i := range instances {
+					if i.Name == "updated" && len(i.NfsExportOptions) == 1 && i.DeletionProtection != nil && i.DeletionProtection.Enabled {
+						return nil
+					}
+				}
+				return fmt.Errorf("updated instance missing from %+v", instances)
+			},
+		},
+		{
+			name: "start create multishare instance",
+			write: func(s *InventoryService) error {
+				_, err := s.StartCreateMultishareInstanceOp(ctx, &MultishareInstance{Project: defaultProject, Location: "us-central1", Name: "instance-2"})
+				return err
+			},
+			check: func(s *InventoryService) error {
+				for _, filter := range []*ListFilter{regionFilter, allFilter} {
+					instances, err := s.ListMultishareInstances(ctx, filter)
+					if err != nil {
+						return err
+					}
+					if len(instances) != 2 || instances[1].State != stateCreating {
+						return fmt.Errorf("created instance missing from %+v", instances)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "start delete multishare instance",
+			write: func(s *InventoryService) error {
+				_, err := s.StartDeleteMultishareInstanceOp(ctx, parent)
+				return err
+			},
+			check: func(s *InventoryService) error {
+				instances, err := s.ListMultishareInstances(ctx, regionFilter)
+				if err != nil {
+					return err
+				}
+				if len(instances) != 1 || instances[0].State != stateDeleting {
+					return fmt.Errorf("got instances %+v, expected instance-1 in state %s", instances, stateDeleting)
+				}
+				return nil
+			},
+		},
+		{
+			name: "start create and resize share",
+			write: func(s *InventoryService) error {
+				if _, err := s.StartCreateShareOp(ctx, &Share{Name: "share-2", Parent: parent, CapacityBytes: 100}); err != nil {
+					return err
+				}
+				_, err := s.StartResizeShareOp(ctx, &Share{Name: "share-2", Parent: parent, CapacityBytes: 200})
+				return err
+			},
+			check: func(s *InventoryService) error {
+				shares, err := s.ListShares(ctx, sharesFilter)
+				if err != nil {
+					return err
+				}
+				for _, share := range shares {
+					if share.Name == "share-2" && share.CapacityBytes == 200 {
+						return nil
+					}
+				}
+				return fmt.Errorf("resized share missing from %+v", shares)
+			},
+		},
+		{
+			name: "start delete share",
+			write: func(s *InventoryService) error {
+				_, err := s.StartDeleteShareOp(ctx, &Share{Name: "share-1", Parent: parent})
+				return err
+			},
+			check: func(s *InventoryService) error {
+				shares, err := s.ListShares(ctx, sharesFilter)
+				if err != nil {
+					return err
+				}
+				if len(shares) != 1 || shares[0].State != stateDeleting {
+					return fmt.Errorf("got shares %+v, expected share-1 in state %s", shares, stateDeleting)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			now := time.Now()
+			s, counting, _ := newTestInventoryService(t, &now)
+			// Fill the cache.
+			if _, err := s.ListInstances(ctx, instanceFilter); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, filter := range []*ListFilter{regionFilter, allFilter} {
+				if _, err := s.ListMultishareInstances(ctx, filter); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if _, err := s.ListShares(ctx, sharesFilter); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := test.write(s); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			lists := make(map[string]int)
+			for resource, count := range counting.lists {
+				lists[resource] = count
+			}
+			if err := test.check(s); err != nil {
+				t.Error(err)
+			}
+			for resource, count := range counting.lists {
+				if count != lists[resource] {
+					t.Errorf("got %d %s lists after the write, expected the cached list to be used", count-lists[resource], resource)
+				}
+			}
+		})
+	}
+}
+
+func TestInventoryOperationDone(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s, counting, _ := newTestInventoryService(t, &now)
+	filter := &ListFilter{Project: defaultProject, Location: "-", InstanceName: "-"}
+	parent := &MultishareInstance{Project: defaultProject, Location: "us-central1", Name: "instance-1"}
+
+	if _, err := s.ListShares(ctx, filter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	op, err := s.StartCreateShareOp(ctx, &Share{Name: "share-2", Parent: parent, CapacityBytes: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.WaitForOpWithOpts(ctx, op.Name, PollOpts{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The shares are listed again to pick up the state of the created share.
+	shares, err := s.ListShares(ctx, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, share := range shares {
+		if share.State == stateCreating {
+			t.Errorf("got share %+v still creating after its operation finished", share)
+		}
+	}
+	if counting.lists[InventoryShares] != 2 {
+		t.Errorf("got %d share lists, expected 2", counting.lists[InventoryShares])
+	}
+}
+
+func TestInventoryListOpsNotCached(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s, counting, _ := newTestInventoryService(t, &now)
+	filter := &ListFilter{Project: defaultProject, Location: "-"}
+
+	if _, err := s.ListOps(ctx, filter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// An operation started by another controller.
+	fake := counting.Service.(*fakeServiceManager)
+	fake.multishareops = append(fake.multishareops, &filev1beta1multishare.Operation{Name: "op-1"})
+
+	ops, err := s.ListOps(ctx, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Name != "op-1" {
+		t.Errorf("got operations %+v, expected op-1", ops)
+	}
+	if counting.lists["operations"] != 2 {
+		t.Errorf("got %d operation lists, expected 2", counting.lists["operations"])
+	}
+}
+
+func TestInventoryItemsAreCopied(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s, _, _ := newTestInventoryService(t, &now)
+	filter := &ListFilter{Project: defaultProject, Location: "us-central1"}
+
+	if _, err := s.StartCreateMultishareInstanceOp(ctx, &MultishareInstance{Project: defaultProject, Location: "us-central1", Name: "instance-2", Labels: map[string]string{"key": "value"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.ListMultishareInstances(ctx, filter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	instances, err := s.ListMultishareInstances(ctx, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, i := range instances {
+		if i.Labels == nil {
+			i.Labels = map[string]string{}
+		}
+		i.Labels["key"] = "changed"
+	}
+
+	instances, err = s.ListMultishareInstances(ctx, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, i := range instances {
+		if i.Labels["key"] == "changed" {
+			t.Errorf("got instance %+v, expected the cached labels not to change", i)
+		}
+	}
+}
+
+func TestInventoryUpdateDuringList(t *testing.T) {
+	ctx := context.Background()
+	c := newInventoryCache(InventoryInstances, InventoryConfig{ResyncPeriod: time.Minute, MaxStaleness: time.Minute}, func(i *ServiceInstance) *ServiceInstance {
+		c := *i
+		return &c
+	})
+	lists := 0
+	filter := ListFilter{Project: defaultProject}
+	list := func(ctx context.Context) ([]*ServiceInstance, error) {
+		lists++
+		if lists == 1 {
+			// An instance created after the list was served by Filestore.
+			c.upsert(instanceFilterMatcher(defaultProject), &ServiceInstance{Project: defaultProject, Name: "new"}, sameInstance)
+			return nil, nil
+		}
+		return []*ServiceInstance{{Project: defaultProject, Name: "new"}}, nil
+	}
+
+	if _, err := c.list(ctx, filter, list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	instances, err := c.list(ctx, filter, list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lists != 2 || len(instances) != 1 {
+		t.Errorf("got %d lists and instances %+v, expected the list racing with an update to be listed again", lists, instances)
+	}
+}
+
+func TestInventoryResync(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s, counting, _ := newTestInventoryService(t, &now)
+
+	if _, err := s.ListInstances(ctx, &ServiceInstance{Project: defaultProject}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.instances.resync(ctx)
+	if counting.lists[InventoryInstances] != 2 {
+		t.Errorf("got %d instance lists, expected 2", counting.lists[InventoryInstances])
+	}
+
+	// Lists not used for a while are dropped.
+	now = now.Add(inventoryIdleResyncs*time.Minute + time.Second)
+	s.instances.resync(ctx)
+	if counting.lists[InventoryInstances] != 2 {
+		t.Errorf("got %d instance lists, expected 2", counting.lists[InventoryInstances])
+	}
+	if len(s.instances.entries) != 0 {
+		t.Errorf("got %d cached lists, expected 0", len(s.instances.entries))
+	}
+}
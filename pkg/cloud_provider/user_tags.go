@@ -111,7 +111,7 @@ type tagServiceManager struct {
 type TagService interface {
 	SetResourceTags(resourceTags)
 	ValidateResourceTags(context.Context, string, string) (resourceTags, error)
-	AttachResourceTags(context.Context, resourceType, string, string, string, map[string]string) error
+	AttachResourceTags(context.Context, resourceType, string, string, string, string, map[string]string) error
 }
 
 // TagServiceOptions is for specifying the optional TagService arguments.
@@ -352,8 +352,9 @@ func (t *tagServiceManager) ValidateResourceTags(ctx context.Context, tagsSource
 
 // AttachResourceTags creates tag bindings on the resource by skipping the
 // tag bindings already existing on the resource either inherited or partial
-// success during previous operation.
-func (t *tagServiceManager) AttachResourceTags(ctx context.Context, rscType resourceType, rscName, rscLocation, reqName string, reqParameters map[string]string) error {
+// success during previous operation. The resource is looked up in rscProject,
+// or in the project of the driver if it is empty.
+func (t *tagServiceManager) AttachResourceTags(ctx context.Context, rscType resourceType, rscProject, rscName, rscLocation, reqName string, reqParameters map[string]string) error {
 	tags, err := extractTags(ctx, t, reqName, reqParameters)
 	if err != nil {
 		return err
@@ -372,12 +373,15 @@ func (t *tagServiceManager) AttachResourceTags(ctx context.Context, rscType reso
 	}
 	defer client.close()
 
+	if rscProject == "" {
+		rscProject = t.Project
+	}
 	var fullResourceName string
 	switch rscType {
 	case FilestoreInstance:
-		fullResourceName = fmt.Sprintf(filestoreInstanceFullNameFmt, t.Project, rscLocation, rscName)
+		fullResourceName = fmt.Sprintf(filestoreInstanceFullNameFmt, rscProject, rscLocation, rscName)
 	case FilestoreBackUp:
-		fullResourceName = fmt.Sprintf(filestoreBackupFullNameFmt, t.Project, rscLocation, rscName)
+		fullResourceName = fmt.Sprintf(filestoreBackupFullNameFmt, rscProject, rscLocation, rscName)
 	default:
 		return fmt.Errorf("unsupported resource type: %s:%s", rscType, rscName)
 	}
@@ -486,7 +486,7 @@ func TestAttachResourceTags(t *testing.T) {
 				})
 			}
 
-			err := tagMgr.AttachResourceTags(ctx, test.rscType, test.rscName, test.rscLocation, test.rscName, test.reqParameters)
+			err := tagMgr.AttachResourceTags(ctx, test.rscType, "", test.rscName, test.rscLocation, test.rscName, test.reqParameters)
 			if (err != nil || test.expectedErr != "") && err.Error() != test.expectedErr {
 				t.Errorf("AttachResourceTags(): got: %v, wantErr: %v", err, test.expectedErr)
 			}
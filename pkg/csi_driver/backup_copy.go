@@ -0,0 +1,190 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// A VolumeSnapshotClass of type backup can keep a copy of each backup in a secondary region
+// with the copy-location parameter. Filestore has no API to copy a backup, so the copy is a
+// second backup of the source volume, with the same name in the secondary region, taken once
+// the primary backup is ready. The source volume may have changed in between, so the copy is
+// not guaranteed to hold the same data as the snapshot and volumes are always restored from
+// the primary backup. The snapshot is ready to use once the primary backup is and its copy is
+// started, the copy is not waited for, or once the copy is given up because the source volume
+// is gone. The primary backup records the region of its copy in its labels, so that
+// DeleteSnapshot and the backup garbage collector delete the copy along with it.
+
+const (
+	// tagKeyBackupCopyLocation is set on a primary backup to the region of its copy.
+	tagKeyBackupCopyLocation = "storage_gke_io_backup-copy-location"
+	// tagKeyBackupCopyOf is set on a copy to the region of its primary backup.
+	tagKeyBackupCopyOf = "storage_gke_io_backup-copy-of"
+)
+
+// validateBackupCopyLocation checks that the copy location of the backup is a region other
+// than the region of the backup.
+func validateBackupCopyLocation(backupInfo *file.BackupInfo, copyLocation string) error {
+	if copyLocation == "" {
+		return nil
+	}
+	if _, _, err := file.CreateBackupURI(backupInfo.Location, backupInfo.Project, backupInfo.Name, copyLocation); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid %s %q: %v", util.VolumeSnapshotCopyLocationKey, copyLocation, err)
+	}
+	if copyLocation == backupInfo.Location {
+		return status.Errorf(codes.InvalidArgument, "%s %q must be different from the location of the backup", util.VolumeSnapshotCopyLocationKey, copyLocation)
+	}
+	return nil
+}
+
+// backupCopyURI returns the URI of the copy of the backup in the given region.
+func backupCopyURI(backupURI, region string) (string, error) {
+	splitID := strings.Split(backupURI, "/")
+	if isBackup, err := util.IsBackupHandle(backupURI); err != nil || !isBackup {
+		return "", fmt.Errorf("invalid backup URI %q", backupURI)
+	}
+	splitID[3] = region
+	return strings.Join(splitID, "/"), nil
+}
+
+// backupRegion returns the region of the backup with the given URI.
+func backupRegion(backupURI string) string {
+	splitID := strings.Split(backupURI, "/")
+	if len(splitID) < 4 {
+		return ""
+	}
+	return splitID[3]
+}
+
+// ensureBackupCopy makes sure that the copy of a ready primary backup was started, if the
+// primary backup has a copy location, and returns whether the copy exists. backupInfo
+// describes the primary backup, including its labels.
+func ensureBackupCopy(ctx context.Context, fileService file.Service, backupInfo *file.BackupInfo) (bool, error) {
+	copyRegion := backupInfo.Labels[tagKeyBackupCopyLocation]
+	if copyRegion == "" {
+		return false, nil
+	}
+	copyURI, err := backupCopyURI(backupInfo.BackupURI, copyRegion)
+	if err != nil {
+		return false, status.Error(codes.Internal, err.Error())
+	}
+
+	existingCopy, err := fileService.GetBackup(ctx, copyURI)
+	copyExists, err := file.CheckBackupExists(existingCopy, err)
+	if err != nil {
+		return false, err
+	}
+	if copyExists {
+		switch existingCopy.Backup.State {
+		case "FAILED":
+			// Start over with a new copy on the next attempt, the primary backup is fine.
+			klog.Warningf("Copy %s of backup %s failed, deleting it", copyURI, backupInfo.BackupURI)
+			if err := fileService.DeleteBackup(ctx, copyURI); err != nil {
+				return false, file.StatusError(err)
+			}
+			return false, status.Errorf(codes.Unavailable, "copy %s of backup %s failed, it will be retaken", copyURI, backupInfo.BackupURI)
+		case "DELETING":
+			return false, status.Errorf(codes.Unavailable, "copy %s of backup %s is in state %s", copyURI, backupInfo.BackupURI, existingCopy.Backup.State)
+		}
+		return true, nil
+	}
+
+	copyInfo := *backupInfo
+	copyInfo.Location = copyRegion
+	copyInfo.BackupURI = copyURI
+	copyInfo.Labels = make(map[string]string)
+	for k, v := range backupInfo.Labels {
+		if k != tagKeyBackupCopyLocation {
+			copyInfo.Labels[k] = v
+		}
+	}
+	copyInfo.Labels[tagKeyBackupCopyOf] = backupInfo.Location
+
+	klog.Infof("Creating copy %s of backup %s", copyURI, backupInfo.BackupURI)
+	op, err := fileService.StartCreateBackupOp(ctx, &copyInfo)
+	if err == nil {
+		err = file.OperationError(op)
+	}
+	if err != nil {
+		// The copy can only be taken from the source volume, retrying would never succeed
+		// once it is gone, so the snapshot is left with its primary backup only.
+		if file.IsNotFoundErr(err) || status.Code(err) == codes.NotFound {
+			klog.Warningf("Source volume %s of backup %s no longer exists, giving up its copy %s: %v", backupInfo.SourceVolumeId, backupInfo.BackupURI, copyURI, err)
+			return false, nil
+		}
+		klog.Errorf("Create copy %s of backup %s failed: %v", copyURI, backupInfo.BackupURI, err.Error())
+		return false, file.StatusError(err)
+	}
+	return true, nil
+}
+
+// completeBackupCopy starts the copy of the primary backup of the snapshot once it is ready,
+// if the backup has a copy location. The snapshot is only reported as ready to use once the
+// copy was started, since CreateSnapshot is not called again afterwards.
+func completeBackupCopy(ctx context.Context, fileService file.Service, tagManager cloud.TagService, backupInfo *file.BackupInfo, snapshot *csi.Snapshot, req *csi.CreateSnapshotRequest) error {
+	copyRegion := backupInfo.Labels[tagKeyBackupCopyLocation]
+	if copyRegion == "" || !snapshot.ReadyToUse {
+		return nil
+	}
+	copyExists, err := ensureBackupCopy(ctx, fileService, backupInfo)
+	if err != nil || !copyExists {
+		return err
+	}
+	if err := tagManager.AttachResourceTags(ctx, cloud.FilestoreBackUp, backupInfo.Project, backupInfo.Name, copyRegion, req.GetName(), req.GetParameters()); err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return nil
+}
+
+// deleteBackupCopy deletes the copy of the backup, if any.
+func deleteBackupCopy(ctx context.Context, fileService file.Service, backup *file.Backup) error {
+	copyRegion := backup.Backup.Labels[tagKeyBackupCopyLocation]
+	if copyRegion == "" {
+		return nil
+	}
+	copyURI, err := backupCopyURI(backup.Backup.Name, copyRegion)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	backupCopy, err := fileService.GetBackup(ctx, copyURI)
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil
+		}
+		return file.StatusError(err)
+	}
+	if backupCopy.Backup.State == "DELETING" {
+		return status.Errorf(codes.DeadlineExceeded, "copy %s of backup %s is in state %s", copyURI, backup.Backup.Name, backupCopy.Backup.State)
+	}
+	if err := fileService.DeleteBackup(ctx, copyURI); err != nil && !file.IsNotFoundErr(err) {
+		klog.Errorf("Delete copy %s of backup %s failed: %v", copyURI, backup.Backup.Name, err.Error())
+		return file.StatusError(err)
+	}
+	klog.Infof("Deleted copy %s of backup %s", copyURI, backup.Backup.Name)
+	return nil
+}
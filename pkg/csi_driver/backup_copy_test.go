@@ -0,0 +1,296 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	filev1beta1 "google.golang.org/api/file/v1beta1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+const testCopyRegion = "us-east1"
+
+func TestCreateSnapshotWithCopy(t *testing.T) {
+	volumeID := "modeInstance/us-central1-c/myinstance/myshare"
+	backupURI := fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testRegion, "mybackup")
+	copyURI := fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testCopyRegion, "mybackup")
+
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+	cs := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: &creatingBackupFileService{Service: fs},
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+	}).(*controllerServer)
+	req := &csi.CreateSnapshotRequest{
+		SourceVolumeId: volumeID,
+		Name:           "mybackup",
+		Parameters: map[string]string{
+			util.VolumeSnapshotTypeKey:         util.VolumeSnapshotTypeBackup,
+			util.VolumeSnapshotCopyLocationKey: testCopyRegion,
+		},
+	}
+	createSnapshot := func(expectReady bool) {
+		t.Helper()
+		resp, err := cs.CreateSnapshot(context.TODO(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Snapshot.SnapshotId != backupURI {
+			t.Errorf("got snapshot id %q, expected %q", resp.Snapshot.SnapshotId, backupURI)
+		}
+		if resp.Snapshot.ReadyToUse != expectReady {
+			t.Errorf("got ready to use %v, expected %v", resp.Snapshot.ReadyToUse, expectReady)
+		}
+	}
+
+	// The copy is only taken once the primary backup is ready.
+	createSnapshot(false)
+	if _, err := fs.GetBackup(context.TODO(), copyURI); !file.IsNotFoundErr(err) {
+		t.Fatalf("expected no copy before the backup is ready, got %v", err)
+	}
+	backup, err := fs.GetBackup(context.TODO(), backupURI)
+	if err != nil {
+		t.Fatalf("failed to get backup: %v", err)
+	}
+	if got := backup.Backup.Labels[tagKeyBackupCopyLocation]; got != testCopyRegion {
+		t.Errorf("got copy location label %q, expected %q", got, testCopyRegion)
+	}
+	backup.Backup.State = "READY"
+
+	// The snapshot is ready once the copy is started, the copy is not waited for.
+	createSnapshot(true)
+	backupCopy, err := fs.GetBackup(context.TODO(), copyURI)
+	if err != nil {
+		t.Fatalf("failed to get copy: %v", err)
+	}
+	if backupCopy.Backup.State != "CREATING" {
+		t.Errorf("got copy state %q, expected CREATING", backupCopy.Backup.State)
+	}
+	if got := backupCopy.Backup.Labels[tagKeyBackupCopyOf]; got != testRegion {
+		t.Errorf("got copy of label %q, expected %q", got, testRegion)
+	}
+	if _, ok := backupCopy.Backup.Labels[tagKeyBackupCopyLocation]; ok {
+		t.Errorf("unexpected copy location label on the copy")
+	}
+
+	// A failed copy is deleted, to be retaken on the next call.
+	backupCopy.Backup.State = "FAILED"
+	if _, err := cs.CreateSnapshot(context.TODO(), req); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected error code %v, got %v", codes.Unavailable, err)
+	}
+	if _, err := fs.GetBackup(context.TODO(), copyURI); !file.IsNotFoundErr(err) {
+		t.Fatalf("expected failed copy to be deleted, got %v", err)
+	}
+	createSnapshot(true)
+	if _, err := fs.GetBackup(context.TODO(), copyURI); err != nil {
+		t.Fatalf("failed to get retaken copy: %v", err)
+	}
+
+	if _, err := cs.DeleteSnapshot(context.TODO(), &csi.DeleteSnapshotRequest{SnapshotId: backupURI}); err != nil {
+		t.Fatalf("unexpected error deleting snapshot: %v", err)
+	}
+	for _, uri := range []string{backupURI, copyURI} {
+		if _, err := fs.GetBackup(context.TODO(), uri); !file.IsNotFoundErr(err) {
+			t.Errorf("expected backup %s to be deleted, got %v", uri, err)
+		}
+	}
+}
+
+func TestCreateSnapshotInvalidCopyLocation(t *testing.T) {
+	cases := []struct {
+		name   string
+		params map[string]string
+	}{
+		{
+			name: "same region as the backup",
+			params: map[string]string{
+				util.VolumeSnapshotTypeKey:         util.VolumeSnapshotTypeBackup,
+				util.VolumeSnapshotCopyLocationKey: testRegion,
+			},
+		},
+		{
+			name: "invalid region",
+			params: map[string]string{
+				util.VolumeSnapshotTypeKey:         util.VolumeSnapshotTypeBackup,
+				util.VolumeSnapshotCopyLocationKey: "us-east1-b-c",
+			},
+		},
+		{
+			name: "snapshot type",
+			params: map[string]string{
+				util.VolumeSnapshotTypeKey:         util.VolumeSnapshotTypeSnapshot,
+				util.VolumeSnapshotCopyLocationKey: testCopyRegion,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cs := initTestController(t).(*controllerServer)
+			cs.config.tagManager = cloud.NewFakeTagManagerForSanityTests()
+			_, err := cs.CreateSnapshot(context.TODO(), &csi.CreateSnapshotRequest{
+				SourceVolumeId: "modeInstance/us-central1-c/myinstance/myshare",
+				Name:           "mybackup",
+				Parameters:     tc.params,
+			})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected error code %v, got %v", codes.InvalidArgument, err)
+			}
+		})
+	}
+}
+
+// deletedSourceFileService fails to back up the source volume, as if it had been deleted.
+type deletedSourceFileService struct {
+	file.Service
+}
+
+func (s *deletedSourceFileService) StartCreateBackupOp(ctx context.Context, backupInfo *file.BackupInfo) (*filev1beta1.Operation, error) {
+	return nil, &googleapi.Error{Code: http.StatusNotFound, Errors: []googleapi.ErrorItem{{Reason: "notFound"}}}
+}
+
+func TestCreateSnapshotCopySourceDeleted(t *testing.T) {
+	volumeID := "modeInstance/us-central1-c/myinstance/myshare"
+	copyURI := fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testCopyRegion, "mybackup")
+
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	if _, err := fs.CreateBackup(context.TODO(), &file.BackupInfo{
+		Name:               "mybackup",
+		SourceVolumeId:     volumeID,
+		SourceInstanceName: "myinstance",
+		SourceShare:        "myshare",
+		Project:            testProject,
+		Location:           testRegion,
+		BackupURI:          fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testRegion, "mybackup"),
+		Labels:             map[string]string{tagKeyBackupCopyLocation: testCopyRegion},
+	}); err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+	cs := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: &deletedSourceFileService{Service: fs},
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+	}).(*controllerServer)
+
+	// The copy is given up instead of being retried forever.
+	resp, err := cs.CreateSnapshot(context.TODO(), &csi.CreateSnapshotRequest{
+		SourceVolumeId: volumeID,
+		Name:           "mybackup",
+		Parameters: map[string]string{
+			util.VolumeSnapshotTypeKey:         util.VolumeSnapshotTypeBackup,
+			util.VolumeSnapshotCopyLocationKey: testCopyRegion,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Snapshot.ReadyToUse {
+		t.Errorf("expected snapshot to be ready to use without its copy")
+	}
+	if _, err := fs.GetBackup(context.TODO(), copyURI); !file.IsNotFoundErr(err) {
+		t.Errorf("expected no copy, got %v", err)
+	}
+}
+
+func TestCreateVolumeFromBackupWithCopy(t *testing.T) {
+	backupURI := fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testRegion, "mybackup")
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	info := &file.BackupInfo{
+		Name:               "mybackup",
+		SourceVolumeId:     "modeInstance/us-central1-c/myinstance/myshare",
+		SourceInstanceName: "myinstance",
+		SourceShare:        "myshare",
+		Project:            testProject,
+		Location:           testRegion,
+		BackupURI:          backupURI,
+		Labels:             map[string]string{tagKeyBackupCopyLocation: testCopyRegion},
+	}
+	if _, err := fs.CreateBackup(context.TODO(), info); err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	copyInfo := *info
+	copyInfo.Location = testCopyRegion
+	copyInfo.BackupURI = fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testCopyRegion, "mybackup")
+	copyInfo.Labels = map[string]string{tagKeyBackupCopyOf: testRegion}
+	if _, err := fs.CreateBackup(context.TODO(), &copyInfo); err != nil {
+		t.Fatalf("failed to create copy: %v", err)
+	}
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+	cs := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: fs,
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+		tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+	})
+
+	// The copy may hold different data than the snapshot, so a volume in the region of the
+	// copy is restored from the primary backup too.
+	_, err = cs.CreateVolume(context.TODO(), &csi.CreateVolumeRequest{
+		Name:                "restored",
+		CapacityRange:       &csi.CapacityRange{RequiredBytes: testBytes},
+		VolumeCapabilities:  []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}, AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER}}},
+		VolumeContentSource: &csi.VolumeContentSource{Type: &csi.VolumeContentSource_Snapshot{Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: backupURI}}},
+		AccessibilityRequirements: &csi.TopologyRequirement{
+			Requisite: []*csi.Topology{{Segments: map[string]string{TopologyKeyZone: testCopyRegion + "-b"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restored, err := fs.GetInstance(context.TODO(), &file.ServiceInstance{Name: "restored"})
+	if err != nil {
+		t.Fatalf("failed to get restored instance: %v", err)
+	}
+	if restored.BackupSource != backupURI {
+		t.Errorf("expected volume to be restored from %q, got %q", backupURI, restored.BackupSource)
+	}
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-csi/csi-lib-utils/leaderelection"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/metrics"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// The backup garbage collector periodically deletes the backups created by the driver, for
+// snapshots or as final backups of deleted volumes, once they expire. A backup expires when it
+// is older than its max age, or when it is not among the keep-last newest ready backups of its
+// source volume in its region. The transient backups of abandoned volume clones expire after
+// the retention set when they were created.
+//
+// A backup declares its own retention through the storage_gke_io_backup_retention (e.g. "30d")
+// and storage_gke_io_backup_keep-last (e.g. "5") labels, which can be set through the labels
+// parameter of the VolumeSnapshotClass. These labels are honored for the backups of any
+// cluster of the project, so that the backups of deleted clusters are collected too. The
+// default policy configured on the driver only applies to the backups of this cluster.
+//
+// The copy of a backup in another region is owned by its primary backup: it is deleted along
+// with its primary backup, or once its primary backup is gone, and never on its own.
+//
+// A backup which is still referenced by a VolumeSnapshotContent of this cluster is never
+// deleted, since that would make its VolumeSnapshot unusable, and a run is skipped if the
+// VolumeSnapshotContents cannot be listed. The backups of other clusters are only deleted
+// according to their own labels. With leader election, only the elected controller collects.
+
+const (
+	tagKeyBackupKeepLast = "storage_gke_io_backup_keep-last"
+
+	backupGCReasonMaxAge         = "max_age"
+	backupGCReasonKeepLast       = "keep_last"
+	backupGCReasonPrimaryDeleted = "primary_deleted"
+
+	// backupGCTimeout bounds a garbage collection run.
+	backupGCTimeout = 30 * time.Minute
+
+	backupGCLeaderLockName = "filestore-backup-gc-leader"
+
+	volumeSnapshotContentsPath = "/apis/snapshot.storage.k8s.io/v1/volumesnapshotcontents"
+	// volumeSnapshotContentsPageSize is the number of VolumeSnapshotContents listed per request.
+	volumeSnapshotContentsPageSize = 500
+)
+
+// backupRetention is the retention policy of a backup. Zero values disable the policy.
+type backupRetention struct {
+	maxAge   time.Duration
+	keepLast int
+}
+
+// expiredBackup is a backup to be deleted by the garbage collector.
+type expiredBackup struct {
+	name   string
+	reason string
+	// copyName is the name of the copy of the backup in another region, if any.
+	copyName string
+}
+
+type backupGarbageCollector struct {
+	fileService     file.Service
+	project         string
+	createdBy       string
+	clusterName     string
+	clusterLocation string
+	config          *FeatureBackupGC
+	metricsManager  *metrics.MetricsManager
+	now             func() time.Time
+	// snapshotHandles returns the snapshot handles of the VolumeSnapshotContents of the cluster.
+	snapshotHandles func(ctx context.Context) (map[string]bool, error)
+}
+
+func newBackupGarbageCollector(config *controllerServerConfig) *backupGarbageCollector {
+	clusterLocation := config.cloud.Zone
+	if config.isRegional {
+		if region, err := util.GetRegionFromZone(clusterLocation); err == nil {
+			clusterLocation = region
+		}
+	}
+	gc := &backupGarbageCollector{
+		fileService:     config.fileService,
+		project:         config.cloud.Project,
+		createdBy:       strings.ReplaceAll(config.driver.config.Name, ".", "_"),
+		clusterName:     config.clusterName,
+		clusterLocation: clusterLocation,
+		config:          config.features.FeatureBackupGC,
+		metricsManager:  config.metricsManager,
+		now:             time.Now,
+	}
+	gc.snapshotHandles = gc.listSnapshotHandles
+	return gc
+}
+
+func (gc *backupGarbageCollector) Run(stopCh <-chan struct{}) {
+	if !gc.config.LeaderElection {
+		gc.run(stopCh)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	le := leaderelection.NewLeaderElection(gc.config.KubeClient, backupGCLeaderLockName, func(ctx context.Context) {
+		gc.run(ctx.Done())
+	})
+	le.WithContext(ctx)
+	if gc.config.LeaderElectionNamespace != "" {
+		le.WithNamespace(gc.config.LeaderElectionNamespace)
+	}
+	le.WithLeaseDuration(gc.config.LeaderElectionLeaseDuration)
+	le.WithRenewDeadline(gc.config.LeaderElectionRenewDeadline)
+	le.WithRetryPeriod(gc.config.LeaderElectionRetryPeriod)
+	if err := le.Run(); err != nil {
+		klog.Fatalf("Failed to initialize leader election for the backup garbage collector: %v", err)
+	}
+}
+
+func (gc *backupGarbageCollector) run(stopCh <-chan struct{}) {
+	klog.Infof("Starting backup garbage collector, period %v, max age %v, keep last %d, dry run %v", gc.config.Period, gc.config.MaxAge, gc.config.KeepLast, gc.config.DryRun)
+	wait.Until(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), backupGCTimeout)
+		defer cancel()
+		gc.collect(ctx)
+	}, gc.config.Period, stopCh)
+}
+
+// collect deletes the expired backups, or only logs them in dry-run mode.
+func (gc *backupGarbageCollector) collect(ctx context.Context) error {
+	start := time.Now()
+	backups, err := gc.fileService.ListBackups(ctx, &file.ListFilter{Project: gc.project, Location: "-"})
+	if err != nil {
+		klog.Errorf("Backup garbage collector failed to list backups: %v", err)
+		gc.metricsManager.RecordBackupGCRun(err, time.Since(start))
+		return err
+	}
+
+	// Listed after the backups, so that the backups of snapshots created in the meantime are
+	// not taken for unreferenced ones.
+	handles, err := gc.snapshotHandles(ctx)
+	if err != nil {
+		klog.Errorf("Backup garbage collector failed to list VolumeSnapshotContents, skipping the run: %v", err)
+		gc.metricsManager.RecordBackupGCRun(err, time.Since(start))
+		return err
+	}
+
+	var errs []error
+	for _, backup := range gc.expiredBackups(backups) {
+		if handles[backup.name] {
+			klog.V(4).Infof("Backup garbage collector keeping expired backup %s (%s), it is referenced by a VolumeSnapshotContent", backup.name, backup.reason)
+			continue
+		}
+		if err := gc.deleteBackup(ctx, backup.name, backup.reason); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if backup.copyName == "" {
+			continue
+		}
+		if handles[backup.copyName] {
+			klog.V(4).Infof("Backup garbage collector keeping copy %s of backup %s, it is referenced by a VolumeSnapshotContent", backup.copyName, backup.name)
+			continue
+		}
+		// A copy left behind is deleted on the next run, once its primary backup is gone.
+		if err := gc.deleteBackup(ctx, backup.copyName, backup.reason); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err = errors.Join(errs...)
+	gc.metricsManager.RecordBackupGCRun(err, time.Since(start))
+	return err
+}
+
+// deleteBackup deletes an expired backup, or only logs it in dry-run mode.
+func (gc *backupGarbageCollector) deleteBackup(ctx context.Context, name, reason string) error {
+	if gc.config.DryRun {
+		klog.Infof("Backup garbage collector would delete backup %s (%s), skipped in dry-run mode", name, reason)
+		gc.metricsManager.RecordBackupGCDeletion(reason, true, nil)
+		return nil
+	}
+	err := gc.fileService.DeleteBackup(ctx, name)
+	if file.IsNotFoundErr(err) {
+		err = nil
+	}
+	gc.metricsManager.RecordBackupGCDeletion(reason, false, err)
+	if err != nil {
+		klog.Errorf("Backup garbage collector failed to delete backup %s: %v", name, err)
+		return err
+	}
+	klog.Infof("Backup garbage collector deleted backup %s (%s)", name, reason)
+	return nil
+}
+
+// expiredBackups returns the backups managed by the driver which expired.
+func (gc *backupGarbageCollector) expiredBackups(backups []*file.Backup) []expiredBackup {
+	type candidate struct {
+		backup    *file.Backup
+		created   time.Time
+		retention backupRetention
+	}
+	names := make(map[string]bool)
+	for _, backup := range backups {
+		if backup.Backup != nil {
+			names[backup.Backup.Name] = true
+		}
+	}
+
+	var expired []expiredBackup
+	bySource := make(map[string][]candidate)
+	for _, backup := range backups {
+		if backup.Backup == nil {
+			continue
+		}
+		retention, ok := gc.retention(backup.Backup.Labels)
+		if !ok {
+			continue
+		}
+		if primaryRegion := backup.Backup.Labels[tagKeyBackupCopyOf]; primaryRegion != "" {
+			// Copies follow their primary backup, unless it is gone.
+			primaryName, err := backupCopyURI(backup.Backup.Name, primaryRegion)
+			if err == nil && !names[primaryName] && isDoneBackupState(backup.Backup.State) {
+				expired = append(expired, expiredBackup{name: backup.Backup.Name, reason: backupGCReasonPrimaryDeleted})
+			}
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, backup.Backup.CreateTime)
+		if err != nil {
+			klog.Warningf("Backup garbage collector skipping backup %s with invalid create time %q", backup.Backup.Name, backup.Backup.CreateTime)
+			continue
+		}
+		// Clone backups are not ranked with the snapshots of their source.
+		source := backup.SourceInstance + "/" + backup.SourceShare + "@" + backupRegion(backup.Backup.Name)
+		if backup.Backup.Labels[tagKeyCloneName] != "" {
+			source = cloneBackupPrefix + "/" + source
+		}
+		bySource[source] = append(bySource[source], candidate{backup: backup, created: created, retention: retention})
+	}
+
+	now := gc.now()
+	for _, candidates := range bySource {
+		// Newest first, the rank only counts the ready backups.
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].created.After(candidates[j].created)
+		})
+		ready := 0
+		for _, c := range candidates {
+			switch c.backup.Backup.State {
+			case "READY":
+				ready++
+			case "FAILED":
+			default:
+				// Being created or deleted.
+				continue
+			}
+			var reason string
+			switch {
+			case c.retention.maxAge > 0 && now.Sub(c.created) > c.retention.maxAge:
+				reason = backupGCReasonMaxAge
+			case c.backup.Backup.State == "READY" && c.retention.keepLast > 0 && ready > c.retention.keepLast:
+				reason = backupGCReasonKeepLast
+			default:
+				continue
+			}
+			backup := expiredBackup{name: c.backup.Backup.Name, reason: reason}
+			if copyRegion := c.backup.Backup.Labels[tagKeyBackupCopyLocation]; copyRegion != "" {
+				if copyName, err := backupCopyURI(c.backup.Backup.Name, copyRegion); err == nil {
+					backup.copyName = copyName
+				}
+			}
+			expired = append(expired, backup)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].name < expired[j].name
+	})
+	return expired
+}
+
+// isDoneBackupState returns true if a backup in the given state is neither being created nor
+// being deleted.
+func isDoneBackupState(state string) bool {
+	return state == "READY" || state == "FAILED"
+}
+
+// retention returns the retention policy of a backup with the given labels, and false if the
+// backup is not managed by the garbage collector.
+func (gc *backupGarbageCollector) retention(labels map[string]string) (backupRetention, bool) {
+	if labels[tagKeyCreatedBy] != gc.createdBy {
+		return backupRetention{}, false
+	}
+	if labels[tagKeyCloneName] != "" {
+		// Transient clone backups are deleted by CreateVolume, their retention label only
+		// bounds the lifetime of the backups of abandoned requests.
+		maxAge, err := parseBackupRetention(labels[tagKeyBackupRetention])
+		if err != nil {
+			return backupRetention{}, false
+		}
+		return backupRetention{maxAge: maxAge}, true
+	}
+	if labels[tagKeySnapshotName] == "" && labels[tagKeyFinalBackup] == "" {
+		return backupRetention{}, false
+	}
+
+	var retention backupRetention
+	if gc.ownedByCluster(labels) {
+		retention = backupRetention{maxAge: gc.config.MaxAge, keepLast: gc.config.KeepLast}
+	}
+	if v, ok := labels[tagKeyBackupRetention]; ok {
+		maxAge, err := parseBackupRetention(v)
+		if err != nil {
+			klog.Warningf("Backup garbage collector ignoring invalid %s label %q", tagKeyBackupRetention, v)
+		} else {
+			retention.maxAge = maxAge
+		}
+	}
+	if v, ok := labels[tagKeyBackupKeepLast]; ok {
+		keepLast, err := strconv.Atoi(v)
+		if err != nil || keepLast < 0 {
+			klog.Warningf("Backup garbage collector ignoring invalid %s label %q", tagKeyBackupKeepLast, v)
+		} else {
+			retention.keepLast = keepLast
+		}
+	}
+	return retention, retention.maxAge > 0 || retention.keepLast > 0
+}
+
+// ownedByCluster returns true if the backup with the given labels was created in this cluster.
+func (gc *backupGarbageCollector) ownedByCluster(labels map[string]string) bool {
+	if labels[TagKeyClusterName] != gc.clusterName {
+		return false
+	}
+	location, ok := labels[TagKeyClusterLocation]
+	return !ok || location == gc.clusterLocation
+}
+
+// parseBackupRetention parses a retention label value such as "30d".
+func parseBackupRetention(v string) (time.Duration, error) {
+	if !backupRetentionRegex.MatchString(v) {
+		return 0, errors.New("retention must be a number of days")
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// listSnapshotHandles returns the snapshot handles of the VolumeSnapshotContents of the cluster,
+// both of the dynamically provisioned and of the pre-provisioned ones.
+func (gc *backupGarbageCollector) listSnapshotHandles(ctx context.Context) (map[string]bool, error) {
+	if gc.config.KubeClient == nil {
+		return nil, errors.New("no kubernetes client configured")
+	}
+	handles := make(map[string]bool)
+	var continueToken string
+	for {
+		req := gc.config.KubeClient.Discovery().RESTClient().Get().
+			AbsPath(volumeSnapshotContentsPath).
+			SetHeader("Accept", "application/json").
+			Param("limit", strconv.Itoa(volumeSnapshotContentsPageSize))
+		if continueToken != "" {
+			req = req.Param("continue", continueToken)
+		}
+		body, err := req.DoRaw(ctx)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				// The snapshot CRDs are not installed, so there are no VolumeSnapshotContents.
+				return handles, nil
+			}
+			return nil, err
+		}
+		var list struct {
+			Metadata struct {
+				Continue string `json:"continue"`
+			} `json:"metadata"`
+			Items []struct {
+				Spec struct {
+					Source struct {
+						SnapshotHandle string `json:"snapshotHandle"`
+					} `json:"source"`
+				} `json:"spec"`
+				Status struct {
+					SnapshotHandle string `json:"snapshotHandle"`
+				} `json:"status"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, err
+		}
+		for _, content := range list.Items {
+			for _, handle := range []string{content.Spec.Source.SnapshotHandle, content.Status.SnapshotHandle} {
+				if handle != "" {
+					handles[handle] = true
+				}
+			}
+		}
+		if list.Metadata.Continue == "" {
+			return handles, nil
+		}
+		continueToken = list.Metadata.Continue
+	}
+}
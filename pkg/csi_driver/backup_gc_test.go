@@ -0,0 +1,325 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+func TestBackupGarbageCollector(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	snapshotLabels := func(extra map[string]string) map[string]string {
+		labels := map[string]string{
+			tagKeyCreatedBy:    "test-driver",
+			tagKeySnapshotName: "snapshot",
+		}
+		for k, v := range extra {
+			labels[k] = v
+		}
+		return labels
+	}
+	type testBackup struct {
+		name     string
+		instance string
+		labels   map[string]string
+		age      time.Duration
+		state    string
+		region   string
+	}
+	cases := []struct {
+		name        string
+		clusterName string
+		config      FeatureBackupGC
+		backups     []testBackup
+		// referenced are the backups referenced by VolumeSnapshotContents.
+		referenced    []string
+		handlesErr    error
+		expectDeleted []string
+	}{
+		{
+			name:   "no policy",
+			config: FeatureBackupGC{},
+			backups: []testBackup{
+				{name: "old", instance: "vol1", labels: snapshotLabels(nil), age: 100 * day},
+			},
+		},
+		{
+			name:   "max age from config",
+			config: FeatureBackupGC{MaxAge: 7 * day},
+			backups: []testBackup{
+				{name: "old", instance: "vol1", labels: snapshotLabels(nil), age: 8 * day},
+				{name: "new", instance: "vol1", labels: snapshotLabels(nil), age: day},
+				{name: "old-failed", instance: "vol1", labels: snapshotLabels(nil), age: 8 * day, state: "FAILED"},
+				{name: "old-creating", instance: "vol1", labels: snapshotLabels(nil), age: 8 * day, state: "CREATING"},
+			},
+			expectDeleted: []string{"old", "old-failed"},
+		},
+		{
+			name:   "keep last from config per source volume",
+			config: FeatureBackupGC{KeepLast: 2},
+			backups: []testBackup{
+				{name: "vol1-a", instance: "vol1", labels: snapshotLabels(nil), age: 4 * day},
+				{name: "vol1-b", instance: "vol1", labels: snapshotLabels(nil), age: 3 * day},
+				{name: "vol1-c", instance: "vol1", labels: snapshotLabels(nil), age: 2 * day},
+				{name: "vol1-failed", instance: "vol1", labels: snapshotLabels(nil), age: day, state: "FAILED"},
+				{name: "vol2-a", instance: "vol2", labels: snapshotLabels(nil), age: 4 * day},
+				{name: "vol2-b", instance: "vol2", labels: snapshotLabels(nil), age: 3 * day},
+			},
+			expectDeleted: []string{"vol1-a"},
+		},
+		{
+			name:   "copies are deleted with their primary backup",
+			config: FeatureBackupGC{KeepLast: 1},
+			backups: []testBackup{
+				{name: "vol1-a", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupCopyLocation: testCopyRegion}), age: 2 * day},
+				{name: "vol1-a", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupCopyOf: testRegion}), age: 2 * day, region: testCopyRegion},
+				{name: "vol1-b", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupCopyLocation: testCopyRegion}), age: day},
+				{name: "vol1-b", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupCopyOf: testRegion}), age: day, region: testCopyRegion},
+				// Older than the primary backup, but ranked with it.
+				{name: "vol1-c", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupCopyLocation: testCopyRegion}), age: day / 2},
+				{name: "vol1-c", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupCopyOf: testRegion}), age: 3 * day, region: testCopyRegion},
+			},
+			expectDeleted: []string{"vol1-a", "vol1-a@" + testCopyRegion, "vol1-b", "vol1-b@" + testCopyRegion},
+		},
+		{
+			name:   "copies of deleted primary backups are deleted",
+			config: FeatureBackupGC{KeepLast: 1},
+			backups: []testBackup{
+				{name: "orphan", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupCopyOf: testRegion}), age: day, region: testCopyRegion},
+				{name: "orphan-creating", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupCopyOf: testRegion}), age: day, region: testCopyRegion, state: "CREATING"},
+				{name: "unmanaged-orphan", instance: "vol1", labels: map[string]string{tagKeyBackupCopyOf: testRegion}, age: day, region: testCopyRegion},
+			},
+			expectDeleted: []string{"orphan@" + testCopyRegion},
+		},
+		{
+			name:   "labels override config",
+			config: FeatureBackupGC{MaxAge: 7 * day},
+			backups: []testBackup{
+				{name: "retained", instance: "vol1", labels: snapshotLabels(map[string]string{tagKeyBackupRetention: "30d"}), age: 8 * day},
+				{name: "expired", instance: "vol2", labels: snapshotLabels(map[string]string{tagKeyBackupRetention: "1d"}), age: 2 * day},
+				{name: "invalid-label", instance: "vol3", labels: snapshotLabels(map[string]string{tagKeyBackupRetention: "1month"}), age: 8 * day},
+			},
+			expectDeleted: []string{"expired", "invalid-label"},
+		},
+		{
+			name:   "final backups are collected",
+			config: FeatureBackupGC{},
+			backups: []testBackup{
+				{name: "final", instance: "vol1", labels: map[string]string{tagKeyCreatedBy: "test-driver", tagKeyFinalBackup: "true", tagKeyBackupRetention: "30d"}, age: 31 * day},
+			},
+			expectDeleted: []string{"final"},
+		},
+		{
+			name:   "clone backups expire after their retention",
+			config: FeatureBackupGC{MaxAge: 7 * day, KeepLast: 1},
+			backups: []testBackup{
+				{name: "snapshot", instance: "vol1", labels: snapshotLabels(nil), age: 3 * day},
+				{name: "abandoned-clone", instance: "vol1", labels: map[string]string{tagKeyCreatedBy: "test-driver", tagKeyCloneName: "clone1", tagKeyBackupRetention: cloneBackupRetention}, age: 2 * day},
+				{name: "running-clone", instance: "vol1", labels: map[string]string{tagKeyCreatedBy: "test-driver", tagKeyCloneName: "clone2", tagKeyBackupRetention: cloneBackupRetention}, age: time.Hour},
+			},
+			expectDeleted: []string{"abandoned-clone"},
+		},
+		{
+			name:   "unmanaged backups are kept",
+			config: FeatureBackupGC{MaxAge: day},
+			backups: []testBackup{
+				{name: "other-driver", instance: "vol1", labels: map[string]string{tagKeyCreatedBy: "other_driver", tagKeySnapshotName: "snapshot"}, age: 8 * day},
+				{name: "clone", instance: "vol1", labels: map[string]string{tagKeyCreatedBy: "test-driver", tagKeyCloneName: "clone"}, age: 8 * day},
+				{name: "user", instance: "vol1", age: 8 * day},
+			},
+		},
+		{
+			name:        "config only applies to the backups of the cluster",
+			clusterName: "test-cluster",
+			config:      FeatureBackupGC{MaxAge: day},
+			backups: []testBackup{
+				{name: "this-cluster", instance: "vol1", labels: snapshotLabels(map[string]string{TagKeyClusterName: "test-cluster", TagKeyClusterLocation: testZone}), age: 8 * day},
+				{name: "other-location", instance: "vol2", labels: snapshotLabels(map[string]string{TagKeyClusterName: "test-cluster", TagKeyClusterLocation: "us-east1-b"}), age: 8 * day},
+				{name: "other-cluster", instance: "vol3", labels: snapshotLabels(map[string]string{TagKeyClusterName: "other-cluster"}), age: 8 * day},
+				{name: "other-cluster-with-retention", instance: "vol4", labels: snapshotLabels(map[string]string{TagKeyClusterName: "other-cluster", tagKeyBackupKeepLast: "1"}), age: 8 * day},
+				{name: "other-cluster-newer", instance: "vol4", labels: snapshotLabels(map[string]string{TagKeyClusterName: "other-cluster", tagKeyBackupKeepLast: "1"}), age: day},
+			},
+			expectDeleted: []string{"other-cluster-with-retention", "this-cluster"},
+		},
+		{
+			name:       "backups referenced by VolumeSnapshotContents are kept",
+			config:     FeatureBackupGC{MaxAge: 7 * day},
+			referenced: []string{"bound"},
+			backups: []testBackup{
+				{name: "bound", instance: "vol1", labels: snapshotLabels(nil), age: 8 * day},
+				{name: "unbound", instance: "vol1", labels: snapshotLabels(nil), age: 8 * day},
+			},
+			expectDeleted: []string{"unbound"},
+		},
+		{
+			name:       "run skipped if VolumeSnapshotContents cannot be listed",
+			config:     FeatureBackupGC{MaxAge: 7 * day},
+			handlesErr: errors.New("forbidden"),
+			backups: []testBackup{
+				{name: "old", instance: "vol1", labels: snapshotLabels(nil), age: 8 * day},
+			},
+		},
+		{
+			name:   "dry run",
+			config: FeatureBackupGC{MaxAge: day, DryRun: true},
+			backups: []testBackup{
+				{name: "old", instance: "vol1", labels: snapshotLabels(nil), age: 8 * day},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := file.NewFakeService()
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+			var names []string
+			for _, b := range tc.backups {
+				region := testRegion
+				if b.region != "" {
+					region = b.region
+				}
+				backupURI := fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, region, b.name)
+				backup, err := fs.CreateBackup(context.Background(), &file.BackupInfo{
+					SourceVolumeId:     fmt.Sprintf("modeInstance/%s/%s/vol1", testZone, b.instance),
+					SourceInstanceName: b.instance,
+					SourceShare:        "vol1",
+					Project:            testProject,
+					Location:           testZone,
+					BackupURI:          backupURI,
+					Labels:             b.labels,
+				})
+				if err != nil {
+					t.Fatalf("failed to create fake backup: %v", err)
+				}
+				backup.CreateTime = now.Add(-b.age).Format(time.RFC3339)
+				if b.state != "" {
+					backup.State = b.state
+				}
+				names = append(names, backupURI)
+			}
+
+			cloudProvider, err := cloud.NewFakeCloudWithFiler(fs, testProject, testZone)
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			config := tc.config
+			config.Enabled = true
+			config.Period = time.Hour
+			cs := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fs,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				clusterName: tc.clusterName,
+				features: &GCFSDriverFeatureOptions{
+					FeatureLockRelease: &FeatureLockRelease{},
+					FeatureBackupGC:    &config,
+				},
+			}).(*controllerServer)
+			gc := cs.config.backupGC
+			if gc == nil {
+				t.Fatalf("backup garbage collector not created")
+			}
+			gc.now = func() time.Time { return now }
+			gc.snapshotHandles = func(context.Context) (map[string]bool, error) {
+				handles := make(map[string]bool)
+				for _, name := range tc.referenced {
+					handles[fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testRegion, name)] = true
+				}
+				return handles, tc.handlesErr
+			}
+
+			if err := gc.collect(context.Background()); (err != nil) != (tc.handlesErr != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var deleted []string
+			for i, name := range names {
+				if _, err := fs.GetBackup(context.Background(), name); file.IsNotFoundErr(err) {
+					b := tc.backups[i]
+					if b.region != "" {
+						deleted = append(deleted, b.name+"@"+b.region)
+					} else {
+						deleted = append(deleted, b.name)
+					}
+				}
+			}
+			sort.Strings(deleted)
+			if !reflect.DeepEqual(deleted, tc.expectDeleted) {
+				t.Errorf("got deleted backups %v, expected %v", deleted, tc.expectDeleted)
+			}
+		})
+	}
+}
+
+func TestListSnapshotHandles(t *testing.T) {
+	pages := map[string]string{
+		"": `{"metadata": {"continue": "page2"}, "items": [
+			{"spec": {"source": {"volumeHandle": "modeInstance/us-central1-c/vol1/vol1"}}, "status": {"snapshotHandle": "projects/test-project/locations/us-central1/backups/dynamic"}}
+		]}`,
+		"page2": `{"metadata": {}, "items": [
+			{"spec": {"source": {"snapshotHandle": "projects/test-project/locations/us-central1/backups/pre-provisioned"}}}
+		]}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != volumeSnapshotContentsPath {
+			http.NotFound(w, r)
+			return
+		}
+		page, ok := pages[r.URL.Query().Get("continue")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	gc := &backupGarbageCollector{config: &FeatureBackupGC{KubeClient: client}}
+	handles, err := gc.listSnapshotHandles(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]bool{
+		"projects/test-project/locations/us-central1/backups/dynamic":         true,
+		"projects/test-project/locations/us-central1/backups/pre-provisioned": true,
+	}
+	if !reflect.DeepEqual(handles, expected) {
+		t.Errorf("got handles %v, expected %v", handles, expected)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+)
+
+// A single share volume provisioned with the backup-on-delete parameter gets a final backup
+// before its instance is deleted. DeleteVolume does not receive the StorageClass parameters,
+// so the policy is recorded in the instance labels at creation time. The final backup is named
+// after the instance so that retried DeleteVolume calls reuse it, and it is kept after the
+// instance is gone.
+
+const (
+	finalBackupPrefix = "final"
+
+	// maxBackupNameLength is the maximum length of a Filestore backup name.
+	maxBackupNameLength = 63
+)
+
+var backupRetentionRegex = regexp.MustCompile(`^[0-9]+d$`)
+
+// backupOnDeletePolicy is the final backup policy of a volume.
+type backupOnDeletePolicy struct {
+	// location is the region of the final backup, the region of the instance if empty.
+	location string
+	// retention is set as the retention label of the final backup, e.g. "30d".
+	retention string
+}
+
+// parseBackupOnDelete returns the final backup policy set in the parameters, or nil if
+// backup-on-delete is not enabled.
+func parseBackupOnDelete(params map[string]string) (*backupOnDeletePolicy, error) {
+	var enabledStr string
+	var hasEnabled, hasOptions bool
+	policy := &backupOnDeletePolicy{}
+	for k, v := range params {
+		switch strings.ToLower(k) {
+		case ParamBackupOnDelete:
+			enabledStr, hasEnabled = v, true
+		case ParamBackupOnDeleteLocation:
+			policy.location, hasOptions = v, true
+		case ParamBackupOnDeleteRetention:
+			policy.retention, hasOptions = v, true
+		}
+	}
+	if !hasEnabled {
+		if hasOptions {
+			return nil, fmt.Errorf("%s and %s require %s to be set", ParamBackupOnDeleteLocation, ParamBackupOnDeleteRetention, ParamBackupOnDelete)
+		}
+		return nil, nil
+	}
+	enabled, err := strconv.ParseBool(enabledStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %v", ParamBackupOnDelete, enabledStr, err)
+	}
+	if !enabled {
+		if hasOptions {
+			return nil, fmt.Errorf("%s and %s can only be set when %s is true", ParamBackupOnDeleteLocation, ParamBackupOnDeleteRetention, ParamBackupOnDelete)
+		}
+		return nil, nil
+	}
+	if policy.retention != "" && !backupRetentionRegex.MatchString(policy.retention) {
+		return nil, fmt.Errorf("invalid %s %q, must be a number of days such as \"30d\"", ParamBackupOnDeleteRetention, policy.retention)
+	}
+	return policy, nil
+}
+
+// labels returns the instance labels recording the policy.
+func (p *backupOnDeletePolicy) labels() map[string]string {
+	labels := map[string]string{tagKeyBackupOnDelete: "true"}
+	if p.location != "" {
+		labels[tagKeyBackupOnDeleteLocation] = p.location
+	}
+	if p.retention != "" {
+		labels[tagKeyBackupOnDeleteRetention] = p.retention
+	}
+	return labels
+}
+
+// backupOnDeletePolicyFromLabels returns the final backup policy recorded in the instance
+// labels, or nil if the instance does not need a final backup.
+func backupOnDeletePolicyFromLabels(labels map[string]string) *backupOnDeletePolicy {
+	if labels[tagKeyBackupOnDelete] != "true" {
+		return nil
+	}
+	return &backupOnDeletePolicy{
+		location:  labels[tagKeyBackupOnDeleteLocation],
+		retention: labels[tagKeyBackupOnDeleteRetention],
+	}
+}
+
+// finalBackupName returns the name of the final backup of the given instance.
+func finalBackupName(instanceName string) string {
+	name := fmt.Sprintf("%s-%s", finalBackupPrefix, instanceName)
+	if len(name) > maxBackupNameLength {
+		name = strings.TrimRight(name[:maxBackupNameLength], "-")
+	}
+	return name
+}
+
+// finalBackupLabels returns the labels of the final backup of the instance. The instance
+// labels are carried over, so that the backup can be traced back to its claim, except for the
+// ones recording the policy.
+func finalBackupLabels(instance *file.ServiceInstance, policy *backupOnDeletePolicy) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range instance.Labels {
+		switch k {
+		case tagKeyBackupOnDelete, tagKeyBackupOnDeleteLocation, tagKeyBackupOnDeleteRetention:
+			continue
+		}
+		labels[k] = v
+	}
+	labels[tagKeyFinalBackup] = "true"
+	if policy.retention != "" {
+		labels[tagKeyBackupRetention] = policy.retention
+	}
+	return labels
+}
+
+// ensureFinalBackup makes sure that the final backup of the volume exists and is ready, if the
+// volume has a backup-on-delete policy. It returns an error while the backup is not ready, so
+// that DeleteVolume is retried and the instance is only deleted once the backup is usable.
+func (s *controllerServer) ensureFinalBackup(ctx context.Context, volumeID string, instance *file.ServiceInstance) error {
+	policy := backupOnDeletePolicyFromLabels(instance.Labels)
+	if policy == nil {
+		return nil
+	}
+	if instance.State == "ERROR" {
+		// Filestore cannot back up an instance in error, retrying would never succeed.
+		return status.Errorf(codes.FailedPrecondition, "final backup of volume %s cannot be taken, instance %s is in state %s, remove the %s label from the instance to delete it without a final backup", volumeID, instance.Name, instance.State, tagKeyBackupOnDelete)
+	}
+
+	backupInfo, err := gatherBackupInfo(finalBackupName(instance.Name), volumeID, s.config.cloud.Project)
+	if err != nil {
+		return err
+	}
+	backupURI, region, err := file.CreateBackupURI(backupInfo.Location, backupInfo.Project, backupInfo.Name, policy.location)
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "invalid final backup location for volume %s: %v", volumeID, err)
+	}
+	backupInfo.Location = region
+	backupInfo.BackupURI = backupURI
+
+	existingBackup, err := s.config.fileService.GetBackup(ctx, backupURI)
+	backupExists, err := file.CheckBackupExists(existingBackup, err)
+	if err != nil {
+		return err
+	}
+	if backupExists {
+		switch existingBackup.Backup.State {
+		case "FAILED":
+			// Start over with a new backup on the next attempt.
+			return deleteFailedBackup(ctx, s.config.fileService, backupInfo)
+		case "DELETING":
+			return status.Errorf(codes.Unavailable, "final backup %s of volume %s is being deleted", backupURI, volumeID)
+		}
+		snapshot, err := file.ProcessExistingBackup(ctx, existingBackup, volumeID, modeInstance)
+		if err != nil {
+			return err
+		}
+		if !snapshot.ReadyToUse {
+			return file.BackupNotReadyError(snapshot)
+		}
+		klog.V(4).Infof("Found final backup %s of volume %s", backupURI, volumeID)
+		return nil
+	}
+
+	backupInfo.Labels = finalBackupLabels(instance, policy)
+	klog.Infof("Creating final backup %s of volume %s before deleting it", backupURI, volumeID)
+	snapshot, err := startBackup(ctx, s.config.fileService, backupInfo, modeInstance)
+	if err != nil {
+		return err
+	}
+	if !snapshot.ReadyToUse {
+		return file.BackupNotReadyError(snapshot)
+	}
+	klog.Infof("Created final backup %s of volume %s", backupURI, volumeID)
+	return nil
+}
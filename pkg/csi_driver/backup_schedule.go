@@ -0,0 +1,342 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/leaderelection"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	v1 "sigs.k8s.io/gcp-filestore-csi-driver/pkg/apis/multishare/v1"
+	clientset "sigs.k8s.io/gcp-filestore-csi-driver/pkg/client/clientset/versioned"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// A FilestoreBackupSchedule backs up the volumes of the PVCs it selects on a cron schedule.
+// The backups are taken through CreateSnapshot, so single share and multishare volumes are
+// backed up exactly as for a VolumeSnapshot, and they are pruned through DeleteSnapshot. The
+// snapshot name of a backup is derived from the schedule, the PVC and the schedule time, so
+// that a backup is taken at most once per schedule time even if the status update is lost.
+
+const (
+	backupScheduleSnapshotPrefix = "fbs"
+
+	// maxBackupScheduleHistory bounds the backups listed in the status of a schedule. Only the
+	// records of backups which were never created are dropped to honor it, the others are needed
+	// to prune their backups.
+	maxBackupScheduleHistory = 100
+
+	backupScheduleLeaderLockName = "filestore-backup-schedule-leader"
+
+	// backupScheduleSyncTimeout bounds a sync of all the schedules.
+	backupScheduleSyncTimeout = 30 * time.Minute
+
+	// Keys for the labels of the backups taken by a schedule.
+	tagKeyBackupScheduleName      = "storage_gke_io_backup-schedule_name"
+	tagKeyBackupScheduleNamespace = "storage_gke_io_backup-schedule_namespace"
+)
+
+type backupScheduleController struct {
+	cs         *controllerServer
+	config     *FeatureBackupSchedules
+	kubeClient kubernetes.Interface
+	client     clientset.Interface
+	driverName string
+	syncPeriod time.Duration
+	now        func() time.Time
+}
+
+func newBackupScheduleController(cs *controllerServer) *backupScheduleController {
+	features := cs.config.features.FeatureBackupSchedules
+	return &backupScheduleController{
+		cs:         cs,
+		config:     features,
+		kubeClient: features.KubeClient,
+		client:     features.ClientSet,
+		driverName: cs.config.driver.config.Name,
+		syncPeriod: features.SyncPeriod,
+		now:        time.Now,
+	}
+}
+
+func (c *backupScheduleController) Run(stopCh <-chan struct{}) {
+	if !c.config.LeaderElection {
+		c.run(stopCh)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	le := leaderelection.NewLeaderElection(c.kubeClient, backupScheduleLeaderLockName, func(ctx context.Context) {
+		c.run(ctx.Done())
+	})
+	le.WithContext(ctx)
+	if c.config.LeaderElectionNamespace != "" {
+		le.WithNamespace(c.config.LeaderElectionNamespace)
+	}
+	le.WithLeaseDuration(c.config.LeaderElectionLeaseDuration)
+	le.WithRenewDeadline(c.config.LeaderElectionRenewDeadline)
+	le.WithRetryPeriod(c.config.LeaderElectionRetryPeriod)
+	if err := le.Run(); err != nil {
+		klog.Fatalf("Failed to initialize leader election for the backup schedule controller: %v", err)
+	}
+}
+
+func (c *backupScheduleController) run(stopCh <-chan struct{}) {
+	klog.Infof("Starting backup schedule controller, sync period %v", c.syncPeriod)
+	wait.Until(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), backupScheduleSyncTimeout)
+		defer cancel()
+		c.sync(ctx)
+	}, c.syncPeriod, stopCh)
+}
+
+func (c *backupScheduleController) sync(ctx context.Context) {
+	schedules, err := c.client.MultishareV1().FilestoreBackupSchedules(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf("Failed to list FilestoreBackupSchedule objects: %v", err)
+		return
+	}
+	for i := range schedules.Items {
+		if err := c.syncSchedule(ctx, &schedules.Items[i]); err != nil {
+			klog.Errorf("Failed to sync FilestoreBackupSchedule %s/%s: %v", schedules.Items[i].Namespace, schedules.Items[i].Name, err)
+		}
+	}
+}
+
+// syncSchedule takes the backups of the last schedule time if they were not taken yet, checks
+// the backups which are not ready yet, prunes the expired backups and updates the status.
+func (c *backupScheduleController) syncSchedule(ctx context.Context, schedule *v1.FilestoreBackupSchedule) error {
+	status := &v1.FilestoreBackupScheduleStatus{}
+	if schedule.Status != nil {
+		status = schedule.Status.DeepCopy()
+	}
+
+	if err := c.updateBackups(ctx, schedule, status); err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Error = ""
+	}
+
+	if reflect.DeepEqual(status, schedule.Status) {
+		return nil
+	}
+	updated := schedule.DeepCopy()
+	updated.Status = status
+	_, err := c.client.MultishareV1().FilestoreBackupSchedules(schedule.Namespace).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *backupScheduleController) updateBackups(ctx context.Context, schedule *v1.FilestoreBackupSchedule, status *v1.FilestoreBackupScheduleStatus) error {
+	cron, err := util.ParseCronSchedule(schedule.Spec.Schedule)
+	if err != nil {
+		return err
+	}
+	if schedule.Spec.Selector == nil {
+		return fmt.Errorf("selector must be set")
+	}
+	selector, err := metav1.LabelSelectorAsSelector(schedule.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+
+	now := c.now()
+	last := schedule.CreationTimestamp.Time
+	if status.LastScheduleTime != nil {
+		last = status.LastScheduleTime.Time
+	}
+	// Only the latest schedule time is taken if several were missed.
+	var scheduleTime time.Time
+	for next := cron.Next(last); !next.IsZero() && !next.After(now); next = cron.Next(next) {
+		scheduleTime = next
+	}
+	if !scheduleTime.IsZero() && !schedule.Spec.Suspend {
+		records, err := c.newBackupRecords(ctx, schedule, selector, scheduleTime)
+		if err != nil {
+			return err
+		}
+		status.Backups = append(status.Backups, records...)
+		status.LastScheduleTime = &metav1.Time{Time: scheduleTime}
+	}
+
+	for i := range status.Backups {
+		record := &status.Backups[i]
+		// Backups which could not be created are only retried until the next schedule time.
+		if record.ReadyToUse || (record.SnapshotHandle == "" && (status.LastScheduleTime == nil || !record.ScheduleTime.Equal(status.LastScheduleTime))) {
+			continue
+		}
+		c.takeBackup(ctx, schedule, record)
+	}
+
+	status.Backups = c.pruneBackups(ctx, schedule, status.Backups, now)
+	return nil
+}
+
+// newBackupRecords returns the records of the backups of the selected PVCs for the schedule
+// time, without taking the backups.
+func (c *backupScheduleController) newBackupRecords(ctx context.Context, schedule *v1.FilestoreBackupSchedule, selector labels.Selector, scheduleTime time.Time) ([]v1.FilestoreBackupRecord, error) {
+	pvcs, err := c.kubeClient.CoreV1().PersistentVolumeClaims(schedule.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVCs: %w", err)
+	}
+	var records []v1.FilestoreBackupRecord
+	for _, pvc := range pvcs.Items {
+		if pvc.Status.Phase != corev1.ClaimBound || pvc.Spec.VolumeName == "" {
+			klog.V(4).Infof("FilestoreBackupSchedule %s/%s skipping unbound PVC %s", schedule.Namespace, schedule.Name, pvc.Name)
+			continue
+		}
+		pv, err := c.kubeClient.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get PV %s of PVC %s: %w", pvc.Spec.VolumeName, pvc.Name, err)
+		}
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != c.driverName {
+			klog.V(4).Infof("FilestoreBackupSchedule %s/%s skipping PVC %s, which is not provisioned by %s", schedule.Namespace, schedule.Name, pvc.Name, c.driverName)
+			continue
+		}
+		records = append(records, v1.FilestoreBackupRecord{
+			Name:         backupScheduleSnapshotName(schedule, pvc.Name, scheduleTime),
+			PVCName:      pvc.Name,
+			VolumeHandle: pv.Spec.CSI.VolumeHandle,
+			ScheduleTime: metav1.Time{Time: scheduleTime},
+		})
+	}
+	return records, nil
+}
+
+// takeBackup creates the backup of the record, or checks whether it is ready if it exists.
+func (c *backupScheduleController) takeBackup(ctx context.Context, schedule *v1.FilestoreBackupSchedule, record *v1.FilestoreBackupRecord) {
+	resp, err := c.cs.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{
+		Name:           record.Name,
+		SourceVolumeId: record.VolumeHandle,
+		Parameters:     backupScheduleParameters(schedule),
+	})
+	if err != nil {
+		klog.Errorf("FilestoreBackupSchedule %s/%s failed to back up PVC %s: %v", schedule.Namespace, schedule.Name, record.PVCName, err)
+		record.Error = err.Error()
+		return
+	}
+	record.SnapshotHandle = resp.GetSnapshot().GetSnapshotId()
+	record.ReadyToUse = resp.GetSnapshot().GetReadyToUse()
+	record.Error = ""
+	klog.V(4).Infof("FilestoreBackupSchedule %s/%s backed up PVC %s to %s, ready %v", schedule.Namespace, schedule.Name, record.PVCName, record.SnapshotHandle, record.ReadyToUse)
+}
+
+// pruneBackups deletes the backups which expired according to the retention of the schedule,
+// and returns the remaining records. A backup expires when it is older than the max age, or
+// when there are at least keep-last newer ready backups of the same PVC.
+func (c *backupScheduleController) pruneBackups(ctx context.Context, schedule *v1.FilestoreBackupSchedule, records []v1.FilestoreBackupRecord, now time.Time) []v1.FilestoreBackupRecord {
+	// Newest first.
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].ScheduleTime.After(records[j].ScheduleTime.Time)
+	})
+
+	retention := schedule.Spec.Retention
+	if retention == nil {
+		retention = &v1.FilestoreBackupRetention{}
+	}
+	newerReady := make(map[string]int)
+	var kept []v1.FilestoreBackupRecord
+	for _, record := range records {
+		expired := (retention.MaxAge.Duration > 0 && now.Sub(record.ScheduleTime.Time) > retention.MaxAge.Duration) ||
+			(retention.KeepLast > 0 && newerReady[record.PVCName] >= int(retention.KeepLast))
+		if record.ReadyToUse {
+			newerReady[record.PVCName]++
+		}
+		if !expired {
+			kept = append(kept, record)
+			continue
+		}
+		if record.SnapshotHandle != "" {
+			if _, err := c.cs.DeleteSnapshot(ctx, &csi.DeleteSnapshotRequest{SnapshotId: record.SnapshotHandle}); err != nil {
+				klog.Errorf("FilestoreBackupSchedule %s/%s failed to delete expired backup %s: %v", schedule.Namespace, schedule.Name, record.SnapshotHandle, err)
+				kept = append(kept, record)
+				continue
+			}
+			klog.Infof("FilestoreBackupSchedule %s/%s deleted expired backup %s of PVC %s", schedule.Namespace, schedule.Name, record.SnapshotHandle, record.PVCName)
+		}
+	}
+	// Drop the oldest records of backups which were never created first. A record of a backup
+	// is never dropped, its backup would not be pruned anymore.
+	for i := len(kept) - 1; i >= 0 && len(kept) > maxBackupScheduleHistory; i-- {
+		if kept[i].SnapshotHandle == "" {
+			kept = append(kept[:i], kept[i+1:]...)
+		}
+	}
+	return kept
+}
+
+// backupScheduleSnapshotName returns the snapshot name of the backup of the PVC taken by the
+// schedule at the schedule time, which is a valid Filestore backup name.
+func backupScheduleSnapshotName(schedule *v1.FilestoreBackupSchedule, pvcName string, scheduleTime time.Time) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s", schedule.Namespace, schedule.Name, pvcName)))
+	return fmt.Sprintf("%s-%s-%d", backupScheduleSnapshotPrefix, hex.EncodeToString(hash[:])[:20], scheduleTime.Unix())
+}
+
+// backupScheduleParameters returns the CreateSnapshot parameters of the backups taken by the
+// schedule.
+func backupScheduleParameters(schedule *v1.FilestoreBackupSchedule) map[string]string {
+	backupLabels := map[string]string{
+		tagKeyBackupScheduleName:      backupScheduleLabelValue(schedule.Name),
+		tagKeyBackupScheduleNamespace: backupScheduleLabelValue(schedule.Namespace),
+	}
+	for k, v := range schedule.Spec.Labels {
+		backupLabels[k] = v
+	}
+	keys := make([]string, 0, len(backupLabels))
+	for k := range backupLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+backupLabels[k])
+	}
+
+	params := map[string]string{
+		util.VolumeSnapshotTypeKey: util.VolumeSnapshotTypeBackup,
+		ParameterKeyLabels:         strings.Join(pairs, ","),
+	}
+	if schedule.Spec.BackupLocation != "" {
+		params[util.VolumeSnapshotLocationKey] = schedule.Spec.BackupLocation
+	}
+	return params
+}
+
+// backupScheduleLabelValue converts a Kubernetes object name into a valid label value.
+func backupScheduleLabelValue(name string) string {
+	value := strings.ReplaceAll(name, ".", "_")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return value
+}
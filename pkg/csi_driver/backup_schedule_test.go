@@ -0,0 +1,339 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	v1 "sigs.k8s.io/gcp-filestore-csi-driver/pkg/apis/multishare/v1"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/client/clientset/versioned/fake"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+func TestBackupScheduleController(t *testing.T) {
+	created := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return created.Add(time.Duration(h) * time.Hour) }
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"backup": "hourly"}}
+	handleA := fmt.Sprintf("modeInstance/%s/instance-a/vol1", testZone)
+
+	pvc := func(name string, labels map[string]string, pvName string) *corev1.PersistentVolumeClaim {
+		claim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: pvName},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+		}
+		if pvName != "" {
+			claim.Status.Phase = corev1.ClaimBound
+		}
+		return claim
+	}
+	pv := func(name, driver, handle string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: handle},
+				},
+			},
+		}
+	}
+	objects := []*corev1.PersistentVolumeClaim{
+		pvc("pvc-a", selector.MatchLabels, "pv-a"),
+		pvc("pvc-other-driver", selector.MatchLabels, "pv-other-driver"),
+		pvc("pvc-not-selected", nil, "pv-not-selected"),
+		pvc("pvc-pending", selector.MatchLabels, ""),
+	}
+	volumes := []*corev1.PersistentVolume{
+		pv("pv-a", "test-driver", handleA),
+		pv("pv-other-driver", "other-driver", "other-handle"),
+		pv("pv-not-selected", "test-driver", fmt.Sprintf("modeInstance/%s/instance-b/vol1", testZone)),
+	}
+
+	type record struct {
+		pvc          string
+		scheduleTime time.Time
+	}
+	cases := []struct {
+		name string
+		spec v1.FilestoreBackupScheduleSpec
+		// existing are the records of the backups taken before the sync.
+		existing      []record
+		now           time.Time
+		expectRecords []record
+		expectLast    time.Time
+		expectError   bool
+	}{
+		{
+			name:       "not due",
+			spec:       v1.FilestoreBackupScheduleSpec{Schedule: "@hourly", Selector: selector},
+			now:        created.Add(30 * time.Minute),
+			expectLast: time.Time{},
+		},
+		{
+			name:          "takes the latest missed run",
+			spec:          v1.FilestoreBackupScheduleSpec{Schedule: "@hourly", Selector: selector},
+			now:           hour(2).Add(30 * time.Minute),
+			expectRecords: []record{{pvc: "pvc-a", scheduleTime: hour(2)}},
+			expectLast:    hour(2),
+		},
+		{
+			name: "suspended",
+			spec: v1.FilestoreBackupScheduleSpec{Schedule: "@hourly", Selector: selector, Suspend: true},
+			now:  hour(2),
+		},
+		{
+			name: "keep last",
+			spec: v1.FilestoreBackupScheduleSpec{
+				Schedule:  "@hourly",
+				Selector:  selector,
+				Retention: &v1.FilestoreBackupRetention{KeepLast: 2},
+			},
+			existing:      []record{{pvc: "pvc-a", scheduleTime: hour(1)}, {pvc: "pvc-a", scheduleTime: hour(2)}},
+			now:           hour(3),
+			expectRecords: []record{{pvc: "pvc-a", scheduleTime: hour(3)}, {pvc: "pvc-a", scheduleTime: hour(2)}},
+			expectLast:    hour(3),
+		},
+		{
+			name: "max age",
+			spec: v1.FilestoreBackupScheduleSpec{
+				Schedule:  "@hourly",
+				Selector:  selector,
+				Retention: &v1.FilestoreBackupRetention{MaxAge: metav1.Duration{Duration: 90 * time.Minute}},
+				Suspend:   true,
+			},
+			existing:      []record{{pvc: "pvc-a", scheduleTime: hour(1)}, {pvc: "pvc-a", scheduleTime: hour(2)}},
+			now:           hour(3),
+			expectRecords: []record{{pvc: "pvc-a", scheduleTime: hour(2)}},
+			expectLast:    hour(2),
+		},
+		{
+			name:        "invalid schedule",
+			spec:        v1.FilestoreBackupScheduleSpec{Schedule: "every hour", Selector: selector},
+			now:         hour(2),
+			expectError: true,
+		},
+		{
+			name:        "missing selector",
+			spec:        v1.FilestoreBackupScheduleSpec{Schedule: "@hourly"},
+			now:         hour(2),
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := file.NewFakeService()
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			kubeClient := k8sfake.NewSimpleClientset()
+			for _, claim := range objects {
+				kubeClient.CoreV1().PersistentVolumeClaims(claim.Namespace).Create(context.Background(), claim, metav1.CreateOptions{})
+			}
+			for _, volume := range volumes {
+				kubeClient.CoreV1().PersistentVolumes().Create(context.Background(), volume, metav1.CreateOptions{})
+			}
+
+			schedule := &v1.FilestoreBackupSchedule{
+				ObjectMeta: metav1.ObjectMeta{Name: "hourly", Namespace: "default", CreationTimestamp: metav1.Time{Time: created}},
+				Spec:       tc.spec,
+			}
+			client := fake.NewSimpleClientset(schedule)
+			cs := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fs,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+				features: &GCFSDriverFeatureOptions{
+					FeatureLockRelease: &FeatureLockRelease{},
+					FeatureBackupSchedules: &FeatureBackupSchedules{
+						Enabled:    true,
+						SyncPeriod: time.Minute,
+						KubeClient: kubeClient,
+						ClientSet:  client,
+					},
+				},
+			}).(*controllerServer)
+			c := cs.config.backupSchedules
+			if c == nil {
+				t.Fatalf("backup schedule controller not created")
+			}
+
+			var existingHandles []string
+			if len(tc.existing) > 0 {
+				status := &v1.FilestoreBackupScheduleStatus{}
+				for _, r := range tc.existing {
+					rec := v1.FilestoreBackupRecord{
+						Name:         backupScheduleSnapshotName(schedule, r.pvc, r.scheduleTime),
+						PVCName:      r.pvc,
+						VolumeHandle: handleA,
+						ScheduleTime: metav1.Time{Time: r.scheduleTime},
+					}
+					c.takeBackup(context.Background(), schedule, &rec)
+					if !rec.ReadyToUse {
+						t.Fatalf("failed to take existing backup: %s", rec.Error)
+					}
+					existingHandles = append(existingHandles, rec.SnapshotHandle)
+					status.Backups = append(status.Backups, rec)
+					status.LastScheduleTime = &metav1.Time{Time: r.scheduleTime}
+				}
+				schedule.Status = status
+				if _, err := client.MultishareV1().FilestoreBackupSchedules("default").UpdateStatus(context.Background(), schedule, metav1.UpdateOptions{}); err != nil {
+					t.Fatalf("failed to update status: %v", err)
+				}
+			}
+
+			c.now = func() time.Time { return tc.now }
+			c.sync(context.Background())
+
+			got, err := client.MultishareV1().FilestoreBackupSchedules("default").Get(context.Background(), "hourly", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get schedule: %v", err)
+			}
+			status := got.Status
+			if status == nil {
+				status = &v1.FilestoreBackupScheduleStatus{}
+			}
+			if tc.expectError != (status.Error != "") {
+				t.Errorf("got status error %q, expected error %v", status.Error, tc.expectError)
+			}
+			var last time.Time
+			if status.LastScheduleTime != nil {
+				last = status.LastScheduleTime.Time
+			}
+			if !last.Equal(tc.expectLast) {
+				t.Errorf("got last schedule time %v, expected %v", last, tc.expectLast)
+			}
+
+			var records []record
+			handles := make(map[string]bool)
+			for _, r := range status.Backups {
+				records = append(records, record{pvc: r.PVCName, scheduleTime: r.ScheduleTime.Time.UTC()})
+				if !r.ReadyToUse || r.Error != "" {
+					t.Errorf("backup %s of PVC %s not ready: %s", r.Name, r.PVCName, r.Error)
+				}
+				if _, err := fs.GetBackup(context.Background(), r.SnapshotHandle); err != nil {
+					t.Errorf("backup %s of PVC %s not found: %v", r.SnapshotHandle, r.PVCName, err)
+				}
+				handles[r.SnapshotHandle] = true
+			}
+			if !reflect.DeepEqual(records, tc.expectRecords) {
+				t.Errorf("got records %v, expected %v", records, tc.expectRecords)
+			}
+			for _, handle := range existingHandles {
+				if _, err := fs.GetBackup(context.Background(), handle); !handles[handle] && !file.IsNotFoundErr(err) {
+					t.Errorf("expected pruned backup %s to be deleted, got %v", handle, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBackupScheduleParameters(t *testing.T) {
+	schedule := &v1.FilestoreBackupSchedule{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly.backups", Namespace: "default"},
+		Spec: v1.FilestoreBackupScheduleSpec{
+			BackupLocation: "us-east1",
+			Labels:         map[string]string{"team": "storage"},
+		},
+	}
+	expected := map[string]string{
+		util.VolumeSnapshotTypeKey:     util.VolumeSnapshotTypeBackup,
+		util.VolumeSnapshotLocationKey: "us-east1",
+		ParameterKeyLabels:             "storage_gke_io_backup-schedule_name=nightly_backups,storage_gke_io_backup-schedule_namespace=default,team=storage",
+	}
+	if got := backupScheduleParameters(schedule); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got parameters %v, expected %v", got, expected)
+	}
+}
+
+func TestBackupSchedulePruneHistory(t *testing.T) {
+	created := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	newRecords := func(backups, failed int) []v1.FilestoreBackupRecord {
+		var records []v1.FilestoreBackupRecord
+		for i := 0; i < failed; i++ {
+			records = append(records, v1.FilestoreBackupRecord{PVCName: "pvc-a", ScheduleTime: metav1.Time{Time: created.Add(time.Duration(i) * time.Hour)}})
+		}
+		for i := failed; i < failed+backups; i++ {
+			records = append(records, v1.FilestoreBackupRecord{
+				PVCName:        "pvc-a",
+				ScheduleTime:   metav1.Time{Time: created.Add(time.Duration(i) * time.Hour)},
+				SnapshotHandle: fmt.Sprintf("projects/%s/locations/%s/backups/backup-%d", testProject, testRegion, i),
+				ReadyToUse:     true,
+			})
+		}
+		return records
+	}
+	cases := []struct {
+		name         string
+		backups      int
+		failed       int
+		expectKept   int
+		expectFailed int
+	}{
+		{
+			name:         "within the history",
+			backups:      10,
+			failed:       2,
+			expectKept:   12,
+			expectFailed: 2,
+		},
+		{
+			name:         "oldest failed records dropped",
+			backups:      maxBackupScheduleHistory - 1,
+			failed:       3,
+			expectKept:   maxBackupScheduleHistory,
+			expectFailed: 1,
+		},
+		{
+			name:         "records of backups kept",
+			backups:      maxBackupScheduleHistory + 2,
+			failed:       2,
+			expectKept:   maxBackupScheduleHistory + 2,
+			expectFailed: 0,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &backupScheduleController{}
+			schedule := &v1.FilestoreBackupSchedule{ObjectMeta: metav1.ObjectMeta{Name: "hourly", Namespace: "default"}}
+			kept := c.pruneBackups(context.Background(), schedule, newRecords(tc.backups, tc.failed), created)
+			var failed int
+			for _, r := range kept {
+				if r.SnapshotHandle == "" {
+					failed++
+				}
+			}
+			if len(kept) != tc.expectKept || failed != tc.expectFailed {
+				t.Errorf("got %d records with %d failed, expected %d with %d failed", len(kept), failed, tc.expectKept, tc.expectFailed)
+			}
+		})
+	}
+}
@@ -20,11 +20,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"golang.org/x/net/context"
+	filev1beta1 "google.golang.org/api/file/v1beta1"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
@@ -70,8 +72,13 @@ const (
 	directPeering        = "DIRECT_PEERING"
 	privateServiceAccess = "PRIVATE_SERVICE_ACCESS"
 
+	// createVolumeOpWaitTimeout is how long CreateVolume waits for a running create
+	// instance operation before returning DeadlineExceeded.
+	createVolumeOpWaitTimeout = 5 * time.Second
+
 	// Keys for Topology.
-	TopologyKeyZone = "topology.gke.io/zone"
+	TopologyKeyZone   = "topology.gke.io/zone"
+	TopologyKeyRegion = "topology.gke.io/region"
 )
 
 // Volume attributes
@@ -88,6 +95,7 @@ const (
 	paramTier                      = "tier"
 	paramLocation                  = "location"
 	paramNetwork                   = "network"
+	paramProject                   = "project"
 	ParamReservedIPV4CIDR          = "reserved-ipv4-cidr"
 	ParamReservedIPRange           = "reserved-ip-range"
 	ParamConnectMode               = "connect-mode"
@@ -98,6 +106,9 @@ const (
 	paramMaxVolumeSize             = "max-volume-size"
 	paramFileProtocol              = "protocol"
 	paramMountOptions              = "mount-options"
+	ParamBackupOnDelete            = "backup-on-delete"
+	ParamBackupOnDeleteLocation    = "backup-on-delete-location"
+	ParamBackupOnDeleteRetention   = "backup-on-delete-retention"
 
 	// Keys for PV and PVC parameters as reported by external-provisioner
 	ParameterKeyPVCName      = "csi.storage.k8s.io/pvc/name"
@@ -113,8 +124,18 @@ const (
 	tagKeyCreatedForVolumeName     = "kubernetes_io_created-for_pv_name"
 	tagKeyCreatedBy                = "storage_gke_io_created-by"
 	tagKeySnapshotName             = "storage_gke_io_created-for_csi_snapshot_name"
+	tagKeyCloneName                = "storage_gke_io_created-for_csi_clone_name"
 	TagKeyClusterName              = "storage_gke_io_cluster_name"
 	TagKeyClusterLocation          = "storage_gke_io_cluster_location"
+
+	// Keys for the labels recording the backup-on-delete policy of an instance.
+	tagKeyBackupOnDelete          = "storage_gke_io_backup-on-delete"
+	tagKeyBackupOnDeleteLocation  = "storage_gke_io_backup-on-delete_location"
+	tagKeyBackupOnDeleteRetention = "storage_gke_io_backup-on-delete_retention"
+
+	// Keys for the labels of the final backup taken before deleting an instance.
+	tagKeyFinalBackup     = "storage_gke_io_final-backup"
+	tagKeyBackupRetention = "storage_gke_io_backup_retention"
 )
 
 // Parameters to define capacity range for Filestore tiers
@@ -170,11 +191,28 @@ type controllerServerConfig struct {
 	features             *GCFSDriverFeatureOptions
 	extraVolumeLabels    map[string]string
 	tagManager           cloud.TagService
+	createOps            *operationTracker
+	zoneFallback         *zoneFallback
+	backupGC             *backupGarbageCollector
+	backupSchedules      *backupScheduleController
 }
 
 func newControllerServer(config *controllerServerConfig) csi.ControllerServer {
 	cs := &controllerServer{config: config}
 	config.ipAllocator = util.NewIPAllocator(make(map[string]bool))
+	if config.features != nil && config.features.FeaturePersistentIPReservations != nil && config.features.FeaturePersistentIPReservations.Enabled {
+		reservations := config.features.FeaturePersistentIPReservations
+		store := util.NewConfigMapIPReservationStore(reservations.KubeClient, reservations.Namespace, util.IPReservationConfigMapName)
+		config.ipAllocator = util.NewPersistentIPAllocator(store, reservations.TTL)
+	}
+	config.createOps = newOperationTracker(config.fileService.WaitForInstanceOp)
+	config.zoneFallback = newZoneFallback()
+	if config.features != nil && config.features.FeatureBackupGC != nil && config.features.FeatureBackupGC.Enabled {
+		config.backupGC = newBackupGarbageCollector(config)
+	}
+	if config.features != nil && config.features.FeatureBackupSchedules != nil && config.features.FeatureBackupSchedules.Enabled {
+		config.backupSchedules = newBackupScheduleController(cs)
+	}
 	if config.enableMultishare {
 		config.multiShareController = NewMultishareController(config)
 		config.multiShareController.opsManager.controllerServer = cs
@@ -195,6 +233,13 @@ func newControllerServer(config *controllerServerConfig) csi.ControllerServer {
 }
 
 func (m *controllerServer) Run(stopCh <-chan struct{}) {
+	if m.config.backupGC != nil {
+		go m.config.backupGC.Run(stopCh)
+	}
+	if m.config.backupSchedules != nil {
+		go m.config.backupSchedules.Run(stopCh)
+	}
+
 	if m.config.multiShareController == nil {
 		return
 	}
@@ -259,41 +304,61 @@ func (s *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolu
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	volumeID := getVolumeIDFromFileInstance(newFiler, modeInstance)
+	locations, err := s.candidateLocations(req.GetAccessibilityRequirements(), newFiler.Tier)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid topology error %v", err)
+	}
+
+	// Retries of the request are serialized on the volume ID of the preferred location, and
+	// a fallback location is locked too while the instance is created there.
+	volumeID := getVolumeIDFromFileInstance(newFiler, modeInstance, s.config.cloud.Project)
 	if acquired := s.config.volumeLocks.TryAcquire(volumeID); !acquired {
 		return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, volumeID)
 	}
 	defer s.config.volumeLocks.Release(volumeID)
 
+	var cloneSourceVolumeID string
+	var restoreSnapshotID string
 	if req.GetVolumeContentSource() != nil {
 		if req.GetVolumeContentSource().GetVolume() != nil {
-			return nil, status.Error(codes.InvalidArgument, "Unsupported volume content source")
+			cloneSourceVolumeID = req.GetVolumeContentSource().GetVolume().GetVolumeId()
+			if err := s.validateCloneSource(ctx, cloneSourceVolumeID, newFiler.Volume.SizeBytes); err != nil {
+				return nil, err
+			}
 		}
 
 		if req.GetVolumeContentSource().GetSnapshot() != nil {
 			id := req.GetVolumeContentSource().GetSnapshot().GetSnapshotId()
-			isBackupSource, err := util.IsBackupHandle(id)
-			if err != nil || !isBackupSource {
-				return nil, status.Errorf(codes.InvalidArgument, "Unsupported volume content source %v", id)
-			}
-			_, err = s.config.fileService.GetBackup(ctx, id)
-			if err != nil {
-				klog.Errorf("Failed to get volume %v source snapshot %v: %v", name, id, err.Error())
-				return nil, file.StatusError(err)
+			if util.IsInstanceSnapshotHandle(id) {
+				// The instance is created empty and restored from the snapshot once it is ready.
+				locations, err = s.validateInstanceSnapshotSource(ctx, id, newFiler, locations)
+				if err != nil {
+					return nil, err
+				}
+				restoreSnapshotID = id
+			} else {
+				isBackupSource, err := util.IsBackupHandle(id)
+				if err != nil || !isBackupSource {
+					return nil, status.Errorf(codes.InvalidArgument, "Unsupported volume content source %v", id)
+				}
+				restoreBackup, err := s.config.fileService.GetBackup(ctx, id)
+				if err != nil {
+					klog.Errorf("Failed to get volume %v source snapshot %v: %v", name, id, err.Error())
+					return nil, file.StatusError(err)
+				}
+				if err := validateRestoreSource(restoreBackup, restoreTarget{
+					tier:          newFiler.Tier,
+					protocol:      newFiler.Protocol,
+					kmsKeyName:    newFiler.KmsKeyName,
+					capacityBytes: newFiler.Volume.SizeBytes,
+				}); err != nil {
+					return nil, err
+				}
+				newFiler.BackupSource = id
 			}
-			newFiler.BackupSource = id
 		}
 	}
 
-	// Check if the instance already exists
-	filer, err := s.config.fileService.GetInstance(ctx, newFiler)
-	// No error is returned if the instance is not found during CreateVolume.
-	if err != nil && !file.IsNotFoundErr(err) {
-		// Failed to GetInstance, however the Filestore instance may already be created.
-		// The error should be non-final.
-		return nil, file.StatusError(err)
-	}
-
 	// If tags are used, check if they exist
 	if tags, ok := req.GetParameters()[cloud.ParameterKeyResourceTags]; ok {
 		_, err = s.config.tagManager.ValidateResourceTags(ctx, "CreateVolumeRequest", tags)
@@ -302,31 +367,156 @@ func (s *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolu
 		}
 	}
 
+	// An earlier attempt of this request may have created the instance in any of the
+	// candidate locations, so look for it before picking a location to create it in.
+	filer, err := s.findInstanceInLocations(ctx, newFiler, locations)
+	if err != nil {
+		return nil, err
+	}
+	var capacityErr error
+	for {
+		if filer != nil {
+			newFiler.Location = filer.Location
+		} else if newFiler.Location = s.config.zoneFallback.nextLocation(name, locations); newFiler.Location == "" {
+			attempted := s.config.zoneFallback.exhaustedLocations(name)
+			// Start over from the most preferred location on the next attempt, capacity may
+			// have become available in the meantime.
+			s.config.zoneFallback.forget(name)
+			err := status.Errorf(codes.ResourceExhausted, "no capacity to create volume %v in any of the attempted locations %v, last error: %v", name, attempted, capacityErr)
+			if cloneSourceVolumeID != "" {
+				s.abandonCloneBackup(ctx, name, cloneSourceVolumeID, err)
+			}
+			return nil, err
+		}
+
+		locationVolumeID := getVolumeIDFromFileInstance(newFiler, modeInstance, s.config.cloud.Project)
+		if locationVolumeID != volumeID {
+			if acquired := s.config.volumeLocks.TryAcquire(locationVolumeID); !acquired {
+				return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, locationVolumeID)
+			}
+		}
+		var exhausted bool
+		filer, exhausted, err = s.createInstanceInLocation(ctx, req, newFiler, filer, cloneSourceVolumeID)
+		if locationVolumeID != volumeID {
+			s.config.volumeLocks.Release(locationVolumeID)
+		}
+		if !exhausted {
+			if err != nil {
+				if cloneSourceVolumeID != "" {
+					s.abandonCloneBackup(ctx, name, cloneSourceVolumeID, err)
+				}
+				return nil, err
+			}
+			break
+		}
+		klog.Warningf("Location %v has no capacity for volume %v, trying the next candidate location: %v", newFiler.Location, name, err)
+		s.config.zoneFallback.markExhausted(name, newFiler.Location)
+		capacityErr = err
+		filer = nil
+	}
+	s.config.zoneFallback.forget(name)
+
+	if cloneSourceVolumeID != "" {
+		// The instance is ready, so the transient backup is no longer needed.
+		if err := s.cleanupCloneBackup(ctx, name, cloneSourceVolumeID); err != nil {
+			return nil, err
+		}
+	}
+	if restoreSnapshotID != "" {
+		if err := s.restoreInstanceSnapshot(ctx, name, filer, restoreSnapshotID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.config.tagManager.AttachResourceTags(ctx, cloud.FilestoreInstance, filer.Project, filer.Name, filer.Location, req.GetName(), req.GetParameters()); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	resp := &csi.CreateVolumeResponse{Volume: s.fileInstanceToCSIVolume(filer, modeInstance)}
+	if restoreSnapshotID != "" {
+		resp.Volume.ContentSource = &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Snapshot{
+				Snapshot: &csi.VolumeContentSource_SnapshotSource{
+					SnapshotId: restoreSnapshotID,
+				},
+			},
+		}
+	}
+	if cloneSourceVolumeID != "" {
+		resp.Volume.ContentSource = &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Volume{
+				Volume: &csi.VolumeContentSource_VolumeSource{
+					VolumeId: cloneSourceVolumeID,
+				},
+			},
+		}
+	}
+	if mountOptions, ok := req.GetParameters()[paramMountOptions]; ok && mountOptions != "" {
+		if resp.Volume.VolumeContext == nil {
+			resp.Volume.VolumeContext = make(map[string]string)
+		}
+		resp.Volume.VolumeContext[attrMountOptions] = mountOptions
+	}
+
+	klog.Infof("CreateVolume succeeded: %+v", resp)
+	return resp, nil
+}
+
+// findInstanceInLocations looks up the instance in each of the candidate locations and
+// returns the first one found, or nil if there is none.
+func (s *controllerServer) findInstanceInLocations(ctx context.Context, newFiler *file.ServiceInstance, locations []string) (*file.ServiceInstance, error) {
+	for _, location := range locations {
+		lookup := *newFiler
+		lookup.Location = location
+		filer, err := s.config.fileService.GetInstance(ctx, &lookup)
+		if err != nil {
+			if file.IsNotFoundErr(err) {
+				continue
+			}
+			// Failed to GetInstance, however the Filestore instance may already be created.
+			// The error should be non-final.
+			return nil, file.StatusError(err)
+		}
+		return filer, nil
+	}
+	return nil, nil
+}
+
+// createInstanceInLocation creates the instance in the location of newFiler, or waits for the
+// existing instance to be ready. It returns whether the instance could not be created because
+// the location ran out of capacity, in which case the failed instance has been deleted.
+func (s *controllerServer) createInstanceInLocation(ctx context.Context, req *csi.CreateVolumeRequest, newFiler, filer *file.ServiceInstance, cloneSourceVolumeID string) (*file.ServiceInstance, bool, error) {
+	name := newFiler.Name
+	volumeID := getVolumeIDFromFileInstance(newFiler, modeInstance, s.config.cloud.Project)
+
+	var err error
 	if filer != nil {
 		klog.V(4).Infof("Found existing instance %+v, current instance %+v\n", filer, newFiler)
 		// Instance already exists, check if it meets the request
 		if err = file.CompareInstances(newFiler, filer); err != nil {
-			return nil, status.Error(codes.AlreadyExists, err.Error())
+			return nil, false, status.Error(codes.AlreadyExists, err.Error())
 		}
-		// Check if the filestore instance is in the process of getting created.
-		if filer.State == "CREATING" {
-			msg := fmt.Sprintf("Volume %v not ready, current state: %v", name, filer.State)
-			klog.V(4).Info(msg)
-			return nil, status.Error(codes.DeadlineExceeded, msg)
-		}
-		if filer.State != "READY" {
-			msg := fmt.Sprintf("Volume %v not ready, current state: %v", name, filer.State)
-			klog.V(4).Info(msg)
-			return nil, status.Error(codes.Unavailable, msg)
+		if _, tracked := s.config.createOps.opName(name); tracked || filer.State != "READY" {
+			filer, err = s.waitForInstanceCreation(ctx, name, filer)
 		}
+	} else if _, tracked := s.config.createOps.opName(name); tracked {
+		// The create operation was started, but the instance is not visible (yet).
+		filer, err = s.waitForInstanceCreation(ctx, name, newFiler)
 	} else {
 		param := req.GetParameters()
+		if cloneSourceVolumeID != "" {
+			backupURI, err := s.prepareCloneBackup(ctx, name, cloneSourceVolumeID, param)
+			if err != nil {
+				return nil, false, err
+			}
+			newFiler.BackupSource = backupURI
+		}
+
 		// If we are creating a new instance, we need to pick an unused CIDR range from reserved-ipv4-cidr
 		// If the param was not provided, we default reservedIPRange to "" and cloud provider takes care of the allocation
 		if newFiler.Network.ConnectMode == privateServiceAccess {
 			if reservedIPRange, ok := param[ParamReservedIPRange]; ok {
 				if IsCIDR(reservedIPRange) {
-					return nil, status.Errorf(codes.InvalidArgument, "When using connect mode PRIVATE_SERVICE_ACCESS, if reserved IP range is specified, it must be a named address range instead of direct CIDR value %v", reservedIPRange)
+					return nil, false, status.Errorf(codes.InvalidArgument, "When using connect mode PRIVATE_SERVICE_ACCESS, if reserved IP range is specified, it must be a named address range instead of direct CIDR value %v", reservedIPRange)
 				}
 				newFiler.Network.ReservedIpRange = reservedIPRange
 			}
@@ -338,7 +528,7 @@ func (s *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolu
 			// In case of abort, the CIDR IP is released and available for reservation
 			defer s.config.ipAllocator.ReleaseIPRange(reservedIPRange)
 			if err != nil {
-				return nil, file.StatusError(err)
+				return nil, false, file.StatusError(err)
 			}
 
 			// Adding the reserved IP range to the instance object
@@ -346,34 +536,147 @@ func (s *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolu
 		}
 
 		// Add labels.
-		labels, err := extractLabels(param, s.config.extraVolumeLabels, s.config.driver.config.Name)
+		newFiler.Labels, err = extractLabels(param, s.config.extraVolumeLabels, s.config.driver.config.Name)
 		if err != nil {
-			return nil, file.StatusError(err)
+			return nil, false, file.StatusError(err)
+		}
+		var backupOnDelete *backupOnDeletePolicy
+		if backupOnDelete, err = parseBackupOnDelete(param); err != nil {
+			return nil, false, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if backupOnDelete != nil {
+			for k, v := range backupOnDelete.labels() {
+				newFiler.Labels[k] = v
+			}
 		}
-		newFiler.Labels = labels
 
-		// Create the instance
-		var createErr error
-		filer, createErr = s.config.fileService.CreateInstance(ctx, newFiler)
+		// Start creating the instance, the operation is polled in the background.
+		op, createErr := s.config.fileService.StartCreateInstanceOp(ctx, newFiler)
 		if createErr != nil {
 			klog.Errorf("Create volume for volume Id %s failed: %v", volumeID, createErr.Error())
-			return nil, file.StatusError(createErr)
+			return nil, file.IsCapacityError(createErr), file.StatusError(createErr)
 		}
+		s.config.createOps.track(name, op.Name)
+		filer, err = s.waitForInstanceCreation(ctx, name, newFiler)
+	}
+	if err == nil {
+		return filer, false, nil
+	}
+	if !file.IsCapacityError(err) {
+		return nil, false, err
 	}
 
-	if err := s.config.tagManager.AttachResourceTags(ctx, cloud.FilestoreInstance, filer.Name, filer.Location, req.GetName(), req.GetParameters()); err != nil {
-		return nil, status.Error(codes.Unavailable, err.Error())
+	// Remove the instance left behind if the create operation could not be started, so that it
+	// is neither found by retries of this request nor leaked once the volume is created
+	// elsewhere. The instance of a failed operation has already been deleted.
+	if err := s.deleteFailedInstance(ctx, newFiler); err != nil {
+		return nil, false, err
 	}
-	resp := &csi.CreateVolumeResponse{Volume: s.fileInstanceToCSIVolume(filer, modeInstance)}
-	if mountOptions, ok := req.GetParameters()[paramMountOptions]; ok && mountOptions != "" {
-		if resp.Volume.VolumeContext == nil {
-			resp.Volume.VolumeContext = make(map[string]string)
+	return nil, true, err
+}
+
+// deleteFailedInstance deletes the instance of a failed create operation, if there is one.
+func (s *controllerServer) deleteFailedInstance(ctx context.Context, obj *file.ServiceInstance) error {
+	filer, err := s.config.fileService.GetInstance(ctx, obj)
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil
+		}
+		return file.StatusError(err)
+	}
+	if filer.State == "DELETING" {
+		return nil
+	}
+	if filer.DeletionProtection != nil && filer.DeletionProtection.Enabled {
+		// The instance was requested with deletion protection, which would block its deletion.
+		klog.V(4).Infof("Disabling deletion protection of instance %v/%v left behind by a failed create operation", filer.Location, filer.Name)
+		if err := s.config.fileService.UpdateInstanceDeletionProtection(ctx, filer, &file.DeletionProtection{Enabled: false}); err != nil {
+			klog.Errorf("Disable deletion protection of failed instance %v/%v failed: %v", filer.Location, filer.Name, err.Error())
+			return file.StatusError(err)
 		}
-		resp.Volume.VolumeContext[attrMountOptions] = mountOptions
 	}
+	klog.V(4).Infof("Deleting instance %v/%v left behind by a failed create operation", filer.Location, filer.Name)
+	if err := s.config.fileService.DeleteInstance(ctx, filer); err != nil {
+		klog.Errorf("Delete failed instance %v/%v failed: %v", filer.Location, filer.Name, err.Error())
+		return file.StatusError(err)
+	}
+	return nil
+}
 
-	klog.Infof("CreateVolume succeeded: %+v", resp)
-	return resp, nil
+// waitForInstanceCreation waits briefly for the create operation of the instance backing the
+// named volume and returns the instance once it is ready. While the operation runs it returns
+// DeadlineExceeded so that the provisioner retries. If the operation is not tracked, e.g.
+// after a controller restart, it is looked up from the instance operations and tracked again.
+func (s *controllerServer) waitForInstanceCreation(ctx context.Context, name string, filer *file.ServiceInstance) (*file.ServiceInstance, error) {
+	if _, tracked := s.config.createOps.opName(name); !tracked {
+		ops, err := s.config.fileService.ListInstanceOps(ctx, filer, util.OpVerbCreate)
+		if err != nil {
+			return nil, file.StatusError(err)
+		}
+		op := latestInstanceOp(ops)
+		if op == nil {
+			msg := fmt.Sprintf("Volume %v not ready, current state: %v", name, filer.State)
+			klog.V(4).Info(msg)
+			if filer.State == "CREATING" {
+				return nil, status.Error(codes.DeadlineExceeded, msg)
+			}
+			return nil, status.Error(codes.Unavailable, msg)
+		}
+		klog.V(4).Infof("Resuming tracking of create operation %v for volume %v", op.Name, name)
+		s.config.createOps.track(name, op.Name)
+	}
+
+	opName, _ := s.config.createOps.opName(name)
+	done, opErr := s.config.createOps.wait(ctx, name, createVolumeOpWaitTimeout)
+	if !done {
+		msg := fmt.Sprintf("Volume %v not ready, create operation %v is still running", name, opName)
+		klog.V(4).Info(msg)
+		return nil, status.Error(codes.DeadlineExceeded, msg)
+	}
+	if opErr != nil {
+		klog.Errorf("Create operation %v for volume %v failed: %v", opName, name, opErr)
+		// Remove the instance left in ERROR by the failed operation, so that a retry creates
+		// it again and it is not leaked if the request is abandoned.
+		if err := s.deleteFailedInstance(ctx, filer); err != nil {
+			return nil, err
+		}
+		return nil, file.StatusError(opErr)
+	}
+
+	filer, err := s.config.fileService.GetInstance(ctx, filer)
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+	if filer.State != "READY" {
+		msg := fmt.Sprintf("Volume %v not ready, current state: %v", name, filer.State)
+		klog.V(4).Info(msg)
+		return nil, status.Error(codes.Unavailable, msg)
+	}
+	return filer, nil
+}
+
+// latestInstanceOp returns the running operation if there is one, or else the most recently
+// created operation.
+func latestInstanceOp(ops []*filev1beta1.Operation) *filev1beta1.Operation {
+	var latest *filev1beta1.Operation
+	var latestCreateTime string
+	for _, op := range ops {
+		if !op.Done {
+			return op
+		}
+		var meta filev1beta1.OperationMetadata
+		if op.Metadata != nil {
+			if err := json.Unmarshal(op.Metadata, &meta); err != nil {
+				klog.Warningf("Failed to parse metadata of operation %v: %v", op.Name, err)
+			}
+		}
+		// RFC3339 timestamps in UTC sort lexically.
+		if latest == nil || meta.CreateTime > latestCreateTime {
+			latest = op
+			latestCreateTime = meta.CreateTime
+		}
+	}
+	return latest
 }
 
 // reserveIPRange returns the available IP in the cidr
@@ -389,7 +692,7 @@ func (s *controllerServer) reserveIPRange(ctx context.Context, filer *file.Servi
 	if filer.Tier == highScaleTier {
 		ipRangeSize = util.IpRangeSizeHighScale
 	}
-	unreservedIPBlock, err := s.config.ipAllocator.GetUnreservedIPRange(cidr, ipRangeSize, cloudInstancesReservedIPRanges)
+	unreservedIPBlock, err := s.config.ipAllocator.ReserveIPRange(ctx, cidr, ipRangeSize, cloudInstancesReservedIPRanges, filer.Name)
 	if err != nil {
 		return "", err
 	}
@@ -464,7 +767,7 @@ func (s *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolu
 		return response, nil
 	}
 
-	filer, _, err := getFileInstanceFromID(volumeID)
+	filer, _, err := s.getFileInstanceFromVolumeID(volumeID)
 	if err != nil {
 		// An invalid ID should be treated as doesn't exist
 		klog.V(5).Infof("failed to get instance for volume %v deletion: %v", volumeID, err)
@@ -476,7 +779,6 @@ func (s *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolu
 	}
 	defer s.config.volumeLocks.Release(volumeID)
 
-	filer.Project = s.config.cloud.Project
 	filer, err = s.config.fileService.GetInstance(ctx, filer)
 	if err != nil {
 		if file.IsNotFoundErr(err) {
@@ -489,6 +791,14 @@ func (s *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolu
 		return nil, status.Errorf(codes.DeadlineExceeded, "Volume %s is in state: %s", volumeID, filer.State)
 	}
 
+	if deletionProtectionEnabled(filer) {
+		return nil, status.Errorf(codes.FailedPrecondition, "Volume %s has deletion protection enabled (reason: %q), set the %s mutable parameter to false before deleting it", volumeID, filer.DeletionProtection.Reason, ParamDeletionProtectionEnabled)
+	}
+
+	if err := s.ensureFinalBackup(ctx, volumeID, filer); err != nil {
+		return nil, err
+	}
+
 	err = s.config.fileService.DeleteInstance(ctx, filer)
 	if err != nil {
 		klog.Errorf("Delete volume for volume Id %s failed: %v", volumeID, err.Error())
@@ -510,13 +820,12 @@ func (s *controllerServer) ValidateVolumeCapabilities(ctx context.Context, req *
 	}
 
 	// Check that the volume exists
-	filer, _, err := getFileInstanceFromID(volumeID)
+	filer, _, err := s.getFileInstanceFromVolumeID(volumeID)
 	if err != nil {
 		// An invalid id format is treated as doesn't exist
 		return nil, status.Error(codes.NotFound, err.Error())
 	}
 
-	filer.Project = s.config.cloud.Project
 	newFiler, err := s.config.fileService.GetInstance(ctx, filer)
 	if err != nil && !file.IsNotFoundErr(err) {
 		return nil, file.StatusError(err)
@@ -548,6 +857,246 @@ func (s *controllerServer) ControllerGetCapabilities(ctx context.Context, req *c
 	}, nil
 }
 
+// ListVolumes lists the Filestore instances and multishare shares created by this driver.
+// The entries are ordered by volume ID and the pagination token is the volume ID of the
+// first entry of the next page, so that pages stay stable when volumes are added or deleted
+// between calls.
+func (s *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	klog.V(4).Infof("ListVolumes called with request %+v", req)
+	if req.GetMaxEntries() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "ListVolumes max entries %d must not be negative", req.GetMaxEntries())
+	}
+
+	token := req.GetStartingToken()
+	if token != "" && !isValidListVolumesToken(token) {
+		return nil, status.Errorf(codes.Aborted, "ListVolumes starting token %q is not valid", token)
+	}
+
+	volumes, err := s.listDriverVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].VolumeId < volumes[j].VolumeId
+	})
+
+	start := 0
+	if token != "" {
+		start = sort.Search(len(volumes), func(i int) bool {
+			return volumes[i].VolumeId >= token
+		})
+	}
+	end := len(volumes)
+	if maxEntries := int(req.GetMaxEntries()); maxEntries > 0 && start+maxEntries < end {
+		end = start + maxEntries
+	}
+
+	resp := &csi.ListVolumesResponse{}
+	for _, volume := range volumes[start:end] {
+		resp.Entries = append(resp.Entries, &csi.ListVolumesResponse_Entry{Volume: volume})
+	}
+	if end < len(volumes) {
+		resp.NextToken = volumes[end].VolumeId
+	}
+	return resp, nil
+}
+
+// listDriverVolumes returns a CSI volume for every single share instance and multishare
+// share carrying the created-by label of this driver.
+func (s *controllerServer) listDriverVolumes(ctx context.Context) ([]*csi.Volume, error) {
+	createdBy := strings.ReplaceAll(s.config.driver.config.Name, ".", "_")
+	project := s.config.cloud.Project
+
+	instances, err := s.config.fileService.ListInstances(ctx, &file.ServiceInstance{Project: project})
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+	var volumes []*csi.Volume
+	for _, instance := range instances {
+		if instance.Labels[tagKeyCreatedBy] != createdBy {
+			continue
+		}
+		volumes = append(volumes, s.fileInstanceToCSIVolume(instance, modeInstance))
+	}
+
+	if s.config.multiShareController == nil {
+		return volumes, nil
+	}
+
+	multishareInstances, err := s.config.fileService.ListMultishareInstances(ctx, &file.ListFilter{Project: project, Location: "-"})
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+	for _, instance := range multishareInstances {
+		instancePrefix := instance.Labels[util.ParamMultishareInstanceScLabelKey]
+		if instance.Labels[tagKeyCreatedBy] != createdBy || instancePrefix == "" {
+			continue
+		}
+		shares, err := s.config.fileService.ListShares(ctx, &file.ListFilter{Project: instance.Project, Location: instance.Location, InstanceName: instance.Name})
+		if err != nil {
+			return nil, file.StatusError(err)
+		}
+		for _, share := range shares {
+			volumeID, err := generateMultishareVolumeIdFromShare(instancePrefix, share)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			volumes = append(volumes, multishareToCSIVolume(volumeID, instance, share))
+		}
+	}
+	return volumes, nil
+}
+
+// multishareToCSIVolume converts a multishare share and its parent instance to a CSI volume.
+func multishareToCSIVolume(volumeID string, instance *file.MultishareInstance, share *file.Share) *csi.Volume {
+	fileProtocol := v3FileProtocol
+	if instance.Protocol == v4_1FileProtocol {
+		fileProtocol = v4_1FileProtocol
+	}
+	return &csi.Volume{
+		VolumeId:      volumeID,
+		CapacityBytes: share.CapacityBytes,
+		VolumeContext: map[string]string{
+			attrIP:           instance.Network.Ip,
+			attrFileProtocol: fileProtocol,
+		},
+	}
+}
+
+// isValidListVolumesToken returns true if the token has the format of a volume ID
+// emitted by ListVolumes.
+func isValidListVolumesToken(token string) bool {
+	if isMultishareVolId(token) {
+		_, _, _, _, _, err := parseMultishareVolId(token)
+		return err == nil
+	}
+	_, mode, err := getFileInstanceFromID(token)
+	return err == nil && mode == modeInstance
+}
+
+// ControllerGetVolume resolves the volume handle to the backing Filestore resource and
+// reports its health through the volume condition.
+func (s *controllerServer) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
+	klog.V(4).Infof("ControllerGetVolume called with request %+v", req)
+	volumeID := req.GetVolumeId()
+	if volumeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is empty")
+	}
+
+	if isSharePoolVolumeID(volumeID) {
+		return s.getSharePoolVolume(volumeID)
+	}
+	if isMultishareVolId(volumeID) {
+		return s.getMultishareVolume(ctx, volumeID)
+	}
+
+	filer, mode, err := s.getFileInstanceFromVolumeID(volumeID)
+	if err != nil {
+		// An invalid id format is treated as doesn't exist
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	instance, err := s.config.fileService.GetInstance(ctx, filer)
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil, status.Errorf(codes.NotFound, "Filestore instance %s of volume %s not found", filer.Name, volumeID)
+		}
+		return nil, file.StatusError(err)
+	}
+
+	return &csi.ControllerGetVolumeResponse{
+		Volume: s.fileInstanceToCSIVolume(instance, mode),
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			VolumeCondition: resourceStateToVolumeCondition("instance", instance.Name, instance.State),
+		},
+	}, nil
+}
+
+func (s *controllerServer) getMultishareVolume(ctx context.Context, volumeID string) (*csi.ControllerGetVolumeResponse, error) {
+	if s.config.multiShareController == nil {
+		return nil, status.Error(codes.InvalidArgument, "multishare controller not enabled")
+	}
+	_, project, location, instanceName, shareName, err := parseMultishareVolId(volumeID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	instance, err := s.config.fileService.GetMultishareInstance(ctx, &file.MultishareInstance{
+		Project:  project,
+		Location: location,
+		Name:     instanceName,
+	})
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil, status.Errorf(codes.NotFound, "Filestore instance %s of volume %s not found", instanceName, volumeID)
+		}
+		return nil, file.StatusError(err)
+	}
+	share, err := s.config.fileService.GetShare(ctx, &file.Share{
+		Parent: instance,
+		Name:   shareName,
+	})
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil, status.Errorf(codes.NotFound, "Filestore share %s of volume %s not found", shareName, volumeID)
+		}
+		return nil, file.StatusError(err)
+	}
+
+	// A share is only healthy if its hosting instance is healthy too.
+	condition := resourceStateToVolumeCondition("instance", instance.Name, instance.State)
+	if !condition.Abnormal {
+		condition = resourceStateToVolumeCondition("share", share.Name, share.State)
+	}
+	return &csi.ControllerGetVolumeResponse{
+		Volume: multishareToCSIVolume(volumeID, instance, share),
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			VolumeCondition: condition,
+		},
+	}, nil
+}
+
+// getSharePoolVolume returns the share pool volume encoded in the volume ID. The share pool
+// API has no method to look up an acquired share, so its health is not verified.
+func (s *controllerServer) getSharePoolVolume(volumeID string) (*csi.ControllerGetVolumeResponse, error) {
+	if s.config.features.FeatureSharePools == nil || !s.config.features.FeatureSharePools.Enabled {
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot get share pool volume %q: Share Pools feature is disabled", volumeID)
+	}
+	_, shareID, ipAddress, err := parseSharePoolVolumeID(volumeID)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "failed to parse composite volume ID: %v", err)
+	}
+	return &csi.ControllerGetVolumeResponse{
+		Volume: &csi.Volume{
+			VolumeId: volumeID,
+			VolumeContext: map[string]string{
+				attrIP:     ipAddress,
+				attrVolume: shareID,
+			},
+		},
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			VolumeCondition: &csi.VolumeCondition{
+				Abnormal: false,
+				Message:  fmt.Sprintf("Share pool share %s health is not reported", shareID),
+			},
+		},
+	}, nil
+}
+
+// resourceStateToVolumeCondition reports any Filestore resource state other than READY
+// (e.g. REPAIRING, ERROR, SUSPENDED) as abnormal.
+func resourceStateToVolumeCondition(kind, name, state string) *csi.VolumeCondition {
+	if state != "READY" {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("Filestore %s %s is in state %s", kind, name, state),
+		}
+	}
+	return &csi.VolumeCondition{
+		Abnormal: false,
+		Message:  fmt.Sprintf("Filestore %s %s is ready", kind, name),
+	}
+}
+
 func (s *controllerServer) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
 	klog.V(4).Infof("ControllerModifyVolume called with request %+v", req)
 
@@ -564,6 +1113,28 @@ func (s *controllerServer) ControllerModifyVolume(ctx context.Context, req *csi.
 		return &csi.ControllerModifyVolumeResponse{}, nil
 	}
 
+	if _, ok := params[ParamMutableNfsExportOptions]; ok {
+		if s.config.features.FeatureNFSExportOptionsOnCreate == nil || !s.config.features.FeatureNFSExportOptionsOnCreate.Enabled {
+			return nil, status.Error(codes.InvalidArgument, "nfsExportOptions are disabled")
+		}
+	}
+
+	if isMultishareVolId(volumeID) {
+		if s.config.multiShareController == nil {
+			return nil, status.Error(codes.InvalidArgument, "multishare controller not enabled")
+		}
+		start := time.Now()
+		response, err := s.config.multiShareController.ControllerModifyVolume(ctx, req)
+		duration := time.Since(start)
+		s.config.metricsManager.RecordOperationMetrics(err, methodModifyVolume, modeMultishare, duration)
+		if err != nil {
+			klog.Errorf("ControllerModifyVolume returned error %v, for request: %+v", err, req)
+			return nil, file.StatusError(err)
+		}
+		klog.Infof("ControllerModifyVolume response %+v, for request: %+v", response, req)
+		return response, nil
+	}
+
 	// Lock the volume to prevent conflicts with ExpandVolume and other operations
 	if acquired := s.config.volumeLocks.TryAcquire(volumeID); !acquired {
 		return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, volumeID)
@@ -571,14 +1142,13 @@ func (s *controllerServer) ControllerModifyVolume(ctx context.Context, req *csi.
 	defer s.config.volumeLocks.Release(volumeID)
 
 	// Parse the volume ID to get the instance details
-	filer, _, err := getFileInstanceFromID(volumeID)
+	filer, _, err := s.getFileInstanceFromVolumeID(volumeID)
 	if err != nil {
 		klog.V(4).Infof("failed to parse volume ID %v: %v", volumeID, err)
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
 	// Get current instance state from the cloud provider
-	filer.Project = s.config.cloud.Project
 	filer, err = s.config.fileService.GetInstance(ctx, filer)
 	if err != nil {
 		if file.IsNotFoundErr(err) {
@@ -595,17 +1165,46 @@ func (s *controllerServer) ControllerModifyVolume(ctx context.Context, req *csi.
 		return nil, status.Errorf(codes.InvalidArgument, "Validation failed: %v", err)
 	}
 
-	// If validation returned nil, it means no relevant performance keys were found
-	if perfConfig == nil {
-		klog.V(4).Infof("No performance parameters found in request for volume %s, returning success", volumeID)
-		return &csi.ControllerModifyVolumeResponse{}, nil
+	nfsExportOptions, err := parseMutableNfsExportOptions(params)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Validation failed: %v", err)
 	}
 
-	// Call cloud provider to update instance performance
-	err = s.config.fileService.UpdateInstancePerformance(ctx, filer, perfConfig)
+	deletionProtection, err := parseDeletionProtection(params)
 	if err != nil {
-		klog.Errorf("Update failed for volume %s: %v", volumeID, err)
-		return nil, file.StatusError(err)
+		return nil, status.Errorf(codes.InvalidArgument, "Validation failed: %v", err)
+	}
+
+	// If validation returned nil, it means no relevant performance keys were found
+	if perfConfig != nil {
+		// Call cloud provider to update instance performance
+		err = s.config.fileService.UpdateInstancePerformance(ctx, filer, perfConfig)
+		if err != nil {
+			klog.Errorf("Update failed for volume %s: %v", volumeID, err)
+			return nil, file.StatusError(err)
+		}
+	}
+
+	if nfsExportOptions != nil && !equalNfsExportOptions(filer.NfsExportOptions, nfsExportOptions) {
+		err = s.config.fileService.UpdateInstanceNfsExportOptions(ctx, filer, nfsExportOptions)
+		if err != nil {
+			klog.Errorf("Update of nfs export options failed for volume %s: %v", volumeID, err)
+			return nil, file.StatusError(err)
+		}
+	}
+
+	if deletionProtection != nil {
+		current := &file.DeletionProtection{}
+		if filer.DeletionProtection != nil {
+			current = filer.DeletionProtection
+		}
+		if *current != *deletionProtection {
+			err = s.config.fileService.UpdateInstanceDeletionProtection(ctx, filer, deletionProtection)
+			if err != nil {
+				klog.Errorf("Update of deletion protection failed for volume %s: %v", volumeID, err)
+				return nil, file.StatusError(err)
+			}
+		}
 	}
 
 	klog.Infof("ControllerModifyVolume succeeded for volume %v", volumeID)
@@ -747,6 +1346,7 @@ func (s *controllerServer) generateNewFileInstance(name string, capBytes int64,
 	connectMode := directPeering
 	kmsKeyName := ""
 	fileProtocol := ""
+	project := s.config.cloud.Project
 
 	// Validate parameters (case-insensitive).
 	for k, v := range params {
@@ -770,7 +1370,15 @@ func (s *controllerServer) generateNewFileInstance(name string, capBytes int64,
 				return nil, fmt.Errorf("failed to parse nfs-export-options-on-create %s: %v", v, err)
 			}
 		case paramNetwork:
+			if err := validateNetwork(v); err != nil {
+				return nil, err
+			}
 			network = v
+		case paramProject:
+			if v == "" {
+				return nil, fmt.Errorf("parameter %q must not be empty", paramProject)
+			}
+			project = v
 		case ParamConnectMode:
 			connectMode = v
 			if connectMode != directPeering && connectMode != privateServiceAccess {
@@ -785,6 +1393,12 @@ func (s *controllerServer) generateNewFileInstance(name string, capBytes int64,
 			continue
 		case cloud.ParameterKeyResourceTags:
 			continue
+		// Deletion protection parameters are parsed by parseDeletionProtection.
+		case ParamDeletionProtectionEnabled, ParamDeletionProtectionReason:
+			continue
+		// Backup on delete parameters are parsed by parseBackupOnDelete.
+		case ParamBackupOnDelete, ParamBackupOnDeleteLocation, ParamBackupOnDeleteRetention:
+			continue
 		case paramFileProtocol:
 			if s.config.features.FeatureNFSv4Support.Enabled {
 				fileProtocol = v
@@ -806,6 +1420,44 @@ func (s *controllerServer) generateNewFileInstance(name string, capBytes int64,
 		fileProtocol = v3FileProtocol
 	}
 
+	if _, ok := mutableParams[ParamMutableNfsExportOptions]; ok {
+		if s.config.features.FeatureNFSExportOptionsOnCreate == nil || !s.config.features.FeatureNFSExportOptionsOnCreate.Enabled {
+			return nil, fmt.Errorf("nfsExportOptions are disabled")
+		}
+		if nfsExportOptions != nil {
+			return nil, fmt.Errorf("cannot specify both %s and %s", ParamNfsExportOptions, ParamMutableNfsExportOptions)
+		}
+		nfsExportOptions, err = parseMutableNfsExportOptions(mutableParams)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deletionProtection, err := parseDeletionProtection(params)
+	if err != nil {
+		return nil, err
+	}
+	mutableDeletionProtection, err := parseDeletionProtection(mutableParams)
+	if err != nil {
+		return nil, err
+	}
+	if mutableDeletionProtection != nil {
+		if deletionProtection != nil {
+			return nil, fmt.Errorf("cannot specify deletion protection both as parameter and as mutable parameter")
+		}
+		deletionProtection = mutableDeletionProtection
+	}
+
+	backupOnDelete, err := parseBackupOnDelete(params)
+	if err != nil {
+		return nil, err
+	}
+	if backupOnDelete != nil {
+		if _, _, err := file.CreateBackupURI(location, project, finalBackupName(name), backupOnDelete.location); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ParamBackupOnDeleteLocation, err)
+		}
+	}
+
 	// Validate and set performance configuration if provided from mutable params.
 	perfConfig, err := validateAndBuildPerformanceConfig(mutableParams, capBytes, tier)
 	if err != nil {
@@ -817,7 +1469,7 @@ func (s *controllerServer) generateNewFileInstance(name string, capBytes int64,
 	}
 
 	return &file.ServiceInstance{
-		Project:  s.config.cloud.Project,
+		Project:  project,
 		Name:     name,
 		Location: location,
 		Tier:     tier,
@@ -829,22 +1481,24 @@ func (s *controllerServer) generateNewFileInstance(name string, capBytes int64,
 			Name:      newInstanceVolume,
 			SizeBytes: capBytes,
 		},
-		KmsKeyName:        kmsKeyName,
-		NfsExportOptions:  nfsExportOptions,
-		Protocol:          fileProtocol,
-		PerformanceConfig: perfConfig,
+		KmsKeyName:         kmsKeyName,
+		NfsExportOptions:   nfsExportOptions,
+		Protocol:           fileProtocol,
+		PerformanceConfig:  perfConfig,
+		DeletionProtection: deletionProtection,
 	}, nil
 }
 
 // fileInstanceToCSIVolume generates a CSI volume spec from the cloud Instance
 func (s *controllerServer) fileInstanceToCSIVolume(instance *file.ServiceInstance, mode string) *csi.Volume {
 	resp := &csi.Volume{
-		VolumeId:      getVolumeIDFromFileInstance(instance, mode),
+		VolumeId:      getVolumeIDFromFileInstance(instance, mode, s.config.cloud.Project),
 		CapacityBytes: instance.Volume.SizeBytes,
 		VolumeContext: map[string]string{
 			attrIP:     instance.Network.Ip,
 			attrVolume: instance.Volume.Name,
 		},
+		AccessibleTopology: instanceAccessibleTopology(instance, s.config.features.regionTopologyEnabled()),
 	}
 	if instance.BackupSource != "" {
 		contentSource := &csi.VolumeContentSource{
@@ -907,12 +1561,11 @@ func (s *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi.
 	}
 	defer s.config.volumeLocks.Release(volumeID)
 
-	filer, _, err := getFileInstanceFromID(volumeID)
+	filer, _, err := s.getFileInstanceFromVolumeID(volumeID)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	filer.Project = s.config.cloud.Project
 	filer, err = s.config.fileService.GetInstance(ctx, filer)
 	if err != nil {
 		return nil, file.StatusError(err)
@@ -994,6 +1647,8 @@ func pickZoneFromTopology(top *csi.TopologyRequirement) (string, error) {
 	return reqZones[0], nil
 }
 
+// listZonesFromTopology returns the zones of the preferred topologies followed by those of
+// the requisite topologies, in order and without duplicates.
 func listZonesFromTopology(top *csi.TopologyRequirement) ([]string, error) {
 	reqZones, err := getZonesFromTopology(top.GetRequisite())
 	if err != nil {
@@ -1005,7 +1660,15 @@ func listZonesFromTopology(top *csi.TopologyRequirement) ([]string, error) {
 		return prefZones, fmt.Errorf("could not get zones from preferred topology: %w", err)
 	}
 
-	return append(reqZones, prefZones...), nil
+	zones := []string{}
+	seen := make(map[string]bool)
+	for _, zone := range append(prefZones, reqZones...) {
+		if !seen[zone] {
+			seen[zone] = true
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
 }
 
 func getZonesFromTopology(topList []*csi.Topology) ([]string, error) {
@@ -1024,12 +1687,32 @@ func getZonesFromTopology(topList []*csi.Topology) ([]string, error) {
 	return zones, nil
 }
 
+// instanceAccessibleTopology returns the topology of a regional or enterprise tier instance,
+// which can be mounted from every zone of its region, if the nodes publish the region key.
+// Otherwise, like instances of the other tiers, the instance has no topology, which keeps it
+// accessible from every node.
+func instanceAccessibleTopology(instance *file.ServiceInstance, regionKey bool) []*csi.Topology {
+	tier := strings.ToLower(instance.Tier)
+	if !regionKey || (tier != enterpriseTier && tier != regionalTier) {
+		return nil
+	}
+
+	region := instance.Location
+	if zoneRegion, err := util.GetRegionFromZone(region); err == nil {
+		// The instance is located in a zone.
+		region = zoneRegion
+	}
+	return []*csi.Topology{{Segments: map[string]string{TopologyKeyRegion: region}}}
+}
+
 func getZoneFromSegment(seg map[string]string) (string, error) {
 	var zone string
 	for k, v := range seg {
 		switch k {
 		case TopologyKeyZone:
 			zone = v
+		case TopologyKeyRegion:
+			// The region is implied by the zone.
 		default:
 			return "", fmt.Errorf("topology segment has unknown key %v", k)
 		}
@@ -1131,6 +1814,9 @@ func (s *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSn
 			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
 	}
+	if util.GetSnapshotType(req.GetParameters()) == util.VolumeSnapshotTypeSnapshot {
+		return s.createInstanceSnapshot(ctx, req, backupInfo)
+	}
 
 	// Check for existing snapshot
 	backupLocation := util.GetBackupLocation(req.GetParameters())
@@ -1141,6 +1827,10 @@ func (s *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSn
 		klog.Errorf("Failed to create backup URI from given name %s and location %s, error: %v", req.Name, backupLocation, err.Error())
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	copyLocation := util.GetBackupCopyLocation(req.GetParameters())
+	if err := validateBackupCopyLocation(backupInfo, copyLocation); err != nil {
+		return nil, err
+	}
 	existingBackup, err := s.config.fileService.GetBackup(ctx, backupUri)
 	backupExists, err := file.CheckBackupExists(existingBackup, err)
 	if err != nil {
@@ -1148,6 +1838,9 @@ func (s *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSn
 	}
 
 	var snapshotResponse *csi.CreateSnapshotResponse
+	if backupExists && existingBackup.Backup.State == "FAILED" {
+		return nil, deleteFailedBackup(ctx, s.config.fileService, backupInfo)
+	}
 	if backupExists {
 		// process existing backup
 		snapshot, err := file.ProcessExistingBackup(ctx, existingBackup, volumeID, modeInstance)
@@ -1157,6 +1850,7 @@ func (s *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSn
 		snapshotResponse = &csi.CreateSnapshotResponse{
 			Snapshot: snapshot,
 		}
+		backupInfo.Labels = existingBackup.Backup.Labels
 	} else {
 		// create new backup
 
@@ -1164,32 +1858,27 @@ func (s *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSn
 		if err != nil {
 			return nil, err
 		}
+		if copyLocation != "" {
+			labels[tagKeyBackupCopyLocation] = copyLocation
+		}
 		backupInfo.Labels = labels
 
-		backupObj, err := s.config.fileService.CreateBackup(ctx, backupInfo)
+		snapshot, err := startBackup(ctx, s.config.fileService, backupInfo, modeInstance)
 		if err != nil {
-			klog.Errorf("Create snapshot for volume Id %s failed: %v", volumeID, err.Error())
-			return nil, file.StatusError(err)
-		}
-		tp, err := util.ParseTimestamp(backupObj.CreateTime)
-		if err != nil {
-			return nil, file.StatusError(err)
+			return nil, err
 		}
 		snapshotResponse = &csi.CreateSnapshotResponse{
-			Snapshot: &csi.Snapshot{
-				SizeBytes:      util.GbToBytes(backupObj.CapacityGb),
-				SnapshotId:     backupObj.Name,
-				SourceVolumeId: volumeID,
-				CreationTime:   tp,
-				ReadyToUse:     true,
-			},
+			Snapshot: snapshot,
 		}
-		klog.V(4).Infof("CreateSnapshot succeeded for volume %v, Backup Id: %v", volumeID, backupObj.Name)
+		klog.V(4).Infof("CreateSnapshot started backup %v for volume %v", snapshot.SnapshotId, volumeID)
 	}
 
-	if err := s.config.tagManager.AttachResourceTags(ctx, cloud.FilestoreBackUp, backupInfo.Name, backupInfo.Location, req.GetName(), req.GetParameters()); err != nil {
+	if err := s.config.tagManager.AttachResourceTags(ctx, cloud.FilestoreBackUp, backupInfo.Project, backupInfo.Name, backupInfo.Location, req.GetName(), req.GetParameters()); err != nil {
 		return nil, status.Error(codes.Unavailable, err.Error())
 	}
+	if err := completeBackupCopy(ctx, s.config.fileService, s.config.tagManager, backupInfo, snapshotResponse.Snapshot, req); err != nil {
+		return nil, err
+	}
 
 	return snapshotResponse, nil
 }
@@ -1203,6 +1892,62 @@ func extractBackupLabels(parameters, cliLabels map[string]string, driverName str
 	return labels, nil
 }
 
+// startBackup starts the creation of a backup and returns its snapshot, which is not ready to
+// use until the backup operation completes. The csi-snapshotter calls CreateSnapshot again
+// until the snapshot is ready, and these calls read the state of the existing backup.
+func startBackup(ctx context.Context, fileService file.Service, backupInfo *file.BackupInfo, mode string) (*csi.Snapshot, error) {
+	op, err := fileService.StartCreateBackupOp(ctx, backupInfo)
+	if err != nil {
+		klog.Errorf("Create snapshot for volume Id %s failed: %v", backupInfo.SourceVolumeId, err.Error())
+		return nil, file.StatusError(err)
+	}
+	if err := file.OperationError(op); err != nil {
+		klog.Errorf("Create snapshot for volume Id %s failed: %v", backupInfo.SourceVolumeId, err.Error())
+		return nil, err
+	}
+	backup, err := fileService.GetBackup(ctx, backupInfo.BackupURI)
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+	return file.ProcessExistingBackup(ctx, backup, backupInfo.SourceVolumeId, mode)
+}
+
+// deleteFailedBackup deletes a failed backup, so that the next attempt creates it again
+// instead of reporting the same failure forever, and returns an error with the reason.
+func deleteFailedBackup(ctx context.Context, fileService file.Service, backupInfo *file.BackupInfo) error {
+	reason := backupFailureReason(ctx, fileService, backupInfo)
+	klog.Warningf("Backup %s of volume %s failed: %s, deleting it", backupInfo.BackupURI, backupInfo.SourceVolumeId, reason)
+	if err := fileService.DeleteBackup(ctx, backupInfo.BackupURI); err != nil {
+		return file.StatusError(err)
+	}
+	return status.Errorf(codes.Unavailable, "backup %s of volume %s failed: %s, it will be recreated", backupInfo.BackupURI, backupInfo.SourceVolumeId, reason)
+}
+
+// backupFailureReason returns the error of the latest operation that created the backup.
+func backupFailureReason(ctx context.Context, fileService file.Service, backupInfo *file.BackupInfo) string {
+	reason := "unknown error"
+	ops, err := fileService.ListOps(ctx, &file.ListFilter{Project: backupInfo.Project, Location: backupInfo.Location})
+	if err != nil {
+		klog.Warningf("Failed to list operations for backup %s: %v", backupInfo.BackupURI, err)
+		return reason
+	}
+	var createTime string
+	for _, op := range ops {
+		if op.Error == nil || op.Metadata == nil {
+			continue
+		}
+		var meta filev1beta1.OperationMetadata
+		if err := json.Unmarshal(op.Metadata, &meta); err != nil {
+			continue
+		}
+		if meta.Target == backupInfo.BackupURI && meta.Verb == util.OpVerbCreate && meta.CreateTime >= createTime {
+			reason = op.Error.Message
+			createTime = meta.CreateTime
+		}
+	}
+	return reason
+}
+
 func (s *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
 	id := req.GetSnapshotId()
 	if len(id) == 0 {
@@ -1216,9 +1961,12 @@ func (s *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSn
 		return &csi.DeleteSnapshotResponse{}, nil
 	}
 
+	if util.IsInstanceSnapshotHandle(id) {
+		return s.deleteInstanceSnapshot(ctx, id)
+	}
 	if !isBackup {
 		klog.Errorf("Deletion of volume snapshot type %q not supported", id)
-		return nil, status.Error(codes.InvalidArgument, "deletion is only supported for volume snapshots of type backup")
+		return nil, status.Error(codes.InvalidArgument, "deletion is only supported for volume snapshots of type backup or snapshot")
 	}
 
 	backup, err := s.config.fileService.GetBackup(ctx, id)
@@ -1234,6 +1982,10 @@ func (s *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSn
 		return nil, status.Errorf(codes.DeadlineExceeded, "Volume snapshot with ID %v is in state %s", id, backup.Backup.State)
 	}
 
+	// The copy is deleted first, so that it is not leaked if the deletion is interrupted.
+	if err := deleteBackupCopy(ctx, s.config.fileService, backup); err != nil {
+		return nil, err
+	}
 	if err = s.config.fileService.DeleteBackup(ctx, id); err != nil {
 		klog.Errorf("Delete snapshot for backup Id %s failed: %v", id, err.Error())
 		return nil, file.StatusError(err)
@@ -1242,6 +1994,200 @@ func (s *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSn
 	return &csi.DeleteSnapshotResponse{}, nil
 }
 
+// ListSnapshots lists the Filestore backups created by this driver, optionally filtered by
+// snapshot ID or source volume ID. Like ListVolumes, entries are ordered by snapshot ID and
+// the pagination token is the snapshot ID of the first entry of the next page.
+func (s *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	klog.V(4).Infof("ListSnapshots called with request %+v", req)
+	if req.GetMaxEntries() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "ListSnapshots max entries %d must not be negative", req.GetMaxEntries())
+	}
+
+	token := req.GetStartingToken()
+	if token != "" {
+		if _, err := util.IsBackupHandle(token); err != nil {
+			return nil, status.Errorf(codes.Aborted, "ListSnapshots starting token %q is not valid", token)
+		}
+	}
+
+	var snapshots []*csi.Snapshot
+	var err error
+	if snapshotID := req.GetSnapshotId(); snapshotID != "" {
+		snapshots, err = s.getDriverSnapshot(ctx, snapshotID)
+	} else {
+		snapshots, err = s.listDriverSnapshots(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceVolumeID := req.GetSourceVolumeId(); sourceVolumeID != "" {
+		var filtered []*csi.Snapshot
+		for _, snapshot := range snapshots {
+			if snapshot.SourceVolumeId == sourceVolumeID {
+				filtered = append(filtered, snapshot)
+			}
+		}
+		snapshots = filtered
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].SnapshotId < snapshots[j].SnapshotId
+	})
+
+	start := 0
+	if token != "" {
+		start = sort.Search(len(snapshots), func(i int) bool {
+			return snapshots[i].SnapshotId >= token
+		})
+	}
+	end := len(snapshots)
+	if maxEntries := int(req.GetMaxEntries()); maxEntries > 0 && start+maxEntries < end {
+		end = start + maxEntries
+	}
+
+	resp := &csi.ListSnapshotsResponse{}
+	for _, snapshot := range snapshots[start:end] {
+		resp.Entries = append(resp.Entries, &csi.ListSnapshotsResponse_Entry{Snapshot: snapshot})
+	}
+	if end < len(snapshots) {
+		resp.NextToken = snapshots[end].SnapshotId
+	}
+	return resp, nil
+}
+
+// getDriverSnapshot looks up a single backup or instance snapshot by its snapshot ID. The
+// created-by label is not required here so that pre-provisioned snapshots can be resolved.
+func (s *controllerServer) getDriverSnapshot(ctx context.Context, snapshotID string) ([]*csi.Snapshot, error) {
+	if util.IsInstanceSnapshotHandle(snapshotID) {
+		snapshot, err := s.getInstanceSnapshot(ctx, snapshotID)
+		if err != nil {
+			if file.IsNotFoundErr(err) {
+				return nil, nil
+			}
+			return nil, file.StatusError(err)
+		}
+		return []*csi.Snapshot{snapshot}, nil
+	}
+	if isBackup, err := util.IsBackupHandle(snapshotID); err != nil || !isBackup {
+		// An invalid snapshot ID is treated as doesn't exist
+		klog.V(4).Infof("Could not parse snapshot handle %v", snapshotID)
+		return nil, nil
+	}
+	backup, err := s.config.fileService.GetBackup(ctx, snapshotID)
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil, nil
+		}
+		return nil, file.StatusError(err)
+	}
+	snapshot, err := s.backupToCSISnapshot(ctx, backup, map[string]string{})
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+	return []*csi.Snapshot{snapshot}, nil
+}
+
+// listDriverSnapshots returns a CSI snapshot for every backup and instance snapshot carrying
+// the created-by label of this driver.
+func (s *controllerServer) listDriverSnapshots(ctx context.Context) ([]*csi.Snapshot, error) {
+	createdBy := strings.ReplaceAll(s.config.driver.config.Name, ".", "_")
+	backups, err := s.config.fileService.ListBackups(ctx, &file.ListFilter{Project: s.config.cloud.Project, Location: "-"})
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+
+	var snapshots []*csi.Snapshot
+	// Storage class prefixes of multishare instances, keyed by instance URI.
+	scPrefixes := map[string]string{}
+	for _, backup := range backups {
+		if backup.Backup == nil || backup.Backup.Labels[tagKeyCreatedBy] != createdBy {
+			continue
+		}
+		// Transient backups of volume clones are not snapshots.
+		if backup.Backup.Labels[tagKeyCloneName] != "" {
+			continue
+		}
+		snapshot, err := s.backupToCSISnapshot(ctx, backup, scPrefixes)
+		if err != nil {
+			return nil, file.StatusError(err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	instanceSnapshots, err := s.listDriverInstanceSnapshots(ctx, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	return append(snapshots, instanceSnapshots...), nil
+}
+
+func (s *controllerServer) backupToCSISnapshot(ctx context.Context, backup *file.Backup, scPrefixes map[string]string) (*csi.Snapshot, error) {
+	sourceVolumeID, err := s.backupSourceVolumeID(ctx, backup, scPrefixes)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := util.ParseTimestamp(backup.Backup.CreateTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse create timestamp for backup %v: %w", backup.Backup.Name, err)
+	}
+	return &csi.Snapshot{
+		SizeBytes:       util.GbToBytes(backup.Backup.CapacityGb),
+		SnapshotId:      backup.Backup.Name,
+		SourceVolumeId:  sourceVolumeID,
+		CreationTime:    tp,
+		ReadyToUse:      backup.Backup.State == "READY",
+		GroupSnapshotId: volumeGroupSnapshotID(backup),
+	}, nil
+}
+
+// backupSourceVolumeID maps the source instance and share of a backup back to the CSI
+// volume handle. Multishare volume handles also carry the storage class prefix, which is
+// recorded in the backup labels, or read from the labels of the source instance for the
+// backups taken before. If the source instance of such a backup is gone, the source
+// volume ID is left empty.
+func (s *controllerServer) backupSourceVolumeID(ctx context.Context, backup *file.Backup, scPrefixes map[string]string) (string, error) {
+	project, location, instanceName, shareName, err := util.ParseShareURI(backup.SourceInstance)
+	if err != nil {
+		// Single share instance backups are sourced from an instance URI.
+		return util.BackupVolumeSourceToCSIVolumeHandle(modeInstance, backup.SourceInstance, backup.SourceShare)
+	}
+	instanceURI := strings.TrimSuffix(backup.SourceInstance, "/shares/"+shareName)
+	prefix := ""
+	if backup.Backup != nil {
+		prefix = backup.Backup.Labels[util.ParamMultishareInstanceScLabelKey]
+	}
+	if prefix == "" {
+		var ok bool
+		prefix, ok = scPrefixes[instanceURI]
+		if !ok {
+			instance, err := s.config.fileService.GetMultishareInstance(ctx, &file.MultishareInstance{
+				Project:  project,
+				Location: location,
+				Name:     instanceName,
+			})
+			if err != nil && !file.IsNotFoundErr(err) {
+				return "", fmt.Errorf("failed to get source instance %v: %w", instanceURI, err)
+			}
+			if instance != nil {
+				prefix = instance.Labels[util.ParamMultishareInstanceScLabelKey]
+			}
+			scPrefixes[instanceURI] = prefix
+		}
+	}
+	if prefix == "" {
+		klog.V(4).Infof("Storage class of the source instance %v of backup %v is unknown, the source volume ID is not reported", instanceURI, backup.Backup.Name)
+		return "", nil
+	}
+	return generateMultishareVolumeIdFromShare(prefix, &file.Share{
+		Name: shareName,
+		Parent: &file.MultishareInstance{
+			Project:  project,
+			Location: location,
+			Name:     instanceName,
+		},
+	})
+}
+
 func parseNfsExportOptions(optionsString string) ([]*file.NfsExportOptions, error) {
 	if optionsString == "" {
 		return nil, nil
@@ -17,13 +17,18 @@ limitations under the License.
 package driver
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/net/context"
+	filev1beta1 "google.golang.org/api/file/v1beta1"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/testing/protocmp"
@@ -395,7 +400,7 @@ func TestCreateVolumeFromSnapshot(t *testing.T) {
 		}
 
 		cs.config.tagManager.(*cloud.FakeTagServiceManager).
-			On("AttachResourceTags", context.TODO(), cloud.FilestoreInstance, testCSIVolume, testLocation, test.req.GetName(), test.req.GetParameters()).
+			On("AttachResourceTags", context.TODO(), cloud.FilestoreInstance, testProject, testCSIVolume, testLocation, test.req.GetName(), test.req.GetParameters()).
 			Return(nil)
 
 		//Create initial backup
@@ -412,7 +417,10 @@ func TestCreateVolumeFromSnapshot(t *testing.T) {
 			backupInfo.BackupURI = test.resp.Volume.ContentSource.GetSnapshot().SnapshotId
 		}
 
-		cs.config.fileService.CreateBackup(context.TODO(), backupInfo)
+		if backup, err := cs.config.fileService.CreateBackup(context.TODO(), backupInfo); err == nil {
+			backup.CapacityGb = util.BytesToGb(test.initialBackup.s.Volume.SizeBytes)
+			backup.SourceInstanceTier = test.initialBackup.s.Tier
+		}
 
 		// Restore from backup
 		resp, err := cs.CreateVolume(context.TODO(), test.req)
@@ -425,17 +433,817 @@ func TestCreateVolumeFromSnapshot(t *testing.T) {
 		if !test.expectErr && test.req.Parameters[ParamNfsExportOptions] != "" {
 			instance, err := cs.config.fileService.GetInstance(context.TODO(), &file.ServiceInstance{Name: test.req.Name})
 			if err != nil {
-				t.Errorf("test %q failed: couldn't get instance %v: %v", test.name, resp.Volume.VolumeId, err)
-				return
+				t.Errorf("test %q failed: couldn't get instance %v: %v", test.name, resp.Volume.VolumeId, err)
+				return
+			}
+			for i := range test.expectedOptions {
+				if !reflect.DeepEqual(instance.NfsExportOptions[i], test.expectedOptions[i]) {
+					t.Errorf("test %q failed; nfs export options not equal at index %d: got %+v, expected %+v", test.name, i, instance.NfsExportOptions[i], test.expectedOptions[i])
+				}
+			}
+		}
+		if !cmp.Equal(resp, test.resp, protocmp.Transform()) {
+			t.Errorf("test %q failed: got resp %+v, expected %+v, diff: %s", test.name, resp, test.resp, cmp.Diff(resp, test.resp, protocmp.Transform()))
+		}
+	}
+}
+
+func TestCreateVolumeFromVolume(t *testing.T) {
+	cloneName := "clone-volume"
+	cloneBackupID := fmt.Sprintf("projects/%s/locations/%s/backups/clone-%s", testProject, testRegion, cloneName)
+	volumeSource := func(id string) *csi.VolumeContentSource {
+		return &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Volume{
+				Volume: &csi.VolumeContentSource_VolumeSource{VolumeId: id},
+			},
+		}
+	}
+	cases := []struct {
+		name   string
+		source string
+		// backupSource is the source instance of a pre-existing clone backup.
+		backupSource string
+		// sourceBytes overrides the capacity of the source instance.
+		sourceBytes  int64
+		params       map[string]string
+		expectedCode codes.Code
+	}{
+		{
+			name:   "clone single share volume",
+			source: testVolumeID,
+		},
+		{
+			name:         "retry with clone backup from previous attempt",
+			source:       testVolumeID,
+			backupSource: testCSIVolume,
+		},
+		{
+			name:         "clone backup of a different source",
+			source:       testVolumeID,
+			backupSource: testCSIVolume2,
+			expectedCode: codes.AlreadyExists,
+		},
+		{
+			name:         "source volume not found",
+			source:       "modeInstance/us-central1-c/deleted/vol1",
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "invalid source volume id",
+			source:       "invalid-id",
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "multishare source volume",
+			source:       testMultishareVolumeID,
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "source volume larger than requested capacity",
+			source:       testVolumeID,
+			sourceBytes:  2 * testBytes,
+			expectedCode: codes.OutOfRange,
+		},
+		{
+			name:   "clone backup deleted on final error",
+			source: testVolumeID,
+			params: map[string]string{
+				ParamConnectMode:     privateServiceAccess,
+				ParamReservedIPRange: "10.0.0.0/29",
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := file.NewFakeService()
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+			for _, name := range []string{testCSIVolume, testCSIVolume2} {
+				sizeBytes := int64(testBytes)
+				if name == testCSIVolume && tc.sourceBytes != 0 {
+					sizeBytes = tc.sourceBytes
+				}
+				if _, err := fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: name, Location: testZone, Tier: defaultTier, Volume: file.Volume{Name: "vol1", SizeBytes: sizeBytes}}); err != nil {
+					t.Fatalf("failed to create fake instance: %v", err)
+				}
+			}
+			if tc.backupSource != "" {
+				if _, err := fs.CreateBackup(context.Background(), &file.BackupInfo{
+					Name:               "clone-" + cloneName,
+					SourceVolumeId:     fmt.Sprintf("modeInstance/%s/%s/vol1", testZone, tc.backupSource),
+					BackupURI:          cloneBackupID,
+					SourceInstanceName: tc.backupSource,
+					SourceShare:        "vol1",
+					Project:            testProject,
+					Location:           testRegion,
+				}); err != nil {
+					t.Fatalf("failed to create fake backup: %v", err)
+				}
+			}
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			ctrl := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fs,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+				tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+			})
+
+			req := &csi.CreateVolumeRequest{
+				Name:                cloneName,
+				CapacityRange:       &csi.CapacityRange{RequiredBytes: testBytes},
+				VolumeCapabilities:  []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}, AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER}}},
+				VolumeContentSource: volumeSource(tc.source),
+				Parameters:          tc.params,
+			}
+			resp, err := ctrl.CreateVolume(context.Background(), req)
+			if tc.expectedCode != codes.OK {
+				if status.Code(err) != tc.expectedCode {
+					t.Fatalf("expected error code %v, got: %v", tc.expectedCode, err)
+				}
+				// The clone backup is only kept if it belongs to another request.
+				_, err := fs.GetBackup(context.Background(), cloneBackupID)
+				if tc.expectedCode == codes.AlreadyExists {
+					if err != nil {
+						t.Errorf("expected conflicting clone backup to be kept, got: %v", err)
+					}
+				} else if !file.IsNotFoundErr(err) {
+					t.Errorf("expected no clone backup after final error, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(volumeSource(tc.source), resp.GetVolume().GetContentSource(), protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected content source (-want +got):\n%s", diff)
+			}
+			clone, err := fs.GetInstance(context.Background(), &file.ServiceInstance{Name: cloneName})
+			if err != nil {
+				t.Fatalf("failed to get cloned instance: %v", err)
+			}
+			if clone.BackupSource != cloneBackupID {
+				t.Errorf("expected cloned instance to be restored from %q, got %q", cloneBackupID, clone.BackupSource)
+			}
+			if _, err := fs.GetBackup(context.Background(), cloneBackupID); !file.IsNotFoundErr(err) {
+				t.Errorf("expected clone backup to be deleted, got: %v", err)
+			}
+
+			// A retried call finds the ready instance and does not create another backup.
+			if _, err := ctrl.CreateVolume(context.Background(), req); err != nil {
+				t.Fatalf("unexpected error on retry: %v", err)
+			}
+			if _, err := fs.GetBackup(context.Background(), cloneBackupID); !file.IsNotFoundErr(err) {
+				t.Errorf("expected no clone backup after retry, got: %v", err)
+			}
+		})
+	}
+}
+
+// asyncCreateFileService starts create instance operations that keep running until they are
+// finished by the test.
+type asyncCreateFileService struct {
+	file.Service
+	mu  sync.Mutex
+	ops map[string]*filev1beta1.Operation
+}
+
+func newAsyncCreateFileService(t *testing.T) *asyncCreateFileService {
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	return &asyncCreateFileService{Service: fs, ops: map[string]*filev1beta1.Operation{}}
+}
+
+func (s *asyncCreateFileService) StartCreateInstanceOp(ctx context.Context, obj *file.ServiceInstance) (*filev1beta1.Operation, error) {
+	instance, err := s.Service.CreateInstance(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	instance.State = "CREATING"
+	op := &filev1beta1.Operation{Name: "operation-" + obj.Name}
+	s.ops[obj.Name] = op
+	return op, nil
+}
+
+func (s *asyncCreateFileService) WaitForInstanceOp(ctx context.Context, opName string) (*filev1beta1.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, op := range s.ops {
+		if op.Name == opName && op.Done {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %v is not done", opName)
+}
+
+func (s *asyncCreateFileService) ListInstanceOps(ctx context.Context, obj *file.ServiceInstance, operationType string) ([]*filev1beta1.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if op, ok := s.ops[obj.Name]; ok && operationType == util.OpVerbCreate {
+		return []*filev1beta1.Operation{op}, nil
+	}
+	return nil, nil
+}
+
+// finish completes the create operation of the instance, failing it if opErr is set.
+func (s *asyncCreateFileService) finish(t *testing.T, name string, opErr *filev1beta1.Status) {
+	instance, err := s.Service.GetInstance(context.Background(), &file.ServiceInstance{Name: name})
+	if err != nil {
+		t.Fatalf("failed to get instance: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops[name].Done = true
+	s.ops[name].Error = opErr
+	instance.State = "READY"
+	if opErr != nil {
+		instance.State = "ERROR"
+	}
+}
+
+func TestCreateVolumeAsync(t *testing.T) {
+	volumeName := "async-volume"
+	stockout := &filev1beta1.Status{Code: int64(codes.ResourceExhausted), Message: "zone does not have enough resources"}
+	cases := []struct {
+		name string
+		// restart replaces the controller server while the operation runs.
+		restart bool
+		// restartAfterFinish replaces the controller server once the operation finished.
+		restartAfterFinish bool
+		opErr              *filev1beta1.Status
+		expectedCode       codes.Code
+	}{
+		{
+			name: "create operation succeeds",
+		},
+		{
+			name:         "create operation fails",
+			opErr:        stockout,
+			expectedCode: codes.ResourceExhausted,
+		},
+		{
+			name:    "create operation succeeds after controller restart",
+			restart: true,
+		},
+		{
+			name:         "create operation fails after controller restart",
+			restart:      true,
+			opErr:        stockout,
+			expectedCode: codes.ResourceExhausted,
+		},
+		{
+			name:         "create operation fails with an internal error",
+			opErr:        &filev1beta1.Status{Code: int64(codes.Internal), Message: "internal error"},
+			expectedCode: codes.Internal,
+		},
+		{
+			name:               "create operation failed while controller was down",
+			restartAfterFinish: true,
+			opErr:              stockout,
+			expectedCode:       codes.ResourceExhausted,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := newAsyncCreateFileService(t)
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			newController := func() *controllerServer {
+				ctrl := newControllerServer(&controllerServerConfig{
+					driver:      initTestDriver(t),
+					fileService: fs,
+					cloud:       cloudProvider,
+					volumeLocks: util.NewVolumeLocks(),
+					features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+					tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+				}).(*controllerServer)
+				ctrl.config.createOps.retryInterval = 10 * time.Millisecond
+				return ctrl
+			}
+			ctrl := newController()
+			req := &csi.CreateVolumeRequest{
+				Name:               volumeName,
+				CapacityRange:      &csi.CapacityRange{RequiredBytes: testBytes},
+				VolumeCapabilities: []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}, AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER}}},
+			}
+			createVolume := func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				_, err := ctrl.CreateVolume(ctx, req)
+				return err
+			}
+
+			// The operation is running, so the call returns quickly.
+			if err := createVolume(); status.Code(err) != codes.DeadlineExceeded {
+				t.Fatalf("expected DeadlineExceeded while the operation runs, got: %v", err)
+			}
+			if tc.restart {
+				ctrl = newController()
+				if err := createVolume(); status.Code(err) != codes.DeadlineExceeded {
+					t.Fatalf("expected DeadlineExceeded while the operation runs after restart, got: %v", err)
+				}
+			}
+
+			fs.finish(t, volumeName, tc.opErr)
+			if tc.restartAfterFinish {
+				ctrl = newController()
+			}
+			var lastErr error
+			for i := 0; i < 50; i++ {
+				lastErr = createVolume()
+				if status.Code(lastErr) != codes.DeadlineExceeded {
+					break
+				}
+			}
+			if status.Code(lastErr) != tc.expectedCode {
+				t.Fatalf("expected error code %v, got: %v", tc.expectedCode, lastErr)
+			}
+			if tc.opErr != nil && !strings.Contains(lastErr.Error(), tc.opErr.Message) {
+				t.Errorf("expected the operation error %q to be surfaced, got: %v", tc.opErr.Message, lastErr)
+			}
+			if _, err := fs.GetInstance(context.Background(), &file.ServiceInstance{Name: volumeName}); tc.opErr != nil && !file.IsNotFoundErr(err) {
+				t.Errorf("expected the failed instance to be deleted, got: %v", err)
+			}
+		})
+	}
+}
+
+// stockoutFileService fails instance creation in the stocked out locations, either right
+// away or through a failed create operation, and records the attempted locations.
+type stockoutFileService struct {
+	file.Service
+	mu sync.Mutex
+	// stockout fails starting the create operation, opStockout fails the operation itself.
+	stockout   map[string]bool
+	opStockout map[string]bool
+	attempted  []string
+	ops        map[string]*filev1beta1.Operation
+	lookups    int
+}
+
+func newStockoutFileService(t *testing.T, stockout, opStockout []string) *stockoutFileService {
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	s := &stockoutFileService{Service: fs, stockout: map[string]bool{}, opStockout: map[string]bool{}, ops: map[string]*filev1beta1.Operation{}}
+	for _, l := range stockout {
+		s.stockout[l] = true
+	}
+	for _, l := range opStockout {
+		s.opStockout[l] = true
+	}
+	return s
+}
+
+func (s *stockoutFileService) StartCreateInstanceOp(ctx context.Context, obj *file.ServiceInstance) (*filev1beta1.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempted = append(s.attempted, obj.Location)
+	if s.stockout[obj.Location] {
+		return nil, status.Errorf(codes.ResourceExhausted, "The zone '%v' does not have enough resources available to fulfill the request", obj.Location)
+	}
+	instance, err := s.Service.CreateInstance(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	instance.Location = obj.Location
+	op := &filev1beta1.Operation{Name: fmt.Sprintf("operation-%v-%v", obj.Location, obj.Name), Done: true}
+	if s.opStockout[obj.Location] {
+		instance.State = "ERROR"
+		op.Error = &filev1beta1.Status{Code: int64(codes.ResourceExhausted), Message: "stockout"}
+	}
+	s.ops[op.Name] = op
+	return op, nil
+}
+
+func (s *stockoutFileService) WaitForInstanceOp(ctx context.Context, opName string) (*filev1beta1.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if op, ok := s.ops[opName]; ok {
+		return op, nil
+	}
+	return s.Service.WaitForInstanceOp(ctx, opName)
+}
+
+func (s *stockoutFileService) GetInstance(ctx context.Context, obj *file.ServiceInstance) (*file.ServiceInstance, error) {
+	s.mu.Lock()
+	s.lookups++
+	s.mu.Unlock()
+	return s.Service.GetInstance(ctx, obj)
+}
+
+func (s *stockoutFileService) attemptedLocations() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.attempted...)
+}
+
+func zoneTopologies(zones ...string) []*csi.Topology {
+	var topologies []*csi.Topology
+	for _, zone := range zones {
+		topologies = append(topologies, &csi.Topology{Segments: map[string]string{TopologyKeyZone: zone}})
+	}
+	return topologies
+}
+
+func TestCreateVolumeZoneFallback(t *testing.T) {
+	volumeName := "fallback-volume"
+	topology := &csi.TopologyRequirement{
+		Requisite: zoneTopologies("us-central1-a", "us-central1-b", "us-central1-c"),
+		Preferred: zoneTopologies("us-central1-b"),
+	}
+	cases := []struct {
+		name       string
+		params     map[string]string
+		stockout   []string
+		opStockout []string
+		// expectedLocation is empty if the volume cannot be created.
+		expectedLocation  string
+		expectedAttempted []string
+	}{
+		{
+			name:              "preferred zone has capacity",
+			expectedLocation:  "us-central1-b",
+			expectedAttempted: []string{"us-central1-b"},
+		},
+		{
+			name:              "preferred zone stocked out",
+			stockout:          []string{"us-central1-b"},
+			expectedLocation:  "us-central1-a",
+			expectedAttempted: []string{"us-central1-b", "us-central1-a"},
+		},
+		{
+			name:              "create operation in preferred zone stocked out",
+			opStockout:        []string{"us-central1-b"},
+			expectedLocation:  "us-central1-a",
+			expectedAttempted: []string{"us-central1-b", "us-central1-a"},
+		},
+		{
+			name:              "create operation of deletion protected instance stocked out",
+			params:            map[string]string{ParamDeletionProtectionEnabled: "true"},
+			opStockout:        []string{"us-central1-b"},
+			expectedLocation:  "us-central1-a",
+			expectedAttempted: []string{"us-central1-b", "us-central1-a"},
+		},
+		{
+			name:              "preferred and first requisite zone stocked out",
+			stockout:          []string{"us-central1-b"},
+			opStockout:        []string{"us-central1-a"},
+			expectedLocation:  "us-central1-c",
+			expectedAttempted: []string{"us-central1-b", "us-central1-a", "us-central1-c"},
+		},
+		{
+			name:              "all zones stocked out",
+			stockout:          []string{"us-central1-a", "us-central1-b", "us-central1-c"},
+			expectedAttempted: []string{"us-central1-b", "us-central1-a", "us-central1-c"},
+		},
+		{
+			name:              "regional tier attempts the region once",
+			params:            map[string]string{paramTier: regionalTier},
+			stockout:          []string{"us-central1"},
+			expectedAttempted: []string{"us-central1"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := newStockoutFileService(t, tc.stockout, tc.opStockout)
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			ctrl := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fs,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+				tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+			}).(*controllerServer)
+			req := &csi.CreateVolumeRequest{
+				Name:                      volumeName,
+				Parameters:                tc.params,
+				CapacityRange:             &csi.CapacityRange{RequiredBytes: 1 * util.Tb},
+				VolumeCapabilities:        []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}, AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER}}},
+				AccessibilityRequirements: topology,
+			}
+
+			resp, err := ctrl.CreateVolume(context.Background(), req)
+			if attempted := fs.attemptedLocations(); !reflect.DeepEqual(attempted, tc.expectedAttempted) {
+				t.Errorf("expected attempted locations %v, got %v", tc.expectedAttempted, attempted)
+			}
+			if tc.expectedLocation == "" {
+				if status.Code(err) != codes.ResourceExhausted {
+					t.Fatalf("expected ResourceExhausted, got: %v", err)
+				}
+				// A retry starts over from the most preferred location.
+				ctrl.CreateVolume(context.Background(), req)
+				if attempted := fs.attemptedLocations(); attempted[len(tc.expectedAttempted)] != tc.expectedAttempted[0] {
+					t.Errorf("expected the retry to start with location %v, got attempted locations %v", tc.expectedAttempted[0], attempted)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expectedID := fmt.Sprintf("%s/%s/%s/vol1", modeInstance, tc.expectedLocation, volumeName)
+			if resp.Volume.VolumeId != expectedID {
+				t.Errorf("expected volume id %v, got %v", expectedID, resp.Volume.VolumeId)
+			}
+			if len(ctrl.config.zoneFallback.exhaustedLocations(volumeName)) != 0 {
+				t.Errorf("expected exhausted locations to be forgotten once the volume is created")
+			}
+		})
+	}
+}
+
+func TestCreateVolumeLocked(t *testing.T) {
+	fs := newStockoutFileService(t, nil, nil)
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+	ctrl := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: fs,
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+		tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+	}).(*controllerServer)
+	// Another attempt of the same request is in progress.
+	ctrl.config.volumeLocks.TryAcquire("modeInstance/us-central1-b/locked-volume/vol1")
+
+	_, err = ctrl.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name:               "locked-volume",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: 1 * util.Tb},
+		VolumeCapabilities: []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}, AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER}}},
+		VolumeContentSource: &csi.VolumeContentSource{Type: &csi.VolumeContentSource_Volume{
+			Volume: &csi.VolumeContentSource_VolumeSource{VolumeId: "modeInstance/us-central1-b/source/vol1"},
+		}},
+		AccessibilityRequirements: &csi.TopologyRequirement{
+			Requisite: zoneTopologies("us-central1-a", "us-central1-b"),
+			Preferred: zoneTopologies("us-central1-b"),
+		},
+	})
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("expected Aborted, got: %v", err)
+	}
+	// Nothing is looked up or created before the lock is acquired.
+	if fs.lookups != 0 || len(fs.attemptedLocations()) != 0 {
+		t.Errorf("expected no lookups and no attempts, got %d lookups and attempts in %v", fs.lookups, fs.attemptedLocations())
+	}
+}
+
+func TestListZonesFromTopology(t *testing.T) {
+	cases := []struct {
+		name     string
+		top      *csi.TopologyRequirement
+		expected []string
+	}{
+		{
+			name:     "requisite only",
+			top:      &csi.TopologyRequirement{Requisite: zoneTopologies("zone-a", "zone-b")},
+			expected: []string{"zone-a", "zone-b"},
+		},
+		{
+			name:     "preferred only",
+			top:      &csi.TopologyRequirement{Preferred: zoneTopologies("zone-b", "zone-a")},
+			expected: []string{"zone-b", "zone-a"},
+		},
+		{
+			name: "segments with region key",
+			top: &csi.TopologyRequirement{Requisite: []*csi.Topology{
+				{Segments: map[string]string{TopologyKeyZone: "us-central1-a", TopologyKeyRegion: "us-central1"}},
+			}},
+			expected: []string{"us-central1-a"},
+		},
+		{
+			name: "preferred before requisite without duplicates",
+			top: &csi.TopologyRequirement{
+				Requisite: zoneTopologies("zone-a", "zone-b", "zone-c"),
+				Preferred: zoneTopologies("zone-c", "zone-a"),
+			},
+			expected: []string{"zone-c", "zone-a", "zone-b"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			zones, err := listZonesFromTopology(tc.top)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(zones, tc.expected) {
+				t.Errorf("expected zones %v, got %v", tc.expected, zones)
+			}
+		})
+	}
+}
+
+func TestInstanceAccessibleTopology(t *testing.T) {
+	cases := []struct {
+		name      string
+		instance  *file.ServiceInstance
+		regionKey bool
+		expected  []*csi.Topology
+	}{
+		{
+			name:      "basic tier",
+			instance:  &file.ServiceInstance{Location: "us-central1-a", Tier: defaultTier},
+			regionKey: true,
+		},
+		{
+			name:      "zonal tier",
+			instance:  &file.ServiceInstance{Location: "us-central1-a", Tier: zonalTier},
+			regionKey: true,
+		},
+		{
+			name:      "regional tier",
+			instance:  &file.ServiceInstance{Location: "us-central1", Tier: regionalTier},
+			regionKey: true,
+			expected:  []*csi.Topology{{Segments: map[string]string{TopologyKeyRegion: "us-central1"}}},
+		},
+		{
+			name:      "enterprise tier",
+			instance:  &file.ServiceInstance{Location: "us-central1", Tier: strings.ToUpper(enterpriseTier)},
+			regionKey: true,
+			expected:  []*csi.Topology{{Segments: map[string]string{TopologyKeyRegion: "us-central1"}}},
+		},
+		{
+			name:      "enterprise tier in a zone",
+			instance:  &file.ServiceInstance{Location: "us-central1-a", Tier: enterpriseTier},
+			regionKey: true,
+			expected:  []*csi.Topology{{Segments: map[string]string{TopologyKeyRegion: "us-central1"}}},
+		},
+		{
+			name:     "regional tier without region key",
+			instance: &file.ServiceInstance{Location: "us-central1", Tier: regionalTier},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := instanceAccessibleTopology(tc.instance, tc.regionKey)
+			if diff := cmp.Diff(tc.expected, got, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected topology (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// projectRecordingFileService records the projects of the instances and backups looked up
+// or created through it.
+type projectRecordingFileService struct {
+	file.Service
+	projects []string
+}
+
+func (s *projectRecordingFileService) GetInstance(ctx context.Context, obj *file.ServiceInstance) (*file.ServiceInstance, error) {
+	s.projects = append(s.projects, obj.Project)
+	return s.Service.GetInstance(ctx, obj)
+}
+
+func (s *projectRecordingFileService) CreateBackup(ctx context.Context, backupInfo *file.BackupInfo) (*filev1beta1.Backup, error) {
+	s.projects = append(s.projects, backupInfo.Project)
+	return s.Service.CreateBackup(ctx, backupInfo)
+}
+
+func (s *projectRecordingFileService) StartCreateBackupOp(ctx context.Context, backupInfo *file.BackupInfo) (*filev1beta1.Operation, error) {
+	s.projects = append(s.projects, backupInfo.Project)
+	return s.Service.StartCreateBackupOp(ctx, backupInfo)
+}
+
+func TestCrossProjectVolume(t *testing.T) {
+	serviceProject := "service-project"
+	snapshotName := "cross-project-backup"
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	recorder := &projectRecordingFileService{Service: fs}
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+	tagManager := cloud.NewFakeTagManager()
+	ctrl := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: recorder,
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+		tagManager:  tagManager,
+	}).(*controllerServer)
+
+	params := map[string]string{
+		paramProject: serviceProject,
+		paramNetwork: "projects/host-project/global/networks/shared-net",
+	}
+	tagManager.On("AttachResourceTags", context.Background(), cloud.FilestoreInstance, serviceProject, testCSIVolume, testZone, testCSIVolume, params).Return(nil)
+	tagManager.On("AttachResourceTags", context.Background(), cloud.FilestoreBackUp, serviceProject, snapshotName, testRegion, snapshotName, map[string]string(nil)).Return(nil)
+
+	createResp, err := ctrl.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name:               testCSIVolume,
+		Parameters:         params,
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: testBytes},
+		VolumeCapabilities: []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}, AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	volumeID := createResp.GetVolume().GetVolumeId()
+	expectedID := fmt.Sprintf("%s/%s/%s/%s/%s/%s", modeInstance, util.VolumeIDVersionWithProject, serviceProject, testZone, testCSIVolume, newInstanceVolume)
+	if volumeID != expectedID {
+		t.Fatalf("expected volume id %v, got %v", expectedID, volumeID)
+	}
+
+	// Snapshotting twice also processes the existing backup.
+	for i := 0; i < 2; i++ {
+		snapshotResp, err := ctrl.CreateSnapshot(context.Background(), &csi.CreateSnapshotRequest{Name: snapshotName, SourceVolumeId: volumeID})
+		if err != nil {
+			t.Fatalf("CreateSnapshot failed: %v", err)
+		}
+		if snapshotResp.GetSnapshot().GetSourceVolumeId() != volumeID {
+			t.Errorf("expected snapshot source volume %v, got %v", volumeID, snapshotResp.GetSnapshot().GetSourceVolumeId())
+		}
+		expectedBackupURI := fmt.Sprintf("projects/%s/locations/%s/backups/%s", serviceProject, testRegion, snapshotName)
+		if snapshotResp.GetSnapshot().GetSnapshotId() != expectedBackupURI {
+			t.Errorf("expected snapshot id %v, got %v", expectedBackupURI, snapshotResp.GetSnapshot().GetSnapshotId())
+		}
+	}
+	if _, err := ctrl.ControllerExpandVolume(context.Background(), &csi.ControllerExpandVolumeRequest{
+		VolumeId:      volumeID,
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 2 * testBytes},
+	}); err != nil {
+		t.Fatalf("ControllerExpandVolume failed: %v", err)
+	}
+	if _, err := ctrl.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: volumeID}); err != nil {
+		t.Fatalf("ControllerGetVolume failed: %v", err)
+	}
+	if _, err := ctrl.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID}); err != nil {
+		t.Fatalf("DeleteVolume failed: %v", err)
+	}
+
+	for _, project := range recorder.projects {
+		if project != serviceProject {
+			t.Errorf("expected all instances and backups in project %v, got lookups %v", serviceProject, recorder.projects)
+			break
+		}
+	}
+	tagManager.AssertExpectations(t)
+}
+
+func TestVolumeIDProject(t *testing.T) {
+	cases := []struct {
+		name       string
+		instance   *file.ServiceInstance
+		expectedID string
+	}{
+		{
+			name:       "default project",
+			instance:   &file.ServiceInstance{Project: testProject, Location: testZone, Name: testCSIVolume, Volume: file.Volume{Name: newInstanceVolume}},
+			expectedID: "modeInstance/us-central1-c/test-csi/vol1",
+		},
+		{
+			name:       "other project",
+			instance:   &file.ServiceInstance{Project: "service-project", Location: testZone, Name: testCSIVolume, Volume: file.Volume{Name: newInstanceVolume}},
+			expectedID: "modeInstance/v2/service-project/us-central1-c/test-csi/vol1",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id := getVolumeIDFromFileInstance(tc.instance, modeInstance, testProject)
+			if id != tc.expectedID {
+				t.Fatalf("expected volume id %v, got %v", tc.expectedID, id)
+			}
+			filer, mode, err := initTestController(t).(*controllerServer).getFileInstanceFromVolumeID(id)
+			if err != nil {
+				t.Fatalf("failed to parse volume id %v: %v", id, err)
 			}
-			for i := range test.expectedOptions {
-				if !reflect.DeepEqual(instance.NfsExportOptions[i], test.expectedOptions[i]) {
-					t.Errorf("test %q failed; nfs export options not equal at index %d: got %+v, expected %+v", test.name, i, instance.NfsExportOptions[i], test.expectedOptions[i])
-				}
+			if mode != modeInstance || !reflect.DeepEqual(filer, tc.instance) {
+				t.Errorf("expected instance %+v in mode %v, got %+v in mode %v", tc.instance, modeInstance, filer, mode)
 			}
-		}
-		if !cmp.Equal(resp, test.resp, protocmp.Transform()) {
-			t.Errorf("test %q failed: got resp %+v, expected %+v, diff: %s", test.name, resp, test.resp, cmp.Diff(resp, test.resp, protocmp.Transform()))
+		})
+	}
+
+	for _, id := range []string{
+		"modeInstance/v2//us-central1-c/test-csi/vol1",
+		"modeMultishare/v2/service-project/us-central1-c/test-csi/vol1",
+		"modeInstance/v3/service-project/us-central1-c/test-csi/vol1",
+	} {
+		if _, _, err := getFileInstanceFromID(id); err == nil {
+			t.Errorf("expected parsing volume id %v to fail", id)
 		}
 	}
 }
@@ -910,10 +1718,10 @@ func TestCreateVolume(t *testing.T) {
 		}
 
 		cs.config.tagManager.(*cloud.FakeTagServiceManager).
-			On("AttachResourceTags", context.TODO(), cloud.FilestoreInstance, testCSIVolume, testLocation, test.req.GetName(), test.req.GetParameters()).
+			On("AttachResourceTags", context.TODO(), cloud.FilestoreInstance, testProject, testCSIVolume, testLocation, test.req.GetName(), test.req.GetParameters()).
 			Return(nil)
 		cs.config.tagManager.(*cloud.FakeTagServiceManager).
-			On("AttachResourceTags", context.TODO(), cloud.FilestoreInstance, testCSIVolume2, testLocation, test.req.GetName(), test.req.GetParameters()).
+			On("AttachResourceTags", context.TODO(), cloud.FilestoreInstance, testProject, testCSIVolume2, testLocation, test.req.GetName(), test.req.GetParameters()).
 			Return(fmt.Errorf("mock failure: error while adding tags to filestore instance"))
 
 		resp, err := cs.CreateVolume(context.TODO(), test.req)
@@ -1062,172 +1870,667 @@ func TestControllerExpandVolume(t *testing.T) {
 			shouldError: true,
 		},
 		{
-			name:        "expand regional within large tier bounds",
-			currentSize: 11 * util.Tb,
-			requiredCap: 50 * util.Tb,
-			tier:        regionalTier,
-			shouldError: false,
+			name:        "expand regional within large tier bounds",
+			currentSize: 11 * util.Tb,
+			requiredCap: 50 * util.Tb,
+			tier:        regionalTier,
+			shouldError: false,
+		},
+		{
+			name:        "expand regional small tier at new minimum boundary",
+			currentSize: regionalSmallTierMinSize,
+			requiredCap: 3 * util.Tb,
+			tier:        regionalTier,
+			shouldError: false,
+		},
+		{
+			name:        "expand regional just below max small tier",
+			currentSize: 9 * util.Tb,
+			requiredCap: 9500 * util.Gb,
+			tier:        regionalTier,
+			shouldError: false,
+		},
+		{
+			name:        "expand regional at max small tier to large tier",
+			currentSize: regionalSmallTierMaxSize,
+			requiredCap: regionalSmallTierMaxSize + 1,
+			tier:        regionalTier,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create fake file service with an instance
+			fs, err := file.NewFakeService()
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+
+			instanceName := "test-expand-instance"
+			_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{
+				Name:     instanceName,
+				Location: testZone,
+				Tier:     tc.tier,
+				Volume:   file.Volume{Name: newInstanceVolume, SizeBytes: tc.currentSize},
+			})
+			if err != nil {
+				t.Fatalf("failed to create instance: %v", err)
+			}
+
+			// Create controller
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("failed to create cloud provider: %v", err)
+			}
+
+			ctrl := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fs,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+				tagManager:  cloud.NewFakeTagManager(),
+			})
+
+			// Create volumeID from instance
+			volumeID := getVolumeIDFromFileInstance(&file.ServiceInstance{
+				Name:     instanceName,
+				Location: testZone,
+				Volume:   file.Volume{Name: newInstanceVolume},
+			}, modeInstance, testProject)
+
+			// Call ControllerExpandVolume
+			req := &csi.ControllerExpandVolumeRequest{
+				VolumeId: volumeID,
+				CapacityRange: &csi.CapacityRange{
+					RequiredBytes: tc.requiredCap,
+				},
+			}
+
+			resp, err := ctrl.ControllerExpandVolume(context.Background(), req)
+
+			if tc.shouldError {
+				if err == nil {
+					t.Errorf("Test %q failed: expected error but got success", tc.name)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Test %q failed: expected success but got error: %v", tc.name, err)
+				}
+				if resp == nil {
+					t.Errorf("Test %q failed: expected non-nil response", tc.name)
+				}
+			}
+		})
+	}
+}
+
+func TestControllerModifyVolume_EmptyVolumeID(t *testing.T) {
+	ctrl := initTestController(t)
+
+	req := &csi.ControllerModifyVolumeRequest{VolumeId: ""}
+	resp, err := ctrl.ControllerModifyVolume(context.Background(), req)
+
+	if resp != nil {
+		t.Fatalf("expected nil response, got: %v", resp)
+	}
+	if err == nil {
+		t.Fatalf("expected error for empty volume id, got nil")
+	}
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for empty volume id, got: %v", err)
+	}
+}
+
+func TestControllerModifyVolume_NoMutableParamsNoop(t *testing.T) {
+	// create fake service and instance
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	// create an instance matching testVolumeID's instance name ("test-csi")
+	_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}})
+	if err != nil {
+		t.Fatalf("failed to create fake instance: %v", err)
+	}
+
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+
+	ctrl := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: fs,
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+		tagManager:  cloud.NewFakeTagManager(),
+	})
+
+	req := &csi.ControllerModifyVolumeRequest{VolumeId: testVolumeID}
+	resp, err := ctrl.ControllerModifyVolume(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected success for no-op modify, got error: %v", err)
+	}
+	if resp == nil {
+		t.Fatalf("expected non-nil response for no-op modify")
+	}
+}
+
+func TestControllerModifyVolume_InvalidPerformanceParams(t *testing.T) {
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	// create an instance with zonal tier so performance params are supported
+	_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}})
+	if err != nil {
+		t.Fatalf("failed to create fake instance: %v", err)
+	}
+
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+
+	ctrl := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: fs,
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+		tagManager:  cloud.NewFakeTagManager(),
+	})
+
+	// set fixed iops below minimum (1999)
+	req := &csi.ControllerModifyVolumeRequest{
+		VolumeId:          testVolumeID,
+		MutableParameters: map[string]string{ParamMaxIOPS: "1999"},
+	}
+	_, err = ctrl.ControllerModifyVolume(context.Background(), req)
+	if err == nil {
+		t.Fatalf("expected error for invalid performance params, got nil")
+	}
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for invalid performance params, got: %v", err)
+	}
+}
+
+func TestControllerModifyVolume_UpdateSuccess(t *testing.T) {
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	// create an instance with zonal tier so performance params are supported
+	_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}})
+	if err != nil {
+		t.Fatalf("failed to create fake instance: %v", err)
+	}
+
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+
+	ctrl := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: fs,
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+		tagManager:  cloud.NewFakeTagManager(),
+	})
+
+	// set valid fixed iops
+	req := &csi.ControllerModifyVolumeRequest{
+		VolumeId:          testVolumeID,
+		MutableParameters: map[string]string{ParamMaxIOPS: "3000"},
+	}
+	resp, err := ctrl.ControllerModifyVolume(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected success for valid update, got error: %v", err)
+	}
+	if resp == nil {
+		t.Fatalf("expected non-nil response for successful update")
+	}
+}
+
+func TestControllerModifyVolume_NfsExportOptions(t *testing.T) {
+	testOptions := `[{"accessMode":"READ_ONLY","ipRanges":["10.0.0.0/24"],"squashMode":"ROOT_SQUASH","anonUid":"1000","anonGid":"1000"}]`
+	cases := []struct {
+		name            string
+		featureEnabled  bool
+		params          map[string]string
+		expectedOptions []*file.NfsExportOptions
+		expectErr       codes.Code
+	}{
+		{
+			name:      "feature disabled",
+			params:    map[string]string{ParamMutableNfsExportOptions: testOptions},
+			expectErr: codes.InvalidArgument,
+		},
+		{
+			name:           "unknown field",
+			featureEnabled: true,
+			params:         map[string]string{ParamMutableNfsExportOptions: `[{"accessMode":"READ_ONLY","foo":"bar"}]`},
+			expectErr:      codes.InvalidArgument,
 		},
 		{
-			name:        "expand regional small tier at new minimum boundary",
-			currentSize: regionalSmallTierMinSize,
-			requiredCap: 3 * util.Tb,
-			tier:        regionalTier,
-			shouldError: false,
+			name:           "empty options",
+			featureEnabled: true,
+			params:         map[string]string{ParamMutableNfsExportOptions: "[]"},
+			expectErr:      codes.InvalidArgument,
 		},
 		{
-			name:        "expand regional just below max small tier",
-			currentSize: 9 * util.Tb,
-			requiredCap: 9500 * util.Gb,
-			tier:        regionalTier,
-			shouldError: false,
+			name:           "update options",
+			featureEnabled: true,
+			params:         map[string]string{ParamMutableNfsExportOptions: testOptions},
+			expectedOptions: []*file.NfsExportOptions{
+				{
+					AccessMode: "READ_ONLY",
+					IpRanges:   []string{"10.0.0.0/24"},
+					SquashMode: "ROOT_SQUASH",
+					AnonUid:    1000,
+					AnonGid:    1000,
+				},
+			},
 		},
 		{
-			name:        "expand regional at max small tier to large tier",
-			currentSize: regionalSmallTierMaxSize,
-			requiredCap: regionalSmallTierMaxSize + 1,
-			tier:        regionalTier,
-			shouldError: true,
+			name:           "update options and performance",
+			featureEnabled: true,
+			params:         map[string]string{ParamMutableNfsExportOptions: testOptions, ParamMaxIOPS: "3000"},
+			expectedOptions: []*file.NfsExportOptions{
+				{
+					AccessMode: "READ_ONLY",
+					IpRanges:   []string{"10.0.0.0/24"},
+					SquashMode: "ROOT_SQUASH",
+					AnonUid:    1000,
+					AnonGid:    1000,
+				},
+			},
 		},
 	}
-
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			// Create fake file service with an instance
 			fs, err := file.NewFakeService()
 			if err != nil {
 				t.Fatalf("failed to init fake file service: %v", err)
 			}
-
-			instanceName := "test-expand-instance"
-			_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{
-				Name:     instanceName,
-				Location: testZone,
-				Tier:     tc.tier,
-				Volume:   file.Volume{Name: newInstanceVolume, SizeBytes: tc.currentSize},
-			})
+			_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}})
 			if err != nil {
-				t.Fatalf("failed to create instance: %v", err)
+				t.Fatalf("failed to create fake instance: %v", err)
 			}
 
-			// Create controller
 			cloudProvider, err := cloud.NewFakeCloud()
 			if err != nil {
-				t.Fatalf("failed to create cloud provider: %v", err)
+				t.Fatalf("Failed to get cloud provider: %v", err)
 			}
-
 			ctrl := newControllerServer(&controllerServerConfig{
 				driver:      initTestDriver(t),
 				fileService: fs,
 				cloud:       cloudProvider,
 				volumeLocks: util.NewVolumeLocks(),
-				features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
-				tagManager:  cloud.NewFakeTagManager(),
+				features: &GCFSDriverFeatureOptions{
+					FeatureLockRelease:              &FeatureLockRelease{},
+					FeatureNFSExportOptionsOnCreate: &FeatureNFSExportOptionsOnCreate{Enabled: tc.featureEnabled},
+				},
+				tagManager: cloud.NewFakeTagManager(),
 			})
 
-			// Create volumeID from instance
-			volumeID := getVolumeIDFromFileInstance(&file.ServiceInstance{
-				Name:     instanceName,
-				Location: testZone,
-				Volume:   file.Volume{Name: newInstanceVolume},
-			}, modeInstance)
-
-			// Call ControllerExpandVolume
-			req := &csi.ControllerExpandVolumeRequest{
-				VolumeId: volumeID,
-				CapacityRange: &csi.CapacityRange{
-					RequiredBytes: tc.requiredCap,
-				},
+			_, err = ctrl.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+				VolumeId:          testVolumeID,
+				MutableParameters: tc.params,
+			})
+			if tc.expectErr != codes.OK {
+				if status.Code(err) != tc.expectErr {
+					t.Fatalf("expected error code %v, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			filer, err := fs.GetInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone})
+			if err != nil {
+				t.Fatalf("failed to get instance: %v", err)
+			}
+			if !reflect.DeepEqual(filer.NfsExportOptions, tc.expectedOptions) {
+				t.Errorf("got nfs export options %+v, expected %+v", filer.NfsExportOptions, tc.expectedOptions)
 			}
 
-			resp, err := ctrl.ControllerExpandVolume(context.Background(), req)
-
-			if tc.shouldError {
-				if err == nil {
-					t.Errorf("Test %q failed: expected error but got success", tc.name)
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Test %q failed: expected success but got error: %v", tc.name, err)
-				}
-				if resp == nil {
-					t.Errorf("Test %q failed: expected non-nil response", tc.name)
-				}
+			// Expanding the volume afterwards keeps the options.
+			if _, err := ctrl.ControllerExpandVolume(context.Background(), &csi.ControllerExpandVolumeRequest{
+				VolumeId:      testVolumeID,
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 2 * testBytes},
+			}); err != nil {
+				t.Fatalf("unexpected error on expand: %v", err)
+			}
+			filer, err = fs.GetInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone})
+			if err != nil {
+				t.Fatalf("failed to get instance: %v", err)
+			}
+			if filer.Volume.SizeBytes != 2*testBytes {
+				t.Errorf("got size %d after expand, expected %d", filer.Volume.SizeBytes, 2*testBytes)
+			}
+			if !reflect.DeepEqual(filer.NfsExportOptions, tc.expectedOptions) {
+				t.Errorf("got nfs export options %+v after expand, expected %+v", filer.NfsExportOptions, tc.expectedOptions)
 			}
 		})
 	}
 }
 
-func TestControllerModifyVolume_EmptyVolumeID(t *testing.T) {
-	ctrl := initTestController(t)
+func TestControllerModifyVolume_DeletionProtection(t *testing.T) {
+	cases := []struct {
+		name               string
+		initial            *file.DeletionProtection
+		params             map[string]string
+		expectedProtection *file.DeletionProtection
+		expectErr          codes.Code
+		expectDeleteErr    codes.Code
+	}{
+		{
+			name:               "enable deletion protection",
+			params:             map[string]string{ParamDeletionProtectionEnabled: "true", ParamDeletionProtectionReason: "shared data"},
+			expectedProtection: &file.DeletionProtection{Enabled: true, Reason: "shared data"},
+			expectDeleteErr:    codes.FailedPrecondition,
+		},
+		{
+			name:               "disable deletion protection",
+			initial:            &file.DeletionProtection{Enabled: true, Reason: "shared data"},
+			params:             map[string]string{ParamDeletionProtectionEnabled: "false"},
+			expectedProtection: &file.DeletionProtection{},
+		},
+		{
+			name:               "protected volume without deletion protection parameters",
+			initial:            &file.DeletionProtection{Enabled: true},
+			params:             map[string]string{ParamMaxIOPS: "3000"},
+			expectedProtection: &file.DeletionProtection{Enabled: true},
+			expectDeleteErr:    codes.FailedPrecondition,
+		},
+		{
+			name:      "reason without enabled",
+			params:    map[string]string{ParamDeletionProtectionReason: "shared data"},
+			expectErr: codes.InvalidArgument,
+		},
+		{
+			name:      "invalid enabled",
+			params:    map[string]string{ParamDeletionProtectionEnabled: "maybe"},
+			expectErr: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := file.NewFakeService()
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+			_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}, DeletionProtection: tc.initial})
+			if err != nil {
+				t.Fatalf("failed to create fake instance: %v", err)
+			}
 
-	req := &csi.ControllerModifyVolumeRequest{VolumeId: ""}
-	resp, err := ctrl.ControllerModifyVolume(context.Background(), req)
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			ctrl := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fs,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				features: &GCFSDriverFeatureOptions{
+					FeatureLockRelease: &FeatureLockRelease{},
+				},
+				tagManager: cloud.NewFakeTagManager(),
+			})
 
-	if resp != nil {
-		t.Fatalf("expected nil response, got: %v", resp)
-	}
-	if err == nil {
-		t.Fatalf("expected error for empty volume id, got nil")
-	}
-	st, ok := status.FromError(err)
-	if !ok || st.Code() != codes.InvalidArgument {
-		t.Fatalf("expected InvalidArgument for empty volume id, got: %v", err)
+			_, err = ctrl.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+				VolumeId:          testVolumeID,
+				MutableParameters: tc.params,
+			})
+			if tc.expectErr != codes.OK {
+				if status.Code(err) != tc.expectErr {
+					t.Fatalf("expected error code %v, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			filer, err := fs.GetInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone})
+			if err != nil {
+				t.Fatalf("failed to get instance: %v", err)
+			}
+			if !reflect.DeepEqual(filer.DeletionProtection, tc.expectedProtection) {
+				t.Errorf("got deletion protection %+v, expected %+v", filer.DeletionProtection, tc.expectedProtection)
+			}
+
+			_, err = ctrl.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID})
+			if status.Code(err) != tc.expectDeleteErr {
+				t.Errorf("expected delete error code %v, got %v", tc.expectDeleteErr, err)
+			}
+		})
 	}
 }
 
-func TestControllerModifyVolume_NoMutableParamsNoop(t *testing.T) {
-	// create fake service and instance
+func TestCreateVolumeBackupOnDelete(t *testing.T) {
 	fs, err := file.NewFakeService()
 	if err != nil {
 		t.Fatalf("failed to init fake file service: %v", err)
 	}
-	// create an instance matching testVolumeID's instance name ("test-csi")
-	_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}})
-	if err != nil {
-		t.Fatalf("failed to create fake instance: %v", err)
-	}
-
 	cloudProvider, err := cloud.NewFakeCloud()
 	if err != nil {
 		t.Fatalf("Failed to get cloud provider: %v", err)
 	}
-
 	ctrl := newControllerServer(&controllerServerConfig{
 		driver:      initTestDriver(t),
 		fileService: fs,
 		cloud:       cloudProvider,
 		volumeLocks: util.NewVolumeLocks(),
-		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
-		tagManager:  cloud.NewFakeTagManager(),
+		features: &GCFSDriverFeatureOptions{
+			FeatureLockRelease: &FeatureLockRelease{},
+		},
+		tagManager: cloud.NewFakeTagManagerForSanityTests(),
 	})
 
-	req := &csi.ControllerModifyVolumeRequest{VolumeId: testVolumeID}
-	resp, err := ctrl.ControllerModifyVolume(context.Background(), req)
+	_, err = ctrl.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name: testCSIVolume,
+		VolumeCapabilities: []*csi.VolumeCapability{
+			{
+				AccessType: &csi.VolumeCapability_Mount{
+					Mount: &csi.VolumeCapability_MountVolume{},
+				},
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+				},
+			},
+		},
+		Parameters: map[string]string{
+			ParamBackupOnDelete:          "true",
+			ParamBackupOnDeleteRetention: "7d",
+		},
+	})
 	if err != nil {
-		t.Fatalf("expected success for no-op modify, got error: %v", err)
+		t.Fatalf("CreateVolume failed: %v", err)
 	}
-	if resp == nil {
-		t.Fatalf("expected non-nil response for no-op modify")
+	filer, err := fs.GetInstance(context.Background(), &file.ServiceInstance{Name: testCSIVolume, Location: testZone})
+	if err != nil {
+		t.Fatalf("failed to get instance: %v", err)
+	}
+	if filer.Labels[tagKeyBackupOnDelete] != "true" || filer.Labels[tagKeyBackupOnDeleteRetention] != "7d" {
+		t.Errorf("instance labels %v do not record the backup on delete policy", filer.Labels)
+	}
+	if _, ok := filer.Labels[tagKeyBackupOnDeleteLocation]; ok {
+		t.Errorf("unexpected label %s in %v", tagKeyBackupOnDeleteLocation, filer.Labels)
 	}
 }
 
-func TestControllerModifyVolume_InvalidPerformanceParams(t *testing.T) {
+func TestDeleteVolumeBackupOnDelete(t *testing.T) {
+	finalBackupURI := fmt.Sprintf("projects/%s/locations/%s/backups/final-%s", testProject, testRegion, testCSIVolume)
+	cases := []struct {
+		name           string
+		labels         map[string]string
+		instanceState  string
+		existingState  string
+		asyncBackup    bool
+		expectErr      codes.Code
+		expectBackup   bool
+		expectLabels   map[string]string
+		expectInstance bool
+	}{
+		{
+			name:   "no backup on delete",
+			labels: map[string]string{tagKeyCreatedBy: "test-driver"},
+		},
+		{
+			name: "final backup taken before deleting the instance",
+			labels: map[string]string{
+				tagKeyCreatedBy:               "test-driver",
+				tagKeyCreatedForClaimName:     "pvc1",
+				tagKeyBackupOnDelete:          "true",
+				tagKeyBackupOnDeleteRetention: "30d",
+			},
+			expectBackup: true,
+			expectLabels: map[string]string{
+				tagKeyCreatedBy:           "test-driver",
+				tagKeyCreatedForClaimName: "pvc1",
+				tagKeyFinalBackup:         "true",
+				tagKeyBackupRetention:     "30d",
+			},
+		},
+		{
+			name:          "final backup already ready",
+			labels:        map[string]string{tagKeyBackupOnDelete: "true"},
+			existingState: "READY",
+			expectBackup:  true,
+		},
+		{
+			name:           "final backup still creating",
+			labels:         map[string]string{tagKeyBackupOnDelete: "true"},
+			existingState:  "CREATING",
+			expectErr:      codes.DeadlineExceeded,
+			expectBackup:   true,
+			expectInstance: true,
+		},
+		{
+			name:           "failed final backup is deleted",
+			labels:         map[string]string{tagKeyBackupOnDelete: "true"},
+			existingState:  "FAILED",
+			expectErr:      codes.Unavailable,
+			expectInstance: true,
+		},
+		{
+			name:           "final backup started",
+			labels:         map[string]string{tagKeyBackupOnDelete: "true"},
+			asyncBackup:    true,
+			expectErr:      codes.DeadlineExceeded,
+			expectBackup:   true,
+			expectInstance: true,
+		},
+		{
+			name:           "instance in error",
+			labels:         map[string]string{tagKeyBackupOnDelete: "true"},
+			instanceState:  "ERROR",
+			expectErr:      codes.FailedPrecondition,
+			expectInstance: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := file.NewFakeService()
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+			instance, err := fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: testCSIVolume, Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}, Labels: tc.labels})
+			if err != nil {
+				t.Fatalf("failed to create fake instance: %v", err)
+			}
+			if tc.instanceState != "" {
+				instance.State = tc.instanceState
+			}
+			if tc.existingState != "" {
+				backup, err := fs.CreateBackup(context.Background(), &file.BackupInfo{
+					SourceVolumeId:     testVolumeID,
+					SourceInstanceName: testCSIVolume,
+					SourceShare:        "vol1",
+					Project:            testProject,
+					Location:           testRegion,
+					BackupURI:          finalBackupURI,
+				})
+				if err != nil {
+					t.Fatalf("failed to create fake backup: %v", err)
+				}
+				backup.State = tc.existingState
+			}
+
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			var fileService file.Service = fs
+			if tc.asyncBackup {
+				fileService = &creatingBackupFileService{Service: fs}
+			}
+			ctrl := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fileService,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				features: &GCFSDriverFeatureOptions{
+					FeatureLockRelease: &FeatureLockRelease{},
+				},
+				tagManager: cloud.NewFakeTagManager(),
+			})
+
+			_, err = ctrl.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID})
+			if status.Code(err) != tc.expectErr {
+				t.Fatalf("expected error code %v, got %v", tc.expectErr, err)
+			}
+
+			_, err = fs.GetInstance(context.Background(), &file.ServiceInstance{Name: testCSIVolume, Location: testZone})
+			if exists := err == nil; exists != tc.expectInstance {
+				t.Errorf("expected instance to exist: %v, got error %v", tc.expectInstance, err)
+			}
+			backup, err := fs.GetBackup(context.Background(), finalBackupURI)
+			if exists := err == nil; exists != tc.expectBackup {
+				t.Fatalf("expected final backup to exist: %v, got error %v", tc.expectBackup, err)
+			}
+			if tc.expectLabels != nil && !reflect.DeepEqual(backup.Backup.Labels, tc.expectLabels) {
+				t.Errorf("got final backup labels %v, expected %v", backup.Backup.Labels, tc.expectLabels)
+			}
+		})
+	}
+}
+
+func TestListVolumes(t *testing.T) {
 	fs, err := file.NewFakeService()
 	if err != nil {
 		t.Fatalf("failed to init fake file service: %v", err)
 	}
-	// create an instance with zonal tier so performance params are supported
-	_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}})
-	if err != nil {
-		t.Fatalf("failed to create fake instance: %v", err)
+	driverLabels := map[string]string{tagKeyCreatedBy: "test-driver"}
+	for _, instance := range []*file.ServiceInstance{
+		{Name: "instance-c", Labels: driverLabels},
+		{Name: "instance-a", Labels: driverLabels},
+		{Name: "instance-b", Labels: driverLabels},
+		{Name: "other-driver", Labels: map[string]string{tagKeyCreatedBy: "other_driver"}},
+		{Name: "unlabeled"},
+	} {
+		instance.Location = testZone
+		instance.Tier = zonalTier
+		instance.Volume = file.Volume{Name: "vol1", SizeBytes: testBytes}
+		if _, err := fs.CreateInstance(context.Background(), instance); err != nil {
+			t.Fatalf("failed to create fake instance: %v", err)
+		}
 	}
 
 	cloudProvider, err := cloud.NewFakeCloud()
 	if err != nil {
 		t.Fatalf("Failed to get cloud provider: %v", err)
 	}
-
 	ctrl := newControllerServer(&controllerServerConfig{
 		driver:      initTestDriver(t),
 		fileService: fs,
@@ -1237,57 +2540,219 @@ func TestControllerModifyVolume_InvalidPerformanceParams(t *testing.T) {
 		tagManager:  cloud.NewFakeTagManager(),
 	})
 
-	// set fixed iops below minimum (1999)
-	req := &csi.ControllerModifyVolumeRequest{
-		VolumeId:          testVolumeID,
-		MutableParameters: map[string]string{ParamMaxIOPS: "1999"},
-	}
-	_, err = ctrl.ControllerModifyVolume(context.Background(), req)
-	if err == nil {
-		t.Fatalf("expected error for invalid performance params, got nil")
+	volA := "modeInstance/us-central1-c/instance-a/vol1"
+	volB := "modeInstance/us-central1-c/instance-b/vol1"
+	volC := "modeInstance/us-central1-c/instance-c/vol1"
+	cases := []struct {
+		name              string
+		req               *csi.ListVolumesRequest
+		expectedIDs       []string
+		expectedNextToken string
+		expectedCode      codes.Code
+	}{
+		{
+			name:        "all volumes",
+			req:         &csi.ListVolumesRequest{},
+			expectedIDs: []string{volA, volB, volC},
+		},
+		{
+			name:              "first page",
+			req:               &csi.ListVolumesRequest{MaxEntries: 2},
+			expectedIDs:       []string{volA, volB},
+			expectedNextToken: volC,
+		},
+		{
+			name:        "last page",
+			req:         &csi.ListVolumesRequest{MaxEntries: 2, StartingToken: volC},
+			expectedIDs: []string{volC},
+		},
+		{
+			name:              "token of a deleted volume",
+			req:               &csi.ListVolumesRequest{MaxEntries: 1, StartingToken: "modeInstance/us-central1-c/instance-aa/vol1"},
+			expectedIDs:       []string{volB},
+			expectedNextToken: volC,
+		},
+		{
+			name:         "invalid token",
+			req:          &csi.ListVolumesRequest{StartingToken: "invalid-token"},
+			expectedCode: codes.Aborted,
+		},
+		{
+			name:         "negative max entries",
+			req:          &csi.ListVolumesRequest{MaxEntries: -1},
+			expectedCode: codes.InvalidArgument,
+		},
 	}
-	st, ok := status.FromError(err)
-	if !ok || st.Code() != codes.InvalidArgument {
-		t.Fatalf("expected InvalidArgument for invalid performance params, got: %v", err)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := ctrl.ListVolumes(context.Background(), tc.req)
+			if tc.expectedCode != codes.OK {
+				if status.Code(err) != tc.expectedCode {
+					t.Fatalf("expected error code %v, got: %v", tc.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var ids []string
+			for _, entry := range resp.GetEntries() {
+				ids = append(ids, entry.GetVolume().GetVolumeId())
+			}
+			if diff := cmp.Diff(tc.expectedIDs, ids); diff != "" {
+				t.Errorf("unexpected volume ids (-want +got):\n%s", diff)
+			}
+			if resp.GetNextToken() != tc.expectedNextToken {
+				t.Errorf("expected next token %q, got %q", tc.expectedNextToken, resp.GetNextToken())
+			}
+		})
 	}
 }
 
-func TestControllerModifyVolume_UpdateSuccess(t *testing.T) {
-	fs, err := file.NewFakeService()
+func TestControllerGetVolume(t *testing.T) {
+	multishareInstance := &file.MultishareInstance{
+		Project:  testProject,
+		Location: testRegion,
+		Name:     "multishare-instance",
+		Network:  file.Network{Ip: testIP},
+		State:    "READY",
+	}
+	repairingInstance := &file.MultishareInstance{
+		Project:  testProject,
+		Location: testRegion,
+		Name:     "repairing-instance",
+		Network:  file.Network{Ip: testIP},
+		State:    "REPAIRING",
+	}
+	fs, err := file.NewFakeServiceForMultishare(
+		[]*file.MultishareInstance{multishareInstance, repairingInstance},
+		[]*file.Share{
+			{Name: "share-ready", Parent: multishareInstance, State: "READY", CapacityBytes: 100 * util.Gb},
+			{Name: "share-creating", Parent: multishareInstance, State: "CREATING", CapacityBytes: 100 * util.Gb},
+			{Name: "share-on-repairing", Parent: repairingInstance, State: "READY", CapacityBytes: 100 * util.Gb},
+		}, nil)
 	if err != nil {
 		t.Fatalf("failed to init fake file service: %v", err)
 	}
-	// create an instance with zonal tier so performance params are supported
-	_, err = fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "test-csi", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}})
-	if err != nil {
+	if _, err := fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: testCSIVolume, Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}}); err != nil {
 		t.Fatalf("failed to create fake instance: %v", err)
 	}
+	if _, err := fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: testCSIVolume2, Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}}); err != nil {
+		t.Fatalf("failed to create fake instance: %v", err)
+	}
+	repairing, err := fs.GetInstance(context.Background(), &file.ServiceInstance{Name: testCSIVolume2})
+	if err != nil {
+		t.Fatalf("failed to get fake instance: %v", err)
+	}
+	repairing.State = "REPAIRING"
 
 	cloudProvider, err := cloud.NewFakeCloud()
 	if err != nil {
 		t.Fatalf("Failed to get cloud provider: %v", err)
 	}
-
 	ctrl := newControllerServer(&controllerServerConfig{
-		driver:      initTestDriver(t),
-		fileService: fs,
-		cloud:       cloudProvider,
-		volumeLocks: util.NewVolumeLocks(),
-		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
-		tagManager:  cloud.NewFakeTagManager(),
+		driver:               initTestDriver(t),
+		fileService:          fs,
+		cloud:                cloudProvider,
+		volumeLocks:          util.NewVolumeLocks(),
+		features:             &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}, FeatureSharePools: &FeatureSharePools{Enabled: true}},
+		tagManager:           cloud.NewFakeTagManager(),
+		multiShareController: &MultishareController{},
 	})
 
-	// set valid fixed iops
-	req := &csi.ControllerModifyVolumeRequest{
-		VolumeId:          testVolumeID,
-		MutableParameters: map[string]string{ParamMaxIOPS: "3000"},
+	multishareVolumeID := func(instance, share string) string {
+		return fmt.Sprintf("%s/%s/%s/%s/%s/%s", modeMultishare, testInstanceScPrefix, testProject, testRegion, instance, share)
 	}
-	resp, err := ctrl.ControllerModifyVolume(context.Background(), req)
-	if err != nil {
-		t.Fatalf("expected success for valid update, got error: %v", err)
+	cases := []struct {
+		name             string
+		volumeID         string
+		expectedAbnormal bool
+		expectedCapacity int64
+		expectedCode     codes.Code
+	}{
+		{
+			name:             "ready instance",
+			volumeID:         testVolumeID,
+			expectedCapacity: testBytes,
+		},
+		{
+			name:             "repairing instance",
+			volumeID:         "modeInstance/us-central1-c/test-csi-2/vol1",
+			expectedAbnormal: true,
+			expectedCapacity: testBytes,
+		},
+		{
+			name:         "deleted instance",
+			volumeID:     "modeInstance/us-central1-c/deleted/vol1",
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:             "ready share",
+			volumeID:         multishareVolumeID("multishare-instance", "share-ready"),
+			expectedCapacity: 100 * util.Gb,
+		},
+		{
+			name:             "creating share",
+			volumeID:         multishareVolumeID("multishare-instance", "share-creating"),
+			expectedAbnormal: true,
+			expectedCapacity: 100 * util.Gb,
+		},
+		{
+			name:             "ready share on repairing instance",
+			volumeID:         multishareVolumeID("repairing-instance", "share-on-repairing"),
+			expectedAbnormal: true,
+			expectedCapacity: 100 * util.Gb,
+		},
+		{
+			name:         "deleted share",
+			volumeID:     multishareVolumeID("multishare-instance", "deleted"),
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "share on deleted instance",
+			volumeID:     multishareVolumeID("deleted", "share-ready"),
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:     "share pool volume",
+			volumeID: "sharepool://test-project/us-central1/pool/shares/share-1?ip=1.1.1.1",
+		},
+		{
+			name:         "empty volume id",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "invalid volume id",
+			volumeID:     "invalid-id",
+			expectedCode: codes.NotFound,
+		},
 	}
-	if resp == nil {
-		t.Fatalf("expected non-nil response for successful update")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := ctrl.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: tc.volumeID})
+			if tc.expectedCode != codes.OK {
+				if status.Code(err) != tc.expectedCode {
+					t.Fatalf("expected error code %v, got: %v", tc.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.GetVolume().GetVolumeId() != tc.volumeID {
+				t.Errorf("expected volume id %q, got %q", tc.volumeID, resp.GetVolume().GetVolumeId())
+			}
+			if resp.GetVolume().GetCapacityBytes() != tc.expectedCapacity {
+				t.Errorf("expected capacity %d, got %d", tc.expectedCapacity, resp.GetVolume().GetCapacityBytes())
+			}
+			condition := resp.GetStatus().GetVolumeCondition()
+			if condition == nil {
+				t.Fatalf("expected a volume condition")
+			}
+			if condition.GetAbnormal() != tc.expectedAbnormal {
+				t.Errorf("expected abnormal %v, got %v: %s", tc.expectedAbnormal, condition.GetAbnormal(), condition.GetMessage())
+			}
+		})
 	}
 }
 
@@ -1756,11 +3221,12 @@ func TestGetRequestCapacity(t *testing.T) {
 
 func TestGenerateNewFileInstance(t *testing.T) {
 	cases := []struct {
-		name      string
-		params    map[string]string
-		toporeq   *csi.TopologyRequirement
-		instance  *file.ServiceInstance
-		expectErr bool
+		name          string
+		params        map[string]string
+		mutableParams map[string]string
+		toporeq       *csi.TopologyRequirement
+		instance      *file.ServiceInstance
+		expectErr     bool
 	}{
 		{
 			name: "default params, nil topology requirement",
@@ -2004,6 +3470,180 @@ func TestGenerateNewFileInstance(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "service project and Shared VPC host network",
+			params: map[string]string{
+				paramProject: "service-project",
+				paramNetwork: "projects/host-project/global/networks/shared-net",
+			},
+			instance: &file.ServiceInstance{
+				Project:  "service-project",
+				Name:     testCSIVolume,
+				Location: testLocation,
+				Tier:     defaultTier,
+				Network: file.Network{
+					Name:        "projects/host-project/global/networks/shared-net",
+					ConnectMode: directPeering,
+				},
+				Volume: file.Volume{
+					Name:      newInstanceVolume,
+					SizeBytes: testBytes,
+				},
+				Protocol: v3FileProtocol,
+			},
+		},
+		{
+			name: "empty project",
+			params: map[string]string{
+				paramProject: "",
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid network resource name",
+			params: map[string]string{
+				paramNetwork: "projects/host-project/networks/shared-net",
+			},
+			expectErr: true,
+		},
+		{
+			name: "deletion protection",
+			params: map[string]string{
+				ParamDeletionProtectionEnabled: "true",
+				ParamDeletionProtectionReason:  "shared data",
+			},
+			instance: &file.ServiceInstance{
+				Project:  testProject,
+				Name:     testCSIVolume,
+				Location: testLocation,
+				Tier:     defaultTier,
+				Network: file.Network{
+					Name:        defaultNetwork,
+					ConnectMode: directPeering,
+				},
+				Volume: file.Volume{
+					Name:      newInstanceVolume,
+					SizeBytes: testBytes,
+				},
+				Protocol:           v3FileProtocol,
+				DeletionProtection: &file.DeletionProtection{Enabled: true, Reason: "shared data"},
+			},
+		},
+		{
+			name: "deletion protection from mutable parameters",
+			mutableParams: map[string]string{
+				ParamDeletionProtectionEnabled: "true",
+			},
+			instance: &file.ServiceInstance{
+				Project:  testProject,
+				Name:     testCSIVolume,
+				Location: testLocation,
+				Tier:     defaultTier,
+				Network: file.Network{
+					Name:        defaultNetwork,
+					ConnectMode: directPeering,
+				},
+				Volume: file.Volume{
+					Name:      newInstanceVolume,
+					SizeBytes: testBytes,
+				},
+				Protocol:           v3FileProtocol,
+				DeletionProtection: &file.DeletionProtection{Enabled: true},
+			},
+		},
+		{
+			name: "deletion protection both as parameter and mutable parameter",
+			params: map[string]string{
+				ParamDeletionProtectionEnabled: "true",
+			},
+			mutableParams: map[string]string{
+				ParamDeletionProtectionEnabled: "false",
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid deletion protection enabled",
+			params: map[string]string{
+				ParamDeletionProtectionEnabled: "yes please",
+			},
+			expectErr: true,
+		},
+		{
+			name: "deletion protection reason without enabled",
+			params: map[string]string{
+				ParamDeletionProtectionReason: "shared data",
+			},
+			expectErr: true,
+		},
+		{
+			name: "deletion protection reason with protection disabled",
+			params: map[string]string{
+				ParamDeletionProtectionEnabled: "false",
+				ParamDeletionProtectionReason:  "shared data",
+			},
+			expectErr: true,
+		},
+		{
+			name: "backup on delete",
+			params: map[string]string{
+				ParamBackupOnDelete:          "true",
+				ParamBackupOnDeleteLocation:  "us-east1",
+				ParamBackupOnDeleteRetention: "30d",
+			},
+			instance: &file.ServiceInstance{
+				Project:  testProject,
+				Name:     testCSIVolume,
+				Location: testLocation,
+				Tier:     defaultTier,
+				Network: file.Network{
+					Name:        defaultNetwork,
+					ConnectMode: directPeering,
+				},
+				Volume: file.Volume{
+					Name:      newInstanceVolume,
+					SizeBytes: testBytes,
+				},
+				Protocol: v3FileProtocol,
+			},
+		},
+		{
+			name: "invalid backup on delete",
+			params: map[string]string{
+				ParamBackupOnDelete: "maybe",
+			},
+			expectErr: true,
+		},
+		{
+			name: "backup on delete location without backup on delete",
+			params: map[string]string{
+				ParamBackupOnDeleteLocation: "us-east1",
+			},
+			expectErr: true,
+		},
+		{
+			name: "backup on delete retention with backup on delete disabled",
+			params: map[string]string{
+				ParamBackupOnDelete:          "false",
+				ParamBackupOnDeleteRetention: "30d",
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid backup on delete retention",
+			params: map[string]string{
+				ParamBackupOnDelete:          "true",
+				ParamBackupOnDeleteRetention: "1month",
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid backup on delete location",
+			params: map[string]string{
+				ParamBackupOnDelete:         "true",
+				ParamBackupOnDeleteLocation: "us-central1-c",
+			},
+			expectErr: true,
+		},
 		{
 			name: "regional tier sets region as location",
 			params: map[string]string{
@@ -2043,7 +3683,7 @@ func TestGenerateNewFileInstance(t *testing.T) {
 			t.Fatalf("couldn't get internal controller")
 		}
 
-		filer, err := internalServer.generateNewFileInstance(testCSIVolume, testBytes, test.params, nil, test.toporeq)
+		filer, err := internalServer.generateNewFileInstance(testCSIVolume, testBytes, test.params, test.mutableParams, test.toporeq)
 		if !test.expectErr && err != nil {
 			t.Errorf("test %q failed: %v", test.name, err)
 		}
@@ -2242,10 +3882,10 @@ func TestVolumeOperationLocks(t *testing.T) {
 	operationUnblocker := make(chan chan struct{}, 1)
 	cs := initBlockingTestController(t, operationUnblocker).(*controllerServer)
 	cs.config.tagManager.(*cloud.FakeTagServiceManager).
-		On("AttachResourceTags", context.Background(), cloud.FilestoreInstance, testCSIVolume, testLocation, testCSIVolume, map[string]string(nil)).
+		On("AttachResourceTags", context.Background(), cloud.FilestoreInstance, testProject, testCSIVolume, testLocation, testCSIVolume, map[string]string(nil)).
 		Return(nil)
 	cs.config.tagManager.(*cloud.FakeTagServiceManager).
-		On("AttachResourceTags", context.Background(), cloud.FilestoreInstance, testCSIVolume2, testLocation, testCSIVolume2, map[string]string(nil)).
+		On("AttachResourceTags", context.Background(), cloud.FilestoreInstance, testProject, testCSIVolume2, testLocation, testCSIVolume2, map[string]string(nil)).
 		Return(nil)
 	runRequest := func(req *RequestConfig) <-chan error {
 		resp := make(chan error)
@@ -2410,7 +4050,7 @@ func TestCreateSnapshot(t *testing.T) {
 			expectErr: true,
 		},
 		{
-			name: "Existing backup found in state CREATING",
+			name: "Existing backup found in state FAILED",
 			req: &csi.CreateSnapshotRequest{
 				SourceVolumeId: "modeInstance/us-central1/myinstance/myshare",
 				Name:           backupName,
@@ -2428,7 +4068,7 @@ func TestCreateSnapshot(t *testing.T) {
 					BackupURI:          defaultBackupUri,
 					SourceVolumeId:     "modeInstance/us-central1/myinstance/myshare",
 				},
-				state: "CREATING",
+				state: "FAILED",
 			},
 			expectErr: true,
 		},
@@ -2442,19 +4082,8 @@ func TestCreateSnapshot(t *testing.T) {
 					ParameterKeyLabels:         "key1:value1",
 				},
 			},
-			initialBackup: &BackupTestInfo{
-				backup: &file.BackupInfo{
-					Project:            project,
-					Location:           region,
-					SourceInstanceName: instanceName,
-					SourceShare:        shareName,
-					Name:               backupName,
-					BackupURI:          defaultBackupUri,
-					SourceVolumeId:     "modeInstance/us-central1/myinstance/myshare",
-				},
-				state: "CREATING",
-			},
-			expectErr: true,
+			initialBackup: nil,
+			expectErr:     true,
 		},
 		// Success test cases
 		{
@@ -2513,7 +4142,37 @@ func TestCreateSnapshot(t *testing.T) {
 					ReadyToUse:     true,
 				},
 			},
-			initialBackup: nil,
+			initialBackup: nil,
+		},
+		{
+			name: "Existing backup found in state CREATING, not ready to use",
+			req: &csi.CreateSnapshotRequest{
+				SourceVolumeId: "modeInstance/us-central1/myinstance/myshare",
+				Name:           backupName,
+				Parameters: map[string]string{
+					util.VolumeSnapshotTypeKey: "backup",
+				},
+			},
+			resp: &csi.CreateSnapshotResponse{
+				Snapshot: &csi.Snapshot{
+					SizeBytes:      1 * util.Tb,
+					SnapshotId:     defaultBackupUri,
+					SourceVolumeId: "modeInstance/us-central1/myinstance/myshare",
+					ReadyToUse:     false,
+				},
+			},
+			initialBackup: &BackupTestInfo{
+				backup: &file.BackupInfo{
+					Project:            project,
+					Location:           region,
+					SourceInstanceName: instanceName,
+					SourceShare:        shareName,
+					Name:               backupName,
+					BackupURI:          defaultBackupUri,
+					SourceVolumeId:     "modeInstance/us-central1/myinstance/myshare",
+				},
+				state: "CREATING",
+			},
 		},
 		{
 			name: "Existing backup found, with same source volume Id (source regional filestore instance)",
@@ -2614,13 +4273,13 @@ func TestCreateSnapshot(t *testing.T) {
 		}).(*controllerServer)
 
 		cs.config.tagManager.(*cloud.FakeTagServiceManager).
-			On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, backupName, region, test.req.GetName(), test.req.GetParameters()).
+			On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, testProject, backupName, region, test.req.GetName(), test.req.GetParameters()).
 			Return(nil)
 		cs.config.tagManager.(*cloud.FakeTagServiceManager).
-			On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, backupName, "us-west1", test.req.GetName(), test.req.GetParameters()).
+			On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, testProject, backupName, "us-west1", test.req.GetName(), test.req.GetParameters()).
 			Return(nil)
 		cs.config.tagManager.(*cloud.FakeTagServiceManager).
-			On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, backupName2, region, test.req.GetName(), test.req.GetParameters()).
+			On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, testProject, backupName2, region, test.req.GetName(), test.req.GetParameters()).
 			Return(fmt.Errorf("mock failure: error while adding tags to filestore backup"))
 
 		if test.initialBackup != nil {
@@ -2668,6 +4327,127 @@ func TestCreateSnapshot(t *testing.T) {
 	}
 }
 
+// creatingBackupFileService leaves the backups it starts in the CREATING state, as the backup
+// operation would until it completes.
+type creatingBackupFileService struct {
+	file.Service
+}
+
+func (s *creatingBackupFileService) StartCreateBackupOp(ctx context.Context, backupInfo *file.BackupInfo) (*filev1beta1.Operation, error) {
+	op, err := s.Service.StartCreateBackupOp(ctx, backupInfo)
+	if err != nil {
+		return nil, err
+	}
+	backup, err := s.Service.GetBackup(ctx, backupInfo.BackupURI)
+	if err != nil {
+		return nil, err
+	}
+	backup.Backup.State = "CREATING"
+	op.Done = false
+	return op, nil
+}
+
+func TestCreateSnapshotAsync(t *testing.T) {
+	volumeID := "modeInstance/us-central1-c/myinstance/myshare"
+	backupURI := fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testRegion, "mybackup")
+	cases := []struct {
+		name string
+		// finalState is the state of the backup once the operation completes.
+		finalState      string
+		expectReady     bool
+		expectFinalCode codes.Code
+	}{
+		{
+			name:        "backup becomes ready",
+			finalState:  "READY",
+			expectReady: true,
+		},
+		{
+			name:            "failed backup is deleted",
+			finalState:      "FAILED",
+			expectFinalCode: codes.Unavailable,
+		},
+	}
+	meta, _ := json.Marshal(&filev1beta1.OperationMetadata{Target: backupURI, Verb: util.OpVerbCreate})
+	failedOp := &filev1beta1.Operation{
+		Name:     "operation-1",
+		Done:     true,
+		Metadata: meta,
+		Error:    &filev1beta1.Status{Code: int64(codes.Internal), Message: "source instance is busy"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := file.NewFakeServiceForMultishare(nil, nil, []*filev1beta1.Operation{failedOp})
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			cs := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: &creatingBackupFileService{Service: fs},
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+			}).(*controllerServer)
+			req := &csi.CreateSnapshotRequest{
+				SourceVolumeId: volumeID,
+				Name:           "mybackup",
+				Parameters:     map[string]string{util.VolumeSnapshotTypeKey: util.VolumeSnapshotTypeBackup},
+			}
+
+			// The first calls return while the backup is being created.
+			for i := 0; i < 2; i++ {
+				resp, err := cs.CreateSnapshot(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("call %d: unexpected error: %v", i, err)
+				}
+				if resp.Snapshot.ReadyToUse {
+					t.Errorf("call %d: expected snapshot not ready to use", i)
+				}
+				if resp.Snapshot.SnapshotId != backupURI || resp.Snapshot.CreationTime == nil {
+					t.Errorf("call %d: unexpected snapshot %+v", i, resp.Snapshot)
+				}
+			}
+
+			backup, err := fs.GetBackup(context.TODO(), backupURI)
+			if err != nil {
+				t.Fatalf("failed to get backup: %v", err)
+			}
+			backup.Backup.State = tc.finalState
+			resp, err := cs.CreateSnapshot(context.TODO(), req)
+			if tc.expectFinalCode != codes.OK {
+				if status.Code(err) != tc.expectFinalCode {
+					t.Fatalf("expected error code %v, got %v", tc.expectFinalCode, err)
+				}
+				if !strings.Contains(err.Error(), failedOp.Error.Message) {
+					t.Errorf("expected error to include the operation error, got %v", err)
+				}
+				if _, err := fs.GetBackup(context.TODO(), backupURI); !file.IsNotFoundErr(err) {
+					t.Fatalf("expected failed backup to be deleted, got %v", err)
+				}
+				// The next call creates the backup again.
+				resp, err := cs.CreateSnapshot(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("unexpected error after the failure: %v", err)
+				}
+				if resp.Snapshot.ReadyToUse || resp.Snapshot.SnapshotId != backupURI {
+					t.Errorf("unexpected snapshot after the failure %+v", resp.Snapshot)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Snapshot.ReadyToUse != tc.expectReady {
+				t.Errorf("got ready to use %v, expected %v", resp.Snapshot.ReadyToUse, tc.expectReady)
+			}
+		})
+	}
+}
+
 func TestDeleteSnapshot(t *testing.T) {
 	backupName := "mybackup"
 	project := "test-project"
@@ -2726,7 +4506,7 @@ func TestDeleteSnapshot(t *testing.T) {
 		}).(*controllerServer)
 
 		cs.config.tagManager.(*cloud.FakeTagServiceManager).
-			On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, backupName, region, test.createReq.GetName(), test.createReq.GetParameters()).
+			On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, testProject, backupName, region, test.createReq.GetName(), test.createReq.GetParameters()).
 			Return(nil)
 
 		_, err = cs.CreateSnapshot(context.TODO(), test.createReq)
@@ -2758,6 +4538,468 @@ func TestDeleteSnapshot(t *testing.T) {
 
 }
 
+func TestListSnapshots(t *testing.T) {
+	multishareInstance := &file.MultishareInstance{
+		Project:  testProject,
+		Location: testRegion,
+		Name:     "multishare-instance",
+		Labels:   map[string]string{util.ParamMultishareInstanceScLabelKey: testInstanceScPrefix},
+		State:    "READY",
+	}
+	fs, err := file.NewFakeServiceForMultishare([]*file.MultishareInstance{multishareInstance}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	driverLabels := map[string]string{tagKeyCreatedBy: "test-driver"}
+	backupID := func(name string) string {
+		return fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testRegion, name)
+	}
+	multishareVolumeID := fmt.Sprintf("%s/%s/%s/%s/%s/%s", modeMultishare, testInstanceScPrefix, testProject, testRegion, "multishare-instance", "share1")
+	deletedMultishareVolumeID := fmt.Sprintf("%s/%s/%s/%s/%s/%s", modeMultishare, testInstanceScPrefix, testProject, testRegion, "deleted-instance", "share1")
+	for _, info := range []*file.BackupInfo{
+		{Name: "backup-a", SourceVolumeId: "modeInstance/us-central1-c/instance-a/vol1", SourceInstanceName: "instance-a", SourceShare: "vol1", Labels: driverLabels},
+		{Name: "backup-b", SourceVolumeId: "modeInstance/us-central1-c/instance-b/vol1", SourceInstanceName: "instance-b", SourceShare: "vol1", Labels: driverLabels},
+		{Name: "backup-c", SourceVolumeId: "modeInstance/us-central1-c/instance-a/vol1", SourceInstanceName: "instance-a", SourceShare: "vol1", Labels: driverLabels},
+		{Name: "backup-d", SourceVolumeId: multishareVolumeID, SourceInstanceName: "multishare-instance", SourceShare: "share1", Labels: driverLabels},
+		// Backups of a deleted multishare instance, with and without the storage class label.
+		{Name: "backup-e", SourceVolumeId: deletedMultishareVolumeID, SourceInstanceName: "deleted-instance", SourceShare: "share1", Labels: map[string]string{tagKeyCreatedBy: "test-driver", util.ParamMultishareInstanceScLabelKey: testInstanceScPrefix}},
+		{Name: "backup-f", SourceVolumeId: deletedMultishareVolumeID, SourceInstanceName: "deleted-instance", SourceShare: "share1", Labels: driverLabels},
+		{Name: "unlabeled", SourceVolumeId: "modeInstance/us-central1-c/instance-a/vol1", SourceInstanceName: "instance-a", SourceShare: "vol1"},
+	} {
+		info.Project = testProject
+		info.Location = testRegion
+		info.BackupURI = backupID(info.Name)
+		if _, err := fs.CreateBackup(context.Background(), info); err != nil {
+			t.Fatalf("failed to create fake backup: %v", err)
+		}
+	}
+
+	// Instance snapshots are listed for the instances created by the driver.
+	for _, instance := range []*file.ServiceInstance{
+		{Name: "zonal-instance", Tier: zonalTier, Labels: driverLabels, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}},
+		{Name: "unlabeled-instance", Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}},
+	} {
+		if _, err := fs.CreateInstance(context.Background(), instance); err != nil {
+			t.Fatalf("failed to create fake instance: %v", err)
+		}
+		if _, err := fs.CreateInstanceSnapshot(context.Background(), &file.SnapshotInfo{
+			Name:               "snapshot",
+			SourceInstanceName: instance.Name,
+			SnapshotURI:        file.CreateInstanceSnapshotURI(testProject, testZone, instance.Name, "snapshot"),
+			Labels:             driverLabels,
+		}); err != nil {
+			t.Fatalf("failed to create fake instance snapshot: %v", err)
+		}
+	}
+	instanceSnapshotID := file.CreateInstanceSnapshotURI(testProject, testZone, "zonal-instance", "snapshot")
+	instanceVolumeID := fmt.Sprintf("%s/%s/%s/%s", modeInstance, testZone, "zonal-instance", "vol1")
+
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+	ctrl := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: fs,
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+		tagManager:  cloud.NewFakeTagManager(),
+	})
+
+	cases := []struct {
+		name              string
+		req               *csi.ListSnapshotsRequest
+		expectedIDs       []string
+		expectedSourceIDs []string
+		expectedNextToken string
+		expectedCode      codes.Code
+	}{
+		{
+			name:        "all snapshots",
+			req:         &csi.ListSnapshotsRequest{},
+			expectedIDs: []string{instanceSnapshotID, backupID("backup-a"), backupID("backup-b"), backupID("backup-c"), backupID("backup-d"), backupID("backup-e"), backupID("backup-f")},
+			expectedSourceIDs: []string{
+				instanceVolumeID,
+				"modeInstance/us-central1-c/instance-a/vol1",
+				"modeInstance/us-central1-c/instance-b/vol1",
+				"modeInstance/us-central1-c/instance-a/vol1",
+				multishareVolumeID,
+				deletedMultishareVolumeID,
+				"",
+			},
+		},
+		{
+			name:              "first page",
+			req:               &csi.ListSnapshotsRequest{MaxEntries: 3},
+			expectedIDs:       []string{instanceSnapshotID, backupID("backup-a"), backupID("backup-b")},
+			expectedNextToken: backupID("backup-c"),
+		},
+		{
+			name:        "last page",
+			req:         &csi.ListSnapshotsRequest{MaxEntries: 4, StartingToken: backupID("backup-c")},
+			expectedIDs: []string{backupID("backup-c"), backupID("backup-d"), backupID("backup-e"), backupID("backup-f")},
+		},
+		{
+			name:              "snapshot id of a deleted multishare instance",
+			req:               &csi.ListSnapshotsRequest{SnapshotId: backupID("backup-e")},
+			expectedIDs:       []string{backupID("backup-e")},
+			expectedSourceIDs: []string{deletedMultishareVolumeID},
+		},
+		{
+			name:              "snapshot id of a deleted multishare instance without storage class label",
+			req:               &csi.ListSnapshotsRequest{SnapshotId: backupID("backup-f")},
+			expectedIDs:       []string{backupID("backup-f")},
+			expectedSourceIDs: []string{""},
+		},
+		{
+			name:        "deleted multishare instance source volume id",
+			req:         &csi.ListSnapshotsRequest{SourceVolumeId: deletedMultishareVolumeID},
+			expectedIDs: []string{backupID("backup-e")},
+		},
+		{
+			name:              "instance snapshot source volume id",
+			req:               &csi.ListSnapshotsRequest{SourceVolumeId: instanceVolumeID},
+			expectedIDs:       []string{instanceSnapshotID},
+			expectedSourceIDs: []string{instanceVolumeID},
+		},
+		{
+			name:        "snapshot id",
+			req:         &csi.ListSnapshotsRequest{SnapshotId: backupID("backup-b")},
+			expectedIDs: []string{backupID("backup-b")},
+		},
+		{
+			name:        "snapshot id without created-by label",
+			req:         &csi.ListSnapshotsRequest{SnapshotId: backupID("unlabeled")},
+			expectedIDs: []string{backupID("unlabeled")},
+		},
+		{
+			name: "snapshot id not found",
+			req:  &csi.ListSnapshotsRequest{SnapshotId: backupID("deleted")},
+		},
+		{
+			name: "invalid snapshot id",
+			req:  &csi.ListSnapshotsRequest{SnapshotId: "none-exist-id"},
+		},
+		{
+			name:        "single share source volume id",
+			req:         &csi.ListSnapshotsRequest{SourceVolumeId: "modeInstance/us-central1-c/instance-a/vol1"},
+			expectedIDs: []string{backupID("backup-a"), backupID("backup-c")},
+		},
+		{
+			name:        "multishare source volume id",
+			req:         &csi.ListSnapshotsRequest{SourceVolumeId: multishareVolumeID},
+			expectedIDs: []string{backupID("backup-d")},
+		},
+		{
+			name:        "snapshot id and mismatched source volume id",
+			req:         &csi.ListSnapshotsRequest{SnapshotId: backupID("backup-b"), SourceVolumeId: "modeInstance/us-central1-c/instance-a/vol1"},
+			expectedIDs: nil,
+		},
+		{
+			name: "unknown source volume id",
+			req:  &csi.ListSnapshotsRequest{SourceVolumeId: "unknown-volume"},
+		},
+		{
+			name:         "invalid token",
+			req:          &csi.ListSnapshotsRequest{StartingToken: "invalid-token"},
+			expectedCode: codes.Aborted,
+		},
+		{
+			name:         "negative max entries",
+			req:          &csi.ListSnapshotsRequest{MaxEntries: -1},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := ctrl.ListSnapshots(context.Background(), tc.req)
+			if tc.expectedCode != codes.OK {
+				if status.Code(err) != tc.expectedCode {
+					t.Fatalf("expected error code %v, got: %v", tc.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var ids, sourceIDs []string
+			for _, entry := range resp.GetEntries() {
+				ids = append(ids, entry.GetSnapshot().GetSnapshotId())
+				sourceIDs = append(sourceIDs, entry.GetSnapshot().GetSourceVolumeId())
+			}
+			if diff := cmp.Diff(tc.expectedIDs, ids); diff != "" {
+				t.Errorf("unexpected snapshot ids (-want +got):\n%s", diff)
+			}
+			if tc.expectedSourceIDs != nil {
+				if diff := cmp.Diff(tc.expectedSourceIDs, sourceIDs); diff != "" {
+					t.Errorf("unexpected source volume ids (-want +got):\n%s", diff)
+				}
+			}
+			if resp.GetNextToken() != tc.expectedNextToken {
+				t.Errorf("expected next token %q, got %q", tc.expectedNextToken, resp.GetNextToken())
+			}
+		})
+	}
+}
+
+func TestInstanceSnapshot(t *testing.T) {
+	snapshotParams := map[string]string{util.VolumeSnapshotTypeKey: util.VolumeSnapshotTypeSnapshot}
+	snapshotID := fmt.Sprintf("projects/%s/locations/%s/instances/%s/snapshots/mysnapshot", testProject, testZone, testCSIVolume)
+	cases := []struct {
+		name string
+		tier string
+		req  *csi.CreateSnapshotRequest
+		// existingState is the state of a snapshot created by a previous call, if any.
+		existingState string
+		expectedCode  codes.Code
+	}{
+		{
+			name: "zonal instance",
+			tier: zonalTier,
+			req:  &csi.CreateSnapshotRequest{Name: "mysnapshot", SourceVolumeId: testVolumeID, Parameters: snapshotParams},
+		},
+		{
+			name: "enterprise instance",
+			tier: enterpriseTier,
+			req:  &csi.CreateSnapshotRequest{Name: "mysnapshot", SourceVolumeId: testVolumeID, Parameters: snapshotParams},
+		},
+		{
+			name:         "basic instance",
+			tier:         basicHDDTier,
+			req:          &csi.CreateSnapshotRequest{Name: "mysnapshot", SourceVolumeId: testVolumeID, Parameters: snapshotParams},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "location parameter",
+			tier: zonalTier,
+			req: &csi.CreateSnapshotRequest{Name: "mysnapshot", SourceVolumeId: testVolumeID, Parameters: map[string]string{
+				util.VolumeSnapshotTypeKey:     util.VolumeSnapshotTypeSnapshot,
+				util.VolumeSnapshotLocationKey: testRegion,
+			}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:          "snapshot being created",
+			tier:          zonalTier,
+			req:           &csi.CreateSnapshotRequest{Name: "mysnapshot", SourceVolumeId: testVolumeID, Parameters: snapshotParams},
+			existingState: "CREATING",
+		},
+		{
+			name:          "failed snapshot",
+			tier:          zonalTier,
+			req:           &csi.CreateSnapshotRequest{Name: "mysnapshot", SourceVolumeId: testVolumeID, Parameters: snapshotParams},
+			existingState: "ERROR",
+			expectedCode:  codes.Internal,
+		},
+		{
+			name:         "source instance not found",
+			tier:         zonalTier,
+			req:          &csi.CreateSnapshotRequest{Name: "mysnapshot", SourceVolumeId: "modeInstance/us-central1-c/deleted/vol1", Parameters: snapshotParams},
+			expectedCode: codes.NotFound,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := file.NewFakeService()
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+			if _, err := fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: testCSIVolume, Location: testZone, Tier: tc.tier, Volume: file.Volume{Name: "vol1", SizeBytes: testBytes}}); err != nil {
+				t.Fatalf("failed to create fake instance: %v", err)
+			}
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			ctrl := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fs,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+				tagManager:  cloud.NewFakeTagManager(),
+			})
+			if tc.existingState != "" {
+				existing, err := fs.CreateInstanceSnapshot(context.Background(), &file.SnapshotInfo{SnapshotURI: snapshotID, SourceInstanceName: testCSIVolume})
+				if err != nil {
+					t.Fatalf("failed to create fake snapshot: %v", err)
+				}
+				existing.State = tc.existingState
+			}
+
+			resp, err := ctrl.CreateSnapshot(context.Background(), tc.req)
+			if tc.expectedCode != codes.OK {
+				if status.Code(err) != tc.expectedCode {
+					t.Fatalf("expected error code %v, got: %v", tc.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.GetSnapshot().GetSnapshotId() != snapshotID {
+				t.Errorf("expected snapshot id %q, got %q", snapshotID, resp.GetSnapshot().GetSnapshotId())
+			}
+			// A snapshot being created is returned as not ready to use, like a backup.
+			expectReady := tc.existingState == ""
+			if resp.GetSnapshot().GetSourceVolumeId() != testVolumeID || resp.GetSnapshot().GetReadyToUse() != expectReady {
+				t.Errorf("unexpected snapshot %+v", resp.GetSnapshot())
+			}
+
+			// Creating the same snapshot again is idempotent.
+			if _, err := ctrl.CreateSnapshot(context.Background(), tc.req); err != nil {
+				t.Fatalf("unexpected error on repeated create: %v", err)
+			}
+
+			listResp, err := ctrl.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SnapshotId: snapshotID})
+			if err != nil {
+				t.Fatalf("unexpected error on list: %v", err)
+			}
+			if len(listResp.GetEntries()) != 1 || listResp.GetEntries()[0].GetSnapshot().GetSourceVolumeId() != testVolumeID {
+				t.Errorf("unexpected list response %+v", listResp)
+			}
+
+			if _, err := ctrl.DeleteSnapshot(context.Background(), &csi.DeleteSnapshotRequest{SnapshotId: snapshotID}); err != nil {
+				t.Fatalf("unexpected error on delete: %v", err)
+			}
+			if _, err := fs.GetInstanceSnapshot(context.Background(), snapshotID); !file.IsNotFoundErr(err) {
+				t.Errorf("expected snapshot to be deleted, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateVolumeFromInstanceSnapshot(t *testing.T) {
+	snapshotID := file.CreateInstanceSnapshotURI(testProject, testZone, "source", "mysnapshot")
+	capabilities := []*csi.VolumeCapability{
+		{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		},
+	}
+	snapshotSource := func(id string) *csi.VolumeContentSource {
+		return &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Snapshot{Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: id}},
+		}
+	}
+	cases := []struct {
+		name         string
+		req          *csi.CreateVolumeRequest
+		expectedCode codes.Code
+	}{
+		{
+			name: "restore",
+			req: &csi.CreateVolumeRequest{
+				Name:                "restored",
+				VolumeCapabilities:  capabilities,
+				Parameters:          map[string]string{"tier": zonalTier},
+				CapacityRange:       &csi.CapacityRange{RequiredBytes: 2 * util.Tb},
+				VolumeContentSource: snapshotSource(snapshotID),
+			},
+		},
+		{
+			name: "snapshot not found",
+			req: &csi.CreateVolumeRequest{
+				Name:                "restored",
+				VolumeCapabilities:  capabilities,
+				Parameters:          map[string]string{"tier": zonalTier},
+				VolumeContentSource: snapshotSource(file.CreateInstanceSnapshotURI(testProject, testZone, "source", "deleted")),
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "tier mismatch",
+			req: &csi.CreateVolumeRequest{
+				Name:                "restored",
+				VolumeCapabilities:  capabilities,
+				Parameters:          map[string]string{"tier": premiumTier},
+				VolumeContentSource: snapshotSource(snapshotID),
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "capacity smaller than the source",
+			req: &csi.CreateVolumeRequest{
+				Name:                "restored",
+				VolumeCapabilities:  capabilities,
+				Parameters:          map[string]string{"tier": zonalTier},
+				CapacityRange:       &csi.CapacityRange{RequiredBytes: 1 * util.Tb},
+				VolumeContentSource: snapshotSource(snapshotID),
+			},
+			expectedCode: codes.OutOfRange,
+		},
+		{
+			name: "location not allowed by the topology",
+			req: &csi.CreateVolumeRequest{
+				Name:                "restored",
+				VolumeCapabilities:  capabilities,
+				Parameters:          map[string]string{"tier": zonalTier},
+				VolumeContentSource: snapshotSource(snapshotID),
+				AccessibilityRequirements: &csi.TopologyRequirement{
+					Requisite: []*csi.Topology{{Segments: map[string]string{TopologyKeyZone: "us-central1-a"}}},
+				},
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := file.NewFakeService()
+			if err != nil {
+				t.Fatalf("failed to init fake file service: %v", err)
+			}
+			if _, err := fs.CreateInstance(context.Background(), &file.ServiceInstance{Name: "source", Location: testZone, Tier: zonalTier, Volume: file.Volume{Name: "vol1", SizeBytes: 2 * util.Tb}}); err != nil {
+				t.Fatalf("failed to create fake instance: %v", err)
+			}
+			if _, err := fs.CreateInstanceSnapshot(context.Background(), &file.SnapshotInfo{Name: "mysnapshot", SourceInstanceName: "source", SnapshotURI: snapshotID}); err != nil {
+				t.Fatalf("failed to create fake instance snapshot: %v", err)
+			}
+			cloudProvider, err := cloud.NewFakeCloud()
+			if err != nil {
+				t.Fatalf("Failed to get cloud provider: %v", err)
+			}
+			tagManager := cloud.NewFakeTagManager()
+			ctrl := newControllerServer(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: fs,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+				features:    &GCFSDriverFeatureOptions{FeatureLockRelease: &FeatureLockRelease{}},
+				tagManager:  tagManager,
+			})
+			tagManager.On("AttachResourceTags", context.Background(), cloud.FilestoreInstance, testProject, tc.req.Name, testZone, tc.req.Name, tc.req.Parameters).
+				Return(nil)
+
+			resp, err := ctrl.CreateVolume(context.Background(), tc.req)
+			if tc.expectedCode != codes.OK {
+				if status.Code(err) != tc.expectedCode {
+					t.Fatalf("expected error code %v, got: %v", tc.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id := resp.GetVolume().GetContentSource().GetSnapshot().GetSnapshotId(); id != snapshotID {
+				t.Errorf("expected content source %q, got %q", snapshotID, id)
+			}
+
+			// Retries of the request do not restore the volume again.
+			if _, err := ctrl.CreateVolume(context.Background(), tc.req); err != nil {
+				t.Fatalf("unexpected error on retry: %v", err)
+			}
+			ops, err := fs.ListInstanceOps(context.Background(), &file.ServiceInstance{Project: testProject, Location: testZone, Name: tc.req.Name}, util.OpVerbRestore)
+			if err != nil {
+				t.Fatalf("failed to list operations: %v", err)
+			}
+			if len(ops) != 1 {
+				t.Errorf("expected 1 restore operation, got %d", len(ops))
+			}
+		})
+	}
+}
+
 func TestCreateBackupURI(t *testing.T) {
 	backupName := "mybackup"
 	project := "test-project"
@@ -31,22 +31,8 @@ func (s *controllerServer) ControllerUnpublishVolume(ctx context.Context, req *c
 	return nil, status.Error(codes.Unimplemented, "ControllerUnpublishVolume unsupported")
 }
 
-func (s *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	// https://cloud.google.com/compute/docs/reference/beta/disks/list
-	// List volumes in the whole region? In only the zone that this controller is running?
-	return nil, status.Error(codes.Unimplemented, "")
-}
-
 func (s *controllerServer) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
 	// https://cloud.google.com/compute/quotas
 	// DISKS_TOTAL_GB.
 	return nil, status.Error(codes.Unimplemented, "")
 }
-
-func (s *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "ListSnapshots unsupported")
-}
-
-func (s *controllerServer) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "ControllerGetVolume unsupported")
-}
@@ -82,6 +82,7 @@ type GCFSDriver struct {
 	ids csi.IdentityServer
 	ns  csi.NodeServer
 	cs  csi.ControllerServer
+	gcs csi.GroupControllerServer
 
 	// Stateful CSI driver
 	recon         *MultishareReconciler
@@ -105,6 +106,91 @@ type GCFSDriverFeatureOptions struct {
 	FeatureNFSExportOptionsOnCreate *FeatureNFSExportOptionsOnCreate
 	FeatureNFSv4Support             *FeatureNFSv4Support
 	FeatureSharePools               *FeatureSharePools
+	// FeaturePersistentIPReservations will persist the IP ranges reserved for reserved-ipv4-cidr provisioning in a configmap,
+	// so that they are shared between controller replicas and survive restarts.
+	FeaturePersistentIPReservations *FeaturePersistentIPReservations
+	// FeatureBackupGC will periodically delete the backups created by the driver once they expire.
+	FeatureBackupGC *FeatureBackupGC
+	// FeatureBackupSchedules will take the backups declared by FilestoreBackupSchedule objects.
+	FeatureBackupSchedules *FeatureBackupSchedules
+	// FeatureMultishareJanitor will periodically delete the multishare instances of this cluster left without shares.
+	FeatureMultishareJanitor *FeatureMultishareJanitor
+	// FeatureMultishareWarmPool will keep the warm pools of empty instances of the multishare StorageClasses.
+	FeatureMultishareWarmPool *FeatureMultishareWarmPool
+	// FeatureTopologyRegion will publish the region topology key on the nodes, and report regional and
+	// enterprise tier volumes as accessible from the whole region.
+	FeatureTopologyRegion *FeatureTopologyRegion
+	// FeatureVolumeGroupSnapshots will serve the CSI GroupController service, backing up the volumes of a
+	// VolumeGroupSnapshot together. The backups are not crash-consistent.
+	FeatureVolumeGroupSnapshots *FeatureVolumeGroupSnapshots
+}
+
+// regionTopologyEnabled returns whether the region topology key is used.
+func (o *GCFSDriverFeatureOptions) regionTopologyEnabled() bool {
+	return o != nil && o.FeatureTopologyRegion != nil && o.FeatureTopologyRegion.Enabled
+}
+
+type FeatureTopologyRegion struct {
+	Enabled bool
+}
+
+type FeatureVolumeGroupSnapshots struct {
+	Enabled bool
+}
+
+type FeatureMultishareWarmPool struct {
+	Enabled    bool
+	SyncPeriod time.Duration
+	// KubeClient lists the StorageClasses, it is only needed without the stateful controller.
+	KubeClient kubernetes.Interface
+}
+
+type FeatureMultishareJanitor struct {
+	Enabled bool
+	Period  time.Duration
+	// GracePeriod is how long an instance stays empty before it is deleted.
+	GracePeriod time.Duration
+	// DryRun only logs the instances which would be deleted.
+	DryRun bool
+}
+
+type FeatureBackupSchedules struct {
+	Enabled    bool
+	SyncPeriod time.Duration
+	KubeClient kubernetes.Interface
+	ClientSet  clientset.Interface
+	// LeaderElection runs the schedules in the elected controller only.
+	LeaderElection              bool
+	LeaderElectionNamespace     string
+	LeaderElectionLeaseDuration time.Duration
+	LeaderElectionRenewDeadline time.Duration
+	LeaderElectionRetryPeriod   time.Duration
+}
+
+type FeatureBackupGC struct {
+	Enabled bool
+	Period  time.Duration
+	// MaxAge and KeepLast are the default retention policy of the backups of this cluster, zero
+	// values disable them.
+	MaxAge   time.Duration
+	KeepLast int
+	// DryRun only logs the backups which would be deleted.
+	DryRun bool
+	// KubeClient lists the VolumeSnapshotContents, whose backups are never deleted.
+	KubeClient kubernetes.Interface
+	// LeaderElection runs the garbage collector in the elected controller only.
+	LeaderElection              bool
+	LeaderElectionNamespace     string
+	LeaderElectionLeaseDuration time.Duration
+	LeaderElectionRenewDeadline time.Duration
+	LeaderElectionRetryPeriod   time.Duration
+}
+
+type FeaturePersistentIPReservations struct {
+	Enabled    bool
+	Namespace  string
+	TTL        time.Duration
+	KubeClient kubernetes.Interface
 }
 
 type FeatureSharePools struct {
@@ -199,6 +285,11 @@ func NewGCFSDriver(config *GCFSDriverConfig) (*GCFSDriver, error) {
 			csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
 			csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
 			csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
+			csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+			csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+			csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
+			csi.ControllerServiceCapability_RPC_GET_VOLUME,
+			csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
 		}
 		driver.addControllerServiceCapabilities(csc)
 
@@ -221,6 +312,9 @@ func NewGCFSDriver(config *GCFSDriverConfig) (*GCFSDriver, error) {
 			extraVolumeLabels: config.ExtraVolumeLabels,
 			tagManager:        config.TagManager,
 		})
+		if config.FeatureOptions.FeatureVolumeGroupSnapshots != nil && config.FeatureOptions.FeatureVolumeGroupSnapshots.Enabled {
+			driver.gcs = newGroupControllerServer(driver.cs.(*controllerServer))
+		}
 	}
 
 	return driver, nil
@@ -339,7 +433,7 @@ func (driver *GCFSDriver) Run(endpoint string) {
 
 	// Start the nonblocking GRPC.
 	s := NewNonBlockingGRPCServer()
-	s.Start(endpoint, driver.ids, driver.cs, driver.ns)
+	s.Start(endpoint, driver.ids, driver.cs, driver.gcs, driver.ns)
 	if driver.config.RunNode && driver.config.FeatureOptions.FeatureLockRelease.Enabled && !driver.config.FeatureOptions.FeatureLockRelease.Standalone {
 		// Start the lock release controller on node driver.
 		driver.ns.(*nodeServer).lockReleaseController.Run(context.Background())
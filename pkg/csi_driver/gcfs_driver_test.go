@@ -274,3 +274,29 @@ func TestModifyVolumeCapability(t *testing.T) {
 		t.Errorf("MODIFY_VOLUME capability should be supported, got error: %v", err)
 	}
 }
+
+func TestGroupControllerFeature(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		c, err := cloud.NewFakeCloud()
+		if err != nil {
+			t.Fatalf("Failed to init cloud")
+		}
+		config := &GCFSDriverConfig{
+			Name:          "test-driver",
+			Version:       "test-version",
+			RunController: true,
+			Cloud:         c,
+			FeatureOptions: &GCFSDriverFeatureOptions{
+				FeatureLockRelease:          &FeatureLockRelease{},
+				FeatureVolumeGroupSnapshots: &FeatureVolumeGroupSnapshots{Enabled: enabled},
+			},
+		}
+		driver, err := NewGCFSDriver(config)
+		if err != nil {
+			t.Fatalf("failed to init driver: %v", err)
+		}
+		if (driver.gcs != nil) != enabled {
+			t.Errorf("got group controller server %v with the feature enabled: %v", driver.gcs, enabled)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// A volume group snapshot is a set of backups of several volumes, taken together. Filestore
+// has no backup spanning several instances, so the backups of the member volumes are started
+// concurrently, and the group is ready to use once all of them are. The members of a group are
+// the backups labeled with the ID of the group, so a group snapshot ID is enough to look them
+// up. When a member backup cannot be taken, the backups already taken for the group are
+// deleted, so that a failed group does not leak backups.
+
+const (
+	// tagKeyVolumeGroupSnapshot is set on the member backups of a volume group snapshot to the
+	// hash of the name of the group.
+	tagKeyVolumeGroupSnapshot = "storage_gke_io_volume-group-snapshot"
+
+	volumeGroupSnapshotPrefix = "vgs"
+	volumeGroupSnapshotIDFmt  = "projects/%s/locations/%s/volumeGroupSnapshots/%s"
+)
+
+type groupControllerServer struct {
+	csi.UnimplementedGroupControllerServer
+	cs *controllerServer
+}
+
+func newGroupControllerServer(cs *controllerServer) csi.GroupControllerServer {
+	return &groupControllerServer{cs: cs}
+}
+
+// volumeGroupSnapshotMember is the backup of a member volume of a volume group snapshot.
+type volumeGroupSnapshotMember struct {
+	volumeID string
+	name     string
+}
+
+func (s *groupControllerServer) GroupControllerGetCapabilities(ctx context.Context, req *csi.GroupControllerGetCapabilitiesRequest) (*csi.GroupControllerGetCapabilitiesResponse, error) {
+	return &csi.GroupControllerGetCapabilitiesResponse{
+		Capabilities: []*csi.GroupControllerServiceCapability{
+			{
+				Type: &csi.GroupControllerServiceCapability_Rpc{
+					Rpc: &csi.GroupControllerServiceCapability_RPC{
+						Type: csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func (s *groupControllerServer) CreateVolumeGroupSnapshot(ctx context.Context, req *csi.CreateVolumeGroupSnapshotRequest) (*csi.CreateVolumeGroupSnapshotResponse, error) {
+	klog.V(4).Infof("CreateVolumeGroupSnapshot called with request %+v", req)
+	if len(req.GetName()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "CreateVolumeGroupSnapshot name must be provided")
+	}
+	if len(req.GetSourceVolumeIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "CreateVolumeGroupSnapshot source volume IDs must be provided")
+	}
+	// If parameters are empty we assume 'backup' type by default, like CreateSnapshot.
+	params := req.GetParameters()
+	if params != nil {
+		if _, err := util.IsSnapshotTypeSupported(params); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if util.GetSnapshotType(params) != util.VolumeSnapshotTypeBackup {
+		return nil, status.Errorf(codes.InvalidArgument, "volume snapshot type %q not supported for volume group snapshots", util.GetSnapshotType(params))
+	}
+
+	groupHash := volumeGroupSnapshotHash(req.GetName())
+	var project, region string
+	var members []volumeGroupSnapshotMember
+	seen := make(map[string]bool)
+	for _, volumeID := range req.GetSourceVolumeIds() {
+		if seen[volumeID] {
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate source volume ID %s", volumeID)
+		}
+		seen[volumeID] = true
+		memberProject, memberRegion, err := s.memberBackupLocation(volumeID, params)
+		if err != nil {
+			return nil, err
+		}
+		if project == "" {
+			project, region = memberProject, memberRegion
+		} else if memberProject != project || memberRegion != region {
+			return nil, status.Errorf(codes.InvalidArgument, "the backups of all the volumes of a group must be in the same project and region, volume %s is backed up to %s/%s, expected %s/%s", volumeID, memberProject, memberRegion, project, region)
+		}
+		members = append(members, volumeGroupSnapshotMember{
+			volumeID: volumeID,
+			name:     volumeGroupSnapshotMemberName(groupHash, volumeID),
+		})
+	}
+	groupID := fmt.Sprintf(volumeGroupSnapshotIDFmt, project, region, groupHash)
+
+	// A group with the same name is only compatible if it has the same members.
+	existing, err := s.listGroupBackups(ctx, project, region, groupHash)
+	if err != nil {
+		return nil, err
+	}
+	memberURIs := make(map[string]bool)
+	for _, member := range members {
+		memberURIs[fmt.Sprintf("projects/%s/locations/%s/backups/%s", project, region, member.name)] = true
+	}
+	for _, backup := range existing {
+		if !memberURIs[backup.Backup.Name] {
+			return nil, status.Errorf(codes.AlreadyExists, "volume group snapshot %s already exists with a different member backup %s", req.GetName(), backup.Backup.Name)
+		}
+	}
+
+	memberParams := volumeGroupSnapshotParameters(params, groupHash)
+	snapshots := make([]*csi.Snapshot, len(members))
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func(i int, member volumeGroupSnapshotMember) {
+			defer wg.Done()
+			resp, err := s.cs.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{
+				Name:           member.name,
+				SourceVolumeId: member.volumeID,
+				Parameters:     memberParams,
+				Secrets:        req.GetSecrets(),
+			})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			snapshots[i] = resp.GetSnapshot()
+		}(i, member)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		klog.Errorf("CreateVolumeGroupSnapshot %s failed to back up volume %s: %v", req.GetName(), members[i].volumeID, err)
+		// An aborted call is retried while the volume is busy, there is nothing to clean up.
+		if status.Code(err) == codes.Aborted {
+			return nil, err
+		}
+		if cleanupErr := s.deleteGroupBackups(ctx, project, region, groupHash, nil); cleanupErr != nil {
+			klog.Errorf("CreateVolumeGroupSnapshot %s failed to delete the backups of the group: %v", req.GetName(), cleanupErr)
+		}
+		return nil, status.Errorf(status.Code(err), "failed to back up volume %s of volume group snapshot %s: %v", members[i].volumeID, req.GetName(), status.Convert(err).Message())
+	}
+
+	groupSnapshot := &csi.VolumeGroupSnapshot{
+		GroupSnapshotId: groupID,
+		ReadyToUse:      true,
+	}
+	for _, snapshot := range snapshots {
+		snapshot.GroupSnapshotId = groupID
+		groupSnapshot.Snapshots = append(groupSnapshot.Snapshots, snapshot)
+		groupSnapshot.ReadyToUse = groupSnapshot.ReadyToUse && snapshot.ReadyToUse
+		// The group is cut once its last member is.
+		if groupSnapshot.CreationTime == nil || snapshot.CreationTime.AsTime().After(groupSnapshot.CreationTime.AsTime()) {
+			groupSnapshot.CreationTime = snapshot.CreationTime
+		}
+	}
+	return &csi.CreateVolumeGroupSnapshotResponse{GroupSnapshot: groupSnapshot}, nil
+}
+
+func (s *groupControllerServer) GetVolumeGroupSnapshot(ctx context.Context, req *csi.GetVolumeGroupSnapshotRequest) (*csi.GetVolumeGroupSnapshotResponse, error) {
+	id := req.GetGroupSnapshotId()
+	if len(id) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "GetVolumeGroupSnapshot group snapshot ID must be provided")
+	}
+	project, region, groupHash, err := parseVolumeGroupSnapshotID(id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	backups, err := s.listGroupBackups(ctx, project, region, groupHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, status.Errorf(codes.NotFound, "volume group snapshot %s not found", id)
+	}
+	if err := checkGroupSnapshotIDs(id, backups, req.GetSnapshotIds()); err != nil {
+		return nil, err
+	}
+
+	groupSnapshot := &csi.VolumeGroupSnapshot{
+		GroupSnapshotId: id,
+		ReadyToUse:      true,
+	}
+	scPrefixes := map[string]string{}
+	for _, backup := range backups {
+		snapshot, err := s.cs.backupToCSISnapshot(ctx, backup, scPrefixes)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get member backup %s of volume group snapshot %s: %v", backup.Backup.Name, id, err)
+		}
+		groupSnapshot.Snapshots = append(groupSnapshot.Snapshots, snapshot)
+		groupSnapshot.ReadyToUse = groupSnapshot.ReadyToUse && snapshot.ReadyToUse
+		if groupSnapshot.CreationTime == nil || snapshot.CreationTime.AsTime().After(groupSnapshot.CreationTime.AsTime()) {
+			groupSnapshot.CreationTime = snapshot.CreationTime
+		}
+	}
+	return &csi.GetVolumeGroupSnapshotResponse{GroupSnapshot: groupSnapshot}, nil
+}
+
+func (s *groupControllerServer) DeleteVolumeGroupSnapshot(ctx context.Context, req *csi.DeleteVolumeGroupSnapshotRequest) (*csi.DeleteVolumeGroupSnapshotResponse, error) {
+	id := req.GetGroupSnapshotId()
+	if len(id) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "DeleteVolumeGroupSnapshot group snapshot ID must be provided")
+	}
+	project, region, groupHash, err := parseVolumeGroupSnapshotID(id)
+	if err != nil {
+		klog.Warningf("Could not parse volume group snapshot ID %v", id)
+		return &csi.DeleteVolumeGroupSnapshotResponse{}, nil
+	}
+	if err := s.deleteGroupBackups(ctx, project, region, groupHash, req.GetSnapshotIds()); err != nil {
+		return nil, err
+	}
+	klog.Infof("Deleted volume group snapshot %s", id)
+	return &csi.DeleteVolumeGroupSnapshotResponse{}, nil
+}
+
+// memberBackupLocation returns the project and region of the backup of a member volume.
+func (s *groupControllerServer) memberBackupLocation(volumeID string, params map[string]string) (string, string, error) {
+	project, location := s.cs.config.cloud.Project, ""
+	if isMultishareVolId(volumeID) {
+		_, shareLocation, _, _, err := parseSourceVolId(volumeID)
+		if err != nil {
+			return "", "", status.Error(codes.InvalidArgument, err.Error())
+		}
+		location = shareLocation
+	} else {
+		backupInfo, err := gatherBackupInfo("", volumeID, project)
+		if err != nil {
+			return "", "", err
+		}
+		project, location = backupInfo.Project, backupInfo.Location
+	}
+	_, region, err := file.CreateBackupURI(location, project, "", util.GetBackupLocation(params))
+	if err != nil {
+		return "", "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	return project, region, nil
+}
+
+// listGroupBackups returns the member backups of the volume group snapshot.
+func (s *groupControllerServer) listGroupBackups(ctx context.Context, project, region, groupHash string) ([]*file.Backup, error) {
+	backups, err := s.cs.config.fileService.ListBackups(ctx, &file.ListFilter{Project: project, Location: region})
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+	prefix := fmt.Sprintf("projects/%s/locations/%s/", project, region)
+	var members []*file.Backup
+	for _, backup := range backups {
+		if backup.Backup == nil || !strings.HasPrefix(backup.Backup.Name, prefix) {
+			continue
+		}
+		if backup.Backup.Labels[tagKeyVolumeGroupSnapshot] == groupHash {
+			members = append(members, backup)
+		}
+	}
+	return members, nil
+}
+
+// deleteGroupBackups deletes the member backups of the volume group snapshot. snapshotIDs, if
+// set, are the members expected by the caller.
+func (s *groupControllerServer) deleteGroupBackups(ctx context.Context, project, region, groupHash string, snapshotIDs []string) error {
+	backups, err := s.listGroupBackups(ctx, project, region, groupHash)
+	if err != nil {
+		return err
+	}
+	if err := checkGroupSnapshotIDs(fmt.Sprintf(volumeGroupSnapshotIDFmt, project, region, groupHash), backups, snapshotIDs); err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		if _, err := s.cs.DeleteSnapshot(ctx, &csi.DeleteSnapshotRequest{SnapshotId: backup.Backup.Name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGroupSnapshotIDs checks that the member backups of a volume group snapshot are among the
+// snapshot IDs given by the caller, if any. Members already deleted are not an error, so that
+// a partially deleted group can be deleted again.
+func checkGroupSnapshotIDs(id string, backups []*file.Backup, snapshotIDs []string) error {
+	if len(snapshotIDs) == 0 {
+		return nil
+	}
+	expected := make(map[string]bool)
+	for _, snapshotID := range snapshotIDs {
+		expected[snapshotID] = true
+	}
+	for _, backup := range backups {
+		if !expected[backup.Backup.Name] {
+			return status.Errorf(codes.InvalidArgument, "backup %s is a member of volume group snapshot %s but not among the given snapshot IDs", backup.Backup.Name, id)
+		}
+	}
+	return nil
+}
+
+// volumeGroupSnapshotParameters returns the CreateSnapshot parameters of the member backups of
+// a volume group snapshot, which carry the label of the group.
+func volumeGroupSnapshotParameters(params map[string]string, groupHash string) map[string]string {
+	memberParams := map[string]string{util.VolumeSnapshotTypeKey: util.VolumeSnapshotTypeBackup}
+	for k, v := range params {
+		memberParams[k] = v
+	}
+	groupLabel := tagKeyVolumeGroupSnapshot + "=" + groupHash
+	if labels := memberParams[ParameterKeyLabels]; labels != "" {
+		memberParams[ParameterKeyLabels] = labels + "," + groupLabel
+	} else {
+		memberParams[ParameterKeyLabels] = groupLabel
+	}
+	return memberParams
+}
+
+// volumeGroupSnapshotHash returns the hash identifying the volume group snapshot with the given
+// name, which is a valid label value.
+func volumeGroupSnapshotHash(name string) string {
+	hash := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(hash[:])[:20]
+}
+
+// volumeGroupSnapshotMemberName returns the name of the backup of a member volume of a volume
+// group snapshot, which is a valid Filestore backup name.
+func volumeGroupSnapshotMemberName(groupHash, volumeID string) string {
+	hash := sha256.Sum256([]byte(volumeID))
+	return fmt.Sprintf("%s-%s-%s", volumeGroupSnapshotPrefix, groupHash, hex.EncodeToString(hash[:])[:8])
+}
+
+// volumeGroupSnapshotID returns the ID of the volume group snapshot of a member backup, if any.
+func volumeGroupSnapshotID(backup *file.Backup) string {
+	groupHash := backup.Backup.Labels[tagKeyVolumeGroupSnapshot]
+	if groupHash == "" {
+		return ""
+	}
+	tokens := strings.Split(backup.Backup.Name, "/")
+	if len(tokens) != 6 {
+		return ""
+	}
+	return fmt.Sprintf(volumeGroupSnapshotIDFmt, tokens[1], tokens[3], groupHash)
+}
+
+// parseVolumeGroupSnapshotID returns the project, region and hash of a volume group snapshot.
+func parseVolumeGroupSnapshotID(id string) (string, string, string, error) {
+	tokens := strings.Split(id, "/")
+	if len(tokens) != 6 || tokens[0] != "projects" || tokens[2] != "locations" || tokens[4] != "volumeGroupSnapshots" {
+		return "", "", "", fmt.Errorf("invalid volume group snapshot ID %q", id)
+	}
+	return tokens[1], tokens[3], tokens[5], nil
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	filev1beta1 "google.golang.org/api/file/v1beta1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// groupBackupFileService serializes the backup calls of the member backups of a group, which
+// the fake file service does not support concurrently, and fails the backups of failInstance.
+type groupBackupFileService struct {
+	file.Service
+	mu           sync.Mutex
+	failInstance string
+}
+
+func (s *groupBackupFileService) StartCreateBackupOp(ctx context.Context, backupInfo *file.BackupInfo) (*filev1beta1.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if backupInfo.SourceInstanceName == s.failInstance {
+		return nil, fmt.Errorf("backup of instance %s failed", s.failInstance)
+	}
+	return s.Service.StartCreateBackupOp(ctx, backupInfo)
+}
+
+func (s *groupBackupFileService) GetBackup(ctx context.Context, backupURI string) (*file.Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Service.GetBackup(ctx, backupURI)
+}
+
+func (s *groupBackupFileService) DeleteBackup(ctx context.Context, backupURI string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Service.DeleteBackup(ctx, backupURI)
+}
+
+func (s *groupBackupFileService) ListBackups(ctx context.Context, filter *file.ListFilter) ([]*file.Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Service.ListBackups(ctx, filter)
+}
+
+func initTestGroupController(t *testing.T, failInstance string) (*groupControllerServer, file.Service) {
+	fs, err := file.NewFakeService()
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	cloudProvider, err := cloud.NewFakeCloud()
+	if err != nil {
+		t.Fatalf("Failed to get cloud provider: %v", err)
+	}
+	cs := newControllerServer(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: &groupBackupFileService{Service: fs, failInstance: failInstance},
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		tagManager:  cloud.NewFakeTagManagerForSanityTests(),
+	}).(*controllerServer)
+	return newGroupControllerServer(cs).(*groupControllerServer), fs
+}
+
+func TestCreateVolumeGroupSnapshot(t *testing.T) {
+	volumeA := "modeInstance/us-central1-c/instance-a/vol1"
+	volumeB := "modeInstance/us-central1-b/instance-b/vol1"
+	backupParams := map[string]string{util.VolumeSnapshotTypeKey: util.VolumeSnapshotTypeBackup}
+	cases := []struct {
+		name         string
+		volumes      []string
+		params       map[string]string
+		failInstance string
+		expectCode   codes.Code
+	}{
+		{
+			name:    "backs up all the volumes",
+			volumes: []string{volumeA, volumeB},
+			params:  map[string]string{util.VolumeSnapshotTypeKey: util.VolumeSnapshotTypeBackup, ParameterKeyLabels: "team=storage"},
+		},
+		{
+			name:    "no parameters",
+			volumes: []string{volumeA, volumeB},
+		},
+		{
+			name:       "no volumes",
+			params:     backupParams,
+			expectCode: codes.InvalidArgument,
+		},
+		{
+			name:       "duplicate volumes",
+			volumes:    []string{volumeA, volumeA},
+			params:     backupParams,
+			expectCode: codes.InvalidArgument,
+		},
+		{
+			name:       "instance snapshots",
+			volumes:    []string{volumeA, volumeB},
+			params:     map[string]string{util.VolumeSnapshotTypeKey: util.VolumeSnapshotTypeSnapshot},
+			expectCode: codes.InvalidArgument,
+		},
+		{
+			name:       "volumes in different regions",
+			volumes:    []string{volumeA, "modeInstance/us-east1-b/instance-c/vol1"},
+			params:     backupParams,
+			expectCode: codes.InvalidArgument,
+		},
+		{
+			name:         "failed member backup",
+			volumes:      []string{volumeA, volumeB},
+			params:       backupParams,
+			failInstance: "instance-b",
+			expectCode:   codes.Internal,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gcs, fs := initTestGroupController(t, tc.failInstance)
+			req := &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "groupsnapshot-1",
+				SourceVolumeIds: tc.volumes,
+				Parameters:      tc.params,
+			}
+			resp, err := gcs.CreateVolumeGroupSnapshot(context.TODO(), req)
+			if tc.expectCode != codes.OK {
+				if status.Code(err) != tc.expectCode {
+					t.Fatalf("expected error code %v, got %v", tc.expectCode, err)
+				}
+				// The backups of a failed group are deleted.
+				backups, _ := fs.ListBackups(context.TODO(), &file.ListFilter{Project: testProject, Location: testRegion})
+				if len(backups) != 0 {
+					t.Errorf("expected no backups left, got %d", len(backups))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			group := resp.GetGroupSnapshot()
+			expectedID := fmt.Sprintf("projects/%s/locations/%s/volumeGroupSnapshots/%s", testProject, testRegion, volumeGroupSnapshotHash(req.Name))
+			if group.GroupSnapshotId != expectedID {
+				t.Errorf("got group snapshot ID %q, expected %q", group.GroupSnapshotId, expectedID)
+			}
+			if !group.ReadyToUse || group.CreationTime == nil {
+				t.Errorf("unexpected group snapshot %+v", group)
+			}
+			var sources []string
+			for _, snapshot := range group.Snapshots {
+				sources = append(sources, snapshot.SourceVolumeId)
+				if snapshot.GroupSnapshotId != expectedID {
+					t.Errorf("got snapshot group ID %q, expected %q", snapshot.GroupSnapshotId, expectedID)
+				}
+				backup, err := fs.GetBackup(context.TODO(), snapshot.SnapshotId)
+				if err != nil {
+					t.Fatalf("failed to get backup %s: %v", snapshot.SnapshotId, err)
+				}
+				if got := backup.Backup.Labels[tagKeyVolumeGroupSnapshot]; got != volumeGroupSnapshotHash(req.Name) {
+					t.Errorf("got group label %q on backup %s", got, snapshot.SnapshotId)
+				}
+				if tc.params[ParameterKeyLabels] != "" && backup.Backup.Labels["team"] != "storage" {
+					t.Errorf("missing user labels on backup %s: %v", snapshot.SnapshotId, backup.Backup.Labels)
+				}
+			}
+			expectedSources := append([]string{}, tc.volumes...)
+			sort.Strings(sources)
+			sort.Strings(expectedSources)
+			if fmt.Sprint(sources) != fmt.Sprint(expectedSources) {
+				t.Errorf("got source volumes %v, expected %v", sources, expectedSources)
+			}
+
+			// Creating the group again returns the same backups.
+			again, err := gcs.CreateVolumeGroupSnapshot(context.TODO(), req)
+			if err != nil {
+				t.Fatalf("unexpected error creating the group again: %v", err)
+			}
+			if len(again.GetGroupSnapshot().Snapshots) != len(group.Snapshots) {
+				t.Errorf("got %d snapshots creating the group again, expected %d", len(again.GetGroupSnapshot().Snapshots), len(group.Snapshots))
+			}
+
+			// A group with the same name and other volumes is incompatible.
+			other := &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            req.Name,
+				SourceVolumeIds: []string{volumeA},
+				Parameters:      tc.params,
+			}
+			if _, err := gcs.CreateVolumeGroupSnapshot(context.TODO(), other); status.Code(err) != codes.AlreadyExists {
+				t.Errorf("expected error code %v, got %v", codes.AlreadyExists, err)
+			}
+		})
+	}
+}
+
+func TestGetAndDeleteVolumeGroupSnapshot(t *testing.T) {
+	gcs, fs := initTestGroupController(t, "")
+	resp, err := gcs.CreateVolumeGroupSnapshot(context.TODO(), &csi.CreateVolumeGroupSnapshotRequest{
+		Name: "groupsnapshot-1",
+		SourceVolumeIds: []string{
+			"modeInstance/us-central1-c/instance-a/vol1",
+			"modeInstance/us-central1-c/instance-b/vol1",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	groupID := resp.GetGroupSnapshot().GroupSnapshotId
+	var snapshotIDs []string
+	for _, snapshot := range resp.GetGroupSnapshot().Snapshots {
+		snapshotIDs = append(snapshotIDs, snapshot.SnapshotId)
+	}
+
+	// Another group is not listed as a member.
+	if _, err := gcs.CreateVolumeGroupSnapshot(context.TODO(), &csi.CreateVolumeGroupSnapshotRequest{
+		Name:            "groupsnapshot-2",
+		SourceVolumeIds: []string{"modeInstance/us-central1-c/instance-a/vol1"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get, err := gcs.GetVolumeGroupSnapshot(context.TODO(), &csi.GetVolumeGroupSnapshotRequest{GroupSnapshotId: groupID, SnapshotIds: snapshotIDs})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(get.GetGroupSnapshot().Snapshots) != 2 || !get.GetGroupSnapshot().ReadyToUse {
+		t.Errorf("unexpected group snapshot %+v", get.GetGroupSnapshot())
+	}
+	for _, snapshot := range get.GetGroupSnapshot().Snapshots {
+		if snapshot.GroupSnapshotId != groupID {
+			t.Errorf("got snapshot group ID %q, expected %q", snapshot.GroupSnapshotId, groupID)
+		}
+	}
+
+	// A creating member makes the group not ready.
+	backup, err := fs.GetBackup(context.TODO(), snapshotIDs[0])
+	if err != nil {
+		t.Fatalf("failed to get backup: %v", err)
+	}
+	backup.Backup.State = "CREATING"
+	get, err = gcs.GetVolumeGroupSnapshot(context.TODO(), &csi.GetVolumeGroupSnapshotRequest{GroupSnapshotId: groupID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if get.GetGroupSnapshot().ReadyToUse {
+		t.Errorf("expected group snapshot not ready to use")
+	}
+	backup.Backup.State = "READY"
+
+	if _, err := gcs.GetVolumeGroupSnapshot(context.TODO(), &csi.GetVolumeGroupSnapshotRequest{GroupSnapshotId: groupID, SnapshotIds: snapshotIDs[:1]}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected error code %v for missing snapshot IDs, got %v", codes.InvalidArgument, err)
+	}
+	if _, err := gcs.DeleteVolumeGroupSnapshot(context.TODO(), &csi.DeleteVolumeGroupSnapshotRequest{GroupSnapshotId: groupID, SnapshotIds: snapshotIDs[:1]}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected error code %v for missing snapshot IDs, got %v", codes.InvalidArgument, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := gcs.DeleteVolumeGroupSnapshot(context.TODO(), &csi.DeleteVolumeGroupSnapshotRequest{GroupSnapshotId: groupID, SnapshotIds: snapshotIDs}); err != nil {
+			t.Fatalf("delete %d: unexpected error: %v", i, err)
+		}
+	}
+	for _, id := range snapshotIDs {
+		if _, err := fs.GetBackup(context.TODO(), id); !file.IsNotFoundErr(err) {
+			t.Errorf("expected backup %s to be deleted, got %v", id, err)
+		}
+	}
+	if _, err := gcs.GetVolumeGroupSnapshot(context.TODO(), &csi.GetVolumeGroupSnapshotRequest{GroupSnapshotId: groupID}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected error code %v, got %v", codes.NotFound, err)
+	}
+	backups, _ := fs.ListBackups(context.TODO(), &file.ListFilter{Project: testProject, Location: testRegion})
+	if len(backups) != 1 {
+		t.Errorf("expected the backup of the other group to be kept, got %d backups", len(backups))
+	}
+}
@@ -38,7 +38,7 @@ func (s *identityServer) GetPluginInfo(ctx context.Context, req *csi.GetPluginIn
 }
 
 func (s *identityServer) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
-	return &csi.GetPluginCapabilitiesResponse{
+	resp := &csi.GetPluginCapabilitiesResponse{
 		Capabilities: []*csi.PluginCapability{
 			{
 				Type: &csi.PluginCapability_Service_{
@@ -69,7 +69,17 @@ func (s *identityServer) GetPluginCapabilities(ctx context.Context, req *csi.Get
 				},
 			},
 		},
-	}, nil
+	}
+	if s.driver.gcs != nil {
+		resp.Capabilities = append(resp.Capabilities, &csi.PluginCapability{
+			Type: &csi.PluginCapability_Service_{
+				Service: &csi.PluginCapability_Service{
+					Type: csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE,
+				},
+			},
+		})
+	}
+	return resp, nil
 }
 
 func (s *identityServer) Probe(ctx context.Context, req *csi.ProbeRequest) (*csi.ProbeResponse, error) {
@@ -122,3 +122,20 @@ func TestProbe(t *testing.T) {
 		t.Fatalf("Probe resp is nil")
 	}
 }
+
+func TestGetPluginCapabilitiesGroupController(t *testing.T) {
+	driver := initTestDriver(t)
+	driver.gcs = &groupControllerServer{}
+	s := newIdentityServer(driver)
+
+	resp, err := s.GetPluginCapabilities(context.TODO(), nil)
+	if err != nil {
+		t.Fatalf("GetPluginCapabilities failed: %v", err)
+	}
+	if len(resp.Capabilities) != 5 {
+		t.Fatalf("returned %v capabilities", len(resp.Capabilities))
+	}
+	if serviceType := resp.Capabilities[4].GetService().GetType(); serviceType != csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE {
+		t.Fatalf("returned %v capability service", serviceType)
+	}
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	filev1beta1 "google.golang.org/api/file/v1beta1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// Instance snapshots are in-place, point-in-time copies of a single share instance.
+// They are only supported by the zonal, regional and enterprise tiers, always live in
+// the location of their instance and are addressed by the Filestore snapshot URI:
+// projects/{project}/locations/{location}/instances/{instance}/snapshots/{name}
+//
+// A volume is restored from an instance snapshot by creating an empty instance in the
+// location of the snapshot, and restoring its file share from the snapshot once it is ready.
+
+func isInstanceSnapshotTierSupported(tier string) bool {
+	switch strings.ToLower(tier) {
+	case zonalTier, regionalTier, enterpriseTier:
+		return true
+	}
+	return false
+}
+
+// createInstanceSnapshot creates an in-place snapshot of the source instance. The caller
+// holds the volume lock of the source volume.
+func (s *controllerServer) createInstanceSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest, backupInfo *file.BackupInfo) (*csi.CreateSnapshotResponse, error) {
+	volumeID := req.GetSourceVolumeId()
+	if util.GetBackupLocation(req.GetParameters()) != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "parameter %q is not supported for volume snapshot type %q, instance snapshots are stored with their instance", util.VolumeSnapshotLocationKey, util.VolumeSnapshotTypeSnapshot)
+	}
+	if util.GetBackupCopyLocation(req.GetParameters()) != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "parameter %q is not supported for volume snapshot type %q, instance snapshots are stored with their instance", util.VolumeSnapshotCopyLocationKey, util.VolumeSnapshotTypeSnapshot)
+	}
+
+	filer, err := s.config.fileService.GetInstance(ctx, &file.ServiceInstance{
+		Project:  backupInfo.Project,
+		Location: backupInfo.Location,
+		Name:     backupInfo.SourceInstanceName,
+	})
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil, status.Errorf(codes.NotFound, "source volume %v not found", volumeID)
+		}
+		return nil, file.StatusError(err)
+	}
+	if !isInstanceSnapshotTierSupported(filer.Tier) {
+		return nil, status.Errorf(codes.InvalidArgument, "volume snapshot type %q is not supported for tier %q", util.VolumeSnapshotTypeSnapshot, filer.Tier)
+	}
+
+	snapshotURI := file.CreateInstanceSnapshotURI(backupInfo.Project, backupInfo.Location, backupInfo.SourceInstanceName, req.GetName())
+	existingSnapshot, err := s.config.fileService.GetInstanceSnapshot(ctx, snapshotURI)
+	if err != nil && !file.IsNotFoundErr(err) {
+		return nil, file.StatusError(err)
+	}
+
+	snapshotObj := existingSnapshot
+	if snapshotObj != nil {
+		// A snapshot in the process of getting created is returned as not ready to use, like a
+		// backup, and the csi-snapshotter calls CreateSnapshot again until it is ready.
+		if snapshotObj.State != "READY" && snapshotObj.State != "CREATING" {
+			return nil, status.Errorf(codes.Internal, "Snapshot %v not yet ready, current state %s", snapshotObj.Name, snapshotObj.State)
+		}
+	} else {
+		labels, err := extractBackupLabels(req.GetParameters(), s.config.extraVolumeLabels, s.config.driver.config.Name, req.GetName())
+		if err != nil {
+			return nil, err
+		}
+		snapshotObj, err = s.config.fileService.CreateInstanceSnapshot(ctx, &file.SnapshotInfo{
+			Name:               req.GetName(),
+			SourceVolumeId:     volumeID,
+			SnapshotURI:        snapshotURI,
+			SourceInstanceName: backupInfo.SourceInstanceName,
+			Project:            backupInfo.Project,
+			Location:           backupInfo.Location,
+			Labels:             labels,
+		})
+		if err != nil {
+			klog.Errorf("Create snapshot for volume Id %s failed: %v", volumeID, err.Error())
+			return nil, file.StatusError(err)
+		}
+	}
+
+	snapshot, err := instanceSnapshotToCSISnapshot(snapshotObj, volumeID)
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+	klog.V(4).Infof("CreateSnapshot succeeded for volume %v, Snapshot Id: %v", volumeID, snapshotObj.Name)
+	return &csi.CreateSnapshotResponse{Snapshot: snapshot}, nil
+}
+
+func (s *controllerServer) deleteInstanceSnapshot(ctx context.Context, id string) (*csi.DeleteSnapshotResponse, error) {
+	snapshot, err := s.config.fileService.GetInstanceSnapshot(ctx, id)
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			klog.Infof("Volume snapshot with ID %v not found", id)
+			return &csi.DeleteSnapshotResponse{}, nil
+		}
+		return nil, file.StatusError(err)
+	}
+
+	if snapshot.State == "DELETING" {
+		return nil, status.Errorf(codes.DeadlineExceeded, "Volume snapshot with ID %v is in state %s", id, snapshot.State)
+	}
+
+	if err = s.config.fileService.DeleteInstanceSnapshot(ctx, id); err != nil {
+		klog.Errorf("Delete snapshot for snapshot Id %s failed: %v", id, err.Error())
+		return nil, file.StatusError(err)
+	}
+
+	return &csi.DeleteSnapshotResponse{}, nil
+}
+
+// getInstanceSnapshot looks up an instance snapshot by its snapshot ID. The share name of the
+// source volume ID is not part of the snapshot URI, so it is read from the source instance.
+func (s *controllerServer) getInstanceSnapshot(ctx context.Context, id string) (*csi.Snapshot, error) {
+	project, location, instanceName, _, err := util.ParseInstanceSnapshotURI(id)
+	if err != nil {
+		return nil, err
+	}
+	snapshotObj, err := s.config.fileService.GetInstanceSnapshot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	filer, err := s.config.fileService.GetInstance(ctx, &file.ServiceInstance{
+		Project:  project,
+		Location: location,
+		Name:     instanceName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instanceSnapshotToCSISnapshot(snapshotObj, getVolumeIDFromFileInstance(filer, modeInstance, s.config.cloud.Project))
+}
+
+// listDriverInstanceSnapshots returns a CSI snapshot for every instance snapshot carrying the
+// given created-by label. Instance snapshots are listed per instance, so only the instances
+// created by the driver in a tier supporting snapshots are looked at.
+func (s *controllerServer) listDriverInstanceSnapshots(ctx context.Context, createdBy string) ([]*csi.Snapshot, error) {
+	project := s.config.cloud.Project
+	instances, err := s.config.fileService.ListInstances(ctx, &file.ServiceInstance{Project: project})
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+
+	var snapshots []*csi.Snapshot
+	for _, instance := range instances {
+		if instance.Labels[tagKeyCreatedBy] != createdBy || !isInstanceSnapshotTierSupported(instance.Tier) {
+			continue
+		}
+		instanceSnapshots, err := s.config.fileService.ListInstanceSnapshots(ctx, instance)
+		if err != nil {
+			if file.IsNotFoundErr(err) {
+				// The instance was deleted since it was listed.
+				continue
+			}
+			return nil, file.StatusError(err)
+		}
+		volumeID := getVolumeIDFromFileInstance(instance, modeInstance, project)
+		for _, snapshotObj := range instanceSnapshots {
+			if snapshotObj.Labels[tagKeyCreatedBy] != createdBy {
+				continue
+			}
+			snapshot, err := instanceSnapshotToCSISnapshot(snapshotObj, volumeID)
+			if err != nil {
+				return nil, file.StatusError(err)
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+func instanceSnapshotToCSISnapshot(snapshot *filev1beta1.Snapshot, sourceVolumeID string) (*csi.Snapshot, error) {
+	tp, err := util.ParseTimestamp(snapshot.CreateTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse create timestamp for snapshot %v: %w", snapshot.Name, err)
+	}
+	return &csi.Snapshot{
+		SizeBytes:      snapshot.FilesystemUsedBytes,
+		SnapshotId:     snapshot.Name,
+		SourceVolumeId: sourceVolumeID,
+		CreationTime:   tp,
+		ReadyToUse:     snapshot.State == "READY",
+	}, nil
+}
+
+// validateInstanceSnapshotSource checks up front that a volume can be restored from the instance
+// snapshot, and returns the candidate locations narrowed to the location of the snapshot. An
+// instance snapshot can only be restored to an instance of the project, location and tier of
+// its source instance, with at least the capacity and the file system protocol of the source.
+func (s *controllerServer) validateInstanceSnapshotSource(ctx context.Context, id string, newFiler *file.ServiceInstance, locations []string) ([]string, error) {
+	project, location, instanceName, _, err := util.ParseInstanceSnapshotURI(id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Unsupported volume content source %v", id)
+	}
+	if project != newFiler.Project {
+		return nil, status.Errorf(codes.InvalidArgument, "instance snapshot %v of project %v cannot be restored to a volume of project %v", id, project, newFiler.Project)
+	}
+	if !slices.Contains(locations, location) {
+		return nil, status.Errorf(codes.InvalidArgument, "instance snapshot %v can only be restored in location %v, which is not allowed by the accessibility requirements, candidate locations: %v", id, location, locations)
+	}
+
+	snapshotObj, err := s.config.fileService.GetInstanceSnapshot(ctx, id)
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil, status.Errorf(codes.NotFound, "source snapshot %v not found", id)
+		}
+		return nil, file.StatusError(err)
+	}
+	if snapshotObj.State != "READY" {
+		return nil, status.Errorf(codes.Unavailable, "source snapshot %v not ready, current state %s", id, snapshotObj.State)
+	}
+
+	source, err := s.config.fileService.GetInstance(ctx, &file.ServiceInstance{
+		Project:  project,
+		Location: location,
+		Name:     instanceName,
+	})
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil, status.Errorf(codes.NotFound, "source instance %v of snapshot %v not found", instanceName, id)
+		}
+		return nil, file.StatusError(err)
+	}
+	if !strings.EqualFold(source.Tier, newFiler.Tier) {
+		return nil, status.Errorf(codes.InvalidArgument, "instance snapshot %v of a %s tier instance cannot be restored to a %s tier instance", id, source.Tier, newFiler.Tier)
+	}
+	if newFiler.Volume.SizeBytes < source.Volume.SizeBytes {
+		return nil, status.Errorf(codes.OutOfRange, "requested capacity %d bytes is smaller than the %d bytes capacity of the source instance of snapshot %s", newFiler.Volume.SizeBytes, source.Volume.SizeBytes, id)
+	}
+	sourceProtocol, protocol := source.Protocol, newFiler.Protocol
+	if sourceProtocol == "" {
+		sourceProtocol = v3FileProtocol
+	}
+	if protocol == "" {
+		protocol = v3FileProtocol
+	}
+	if !strings.EqualFold(sourceProtocol, protocol) {
+		return nil, status.Errorf(codes.InvalidArgument, "instance snapshot %v of an %s instance cannot be restored to an %s instance, set the %q parameter to %q", id, sourceProtocol, protocol, paramFileProtocol, sourceProtocol)
+	}
+	return []string{location}, nil
+}
+
+// restoreInstanceSnapshot restores the file share of the instance backing the named volume from
+// the instance snapshot. Like the create operation, the restore operation is polled in the
+// background and looked up from the instance operations after a controller restart, so that
+// it is started only once. The instance is not READY while it is restored, retries of the
+// request wait for it in waitForInstanceCreation.
+func (s *controllerServer) restoreInstanceSnapshot(ctx context.Context, name string, filer *file.ServiceInstance, snapshotID string) error {
+	key := restoreOpKey(name)
+	if _, tracked := s.config.createOps.opName(key); !tracked {
+		ops, err := s.config.fileService.ListInstanceOps(ctx, filer, util.OpVerbRestore)
+		if err != nil {
+			return file.StatusError(err)
+		}
+		op := latestInstanceOp(ops)
+		if op != nil && op.Done {
+			opErr := file.OperationError(op)
+			if opErr == nil {
+				return nil
+			}
+			klog.Warningf("Restore operation %v of volume %v from snapshot %v failed, restoring again: %v", op.Name, name, snapshotID, opErr)
+			op = nil
+		}
+		if op == nil {
+			klog.V(4).Infof("Restoring volume %v from snapshot %v", name, snapshotID)
+			op, err = s.config.fileService.StartRestoreInstanceOp(ctx, filer, snapshotID)
+			if err != nil {
+				klog.Errorf("Restore of volume %v from snapshot %v failed: %v", name, snapshotID, err)
+				return file.StatusError(err)
+			}
+		}
+		s.config.createOps.track(key, op.Name)
+	}
+
+	opName, _ := s.config.createOps.opName(key)
+	done, opErr := s.config.createOps.wait(ctx, key, createVolumeOpWaitTimeout)
+	if !done {
+		msg := fmt.Sprintf("Volume %v not ready, restore operation %v is still running", name, opName)
+		klog.V(4).Info(msg)
+		return status.Error(codes.DeadlineExceeded, msg)
+	}
+	if opErr != nil {
+		klog.Errorf("Restore operation %v of volume %v from snapshot %v failed: %v", opName, name, snapshotID, opErr)
+		return file.StatusError(opErr)
+	}
+	return nil
+}
+
+// restoreOpKey is the operation tracker key of the restore operation of the named volume.
+func restoreOpKey(name string) string {
+	return "restore/" + name
+}
@@ -39,6 +39,7 @@ import (
 	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
 	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/common"
 	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/validation"
 )
 
 const (
@@ -47,6 +48,7 @@ const (
 	methodCreateVolume              = "CreateVolume"
 	methodDeleteVolume              = "DeleteVolume"
 	methodExpandVolume              = "ExpandVolume"
+	methodModifyVolume              = "ModifyVolume"
 	methodCreateSnapshot            = "CreateSnapshot"
 	methodDeleteSnapshot            = "DeleteSnapshot"
 	ecfsDataPlaneVersionFormat      = "GoogleReserved-CustomVMImage=clh.image.ems.path:projects/%s/global/images/ems-filestore-scaleout-%s"
@@ -71,6 +73,8 @@ type MultishareController struct {
 	featureNFSExportOptionsOnCreate bool
 	extraVolumeLabels               map[string]string
 	tagManager                      cloud.TagService
+	janitor                         *multishareJanitor
+	warmPool                        *multishareWarmPool
 
 	// Filestore instance description overrides
 	descOverrideMaxSharesPerInstance string
@@ -112,11 +116,31 @@ func NewMultishareController(config *controllerServerConfig) *MultishareControll
 	if config.features != nil && config.features.FeatureNFSExportOptionsOnCreate != nil {
 		c.featureNFSExportOptionsOnCreate = config.features.FeatureNFSExportOptionsOnCreate.Enabled
 	}
+	if config.features != nil && config.features.FeatureMultishareJanitor != nil && config.features.FeatureMultishareJanitor.Enabled {
+		// The stateful reconciler tracks the shares assigned to instances before they are created, and deletes empty instances itself.
+		if config.features.FeatureStateful != nil && config.features.FeatureStateful.Enabled {
+			klog.Warning("Multishare janitor is not supported with the stateful multishare controller, skipping it")
+		} else {
+			c.janitor = newMultishareJanitor(config, c.opsManager)
+		}
+	}
+	if config.features != nil && config.features.FeatureMultishareWarmPool != nil && config.features.FeatureMultishareWarmPool.Enabled {
+		// The stateful reconciler keeps the warm pools itself.
+		if config.features.FeatureStateful == nil || !config.features.FeatureStateful.Enabled {
+			c.warmPool = newMultishareWarmPool(config, c)
+		}
+	}
 
 	return c
 }
 
 func (m *MultishareController) Run(stopCh <-chan struct{}) {
+	if m.janitor != nil {
+		go m.janitor.Run(stopCh)
+	}
+	if m.warmPool != nil {
+		go m.warmPool.Run(stopCh)
+	}
 	if !m.featureMaxSharePerInstance {
 		return
 	}
@@ -139,11 +163,6 @@ func (m *MultishareController) CreateVolume(ctx context.Context, req *csi.Create
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	sourceSnapshotId, err := m.checkVolumeContentSource(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-
 	instanceScPrefix, err := getInstanceSCLabel(req)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
@@ -153,6 +172,9 @@ func (m *MultishareController) CreateVolume(ctx context.Context, req *csi.Create
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	if _, ok := req.GetMutableParameters()[ParamMutableNfsExportOptions]; ok && !m.featureNFSExportOptionsOnCreate {
+		return nil, status.Error(codes.InvalidArgument, "nfsExportOptions are disabled")
+	}
 
 	var reqBytes int64
 	if m.featureMaxSharePerInstance {
@@ -189,6 +211,15 @@ func (m *MultishareController) CreateVolume(ctx context.Context, req *csi.Create
 		instance.Description = fmt.Sprintf(ecfsCustom100sharesConfigFormat, sharesPerInstance, minShareSizeGB)
 	}
 
+	sourceBackup, err := m.checkVolumeContentSource(ctx, req, instance, reqBytes)
+	if err != nil {
+		return nil, err
+	}
+	var sourceSnapshotId string
+	if sourceBackup != nil {
+		sourceSnapshotId = sourceBackup.Backup.Name
+	}
+
 	workflow, share, err := m.opsManager.setupEligibleInstanceAndStartWorkflow(ctx, req, instance, sourceSnapshotId)
 	if err != nil {
 		return nil, file.StatusError(err)
@@ -254,6 +285,9 @@ func (m *MultishareController) CreateSnapshot(ctx context.Context, req *csi.Crea
 			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
 	}
+	if util.GetSnapshotType(req.GetParameters()) == util.VolumeSnapshotTypeSnapshot {
+		return nil, status.Errorf(codes.InvalidArgument, "volume snapshot type %q not supported for multishare backed volumes", util.VolumeSnapshotTypeSnapshot)
+	}
 	_, location, instanceName, shareName, err := parseSourceVolId(volumeID)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
@@ -267,6 +301,20 @@ func (m *MultishareController) CreateSnapshot(ctx context.Context, req *csi.Crea
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	backupInfo := &file.BackupInfo{
+		Name:               name,
+		SourceVolumeId:     volumeID,
+		Project:            project,
+		Location:           backupRegion,
+		SourceShare:        shareName,
+		SourceInstanceName: instanceName,
+		BackupURI:          backupURI,
+	}
+	copyLocation := util.GetBackupCopyLocation(req.GetParameters())
+	if err := validateBackupCopyLocation(backupInfo, copyLocation); err != nil {
+		return nil, err
+	}
+
 	existingBackup, err := m.cloud.File.GetBackup(ctx, backupURI)
 	backupExists, err := file.CheckBackupExists(existingBackup, err)
 	if err != nil {
@@ -274,6 +322,9 @@ func (m *MultishareController) CreateSnapshot(ctx context.Context, req *csi.Crea
 	}
 
 	var snapshotResponse *csi.CreateSnapshotResponse
+	if backupExists && existingBackup.Backup.State == "FAILED" {
+		return nil, deleteFailedBackup(ctx, m.cloud.File, backupInfo)
+	}
 	if backupExists {
 		// process existing backup
 
@@ -284,25 +335,31 @@ func (m *MultishareController) CreateSnapshot(ctx context.Context, req *csi.Crea
 		snapshotResponse = &csi.CreateSnapshotResponse{
 			Snapshot: snapshot,
 		}
+		backupInfo.Labels = existingBackup.Backup.Labels
 	} else {
 		//no existing backup
-		backupInfo := &file.BackupInfo{
-			Name:               name,
-			SourceVolumeId:     volumeID,
-			Project:            project,
-			Location:           backupRegion,
-			SourceShare:        shareName,
-			SourceInstanceName: instanceName,
-			BackupURI:          backupURI,
-		}
-
 		labels, err := extractBackupLabels(req.GetParameters(), m.extraVolumeLabels, m.driver.config.Name, req.Name)
 		if err != nil {
 			return nil, err
 		}
+		if copyLocation != "" {
+			labels[tagKeyBackupCopyLocation] = copyLocation
+		}
+		// The storage class prefix is part of the source volume ID reported by ListSnapshots,
+		// record it since the source instance is deleted once it has no shares left.
+		instance, err := m.cloud.File.GetMultishareInstance(ctx, &file.MultishareInstance{
+			Project:  project,
+			Location: location,
+			Name:     instanceName,
+		})
+		if err != nil {
+			klog.Warningf("Failed to get source instance of volume %v, the backup %v is not labeled with its storage class: %v", volumeID, name, err)
+		} else if prefix := instance.Labels[util.ParamMultishareInstanceScLabelKey]; prefix != "" {
+			labels[util.ParamMultishareInstanceScLabelKey] = prefix
+		}
 		backupInfo.Labels = labels
 
-		snapshot, err := m.createNewBackup(ctx, backupInfo)
+		snapshot, err := startBackup(ctx, m.cloud.File, backupInfo, modeMultishare)
 		if err != nil {
 			return nil, err
 		}
@@ -312,33 +369,14 @@ func (m *MultishareController) CreateSnapshot(ctx context.Context, req *csi.Crea
 		}
 	}
 
-	if err := m.tagManager.AttachResourceTags(ctx, cloud.FilestoreBackUp, name, backupRegion, req.GetName(), req.GetParameters()); err != nil {
+	if err := m.tagManager.AttachResourceTags(ctx, cloud.FilestoreBackUp, m.cloud.Project, name, backupRegion, req.GetName(), req.GetParameters()); err != nil {
 		return nil, status.Error(codes.Unavailable, err.Error())
 	}
-
-	return snapshotResponse, nil
-}
-
-func (m *MultishareController) createNewBackup(ctx context.Context, backupInfo *file.BackupInfo) (*csi.Snapshot, error) {
-
-	backupObj, err := m.cloud.File.CreateBackup(ctx, backupInfo)
-	if err != nil {
-		klog.Errorf("Create snapshot for volume Id %s failed: %v", backupInfo.SourceVolumeId, err.Error())
-		return nil, file.StatusError(err)
-	}
-	tp, err := util.ParseTimestamp(backupObj.CreateTime)
-	if err != nil {
-		return nil, file.StatusError(err)
-	}
-	snapshot := &csi.Snapshot{
-		SizeBytes:      util.GbToBytes(backupObj.CapacityGb),
-		SnapshotId:     backupObj.Name,
-		SourceVolumeId: backupInfo.SourceVolumeId,
-		CreationTime:   tp,
-		ReadyToUse:     true,
+	if err := completeBackupCopy(ctx, m.cloud.File, m.tagManager, backupInfo, snapshotResponse.Snapshot, req); err != nil {
+		return nil, err
 	}
 
-	return snapshot, nil
+	return snapshotResponse, nil
 }
 
 func (m *MultishareController) getShareAndGenerateCSICreateVolumeResponse(ctx context.Context, instancePrefix string, s *file.Share, maxShareSizeSizeBytes int64) (*csi.CreateVolumeResponse, error) {
@@ -526,6 +564,73 @@ func (m *MultishareController) ControllerExpandVolume(ctx context.Context, req *
 	return resp, file.StatusError(err)
 }
 
+// ControllerModifyVolume updates the mutable parameters of a multishare volume. Only the NFS
+// export options of the share can be modified.
+func (m *MultishareController) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
+	volumeId := req.GetVolumeId()
+	if len(volumeId) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "ControllerModifyVolume volume ID must be provided")
+	}
+
+	params := req.GetMutableParameters()
+	for k := range params {
+		if k != ParamMutableNfsExportOptions {
+			return nil, status.Errorf(codes.InvalidArgument, "mutable parameter %q is not supported for multishare volumes", k)
+		}
+	}
+	nfsExportOptions, err := parseMutableNfsExportOptions(params)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Validation failed: %v", err)
+	}
+	if nfsExportOptions == nil {
+		return &csi.ControllerModifyVolumeResponse{}, nil
+	}
+
+	_, project, location, instanceName, shareName, err := parseMultishareVolId(volumeId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	klog.Infof("ControllerModifyVolume called for multishare with request %+v", req)
+	if acquired := m.volumeLocks.TryAcquire(volumeId); !acquired {
+		return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, volumeId)
+	}
+	defer m.volumeLocks.Release(volumeId)
+
+	share, err := m.cloud.File.GetShare(ctx, &file.Share{
+		Parent: &file.MultishareInstance{
+			Project:  project,
+			Location: location,
+			Name:     instanceName,
+		},
+		Name: shareName,
+	})
+	if share == nil || file.IsNotFoundErr(err) {
+		return nil, status.Errorf(codes.NotFound, "Couldn't find share with name %q", volumeId)
+	}
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+
+	if equalNfsExportOptions(share.NfsExportOptions, nfsExportOptions) {
+		klog.Infof("Controller modify volume succeeded for volume %v, nfs export options already up to date", volumeId)
+		return &csi.ControllerModifyVolumeResponse{}, nil
+	}
+
+	share.NfsExportOptions = nfsExportOptions
+	workflow, err := m.opsManager.startShareNfsExportOptionsUpdateWorkflowSafe(ctx, share)
+	if err != nil {
+		return nil, file.StatusError(err)
+	}
+
+	err = m.waitOnWorkflow(ctx, workflow)
+	if err != nil {
+		return nil, file.StatusError(fmt.Errorf("wait on share update op %q failed with error: %w", workflow.opName, err))
+	}
+	klog.Infof("Wait for operation %s (type %s) completed", workflow.opName, workflow.opType.String())
+	return &csi.ControllerModifyVolumeResponse{}, nil
+}
+
 func (m *MultishareController) getShareAndGenerateCSIControllerExpandVolumeResponse(ctx context.Context, share *file.Share, reqBytes int64) (*csi.ControllerExpandVolumeResponse, error) {
 	share, err := m.cloud.File.GetShare(ctx, share)
 	if err != nil {
@@ -604,6 +709,14 @@ func (m *MultishareController) generateNewMultishareInstance(instanceName string
 			continue
 		case paramMaxVolumeSize:
 			continue
+		case ParamSharePlacementPolicy:
+			if _, err := getSharePlacementPolicy(req.GetParameters()); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+		case ParamWarmPoolSize:
+			if _, err := parseWarmPoolSize(req.GetParameters()); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
 		case cloud.ParameterKeyResourceTags:
 			continue
 		case ParameterKeyLabels, ParameterKeyPVCName, ParameterKeyPVCNamespace, ParameterKeyPVName, paramMultishare:
@@ -654,30 +767,40 @@ func (m *MultishareController) generateNewMultishareInstance(instanceName string
 	return f, nil
 }
 
-func (m *MultishareController) checkVolumeContentSource(ctx context.Context, req *csi.CreateVolumeRequest) (string, error) {
+// checkVolumeContentSource returns the backup to restore the volume from, if any, once it
+// checked that a share of reqBytes on the instance can be restored from it.
+func (m *MultishareController) checkVolumeContentSource(ctx context.Context, req *csi.CreateVolumeRequest, instance *file.MultishareInstance, reqBytes int64) (*file.Backup, error) {
 	if req.GetVolumeContentSource() != nil {
 		if !m.featureMultishareBackups {
-			return "", status.Error(codes.InvalidArgument, "Multishare backed volumes do not support volume content source")
+			return nil, status.Error(codes.InvalidArgument, "Multishare backed volumes do not support volume content source")
 		}
 		if req.GetVolumeContentSource().GetVolume() != nil {
-			return "", status.Error(codes.InvalidArgument, "Unsupported volume content source type \"volume\"")
+			return nil, status.Error(codes.InvalidArgument, "Unsupported volume content source type \"volume\"")
 		}
 
 		if req.GetVolumeContentSource().GetSnapshot() != nil {
 			id := req.GetVolumeContentSource().GetSnapshot().GetSnapshotId()
 			isBackupSource, err := util.IsBackupHandle(id)
 			if err != nil || !isBackupSource {
-				return "", status.Errorf(codes.InvalidArgument, "Unsupported volume content source %v", id)
+				return nil, status.Errorf(codes.InvalidArgument, "Unsupported volume content source %v", id)
 			}
-			_, err = m.cloud.File.GetBackup(ctx, id)
+			backup, err := m.cloud.File.GetBackup(ctx, id)
 			if err != nil {
 				klog.Errorf("Failed to get volume %v source snapshot %v: %v", req.GetName(), id, err.Error())
-				return "", file.StatusError(err)
+				return nil, file.StatusError(err)
 			}
-			return id, nil
+			if err := validateRestoreSource(backup, restoreTarget{
+				tier:          instance.Tier,
+				protocol:      instance.Protocol,
+				kmsKeyName:    instance.KmsKeyName,
+				capacityBytes: reqBytes,
+			}); err != nil {
+				return nil, err
+			}
+			return backup, nil
 		}
 	}
-	return "", nil
+	return nil, nil
 
 }
 
@@ -697,6 +820,15 @@ func generateNewShare(name string, parent *file.MultishareInstance, req *csi.Cre
 			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
 	}
+	if _, ok := req.GetMutableParameters()[ParamMutableNfsExportOptions]; ok {
+		if nfsExportOptions != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "cannot specify both %s and %s", ParamNfsExportOptions, ParamMutableNfsExportOptions)
+		}
+		nfsExportOptions, err = parseMutableNfsExportOptions(req.GetMutableParameters())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
 
 	share := &file.Share{
 		Name:             name,
@@ -917,41 +1049,11 @@ func (m *MultishareController) parseMaxVolumeSizeParam(params map[string]string)
 	if !ok {
 		return util.MaxSharesPerInstance, util.MaxShareSizeBytes, nil
 	}
-	if v == "" {
-		return 0, 0, fmt.Errorf("value is empty for %q key", paramMaxVolumeSize)
-	}
-	val, err := resource.ParseQuantity(v)
+	maxVolumeSizeBytes, sharesPerInstance, err := validation.DefaultMultishareLimits.ParseMaxVolumeSize(v)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, fmt.Errorf("invalid %q parameter: %w", paramMaxVolumeSize, err)
 	}
-
-	valBytes := val.Value()
-	sharesPerInstance, err := getSharesPerInstance(valBytes)
-	if err != nil {
-		return 0, 0, err
-	}
-	return sharesPerInstance, valBytes, nil
-}
-
-func getSharesPerInstance(volSizeBytes int64) (int, error) {
-	if !isValidMaxVolSize(volSizeBytes) {
-		return 0, fmt.Errorf("unsupported max volume size %d, supported sizes: '128Gi', '256Gi', '512Gi', '1024Gi'", volSizeBytes)
-	}
-	return int(util.MaxMultishareInstanceSizeBytes / volSizeBytes), nil
-}
-
-func isValidMaxVolSize(val int64) bool {
-	switch val {
-	case 128 * util.Gb:
-		return true
-	case 256 * util.Gb:
-		return true
-	case 512 * util.Gb:
-		return true
-	case util.Tb:
-		return true
-	}
-	return false
+	return sharesPerInstance, maxVolumeSizeBytes, nil
 }
 
 func (m *MultishareController) GetShareMaxSizeFromPV(ctx context.Context, volHandle string) (int64, error) {
@@ -28,6 +28,8 @@ import (
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/google/go-cmp/cmp"
 	filev1beta1multishare "google.golang.org/api/file/v1beta1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/testing/protocmp"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
@@ -1517,8 +1519,10 @@ func TestMultishareCreateVolume(t *testing.T) {
 
 func TestMultishareCreateVolumeFromBackup(t *testing.T) {
 	type BackupTestInfo struct {
-		backup *file.BackupInfo
-		state  string
+		backup     *file.BackupInfo
+		state      string
+		capacityGb int64
+		protocol   string
 	}
 	testVolName := "pvc-" + string(uuid.NewUUID())
 	testShareName := util.ConvertVolToShareName(testVolName)
@@ -1553,6 +1557,16 @@ func TestMultishareCreateVolumeFromBackup(t *testing.T) {
 			BackupURI:          "projects/test-project/locations/us-central1/backups/mybackup",
 			SourceVolumeId:     modeMultishare + "/" + testRegion + "/" + testInstanceName1 + "/" + testShareName,
 		},
+		capacityGb: 100,
+	}
+	largeBackup := &BackupTestInfo{
+		backup:     defaultBackup.backup,
+		capacityGb: 200,
+	}
+	v4_1Backup := &BackupTestInfo{
+		backup:     defaultBackup.backup,
+		capacityGb: 100,
+		protocol:   v4_1FileProtocol,
 	}
 	type OpItem struct {
 		id     string
@@ -1716,6 +1730,52 @@ func TestMultishareCreateVolumeFromBackup(t *testing.T) {
 			initialBackup:     defaultBackup,
 			checkOnlyVolidFmt: true,
 		},
+		{
+			name: "create volume called with volume content source smaller than the backup",
+			req: &csi.CreateVolumeRequest{
+				Name: testVolName,
+				CapacityRange: &csi.CapacityRange{
+					RequiredBytes: 100 * util.Gb,
+				},
+				Parameters: map[string]string{
+					ParamMultishareInstanceScLabel: testInstanceScPrefix,
+				},
+				VolumeCapabilities: volumeCapabilities,
+				VolumeContentSource: &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{
+							SnapshotId: "projects/test-project/locations/us-central1/backups/mybackup",
+						},
+					},
+				},
+			},
+			features:      features,
+			initialBackup: largeBackup,
+			errorExpected: true,
+		},
+		{
+			name: "create volume called with volume content source of another protocol",
+			req: &csi.CreateVolumeRequest{
+				Name: testVolName,
+				CapacityRange: &csi.CapacityRange{
+					RequiredBytes: 100 * util.Gb,
+				},
+				Parameters: map[string]string{
+					ParamMultishareInstanceScLabel: testInstanceScPrefix,
+				},
+				VolumeCapabilities: volumeCapabilities,
+				VolumeContentSource: &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{
+							SnapshotId: "projects/test-project/locations/us-central1/backups/mybackup",
+						},
+					},
+				},
+			},
+			features:      features,
+			initialBackup: v4_1Backup,
+			errorExpected: true,
+		},
 		{
 			name: "1 initial ready 1Tib instance with 0 shares, 1 busy instance,  create 100Gib share with content source in free instance, success response",
 			initInstances: []*file.MultishareInstance{
@@ -2033,6 +2093,10 @@ func TestMultishareCreateVolumeFromBackup(t *testing.T) {
 				if tc.initialBackup.state != "" {
 					existingBackup.State = tc.initialBackup.state
 				}
+				if tc.initialBackup.capacityGb != 0 {
+					existingBackup.CapacityGb = tc.initialBackup.capacityGb
+				}
+				existingBackup.FileSystemProtocol = tc.initialBackup.protocol
 			}
 			resp, err := mcs.CreateVolume(context.Background(), tc.req)
 			if tc.errorExpected && err == nil {
@@ -2675,72 +2739,160 @@ func TestMultishareControllerExpandVolume(t *testing.T) {
 	}
 }
 
-func TestIsValidMaxVolSize(t *testing.T) {
-	tests := []struct {
-		val         int64
-		expectedret bool
-	}{
-		{
-			val: 0,
-		},
-		{
-			val:         128 * util.Gb,
-			expectedret: true,
-		},
-		{
-			val:         256 * util.Gb,
-			expectedret: true,
-		},
-		{
-			val:         512 * util.Gb,
-			expectedret: true,
-		},
-		{
-			val:         1 * util.Tb,
-			expectedret: true,
-		},
-		{
-			val:         1024 * util.Gb,
-			expectedret: true,
-		},
+func TestMultishareControllerModifyVolume(t *testing.T) {
+	testVolName := "pvc-" + string(uuid.NewUUID())
+	testShareName := util.ConvertVolToShareName(testVolName)
+	testInstanceName1 := "fs-" + string(uuid.NewUUID())
+	testVolId := fmt.Sprintf("%s/%s/%s/%s/%s/%s", modeMultishare, testInstanceScPrefix, testProject, testRegion, testInstanceName1, testShareName)
+	testOptions := `[{"accessMode":"READ_WRITE","ipRanges":["10.0.0.0/24"],"squashMode":"NO_ROOT_SQUASH"}]`
+	parsedOptions := []*file.NfsExportOptions{
 		{
-			val:         131072 * util.Mb,
-			expectedret: true,
+			AccessMode: "READ_WRITE",
+			IpRanges:   []string{"10.0.0.0/24"},
+			SquashMode: "NO_ROOT_SQUASH",
 		},
-		// Negative test cases
+	}
+	newInstance := func() *file.MultishareInstance {
+		return &file.MultishareInstance{
+			Name:     testInstanceName1,
+			Location: testRegion,
+			Project:  testProject,
+			Labels: map[string]string{
+				util.ParamMultishareInstanceScLabelKey: testInstanceScPrefix,
+			},
+			CapacityBytes: 1 * util.Tb,
+			Tier:          "Enterprise",
+			Network: file.Network{
+				Ip: testIP,
+			},
+		}
+	}
+	newShare := func(options []*file.NfsExportOptions) *file.Share {
+		return &file.Share{
+			Name:             testShareName,
+			Parent:           newInstance(),
+			MountPointName:   testShareName,
+			CapacityBytes:    100 * util.Gb,
+			NfsExportOptions: options,
+		}
+	}
+	type OpItem struct {
+		id     string
+		target string
+		verb   string
+	}
+	tests := []struct {
+		name            string
+		ops             []OpItem
+		initShares      []*file.Share
+		params          map[string]string
+		expectedOptions []*file.NfsExportOptions
+		expectErr       codes.Code
+	}{
 		{
-			val:         131073 * util.Mb,
-			expectedret: false,
+			name:       "unsupported mutable parameter",
+			initShares: []*file.Share{newShare(nil)},
+			params:     map[string]string{ParamMaxIOPS: "3000"},
+			expectErr:  codes.InvalidArgument,
 		},
 		{
-			val:         131073,
-			expectedret: false,
+			name:       "unknown field in nfs export options",
+			initShares: []*file.Share{newShare(nil)},
+			params:     map[string]string{ParamMutableNfsExportOptions: `[{"accessMode":"READ_WRITE","foo":"bar"}]`},
+			expectErr:  codes.InvalidArgument,
 		},
 		{
-			val:         1023 * util.Tb,
-			expectedret: false,
+			name:       "empty nfs export options",
+			initShares: []*file.Share{newShare(nil)},
+			params:     map[string]string{ParamMutableNfsExportOptions: "[]"},
+			expectErr:  codes.InvalidArgument,
 		},
 		{
-			val:         127 * util.Gb,
-			expectedret: false,
+			name:      "share not found",
+			params:    map[string]string{ParamMutableNfsExportOptions: testOptions},
+			expectErr: codes.NotFound,
 		},
 		{
-			val:         257 * util.Gb,
-			expectedret: false,
+			name:       "share op ongoing",
+			initShares: []*file.Share{newShare(nil)},
+			params:     map[string]string{ParamMutableNfsExportOptions: testOptions},
+			ops: []OpItem{
+				{
+					id:     "op1",
+					target: fmt.Sprintf(shareUriFmt, testProject, testRegion, testInstanceName1, testShareName),
+					verb:   "update",
+				},
+			},
+			expectErr: codes.Aborted,
 		},
 		{
-			val:         -1024 * util.Gb,
-			expectedret: false,
+			name:            "nfs export options already up to date",
+			initShares:      []*file.Share{newShare(parsedOptions)},
+			params:          map[string]string{ParamMutableNfsExportOptions: testOptions},
+			expectedOptions: parsedOptions,
+			ops: []OpItem{
+				{
+					id:     "op1",
+					target: fmt.Sprintf(shareUriFmt, testProject, testRegion, testInstanceName1, testShareName),
+					verb:   "update",
+				},
+			},
 		},
 		{
-			val:         0,
-			expectedret: false,
+			name:            "update nfs export options",
+			initShares:      []*file.Share{newShare(nil)},
+			params:          map[string]string{ParamMutableNfsExportOptions: testOptions},
+			expectedOptions: parsedOptions,
 		},
 	}
 	for _, tc := range tests {
-		if isValidMaxVolSize(tc.val) != tc.expectedret {
-			t.Errorf("failed for val %d, expected ret %v", tc.val, tc.expectedret)
-		}
+		t.Run(tc.name, func(t *testing.T) {
+			var v1beta1ops []*filev1beta1multishare.Operation
+			for _, item := range tc.ops {
+				var meta filev1beta1multishare.OperationMetadata
+				meta.Target = item.target
+				meta.Verb = item.verb
+				bytes, _ := json.Marshal(meta)
+				v1beta1ops = append(v1beta1ops, &filev1beta1multishare.Operation{
+					Name:     item.id,
+					Metadata: bytes,
+				})
+			}
+
+			s, err := file.NewFakeServiceForMultishare([]*file.MultishareInstance{newInstance()}, tc.initShares, v1beta1ops)
+			if err != nil {
+				t.Fatalf("failed to fake service: %v", err)
+			}
+			cloudProvider, _ := cloud.NewFakeCloud()
+			cloudProvider.File = s
+			config := &controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: s,
+				cloud:       cloudProvider,
+				volumeLocks: util.NewVolumeLocks(),
+			}
+			mcs := NewMultishareController(config)
+			_, err = mcs.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+				VolumeId:          testVolId,
+				MutableParameters: tc.params,
+			})
+			if tc.expectErr != codes.OK {
+				if status.Code(err) != tc.expectErr {
+					t.Fatalf("expected error code %v, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			share, err := s.GetShare(context.Background(), &file.Share{Name: testShareName, Parent: newInstance()})
+			if err != nil {
+				t.Fatalf("failed to get share: %v", err)
+			}
+			if !reflect.DeepEqual(share.NfsExportOptions, tc.expectedOptions) {
+				t.Errorf("got nfs export options %+v, expected %+v", share.NfsExportOptions, tc.expectedOptions)
+			}
+		})
 	}
 }
 
@@ -2805,7 +2957,7 @@ func TestParseMaxVolumeSizeParam(t *testing.T) {
 			expectError: true,
 		},
 		{
-			name: "feature enabled, param set, unexpected value test1",
+			name: "feature enabled, param set, value below min share size",
 			features: &GCFSDriverFeatureOptions{
 				FeatureMaxSharesPerInstance: &FeatureMaxSharesPerInstance{
 					Enabled: true,
@@ -2813,13 +2965,13 @@ func TestParseMaxVolumeSizeParam(t *testing.T) {
 			},
 			req: &csi.CreateVolumeRequest{
 				Parameters: map[string]string{
-					paramMaxVolumeSize: "100Gi",
+					paramMaxVolumeSize: "5Gi",
 				},
 			},
 			expectError: true,
 		},
 		{
-			name: "feature enabled, param set, unexpected value test2",
+			name: "feature enabled, param set, value above max share size",
 			features: &GCFSDriverFeatureOptions{
 				FeatureMaxSharesPerInstance: &FeatureMaxSharesPerInstance{
 					Enabled: true,
@@ -2827,7 +2979,21 @@ func TestParseMaxVolumeSizeParam(t *testing.T) {
 			},
 			req: &csi.CreateVolumeRequest{
 				Parameters: map[string]string{
-					paramMaxVolumeSize: "100Gi",
+					paramMaxVolumeSize: "2Ti",
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "feature enabled, param set, value not aligned to Gi",
+			features: &GCFSDriverFeatureOptions{
+				FeatureMaxSharesPerInstance: &FeatureMaxSharesPerInstance{
+					Enabled: true,
+				},
+			},
+			req: &csi.CreateVolumeRequest{
+				Parameters: map[string]string{
+					paramMaxVolumeSize: "1500Mi",
 				},
 			},
 			expectError: true,
@@ -2905,6 +3071,36 @@ func TestParseMaxVolumeSizeParam(t *testing.T) {
 			expectedSharesPerInstance:     10,
 			expectedMaxShareCapacityBytes: 1 * util.Tb,
 		},
+		{
+			name: "feature enabled, param set, share count capped",
+			features: &GCFSDriverFeatureOptions{
+				FeatureMaxSharesPerInstance: &FeatureMaxSharesPerInstance{
+					Enabled: true,
+				},
+			},
+			req: &csi.CreateVolumeRequest{
+				Parameters: map[string]string{
+					paramMaxVolumeSize: "100Gi",
+				},
+			},
+			expectedSharesPerInstance:     80,
+			expectedMaxShareCapacityBytes: 100 * util.Gb,
+		},
+		{
+			name: "feature enabled, param set, arbitrary value",
+			features: &GCFSDriverFeatureOptions{
+				FeatureMaxSharesPerInstance: &FeatureMaxSharesPerInstance{
+					Enabled: true,
+				},
+			},
+			req: &csi.CreateVolumeRequest{
+				Parameters: map[string]string{
+					paramMaxVolumeSize: "200Gi",
+				},
+			},
+			expectedSharesPerInstance:     51,
+			expectedMaxShareCapacityBytes: 200 * util.Gb,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -3007,7 +3203,7 @@ func TestCreateMultishareSnapshot(t *testing.T) {
 			expectErr: true,
 		},
 		{
-			name: "Existing backup found in state CREATING, error expected",
+			name: "Existing backup found in state CREATING, not ready to use",
 			req: &csi.CreateSnapshotRequest{
 				SourceVolumeId: defaultSourceVolumeID,
 				Name:           backupName,
@@ -3015,6 +3211,14 @@ func TestCreateMultishareSnapshot(t *testing.T) {
 					util.VolumeSnapshotTypeKey: "backup",
 				},
 			},
+			resp: &csi.CreateSnapshotResponse{
+				Snapshot: &csi.Snapshot{
+					SizeBytes:      1 * util.Tb,
+					SnapshotId:     defaultBackupUri,
+					SourceVolumeId: defaultSourceVolumeID,
+					ReadyToUse:     false,
+				},
+			},
 			features: features,
 			initialBackup: &BackupTestInfo{
 				backup: &file.BackupInfo{
@@ -3028,10 +3232,9 @@ func TestCreateMultishareSnapshot(t *testing.T) {
 				},
 				state: "CREATING",
 			},
-			expectErr: true,
 		},
 		{
-			name: "Existing backup found in state FINALIZING, error expected",
+			name: "Existing backup found in state FINALIZING, not ready to use",
 			req: &csi.CreateSnapshotRequest{
 				SourceVolumeId: defaultSourceVolumeID,
 				Name:           backupName,
@@ -3039,6 +3242,14 @@ func TestCreateMultishareSnapshot(t *testing.T) {
 					util.VolumeSnapshotTypeKey: "backup",
 				},
 			},
+			resp: &csi.CreateSnapshotResponse{
+				Snapshot: &csi.Snapshot{
+					SizeBytes:      1 * util.Tb,
+					SnapshotId:     defaultBackupUri,
+					SourceVolumeId: defaultSourceVolumeID,
+					ReadyToUse:     false,
+				},
+			},
 			features: features,
 			initialBackup: &BackupTestInfo{
 				backup: &file.BackupInfo{
@@ -3052,7 +3263,6 @@ func TestCreateMultishareSnapshot(t *testing.T) {
 				},
 				state: "FINALIZING",
 			},
-			expectErr: true,
 		},
 		{
 			name: "Parameters contain misconfigured labels(invalid KV separator(:) used)",
@@ -3169,10 +3379,10 @@ func TestCreateMultishareSnapshot(t *testing.T) {
 			fileService := m.fileService
 
 			m.tagManager.(*cloud.FakeTagServiceManager).
-				On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, backupName, testRegion, tc.req.GetName(), tc.req.GetParameters()).
+				On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, testProject, backupName, testRegion, tc.req.GetName(), tc.req.GetParameters()).
 				Return(nil)
 			m.tagManager.(*cloud.FakeTagServiceManager).
-				On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, backupName2, testRegion, tc.req.GetName(), tc.req.GetParameters()).
+				On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, testProject, backupName2, testRegion, tc.req.GetName(), tc.req.GetParameters()).
 				Return(fmt.Errorf("mock failure: error while adding tags to multishare snapshot"))
 
 			if tc.initialBackup != nil {
@@ -3216,3 +3426,45 @@ func TestCreateMultishareSnapshot(t *testing.T) {
 
 	}
 }
+
+func TestCreateMultishareSnapshotStorageClassLabel(t *testing.T) {
+	instance := &file.MultishareInstance{
+		Project:  testProject,
+		Location: testRegion,
+		Name:     "multishare-instance",
+		Labels:   map[string]string{util.ParamMultishareInstanceScLabelKey: testInstanceScPrefix},
+		State:    "READY",
+	}
+	fileService, err := file.NewFakeServiceForMultishare([]*file.MultishareInstance{instance}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to init fake file service: %v", err)
+	}
+	m := initTestMultishareControllerWithFeatureOpts(t, &GCFSDriverFeatureOptions{
+		FeatureMultishareBackups: &FeatureMultishareBackups{
+			Enabled: true,
+		},
+	})
+	m.fileService = fileService
+	m.cloud.File = fileService
+
+	req := &csi.CreateSnapshotRequest{
+		SourceVolumeId: modeMultishare + "/" + testRegion + "/" + instance.Name + "/" + testShareName,
+		Name:           "mybackup",
+		Parameters: map[string]string{
+			util.VolumeSnapshotTypeKey: "backup",
+		},
+	}
+	m.tagManager.(*cloud.FakeTagServiceManager).
+		On("AttachResourceTags", context.TODO(), cloud.FilestoreBackUp, testProject, req.Name, testRegion, req.GetName(), req.GetParameters()).
+		Return(nil)
+	if _, err := m.CreateSnapshot(context.TODO(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	backup, err := fileService.GetBackup(context.TODO(), fmt.Sprintf("projects/%s/locations/%s/backups/%s", testProject, testRegion, req.Name))
+	if err != nil {
+		t.Fatalf("failed to get backup: %v", err)
+	}
+	if got := backup.Backup.Labels[util.ParamMultishareInstanceScLabelKey]; got != testInstanceScPrefix {
+		t.Errorf("got storage class label %q, want %q", got, testInstanceScPrefix)
+	}
+}
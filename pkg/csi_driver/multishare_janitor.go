@@ -0,0 +1,204 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/metrics"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// The multishare janitor periodically deletes the multishare instances of this cluster which are
+// left without shares, such as the instances whose creation outlived the CreateVolume call that
+// started it. An instance is deleted once it has been READY, without shares and without running
+// operations for the grace period. Since the janitor keeps track of empty instances in memory,
+// the grace period starts over when the controller restarts. No Kubernetes events are emitted,
+// since an empty instance has no Kubernetes object to report them on: the deletions are logged
+// and counted in the metrics instead.
+
+// multishareJanitorTimeout bounds a janitor run.
+const multishareJanitorTimeout = 30 * time.Minute
+
+// clusterInstances selects the multishare instances created by the driver for a StorageClass of this cluster.
+type clusterInstances struct {
+	createdBy       string
+	clusterName     string
+	clusterLocation string
+}
+
+func newClusterInstances(config *controllerServerConfig) clusterInstances {
+	clusterLocation := config.cloud.Zone
+	if config.isRegional {
+		if region, err := util.GetRegionFromZone(clusterLocation); err == nil {
+			clusterLocation = region
+		}
+	}
+	return clusterInstances{
+		createdBy:       strings.ReplaceAll(config.driver.config.Name, ".", "_"),
+		clusterName:     config.clusterName,
+		clusterLocation: clusterLocation,
+	}
+}
+
+// owns returns true if the instance was created by the driver for a StorageClass of this cluster.
+func (c clusterInstances) owns(instance *file.MultishareInstance) bool {
+	return instance.Labels[tagKeyCreatedBy] == c.createdBy &&
+		instance.Labels[TagKeyClusterName] == c.clusterName &&
+		instance.Labels[TagKeyClusterLocation] == c.clusterLocation &&
+		instance.Labels[util.ParamMultishareInstanceScLabelKey] != ""
+}
+
+type multishareJanitor struct {
+	opsManager     *MultishareOpsManager
+	fileService    file.Service
+	project        string
+	cluster        clusterInstances
+	config         *FeatureMultishareJanitor
+	metricsManager *metrics.MetricsManager
+	now            func() time.Time
+	// keepWarmPool leaves the warm pool instances to the warm pool.
+	keepWarmPool bool
+
+	// emptySince is the time each empty instance, by URI, was first found empty.
+	emptySince map[string]time.Time
+}
+
+func newMultishareJanitor(config *controllerServerConfig, opsManager *MultishareOpsManager) *multishareJanitor {
+	return &multishareJanitor{
+		opsManager:     opsManager,
+		fileService:    config.fileService,
+		project:        config.cloud.Project,
+		cluster:        newClusterInstances(config),
+		config:         config.features.FeatureMultishareJanitor,
+		metricsManager: config.metricsManager,
+		now:            time.Now,
+		keepWarmPool:   config.features.FeatureMultishareWarmPool != nil && config.features.FeatureMultishareWarmPool.Enabled,
+		emptySince:     make(map[string]time.Time),
+	}
+}
+
+func (j *multishareJanitor) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting multishare janitor, period %v, grace period %v, dry run %v", j.config.Period, j.config.GracePeriod, j.config.DryRun)
+	wait.Until(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), multishareJanitorTimeout)
+		defer cancel()
+		j.collect(ctx)
+	}, j.config.Period, stopCh)
+}
+
+// collect deletes the instances which have been empty for the grace period, or only logs them in dry-run mode.
+func (j *multishareJanitor) collect(ctx context.Context) error {
+	start := time.Now()
+	err := j.collectInstances(ctx)
+	j.metricsManager.RecordMultishareJanitorRun(err, time.Since(start))
+	return err
+}
+
+func (j *multishareJanitor) collectInstances(ctx context.Context) error {
+	instances, err := j.fileService.ListMultishareInstances(ctx, &file.ListFilter{Project: j.project, Location: "-"})
+	if err != nil {
+		klog.Errorf("Multishare janitor failed to list instances: %v", err)
+		return err
+	}
+	ops, err := j.opsManager.listMultishareResourceRunningOps(ctx)
+	if err != nil {
+		klog.Errorf("Multishare janitor failed to list operations: %v", err)
+		return err
+	}
+
+	now := j.now()
+	empty := make(map[string]bool)
+	var errs []error
+	for _, instance := range instances {
+		if !j.cluster.owns(instance) || (j.keepWarmPool && instance.Labels[tagKeyWarmPool] != "") {
+			continue
+		}
+		instanceURI, err := file.GenerateMultishareInstanceURI(instance)
+		if err != nil {
+			klog.Warningf("Multishare janitor skipping instance %s: %v", instance.String(), err)
+			continue
+		}
+		isEmpty, err := j.isEmpty(ctx, instance, ops)
+		if err != nil {
+			klog.Errorf("Multishare janitor failed to check instance %s: %v", instanceURI, err)
+			errs = append(errs, err)
+			continue
+		}
+		if !isEmpty {
+			continue
+		}
+		empty[instanceURI] = true
+		since, ok := j.emptySince[instanceURI]
+		if !ok {
+			klog.V(4).Infof("Multishare janitor found empty instance %s", instanceURI)
+			j.emptySince[instanceURI] = now
+			since = now
+		}
+		if now.Sub(since) < j.config.GracePeriod {
+			continue
+		}
+
+		if j.config.DryRun {
+			klog.Infof("Multishare janitor would delete instance %s, empty since %v, skipped in dry-run mode", instanceURI, since)
+			j.metricsManager.RecordMultishareJanitorDeletion(true, nil)
+			continue
+		}
+		_, err = j.opsManager.checkAndStartEmptyInstanceDeleteWorkflow(ctx, instance)
+		j.metricsManager.RecordMultishareJanitorDeletion(false, err)
+		if err != nil {
+			klog.Errorf("Multishare janitor failed to delete instance %s: %v", instanceURI, err)
+			errs = append(errs, err)
+			continue
+		}
+		klog.Infof("Multishare janitor started deletion of instance %s, empty since %v", instanceURI, since)
+		delete(j.emptySince, instanceURI)
+	}
+
+	// Forget the instances which got a share or are gone.
+	for instanceURI := range j.emptySince {
+		if !empty[instanceURI] {
+			delete(j.emptySince, instanceURI)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isEmpty returns true if the instance is ready, without running operations and without shares.
+func (j *multishareJanitor) isEmpty(ctx context.Context, instance *file.MultishareInstance, ops []*OpInfo) (bool, error) {
+	if instance.State != "READY" {
+		return false, nil
+	}
+	op, err := containsOpWithInstanceTargetPrefix(instance, ops)
+	if err != nil {
+		return false, err
+	}
+	if op != nil {
+		return false, nil
+	}
+	shares, err := j.fileService.ListShares(ctx, &file.ListFilter{Project: instance.Project, Location: instance.Location, InstanceName: instance.Name})
+	if err != nil {
+		return false, err
+	}
+	return len(shares) == 0, nil
+}
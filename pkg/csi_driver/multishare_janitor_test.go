@@ -0,0 +1,220 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	filev1beta1multishare "google.golang.org/api/file/v1beta1"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+func TestMultishareJanitor(t *testing.T) {
+	const gracePeriod = time.Hour
+	newInstance := func(clusterName, state string) *file.MultishareInstance {
+		return &file.MultishareInstance{
+			Name:     "test-instance",
+			Project:  testProject,
+			Location: testRegion,
+			State:    state,
+			Labels: map[string]string{
+				tagKeyCreatedBy:                        "test-driver",
+				TagKeyClusterName:                      clusterName,
+				TagKeyClusterLocation:                  testLocation,
+				util.ParamMultishareInstanceScLabelKey: testInstanceScPrefix,
+			},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		instance       *file.MultishareInstance
+		share          bool
+		opVerb         string
+		elapsed        time.Duration
+		dryRun         bool
+		expectDeletion bool
+	}{
+		{
+			name:           "empty instance past the grace period",
+			instance:       newInstance(testClusterName, "READY"),
+			elapsed:        gracePeriod,
+			expectDeletion: true,
+		},
+		{
+			name:     "empty instance within the grace period",
+			instance: newInstance(testClusterName, "READY"),
+			elapsed:  gracePeriod - time.Minute,
+		},
+		{
+			name:     "instance with a share",
+			instance: newInstance(testClusterName, "READY"),
+			share:    true,
+			elapsed:  gracePeriod,
+		},
+		{
+			name:     "instance being created",
+			instance: newInstance(testClusterName, "CREATING"),
+			elapsed:  gracePeriod,
+		},
+		{
+			name:     "instance with a running op",
+			instance: newInstance(testClusterName, "READY"),
+			opVerb:   "update",
+			elapsed:  gracePeriod,
+		},
+		{
+			name:     "instance of another cluster",
+			instance: newInstance("other-cluster", "READY"),
+			elapsed:  gracePeriod,
+		},
+		{
+			name:     "dry run",
+			instance: newInstance(testClusterName, "READY"),
+			elapsed:  gracePeriod,
+			dryRun:   true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var shares []*file.Share
+			if tc.share {
+				shares = append(shares, &file.Share{Name: "test-share", Parent: tc.instance, CapacityBytes: 100 * util.Gb})
+			}
+			var ops []*filev1beta1multishare.Operation
+			if tc.opVerb != "" {
+				meta, _ := json.Marshal(filev1beta1multishare.OperationMetadata{
+					Target: instanceURI(testProject, testRegion, tc.instance.Name),
+					Verb:   tc.opVerb,
+				})
+				ops = append(ops, &filev1beta1multishare.Operation{Name: "op1", Metadata: meta})
+			}
+			s, err := file.NewFakeServiceForMultishare([]*file.MultishareInstance{tc.instance}, shares, ops)
+			if err != nil {
+				t.Fatalf("failed to fake service: %v", err)
+			}
+			cloudProvider, _ := cloud.NewFakeCloud()
+			cloudProvider.File = s
+			config := &controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: s,
+				cloud:       cloudProvider,
+				clusterName: testClusterName,
+				features: &GCFSDriverFeatureOptions{
+					FeatureMultishareJanitor: &FeatureMultishareJanitor{
+						Enabled:     true,
+						Period:      time.Minute,
+						GracePeriod: gracePeriod,
+						DryRun:      tc.dryRun,
+					},
+				},
+			}
+			mcs := NewMultishareController(config)
+			if mcs.janitor == nil {
+				t.Fatalf("expected janitor to be enabled")
+			}
+			now := time.Now()
+			mcs.janitor.now = func() time.Time { return now }
+
+			for _, elapsed := range []time.Duration{0, tc.elapsed} {
+				now = now.Add(elapsed)
+				if err := mcs.janitor.collect(context.TODO()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			_, err = s.GetMultishareInstance(context.TODO(), tc.instance)
+			deleted := file.IsNotFoundErr(err)
+			if deleted != tc.expectDeletion {
+				t.Errorf("got instance deleted %v, expected %v", deleted, tc.expectDeletion)
+			}
+		})
+	}
+}
+
+func TestMultishareJanitorForgetsInstances(t *testing.T) {
+	instance := &file.MultishareInstance{
+		Name:     "test-instance",
+		Project:  testProject,
+		Location: testRegion,
+		State:    "READY",
+		Labels: map[string]string{
+			tagKeyCreatedBy:                        "test-driver",
+			TagKeyClusterName:                      testClusterName,
+			TagKeyClusterLocation:                  testLocation,
+			util.ParamMultishareInstanceScLabelKey: testInstanceScPrefix,
+		},
+	}
+	s, err := file.NewFakeServiceForMultishare([]*file.MultishareInstance{instance}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to fake service: %v", err)
+	}
+	cloudProvider, _ := cloud.NewFakeCloud()
+	cloudProvider.File = s
+	mcs := NewMultishareController(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: s,
+		cloud:       cloudProvider,
+		clusterName: testClusterName,
+		features: &GCFSDriverFeatureOptions{
+			FeatureMultishareJanitor: &FeatureMultishareJanitor{
+				Enabled:     true,
+				Period:      time.Minute,
+				GracePeriod: time.Hour,
+			},
+		},
+	})
+	now := time.Now()
+	mcs.janitor.now = func() time.Time { return now }
+	collect := func() {
+		t.Helper()
+		if err := mcs.janitor.collect(context.TODO()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	collect()
+	// A share placed on the instance restarts the grace period once it is deleted.
+	share := &file.Share{Name: "test-share", Parent: instance, CapacityBytes: 100 * util.Gb}
+	if _, err := s.StartCreateShareOp(context.TODO(), share); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	now = now.Add(30 * time.Minute)
+	collect()
+	if _, ok := mcs.janitor.emptySince[instanceURI(testProject, testRegion, instance.Name)]; ok {
+		t.Fatalf("expected instance with a share to be forgotten")
+	}
+	if _, err := s.StartDeleteShareOp(context.TODO(), share); err != nil {
+		t.Fatalf("failed to delete share: %v", err)
+	}
+	now = now.Add(30 * time.Minute)
+	collect()
+	now = now.Add(59 * time.Minute)
+	collect()
+	if _, err := s.GetMultishareInstance(context.TODO(), instance); err != nil {
+		t.Errorf("expected instance to be kept within the grace period, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	collect()
+	if _, err := s.GetMultishareInstance(context.TODO(), instance); !file.IsNotFoundErr(err) {
+		t.Errorf("expected instance to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+)
+
+// multishareLocks serializes the multishare workflows acting on the same resources, while the
+// workflows of different instances and StorageClasses run concurrently:
+//   - An op on an instance or on one of its shares is only started under the lock of the instance,
+//     after the running ops were listed under that lock, so that no two workflows start ops on the
+//     same instance based on the same view of its running ops.
+//   - The placement of a share on the instances of a StorageClass is done under the lock of the
+//     StorageClass, so that concurrent placements do not both pick the last free share slot of an
+//     instance, or both create a new instance.
+//
+// When a workflow holds both, the StorageClass lock is acquired before the instance lock.
+type multishareLocks struct {
+	mu    sync.Mutex
+	locks map[string]*multishareLock
+}
+
+type multishareLock struct {
+	sync.Mutex
+	// refs counts the holder of the lock and the workflows waiting for it. The lock is dropped once it has none.
+	refs int
+}
+
+func newMultishareLocks() *multishareLocks {
+	return &multishareLocks{
+		locks: make(map[string]*multishareLock),
+	}
+}
+
+// lock acquires the lock of the given key, and returns the function releasing it.
+func (l *multishareLocks) lock(key string) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &multishareLock{}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
+
+// lockStorageClass acquires the placement lock of the StorageClass with the given instance StorageClass label.
+func (l *multishareLocks) lockStorageClass(scLabel string) func() {
+	return l.lock("storageclasses/" + scLabel)
+}
+
+// lockInstance acquires the lock of the given instance.
+func (l *multishareLocks) lockInstance(instance *file.MultishareInstance) (func(), error) {
+	instanceUri, err := file.GenerateMultishareInstanceURI(instance)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to parse instance handle, err: %v", err)
+	}
+	return l.lock(instanceUri), nil
+}
+
+// lockShareInstance acquires the lock of the instance of the given share.
+func (l *multishareLocks) lockShareInstance(share *file.Share) (func(), error) {
+	if share.Parent == nil {
+		return nil, status.Errorf(codes.Internal, "share parent not found in share %q", share.Name)
+	}
+	return l.lockInstance(share.Parent)
+}
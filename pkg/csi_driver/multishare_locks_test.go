@@ -0,0 +1,359 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	filev1beta1multishare "google.golang.org/api/file/v1beta1"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+func TestMultishareLocks(t *testing.T) {
+	locks := newMultishareLocks()
+	instanceA := &file.MultishareInstance{Project: testProject, Location: testRegion, Name: "instance-a"}
+	instanceB := &file.MultishareInstance{Project: testProject, Location: testRegion, Name: "instance-b"}
+
+	unlockA, err := locks.lockInstance(instanceA)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Other instances and StorageClasses are not blocked.
+	unlockB, err := locks.lockInstance(instanceB)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unlockB()
+	locks.lockStorageClass(testInstanceScPrefix)()
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, _ := locks.lockShareInstance(&file.Share{Name: "test-share", Parent: instanceA})
+		close(acquired)
+		unlock()
+	}()
+	select {
+	case <-acquired:
+		t.Fatalf("expected the lock of the instance to be held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockA()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the lock of the instance to be released")
+	}
+
+	if _, err := locks.lockShareInstance(&file.Share{Name: "test-share"}); err == nil {
+		t.Errorf("expected error for share without parent")
+	}
+	locks.mu.Lock()
+	defer locks.mu.Unlock()
+	if len(locks.locks) != 0 {
+		t.Errorf("expected released locks to be dropped, got %d", len(locks.locks))
+	}
+}
+
+// concurrentFakeService makes the fake file service safe for concurrent use. The ops it starts are
+// reported running until they are waited for, like the ops of the Filestore API, and the ops started
+// on an instance or a share while a conflicting op is running are recorded. Reads take a while to
+// return, so that the workflows act on stale reads if they are not serialized.
+type concurrentFakeService struct {
+	file.Service
+
+	mu sync.Mutex
+	// running are the running ops by name, with their target.
+	running   map[string]*filev1beta1multishare.Operation
+	targets   map[string]string
+	conflicts []string
+}
+
+func newConcurrentFakeService(t *testing.T) *concurrentFakeService {
+	s, err := file.NewFakeServiceForMultishare(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to fake service: %v", err)
+	}
+	return &concurrentFakeService{
+		Service: s,
+		running: make(map[string]*filev1beta1multishare.Operation),
+		targets: make(map[string]string),
+	}
+}
+
+// startOp starts an op on the given target, and records a conflict if an op is running on the target,
+// on the instance of a share target, or on a share of an instance target.
+func (s *concurrentFakeService) startOp(target, parent string, start func() (*filev1beta1multishare.Operation, error)) (*filev1beta1multishare.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, running := range s.targets {
+		if running == target || running == parent || strings.HasPrefix(running, target+"/") {
+			s.conflicts = append(s.conflicts, fmt.Sprintf("op on %s started while op %s on %s is running", target, name, running))
+		}
+	}
+	op, err := start()
+	if err != nil {
+		return nil, err
+	}
+	s.running[op.Name] = op
+	s.targets[op.Name] = target
+	return op, nil
+}
+
+func (s *concurrentFakeService) startInstanceOp(obj *file.MultishareInstance, start func() (*filev1beta1multishare.Operation, error)) (*filev1beta1multishare.Operation, error) {
+	return s.startOp(instanceURI(obj.Project, obj.Location, obj.Name), "", start)
+}
+
+func (s *concurrentFakeService) startShareOp(obj *file.Share, start func() (*filev1beta1multishare.Operation, error)) (*filev1beta1multishare.Operation, error) {
+	parent := instanceURI(obj.Parent.Project, obj.Parent.Location, obj.Parent.Name)
+	return s.startOp(parent+"/shares/"+obj.Name, parent, start)
+}
+
+// fakeReadLatency is the time reads of the concurrentFakeService take to return.
+const fakeReadLatency = time.Millisecond
+
+func copyFakeInstance(instance *file.MultishareInstance) *file.MultishareInstance {
+	if instance == nil {
+		return nil
+	}
+	c := *instance
+	return &c
+}
+
+func copyFakeShare(share *file.Share) *file.Share {
+	if share == nil {
+		return nil
+	}
+	c := *share
+	c.Parent = copyFakeInstance(share.Parent)
+	return &c
+}
+
+func (s *concurrentFakeService) GetMultishareInstance(ctx context.Context, obj *file.MultishareInstance) (*file.MultishareInstance, error) {
+	defer time.Sleep(fakeReadLatency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	instance, err := s.Service.GetMultishareInstance(ctx, obj)
+	return copyFakeInstance(instance), err
+}
+
+func (s *concurrentFakeService) ListMultishareInstances(ctx context.Context, filter *file.ListFilter) ([]*file.MultishareInstance, error) {
+	defer time.Sleep(fakeReadLatency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	instances, err := s.Service.ListMultishareInstances(ctx, filter)
+	var copies []*file.MultishareInstance
+	for _, instance := range instances {
+		copies = append(copies, copyFakeInstance(instance))
+	}
+	return copies, err
+}
+
+func (s *concurrentFakeService) StartCreateMultishareInstanceOp(ctx context.Context, obj *file.MultishareInstance) (*filev1beta1multishare.Operation, error) {
+	return s.startInstanceOp(obj, func() (*filev1beta1multishare.Operation, error) {
+		return s.Service.StartCreateMultishareInstanceOp(ctx, obj)
+	})
+}
+
+func (s *concurrentFakeService) StartDeleteMultishareInstanceOp(ctx context.Context, obj *file.MultishareInstance) (*filev1beta1multishare.Operation, error) {
+	return s.startInstanceOp(obj, func() (*filev1beta1multishare.Operation, error) {
+		return s.Service.StartDeleteMultishareInstanceOp(ctx, obj)
+	})
+}
+
+func (s *concurrentFakeService) StartResizeMultishareInstanceOp(ctx context.Context, obj *file.MultishareInstance) (*filev1beta1multishare.Operation, error) {
+	return s.startInstanceOp(obj, func() (*filev1beta1multishare.Operation, error) {
+		return s.Service.StartResizeMultishareInstanceOp(ctx, obj)
+	})
+}
+
+func (s *concurrentFakeService) ListShares(ctx context.Context, filter *file.ListFilter) ([]*file.Share, error) {
+	defer time.Sleep(fakeReadLatency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shares, err := s.Service.ListShares(ctx, filter)
+	var copies []*file.Share
+	for _, share := range shares {
+		copies = append(copies, copyFakeShare(share))
+	}
+	return copies, err
+}
+
+func (s *concurrentFakeService) GetShare(ctx context.Context, obj *file.Share) (*file.Share, error) {
+	defer time.Sleep(fakeReadLatency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	share, err := s.Service.GetShare(ctx, obj)
+	return copyFakeShare(share), err
+}
+
+func (s *concurrentFakeService) StartCreateShareOp(ctx context.Context, obj *file.Share) (*filev1beta1multishare.Operation, error) {
+	return s.startShareOp(obj, func() (*filev1beta1multishare.Operation, error) {
+		return s.Service.StartCreateShareOp(ctx, obj)
+	})
+}
+
+func (s *concurrentFakeService) StartDeleteShareOp(ctx context.Context, obj *file.Share) (*filev1beta1multishare.Operation, error) {
+	return s.startShareOp(obj, func() (*filev1beta1multishare.Operation, error) {
+		return s.Service.StartDeleteShareOp(ctx, obj)
+	})
+}
+
+func (s *concurrentFakeService) StartResizeShareOp(ctx context.Context, obj *file.Share) (*filev1beta1multishare.Operation, error) {
+	return s.startShareOp(obj, func() (*filev1beta1multishare.Operation, error) {
+		return s.Service.StartResizeShareOp(ctx, obj)
+	})
+}
+
+func (s *concurrentFakeService) ListOps(ctx context.Context, filter *file.ListFilter) ([]*filev1beta1multishare.Operation, error) {
+	defer time.Sleep(fakeReadLatency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ops []*filev1beta1multishare.Operation
+	for _, op := range s.running {
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func (s *concurrentFakeService) WaitForOpWithOpts(ctx context.Context, op string, opts file.PollOpts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, op)
+	delete(s.targets, op)
+	return nil
+}
+
+func TestMultishareOpsManagerConcurrency(t *testing.T) {
+	const (
+		storageClasses = 3
+		volumes        = 30
+		attempts       = 1000
+	)
+	s := newConcurrentFakeService(t)
+	cloudProvider, _ := cloud.NewFakeCloud()
+	cloudProvider.File = s
+	mcs := NewMultishareController(&controllerServerConfig{
+		driver:      initTestDriver(t),
+		fileService: s,
+		cloud:       cloudProvider,
+		volumeLocks: util.NewVolumeLocks(),
+		clusterName: testClusterName,
+	})
+
+	// retry calls the CSI call until it succeeds, like the external-provisioner would.
+	retry := func(call func() error) error {
+		var err error
+		for i := 0; i < attempts; i++ {
+			if err = call(); err == nil {
+				return nil
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return err
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, storageClasses*volumes)
+	for sc := 0; sc < storageClasses; sc++ {
+		for v := 0; v < volumes; v++ {
+			wg.Add(1)
+			go func(sc, v int) {
+				defer wg.Done()
+				// Some shares do not fit the minimum instance size, so the instances get expanded.
+				capacityBytes := int64(100 * util.Gb)
+				if v%4 == 0 {
+					capacityBytes = 300 * util.Gb
+				}
+				req := &csi.CreateVolumeRequest{
+					Name:          fmt.Sprintf("pvc-%d-%d", sc, v),
+					CapacityRange: &csi.CapacityRange{RequiredBytes: capacityBytes},
+					Parameters: map[string]string{
+						ParamMultishareInstanceScLabel: fmt.Sprintf("%s-%d", testInstanceScPrefix, sc),
+					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						{
+							AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+							AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+						},
+					},
+				}
+				var resp *csi.CreateVolumeResponse
+				if err := retry(func() (err error) {
+					resp, err = mcs.CreateVolume(context.TODO(), req)
+					return err
+				}); err != nil {
+					errs <- fmt.Errorf("CreateVolume %s failed: %w", req.Name, err)
+					return
+				}
+				// Every other volume is deleted again, concurrently with the creation of the others.
+				if v%2 == 1 {
+					if err := retry(func() error {
+						_, err := mcs.DeleteVolume(context.TODO(), &csi.DeleteVolumeRequest{VolumeId: resp.Volume.VolumeId})
+						return err
+					}); err != nil {
+						errs <- fmt.Errorf("DeleteVolume %s failed: %w", resp.Volume.VolumeId, err)
+					}
+				}
+			}(sc, v)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	for _, conflict := range s.conflicts {
+		t.Errorf("conflicting op: %s", conflict)
+	}
+
+	instances, _ := s.ListMultishareInstances(context.TODO(), &file.ListFilter{Project: testProject, Location: "-"})
+	shareCount := 0
+	for _, instance := range instances {
+		shares, _ := s.ListShares(context.TODO(), &file.ListFilter{InstanceName: instance.Name})
+		shareCount += len(shares)
+		if len(shares) > util.MaxSharesPerInstance {
+			t.Errorf("instance %s has %d shares, more than %d", instance.Name, len(shares), util.MaxSharesPerInstance)
+		}
+		var sumShareBytes int64
+		for _, share := range shares {
+			sumShareBytes += share.CapacityBytes
+			if share.Parent.Labels[util.ParamMultishareInstanceScLabelKey] != instance.Labels[util.ParamMultishareInstanceScLabelKey] {
+				t.Errorf("share %s placed on instance %s of another StorageClass", share.Name, instance.Name)
+			}
+		}
+		if sumShareBytes > instance.CapacityBytes {
+			t.Errorf("instance %s of %d bytes has %d bytes of shares", instance.Name, instance.CapacityBytes, sumShareBytes)
+		}
+	}
+	if expected := storageClasses * volumes / 2; shareCount != expected {
+		t.Errorf("got %d shares, expected %d", shareCount, expected)
+	}
+	var meta filev1beta1multishare.OperationMetadata
+	for _, op := range s.running {
+		json.Unmarshal(op.Metadata, &meta)
+		t.Errorf("op %s on %s is still running", op.Name, meta.Target)
+	}
+}
@@ -20,9 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"strings"
-	"sync"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	filev1beta1multishare "google.golang.org/api/file/v1beta1"
@@ -50,7 +48,7 @@ type Workflow struct {
 
 // MultishareOpsManager manages the lifecycle of all instance and share operations.
 type MultishareOpsManager struct {
-	sync.Mutex         // Lock to perform thread safe multishare operations.
+	locks              *multishareLocks // Locks to perform thread safe multishare operations.
 	cloud              *cloud.Cloud
 	controllerServer   *controllerServer
 	msControllerServer *MultishareController
@@ -58,6 +56,7 @@ type MultishareOpsManager struct {
 
 func NewMultishareOpsManager(cloud *cloud.Cloud, mcs *MultishareController) *MultishareOpsManager {
 	return &MultishareOpsManager{
+		locks:              newMultishareLocks(),
 		cloud:              cloud,
 		msControllerServer: mcs,
 	}
@@ -65,8 +64,8 @@ func NewMultishareOpsManager(cloud *cloud.Cloud, mcs *MultishareController) *Mul
 
 // setupEligibleInstanceAndStartWorkflow returns a workflow object (to indicate an instance or share level workflow is started), or a share object (if existing share already found), or error.
 func (m *MultishareOpsManager) setupEligibleInstanceAndStartWorkflow(ctx context.Context, req *csi.CreateVolumeRequest, instance *file.MultishareInstance, sourceSnapshotId string) (*Workflow, *file.Share, error) {
-	m.Lock()
-	defer m.Unlock()
+	// The placement is serialized with the other placements on the instances of the StorageClass, the instance the share is placed on is locked once picked.
+	defer m.locks.lockStorageClass(instance.Labels[util.ParamMultishareInstanceScLabelKey])()
 
 	// Check ShareCreateMap if a share create is already in progress.
 	shareName := util.ConvertVolToShareName(req.Name)
@@ -101,16 +100,36 @@ func (m *MultishareOpsManager) setupEligibleInstanceAndStartWorkflow(ctx context
 	}
 
 	// No share or running share create op found. Proceed to eligible instance check.
-	eligible, err := m.runEligibleInstanceCheck(ctx, req, ops, instance, regions)
+	eligible, instanceShares, err := m.runEligibleInstanceCheck(ctx, req, ops, instance, regions)
 	if err != nil {
 		return nil, nil, status.Error(codes.Aborted, err.Error())
 	}
 
 	if len(eligible) > 0 {
-		// pick a random eligible instance
-		index := rand.Intn(len(eligible))
-		klog.V(5).Infof("For share %s, using instance %s as placeholder", shareName, eligible[index].String())
-		share, err := generateNewShare(shareName, eligible[index], req, sourceSnapshotId)
+		target, err := m.placeShare(req, eligible, instanceShares)
+		if err != nil {
+			return nil, nil, err
+		}
+		unlock, err := m.locks.lockInstance(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer unlock()
+		// Workflows which do not place shares, e.g. share deletions, may have started ops on the instance,
+		// or resized or deleted it, since it was listed.
+		ops, err = m.listMultishareResourceRunningOps(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		target, err = m.cloud.File.GetMultishareInstance(ctx, target)
+		if err != nil {
+			if file.IsNotFoundErr(err) {
+				return nil, nil, status.Errorf(codes.Aborted, "instance picked for share %s was deleted", shareName)
+			}
+			return nil, nil, err
+		}
+		klog.V(5).Infof("For share %s, using instance %s as placeholder", shareName, target.String())
+		share, err := generateNewShare(shareName, target, req, sourceSnapshotId)
 		if err != nil {
 			return nil, nil, status.Error(codes.Internal, err.Error())
 		}
@@ -121,8 +140,8 @@ func (m *MultishareOpsManager) setupEligibleInstanceAndStartWorkflow(ctx context
 		}
 
 		if needExpand {
-			eligible[index].CapacityBytes = targetBytes
-			w, err := m.startInstanceWorkflow(ctx, &Workflow{instance: eligible[index], opType: util.InstanceUpdate}, ops)
+			target.CapacityBytes = targetBytes
+			w, err := m.startInstanceWorkflow(ctx, &Workflow{instance: target, opType: util.InstanceUpdate}, ops)
 			return w, nil, err
 		}
 
@@ -130,13 +149,38 @@ func (m *MultishareOpsManager) setupEligibleInstanceAndStartWorkflow(ctx context
 		return w, nil, err
 	}
 
-	param := req.GetParameters()
+	unlock, err := m.locks.lockInstance(instance)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+	w, err := m.startNewInstanceWorkflow(ctx, instance, req.GetParameters(), ops)
+	return w, nil, err
+}
+
+// startNewInstanceWorkflowSafe starts the creation of a new instance with the given StorageClass parameters.
+func (m *MultishareOpsManager) startNewInstanceWorkflowSafe(ctx context.Context, instance *file.MultishareInstance, param map[string]string) (*Workflow, error) {
+	defer m.locks.lockStorageClass(instance.Labels[util.ParamMultishareInstanceScLabelKey])()
+	unlock, err := m.locks.lockInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	ops, err := m.listMultishareResourceRunningOps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.startNewInstanceWorkflow(ctx, instance, param, ops)
+}
+
+func (m *MultishareOpsManager) startNewInstanceWorkflow(ctx context.Context, instance *file.MultishareInstance, param map[string]string, ops []*OpInfo) (*Workflow, error) {
 	// If we are creating a new instance, we need pick an unused CIDR range from reserved-ipv4-cidr
 	// If the param was not provided, we default reservedIPRange to "" and cloud provider takes care of the allocation
 	if instance.Network.ConnectMode == privateServiceAccess {
 		if reservedIPRange, ok := param[ParamReservedIPRange]; ok {
 			if IsCIDR(reservedIPRange) {
-				return nil, nil, status.Error(codes.InvalidArgument, "When using connect mode PRIVATE_SERVICE_ACCESS, if reserved IP range is specified, it must be a named address range instead of direct CIDR value")
+				return nil, status.Error(codes.InvalidArgument, "When using connect mode PRIVATE_SERVICE_ACCESS, if reserved IP range is specified, it must be a named address range instead of direct CIDR value")
 			}
 			instance.Network.ReservedIpRange = reservedIPRange
 		}
@@ -154,15 +198,14 @@ func (m *MultishareOpsManager) setupEligibleInstanceAndStartWorkflow(ctx context
 		// In case of abort, the CIDR IP is released and available for reservation
 		defer m.controllerServer.config.ipAllocator.ReleaseIPRange(reservedIPRange)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		// Adding the reserved IP range to the instance object
 		instance.Network.ReservedIpRange = reservedIPRange
 	}
 
-	w, err := m.startInstanceWorkflow(ctx, &Workflow{instance: instance, opType: util.InstanceCreate}, ops)
-	return w, nil, err
+	return m.startInstanceWorkflow(ctx, &Workflow{instance: instance, opType: util.InstanceCreate}, ops)
 }
 
 func (m *MultishareOpsManager) listRegions(top *csi.TopologyRequirement) ([]string, error) {
@@ -200,8 +243,11 @@ func (m *MultishareOpsManager) listRegions(top *csi.TopologyRequirement) ([]stri
 }
 
 func (m *MultishareOpsManager) startShareCreateWorkflowSafe(ctx context.Context, share *file.Share) (*Workflow, error) {
-	m.Lock()
-	defer m.Unlock()
+	unlock, err := m.locks.lockShareInstance(share)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	ops, err := m.listMultishareResourceRunningOps(ctx)
 	if err != nil {
 		return nil, err
@@ -340,12 +386,40 @@ func (m *MultishareOpsManager) verifyNoRunningInstanceOrShareOpsForInstance(inst
 	return nil
 }
 
-// runEligibleInstanceCheck returns a list of ready and non-ready instances.
-func (m *MultishareOpsManager) runEligibleInstanceCheck(ctx context.Context, req *csi.CreateVolumeRequest, ops []*OpInfo, target *file.MultishareInstance, regions []string) ([]*file.MultishareInstance, error) {
+// placeShare picks the eligible instance the share of the request is placed on, with the placement
+// policy of the StorageClass. instanceShares maps each eligible instance, by its String(), to its shares.
+func (m *MultishareOpsManager) placeShare(req *csi.CreateVolumeRequest, eligible []*file.MultishareInstance, instanceShares map[string][]*file.Share) (*file.MultishareInstance, error) {
+	policy, err := getSharePlacementPolicy(req.GetParameters())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// The share size request is already validated in CreateVolume call
+	capacityBytes, err := getShareRequestCapacity(req.GetCapacityRange(), util.ConfigurablePackMinShareSizeBytes, util.MaxShareSizeBytes)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	instances := make(map[string]*file.MultishareInstance, len(eligible))
+	candidates := make([]*placementCandidate, 0, len(eligible))
+	for _, instance := range eligible {
+		candidate := newInstancePlacementCandidate(instance, instanceShares[instance.String()])
+		instances[candidate.name] = instance
+		candidates = append(candidates, candidate)
+	}
+	sortPlacementCandidates(candidates)
+	share := &placementShare{
+		capacityBytes: capacityBytes,
+		namespace:     req.GetParameters()[ParameterKeyPVCNamespace],
+	}
+	return instances[candidates[policy.pick(share, candidates)].name], nil
+}
+
+// runEligibleInstanceCheck returns a list of ready and non-ready instances, and the shares of the ready instances keyed by instance String().
+func (m *MultishareOpsManager) runEligibleInstanceCheck(ctx context.Context, req *csi.CreateVolumeRequest, ops []*OpInfo, target *file.MultishareInstance, regions []string) ([]*file.MultishareInstance, map[string][]*file.Share, error) {
 	klog.Infof("ListMultishareInstances call initiated for request %+v.", req)
 	instances, err := m.listMatchedInstances(ctx, req, target, regions)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	klog.Infof("ListMultishareInstances call returned successfully with %d instances for request %+v.", len(instances), req)
 	// An instance is considered as eligible if and only if the state is 'READY', and there's no ops running against it.
@@ -354,6 +428,7 @@ func (m *MultishareOpsManager) runEligibleInstanceCheck(ctx context.Context, req
 	// 1. The instance state is "CREATING" or "REPAIRING".
 	// 2. The instance state is 'READY', but running ops are found on it.
 	var nonReadyEligibleInstances []*file.MultishareInstance
+	instanceShares := make(map[string][]*file.Share)
 
 	for _, instance := range instances {
 		klog.Infof("Found multishare instance %s/%s/%s with state %s and max share count %d", instance.Project, instance.Location, instance.Name, instance.State, instance.MaxShareCount)
@@ -371,14 +446,14 @@ func (m *MultishareOpsManager) runEligibleInstanceCheck(ctx context.Context, req
 		op, err := containsOpWithInstanceTargetPrefix(instance, ops)
 		if err != nil {
 			klog.Errorf("failed to check eligibility of instance %s", instance.Name)
-			return nil, err
+			return nil, nil, err
 		}
 
 		if op == nil {
 			shares, err := m.cloud.File.ListShares(ctx, &file.ListFilter{Project: instance.Project, Location: instance.Location, InstanceName: instance.Name})
 			if err != nil {
 				klog.Errorf("Failed to list shares of instance %s/%s/%s, err:%v", instance.Project, instance.Location, instance.Name, err.Error())
-				return nil, err
+				return nil, nil, err
 			}
 
 			// If we encounter a scenario where the configurable shares per Filestore instance feature is disabled, CSI driver will continue to place max 10 shares per instance, irrespective of the actual max shares the Filestore instance can support.
@@ -392,6 +467,7 @@ func (m *MultishareOpsManager) runEligibleInstanceCheck(ctx context.Context, req
 			}
 
 			readyEligibleInstances = append(readyEligibleInstances, instance)
+			instanceShares[instance.String()] = shares
 			klog.Infof("Adding instance %s to eligible list", instance.String())
 			continue
 		}
@@ -399,7 +475,7 @@ func (m *MultishareOpsManager) runEligibleInstanceCheck(ctx context.Context, req
 		klog.Infof("Instance %s/%s/%s with state %s is not ready with ongoing operation %s type %s", instance.Project, instance.Location, instance.Name, instance.State, op.Id, op.Type.String())
 		nonReadyEligibleInstances = append(nonReadyEligibleInstances, instance)
 
-		// TODO: If we see > 1 instances with 0 shares (these could be possibly leaked instances where the driver hit timeout during creation op was in progress), should we trigger delete op for such instances? Possibly yes. Given that instance create/delete and share create/delete is serialized, maybe yes.
+		// Instances left with 0 shares, e.g. when the driver hit timeout while the creation op was in progress, are deleted by the multishare janitor.
 	}
 
 	if len(readyEligibleInstances) == 0 && len(nonReadyEligibleInstances) > 0 {
@@ -409,7 +485,7 @@ func (m *MultishareOpsManager) runEligibleInstanceCheck(ctx context.Context, req
 			op, err := containsOpWithInstanceTargetPrefix(instance, ops) // Error for this call is already checked above
 			if err != nil {
 				klog.Errorf("failed to check eligibility of instance %s", instance.Name)
-				return nil, err
+				return nil, nil, err
 			}
 			if op != nil {
 				errorString = fmt.Sprintf("%s Instance %s busy with operation type %s\n", errorString, instance.Name, op.Type)
@@ -418,11 +494,11 @@ func (m *MultishareOpsManager) runEligibleInstanceCheck(ctx context.Context, req
 			}
 		}
 
-		return nil, status.Error(codes.Aborted, errorString)
+		return nil, nil, status.Error(codes.Aborted, errorString)
 
 	}
 
-	return readyEligibleInstances, nil
+	return readyEligibleInstances, instanceShares, nil
 }
 
 func (m *MultishareOpsManager) instanceNeedsExpand(ctx context.Context, share *file.Share, capacityNeeded int64) (bool, int64, error) {
@@ -453,8 +529,11 @@ func (m *MultishareOpsManager) instanceNeedsExpand(ctx context.Context, share *f
 }
 
 func (m *MultishareOpsManager) checkAndStartInstanceOrShareExpandWorkflow(ctx context.Context, share *file.Share, reqBytes int64) (*Workflow, error) {
-	m.Lock()
-	defer m.Unlock()
+	unlock, err := m.locks.lockShareInstance(share)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	ops, err := m.listMultishareResourceRunningOps(ctx)
 	if err != nil {
@@ -496,8 +575,11 @@ func (m *MultishareOpsManager) checkAndStartInstanceOrShareExpandWorkflow(ctx co
 }
 
 func (m *MultishareOpsManager) startShareExpandWorkflowSafe(ctx context.Context, share *file.Share, reqBytes int64) (*Workflow, error) {
-	m.Lock()
-	defer m.Unlock()
+	unlock, err := m.locks.lockShareInstance(share)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	ops, err := m.listMultishareResourceRunningOps(ctx)
 	if err != nil {
 		return nil, err
@@ -507,9 +589,40 @@ func (m *MultishareOpsManager) startShareExpandWorkflowSafe(ctx context.Context,
 	return m.startShareWorkflow(ctx, &Workflow{share: share, opType: util.ShareUpdate}, ops)
 }
 
+// startShareNfsExportOptionsUpdateWorkflowSafe starts a patch of the NFS export options of the given share.
+// A running update op on the share may be a resize, so instead of joining it the caller is asked to retry.
+func (m *MultishareOpsManager) startShareNfsExportOptionsUpdateWorkflowSafe(ctx context.Context, share *file.Share) (*Workflow, error) {
+	unlock, err := m.locks.lockShareInstance(share)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ops, err := m.listMultishareResourceRunningOps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.verifyNoRunningInstanceOps(share.Parent, ops)
+	if err != nil {
+		return nil, err
+	}
+	err = m.verifyNoRunningShareOps(share, ops)
+	if err != nil {
+		return nil, err
+	}
+	op, err := m.cloud.File.StartUpdateShareNfsExportOptionsOp(ctx, share)
+	if err != nil {
+		return nil, err
+	}
+	return &Workflow{share: share, opName: op.Name, opType: util.ShareUpdate}, nil
+}
+
 func (m *MultishareOpsManager) checkAndStartShareDeleteWorkflow(ctx context.Context, share *file.Share) (*Workflow, error) {
-	m.Lock()
-	defer m.Unlock()
+	unlock, err := m.locks.lockShareInstance(share)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	ops, err := m.listMultishareResourceRunningOps(ctx)
 	if err != nil {
@@ -529,8 +642,11 @@ func (m *MultishareOpsManager) checkAndStartShareDeleteWorkflow(ctx context.Cont
 }
 
 func (m *MultishareOpsManager) checkAndStartInstanceDeleteOrShrinkWorkflow(ctx context.Context, instance *file.MultishareInstance) (*Workflow, error) {
-	m.Lock()
-	defer m.Unlock()
+	unlock, err := m.locks.lockInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	ops, err := m.listMultishareResourceRunningOps(ctx)
 	if err != nil {
@@ -600,6 +716,29 @@ func (m *MultishareOpsManager) checkAndStartInstanceDeleteOrShrinkWorkflow(ctx c
 	return nil, nil
 }
 
+// checkAndStartEmptyInstanceDeleteWorkflow starts the deletion of an instance found empty by the multishare janitor,
+// once it checked under the lock that no share was placed on the instance and no op started on it since.
+func (m *MultishareOpsManager) checkAndStartEmptyInstanceDeleteWorkflow(ctx context.Context, instance *file.MultishareInstance) (*Workflow, error) {
+	unlock, err := m.locks.lockInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	ops, err := m.listMultishareResourceRunningOps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := m.cloud.File.ListShares(ctx, &file.ListFilter{Project: instance.Project, Location: instance.Location, InstanceName: instance.Name})
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) != 0 {
+		return nil, status.Errorf(codes.Aborted, "instance %s has %d shares", instance.String(), len(shares))
+	}
+	return m.startInstanceWorkflow(ctx, &Workflow{instance: instance, opType: util.InstanceDelete}, ops)
+}
+
 // listMultishareOps reports all running ops related to multishare instances and share resources. The op target is of the form "projects/<>/locations/<>/instances/<>" or "projects/<>/locations/<>/instances/<>/shares/<>"
 func (m *MultishareOpsManager) listMultishareResourceRunningOps(ctx context.Context) ([]*OpInfo, error) {
 	ops, err := m.cloud.File.ListOps(ctx, &file.ListFilter{Project: m.cloud.Project, Location: "-"})
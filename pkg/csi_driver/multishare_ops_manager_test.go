@@ -2476,7 +2476,7 @@ func TestRunEligibleInstanceCheck(t *testing.T) {
 				features:    tc.features,
 			}
 			mcs := NewMultishareController(config)
-			ready, err := mcs.opsManager.runEligibleInstanceCheck(context.Background(), tc.req, tc.ops, tc.target, testRegions)
+			ready, _, err := mcs.opsManager.runEligibleInstanceCheck(context.Background(), tc.req, tc.ops, tc.target, testRegions)
 			if err != nil && !tc.expectError {
 				t.Errorf("unexpected error")
 			}
@@ -80,6 +80,13 @@ func (m *MultishareStatefulController) CreateVolume(ctx context.Context, req *cs
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if _, err := getSharePlacementPolicy(req.GetParameters()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := parseWarmPoolSize(req.GetParameters()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	var reqBytes int64
 	if m.mc.featureMaxSharePerInstance {
 		reqBytes, err = getShareRequestCapacity(req.GetCapacityRange(), util.ConfigurablePackMinShareSizeBytes, maxShareSizeBytes)
@@ -205,10 +205,20 @@ func (s *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpub
 }
 
 func (s *nodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	zone := s.metaService.GetZone()
+	segments := map[string]string{TopologyKeyZone: zone}
+	// Regional and enterprise tier volumes are accessible from every zone of their region.
+	if s.features.regionTopologyEnabled() {
+		if region, err := util.GetRegionFromZone(zone); err == nil {
+			segments[TopologyKeyRegion] = region
+		} else {
+			klog.Warningf("Failed to get region from zone %v: %v", zone, err)
+		}
+	}
 	return &csi.NodeGetInfoResponse{
 		NodeId: s.driver.config.NodeName,
 		AccessibleTopology: &csi.Topology{
-			Segments: map[string]string{TopologyKeyZone: s.metaService.GetZone()},
+			Segments: segments,
 		},
 	}, nil
 }
@@ -603,8 +603,38 @@ func TestValidateVolumeAttributes(t *testing.T) {
 func TestNodeGetId(t *testing.T) {
 }
 
-// TODO
 func TestNodeGetInfo(t *testing.T) {
+	cases := []struct {
+		name           string
+		topologyRegion bool
+		expected       map[string]string
+	}{
+		{
+			name:     "zone only",
+			expected: map[string]string{TopologyKeyZone: "us-central1-c"},
+		},
+		{
+			name:           "zone and region",
+			topologyRegion: true,
+			expected: map[string]string{
+				TopologyKeyZone:   "us-central1-c",
+				TopologyKeyRegion: "us-central1",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			testEnv := initTestNodeServer(t)
+			testEnv.ns.(*nodeServer).features.FeatureTopologyRegion = &FeatureTopologyRegion{Enabled: tc.topologyRegion}
+			resp, err := testEnv.ns.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+			if err != nil {
+				t.Fatalf("NodeGetInfo failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, resp.GetAccessibleTopology().GetSegments()); diff != "" {
+				t.Errorf("unexpected topology segments (-want +got):\n%s", diff)
+			}
+		})
+	}
 }
 
 // TODO
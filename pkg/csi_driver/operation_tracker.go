@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	filev1beta1 "google.golang.org/api/file/v1beta1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+)
+
+const (
+	// opTrackerRetryInterval is how long the tracker waits before polling an operation
+	// again after polling failed.
+	opTrackerRetryInterval = 30 * time.Second
+	// opTrackerMaxPollDuration bounds how long an operation is polled. An operation still
+	// running by then stops being tracked, and is looked up again by the next request.
+	opTrackerMaxPollDuration = 6 * time.Hour
+	// opTrackerResultTTL is how long the result of a finished operation is kept for a retry
+	// of the request which started it. Requests which are not retried, e.g. because the PVC
+	// was deleted, would otherwise leave their operations tracked forever.
+	opTrackerResultTTL = time.Hour
+)
+
+// trackedOperation is a long-running operation polled in the background.
+type trackedOperation struct {
+	name string
+	// done is closed once the operation finished or stopped being polled, err holds its
+	// final error.
+	done chan struct{}
+	err  error
+	// expired is set if polling stopped before the operation finished.
+	expired bool
+}
+
+// operationTracker tracks long-running instance operations keyed by the name of the CSI
+// request that started them. Each operation is polled in the background until it is done,
+// so that retried requests can return quickly while the operation runs and pick up its
+// final result once it finished.
+type operationTracker struct {
+	mu  sync.Mutex
+	ops map[string]*trackedOperation
+
+	waitForOp       func(ctx context.Context, opName string) (*filev1beta1.Operation, error)
+	retryInterval   time.Duration
+	maxPollDuration time.Duration
+	resultTTL       time.Duration
+}
+
+func newOperationTracker(waitForOp func(ctx context.Context, opName string) (*filev1beta1.Operation, error)) *operationTracker {
+	return &operationTracker{
+		ops:             make(map[string]*trackedOperation),
+		waitForOp:       waitForOp,
+		retryInterval:   opTrackerRetryInterval,
+		maxPollDuration: opTrackerMaxPollDuration,
+		resultTTL:       opTrackerResultTTL,
+	}
+}
+
+// track starts polling the operation for the given key. If an operation is already tracked
+// for the key, the existing one is kept.
+func (t *operationTracker) track(key, opName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.ops[key]; ok {
+		return
+	}
+	op := &trackedOperation{name: opName, done: make(chan struct{})}
+	t.ops[key] = op
+	go t.poll(key, op)
+}
+
+func (t *operationTracker) poll(key string, op *trackedOperation) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.maxPollDuration)
+	defer cancel()
+	for {
+		finalOp, err := t.waitForOp(ctx, op.name)
+		if err == nil {
+			op.err = file.OperationError(finalOp)
+			break
+		}
+		if file.IsNotFoundErr(err) {
+			op.err = err
+			break
+		}
+		if ctx.Err() != nil {
+			op.expired = true
+			break
+		}
+		klog.Warningf("Polling operation %q for %q failed, retrying in %v: %v", op.name, key, t.retryInterval, err)
+		select {
+		case <-time.After(t.retryInterval):
+		case <-ctx.Done():
+		}
+	}
+
+	if op.expired {
+		klog.Warningf("Stopped polling operation %q for %q after %v", op.name, key, t.maxPollDuration)
+		t.remove(key, op)
+	} else {
+		klog.V(4).Infof("Operation %q for %q is done, error: %v", op.name, key, op.err)
+		// Evict the result if no retry of the request picks it up.
+		time.AfterFunc(t.resultTTL, func() { t.remove(key, op) })
+	}
+	close(op.done)
+}
+
+// remove stops tracking the operation for the given key, unless another operation has been
+// tracked for the key since.
+func (t *operationTracker) remove(key string, op *trackedOperation) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ops[key] == op {
+		delete(t.ops, key)
+	}
+}
+
+// opName returns the name of the operation tracked for the given key.
+func (t *operationTracker) opName(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	op, ok := t.ops[key]
+	if !ok {
+		return "", false
+	}
+	return op.name, true
+}
+
+// wait waits up to the timeout for the operation tracked for the given key. It returns
+// whether the operation is done and, if so, its final error. A finished operation stops
+// being tracked once its result has been returned, or once it stopped being polled.
+func (t *operationTracker) wait(ctx context.Context, key string, timeout time.Duration) (bool, error) {
+	t.mu.Lock()
+	op, ok := t.ops[key]
+	t.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-op.done:
+	case <-timer.C:
+		return false, nil
+	case <-ctx.Done():
+		return false, nil
+	}
+	if op.expired {
+		return false, nil
+	}
+
+	t.remove(key, op)
+	return true, op.err
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	filev1beta1 "google.golang.org/api/file/v1beta1"
+)
+
+func TestOperationTrackerEviction(t *testing.T) {
+	cases := []struct {
+		name string
+		// running keeps the operation running forever.
+		running bool
+	}{
+		{
+			name: "unobserved result is evicted",
+		},
+		{
+			name:    "polling stops after the max poll duration",
+			running: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newOperationTracker(func(ctx context.Context, opName string) (*filev1beta1.Operation, error) {
+				if tc.running {
+					<-ctx.Done()
+					return nil, fmt.Errorf("operation %v is not done", opName)
+				}
+				return &filev1beta1.Operation{Name: opName, Done: true}, nil
+			})
+			tracker.retryInterval = 10 * time.Millisecond
+			tracker.maxPollDuration = 50 * time.Millisecond
+			tracker.resultTTL = 50 * time.Millisecond
+
+			tracker.track("volume", "operation-1")
+			if _, tracked := tracker.opName("volume"); !tracked {
+				t.Fatalf("expected operation to be tracked")
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				if _, tracked := tracker.opName("volume"); !tracked {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("expected operation to stop being tracked")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if done, err := tracker.wait(context.Background(), "volume", time.Millisecond); done || err != nil {
+				t.Errorf("expected untracked operation not to be done, got done %v, error %v", done, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	v1 "sigs.k8s.io/gcp-filestore-csi-driver/pkg/apis/multishare/v1"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+const (
+	// ParamSharePlacementPolicy selects how the shares of a multishare StorageClass are placed on its instances.
+	ParamSharePlacementPolicy = "share-placement-policy"
+
+	placementPolicyFirstFit          = "first-fit"
+	placementPolicyBestFit           = "best-fit"
+	placementPolicySpread            = "spread"
+	placementPolicyNamespaceAffinity = "namespace-affinity"
+)
+
+// placementCandidate is an instance with a free share slot that a new share can be placed on.
+type placementCandidate struct {
+	name          string
+	capacityBytes int64
+	// usedBytes is the capacity of the shares of the instance, including expansions that are still pending.
+	usedBytes  int64
+	shareCount int
+	// namespaces counts the shares of the instance per PVC namespace.
+	namespaces map[string]int
+}
+
+// headroom returns the capacity of the instance that is not used by its shares.
+func (c *placementCandidate) headroom() int64 {
+	return c.capacityBytes - c.usedBytes
+}
+
+// placementShare is the share to place.
+type placementShare struct {
+	capacityBytes int64
+	namespace     string
+}
+
+// sharePlacementPolicy picks the instance a new share is placed on.
+type sharePlacementPolicy interface {
+	// pick returns the index of the candidate to place the share on. candidates is never empty.
+	pick(share *placementShare, candidates []*placementCandidate) int
+}
+
+// getSharePlacementPolicy returns the placement policy selected by the StorageClass parameters.
+// Shares are placed on a random candidate if the parameter is not set.
+func getSharePlacementPolicy(params map[string]string) (sharePlacementPolicy, error) {
+	v, ok := params[ParamSharePlacementPolicy]
+	if !ok {
+		return randomPlacement{}, nil
+	}
+	switch v {
+	case placementPolicyFirstFit:
+		return firstFitPlacement{}, nil
+	case placementPolicyBestFit:
+		return bestFitPlacement{}, nil
+	case placementPolicySpread:
+		return spreadPlacement{}, nil
+	case placementPolicyNamespaceAffinity:
+		return namespaceAffinityPlacement{}, nil
+	default:
+		return nil, fmt.Errorf("invalid %q parameter %q, must be one of %q, %q, %q or %q", ParamSharePlacementPolicy, v, placementPolicyFirstFit, placementPolicyBestFit, placementPolicySpread, placementPolicyNamespaceAffinity)
+	}
+}
+
+type randomPlacement struct{}
+
+func (randomPlacement) pick(_ *placementShare, candidates []*placementCandidate) int {
+	return rand.Intn(len(candidates))
+}
+
+// firstFitPlacement places the share on the first candidate, in the order of the instance names.
+type firstFitPlacement struct{}
+
+func (firstFitPlacement) pick(_ *placementShare, _ []*placementCandidate) int {
+	return 0
+}
+
+// bestFitPlacement places the share on the candidate with the least headroom that fits it without
+// an expansion, or on the candidate with the most headroom if none does.
+type bestFitPlacement struct{}
+
+func (bestFitPlacement) pick(share *placementShare, candidates []*placementCandidate) int {
+	best := -1
+	for i, c := range candidates {
+		if c.headroom() >= share.capacityBytes && (best < 0 || c.headroom() < candidates[best].headroom()) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+	return mostHeadroom(candidates)
+}
+
+// spreadPlacement places the share on the candidate with the fewest shares, breaking ties by headroom.
+type spreadPlacement struct{}
+
+func (spreadPlacement) pick(_ *placementShare, candidates []*placementCandidate) int {
+	best := 0
+	for i, c := range candidates[1:] {
+		b := candidates[best]
+		if c.shareCount < b.shareCount || (c.shareCount == b.shareCount && c.headroom() > b.headroom()) {
+			best = i + 1
+		}
+	}
+	return best
+}
+
+// namespaceAffinityPlacement places the share on the candidate with the most shares of the same PVC
+// namespace, and spreads the shares of namespaces that have none.
+type namespaceAffinityPlacement struct{}
+
+func (namespaceAffinityPlacement) pick(share *placementShare, candidates []*placementCandidate) int {
+	best := -1
+	if share.namespace != "" {
+		for i, c := range candidates {
+			if c.namespaces[share.namespace] > 0 && (best < 0 || c.namespaces[share.namespace] > candidates[best].namespaces[share.namespace]) {
+				best = i
+			}
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+	return spreadPlacement{}.pick(share, candidates)
+}
+
+func mostHeadroom(candidates []*placementCandidate) int {
+	best := 0
+	for i, c := range candidates[1:] {
+		if c.headroom() > candidates[best].headroom() {
+			best = i + 1
+		}
+	}
+	return best
+}
+
+// sortPlacementCandidates sorts the candidates by instance name, so that the policies break ties the same way on every call.
+func sortPlacementCandidates(candidates []*placementCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].name < candidates[j].name
+	})
+}
+
+// newInstancePlacementCandidate returns the placement candidate of a multishare instance with the given shares.
+func newInstancePlacementCandidate(instance *file.MultishareInstance, shares []*file.Share) *placementCandidate {
+	name, _ := file.GenerateMultishareInstanceURI(instance)
+	c := &placementCandidate{
+		name:          name,
+		capacityBytes: instance.CapacityBytes,
+		shareCount:    len(shares),
+		namespaces:    make(map[string]int),
+	}
+	for _, s := range shares {
+		c.usedBytes += s.CapacityBytes
+		if ns := s.Labels[tagKeyCreatedForClaimNamespace]; ns != "" {
+			c.namespaces[ns]++
+		}
+	}
+	return c
+}
+
+// newInstanceInfoPlacementCandidate returns the placement candidate of an instanceInfo. The capacity of
+// the instance and its shares are the requested ones, so pending expansions are accounted for.
+func newInstanceInfoPlacementCandidate(instanceInfo *v1.InstanceInfo, shareInfos map[string]*v1.ShareInfo) *placementCandidate {
+	c := &placementCandidate{
+		name:          util.InstanceInfoNameToInstanceURI(instanceInfo.Name),
+		capacityBytes: instanceInfo.Spec.CapacityBytes,
+		namespaces:    make(map[string]int),
+	}
+	if instanceInfo.Status == nil {
+		return c
+	}
+	c.shareCount = len(instanceInfo.Status.ShareNames)
+	for _, shareName := range instanceInfo.Status.ShareNames {
+		shareInfo, ok := shareInfos[shareName]
+		if !ok {
+			continue
+		}
+		c.usedBytes += shareInfo.Spec.CapacityBytes
+		if ns := shareInfo.Spec.Parameters[ParameterKeyPVCNamespace]; ns != "" {
+			c.namespaces[ns]++
+		}
+	}
+	return c
+}
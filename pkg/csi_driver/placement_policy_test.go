@@ -0,0 +1,367 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "sigs.k8s.io/gcp-filestore-csi-driver/pkg/apis/multishare/v1"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/client/clientset/versioned/fake"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+const testPlacementNamespace = "team-a"
+
+func TestGetSharePlacementPolicy(t *testing.T) {
+	cases := []struct {
+		name        string
+		params      map[string]string
+		expected    sharePlacementPolicy
+		expectError bool
+	}{
+		{
+			name:     "unset",
+			expected: randomPlacement{},
+		},
+		{
+			name:     "first fit",
+			params:   map[string]string{ParamSharePlacementPolicy: placementPolicyFirstFit},
+			expected: firstFitPlacement{},
+		},
+		{
+			name:     "best fit",
+			params:   map[string]string{ParamSharePlacementPolicy: placementPolicyBestFit},
+			expected: bestFitPlacement{},
+		},
+		{
+			name:     "spread",
+			params:   map[string]string{ParamSharePlacementPolicy: placementPolicySpread},
+			expected: spreadPlacement{},
+		},
+		{
+			name:     "namespace affinity",
+			params:   map[string]string{ParamSharePlacementPolicy: placementPolicyNamespaceAffinity},
+			expected: namespaceAffinityPlacement{},
+		},
+		{
+			name:        "invalid",
+			params:      map[string]string{ParamSharePlacementPolicy: "worst-fit"},
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := getSharePlacementPolicy(tc.params)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if policy != tc.expected {
+				t.Errorf("got policy %T, expected %T", policy, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSharePlacementPolicies(t *testing.T) {
+	candidates := []*placementCandidate{
+		{name: "a", capacityBytes: 1024 * util.Gb, usedBytes: 900 * util.Gb, shareCount: 3},
+		{name: "b", capacityBytes: 1024 * util.Gb, usedBytes: 500 * util.Gb, shareCount: 2, namespaces: map[string]int{testPlacementNamespace: 1}},
+		{name: "c", capacityBytes: 2048 * util.Gb, usedBytes: 100 * util.Gb, shareCount: 2},
+		{name: "d", capacityBytes: 1024 * util.Gb, usedBytes: 824 * util.Gb, shareCount: 4, namespaces: map[string]int{testPlacementNamespace: 3}},
+	}
+	cases := []struct {
+		name     string
+		policy   sharePlacementPolicy
+		share    *placementShare
+		expected string
+	}{
+		{
+			name:     "first fit",
+			policy:   firstFitPlacement{},
+			share:    &placementShare{capacityBytes: 100 * util.Gb},
+			expected: "a",
+		},
+		{
+			name:     "best fit",
+			policy:   bestFitPlacement{},
+			share:    &placementShare{capacityBytes: 150 * util.Gb},
+			expected: "d",
+		},
+		{
+			name:     "best fit with exact headroom",
+			policy:   bestFitPlacement{},
+			share:    &placementShare{capacityBytes: 124 * util.Gb},
+			expected: "a",
+		},
+		{
+			name:     "best fit without headroom",
+			policy:   bestFitPlacement{},
+			share:    &placementShare{capacityBytes: 4096 * util.Gb},
+			expected: "c",
+		},
+		{
+			name:     "spread breaks ties by headroom",
+			policy:   spreadPlacement{},
+			share:    &placementShare{capacityBytes: 100 * util.Gb},
+			expected: "c",
+		},
+		{
+			name:     "namespace affinity",
+			policy:   namespaceAffinityPlacement{},
+			share:    &placementShare{capacityBytes: 100 * util.Gb, namespace: testPlacementNamespace},
+			expected: "d",
+		},
+		{
+			name:     "namespace affinity without shares of the namespace",
+			policy:   namespaceAffinityPlacement{},
+			share:    &placementShare{capacityBytes: 100 * util.Gb, namespace: "team-b"},
+			expected: "c",
+		},
+		{
+			name:     "namespace affinity without namespace",
+			policy:   namespaceAffinityPlacement{},
+			share:    &placementShare{capacityBytes: 100 * util.Gb},
+			expected: "c",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := candidates[tc.policy.pick(tc.share, candidates)].name; got != tc.expected {
+				t.Errorf("got candidate %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestPlaceShare(t *testing.T) {
+	labels := map[string]string{
+		util.ParamMultishareInstanceScLabelKey: testInstanceScPrefix,
+		TagKeyClusterLocation:                  testLocation,
+		TagKeyClusterName:                      testClusterName,
+	}
+	newInstance := func(name string, capacityBytes int64) *file.MultishareInstance {
+		return &file.MultishareInstance{
+			Name:          name,
+			Project:       testProject,
+			Location:      testRegion,
+			Labels:        labels,
+			State:         "READY",
+			CapacityBytes: capacityBytes,
+		}
+	}
+	instances := []*file.MultishareInstance{
+		newInstance("instance-1", 1024*util.Gb),
+		newInstance("instance-2", 2048*util.Gb),
+		newInstance("instance-3", 1024*util.Gb),
+	}
+	newShare := func(name string, parent *file.MultishareInstance, capacityBytes int64, namespace string) *file.Share {
+		return &file.Share{
+			Name:          name,
+			Parent:        parent,
+			CapacityBytes: capacityBytes,
+			Labels:        map[string]string{tagKeyCreatedForClaimNamespace: namespace},
+		}
+	}
+	shares := []*file.Share{
+		newShare("share-1", instances[0], 900*util.Gb, "team-b"),
+		newShare("share-2", instances[1], 100*util.Gb, "team-b"),
+		newShare("share-3", instances[1], 100*util.Gb, "team-b"),
+		newShare("share-4", instances[2], 500*util.Gb, testPlacementNamespace),
+	}
+
+	cases := []struct {
+		name     string
+		policy   string
+		expected string
+	}{
+		{
+			name:     "first fit",
+			policy:   placementPolicyFirstFit,
+			expected: "instance-1",
+		},
+		{
+			name:     "best fit",
+			policy:   placementPolicyBestFit,
+			expected: "instance-1",
+		},
+		{
+			name:     "spread",
+			policy:   placementPolicySpread,
+			expected: "instance-3",
+		},
+		{
+			name:     "namespace affinity",
+			policy:   placementPolicyNamespaceAffinity,
+			expected: "instance-3",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := file.NewFakeServiceForMultishare(instances, shares, nil)
+			if err != nil {
+				t.Fatalf("failed to fake service: %v", err)
+			}
+			cloudProvider, _ := cloud.NewFakeCloud()
+			cloudProvider.File = s
+			mcs := NewMultishareController(&controllerServerConfig{
+				driver:      initTestDriver(t),
+				fileService: s,
+				cloud:       cloudProvider,
+			})
+			req := &csi.CreateVolumeRequest{
+				CapacityRange: &csi.CapacityRange{
+					RequiredBytes: 100 * util.Gb,
+				},
+				Parameters: map[string]string{
+					ParamMultishareInstanceScLabel: testInstanceScPrefix,
+					ParamSharePlacementPolicy:      tc.policy,
+					ParameterKeyPVCNamespace:       testPlacementNamespace,
+				},
+			}
+			target := newInstance("test-target-instance", 0)
+			eligible, instanceShares, err := mcs.opsManager.runEligibleInstanceCheck(context.Background(), req, nil, target, []string{testRegion})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			instance, err := mcs.opsManager.placeShare(req, eligible, instanceShares)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if instance.Name != tc.expected {
+				t.Errorf("got instance %q, expected %q", instance.Name, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAssignSharesPlacementPolicy(t *testing.T) {
+	newInstanceInfo := func(name string, capacityBytes int64, shareNames ...string) *v1.InstanceInfo {
+		return &v1.InstanceInfo{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   util.InstanceURIToInstanceInfoName(instanceURI(testProject, testRegion, name)),
+				Labels: map[string]string{ParamMultishareInstanceScLabel: testInstanceScPrefix},
+			},
+			Spec: v1.InstanceInfoSpec{
+				CapacityBytes: capacityBytes,
+			},
+			Status: &v1.InstanceInfoStatus{
+				ShareNames: shareNames,
+			},
+		}
+	}
+	newShareInfo := func(name string, capacityBytes int64, params map[string]string) *v1.ShareInfo {
+		return &v1.ShareInfo{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1.ShareInfoSpec{
+				ShareName:       name,
+				CapacityBytes:   capacityBytes,
+				Region:          testRegion,
+				InstancePoolTag: testInstanceScPrefix,
+				Parameters:      params,
+			},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		policy   string
+		expected string
+	}{
+		{
+			name:     "first fit",
+			policy:   placementPolicyFirstFit,
+			expected: "instance-1",
+		},
+		{
+			name:     "best fit",
+			policy:   placementPolicyBestFit,
+			expected: "instance-1",
+		},
+		{
+			name:     "spread",
+			policy:   placementPolicySpread,
+			expected: "instance-2",
+		},
+		{
+			name:     "namespace affinity",
+			policy:   placementPolicyNamespaceAffinity,
+			expected: "instance-3",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			shareInfos := map[string]*v1.ShareInfo{
+				// share-1 has a pending expansion to 900Gi.
+				"share-1": newShareInfo("share-1", 900*util.Gb, nil),
+				"share-2": newShareInfo("share-2", 100*util.Gb, nil),
+				"share-3": newShareInfo("share-3", 500*util.Gb, map[string]string{ParameterKeyPVCNamespace: testPlacementNamespace}),
+				"share-4": newShareInfo("share-4", 100*util.Gb, nil),
+				"new-share": newShareInfo("new-share", 100*util.Gb, map[string]string{
+					ParamSharePlacementPolicy: tc.policy,
+					ParameterKeyPVCNamespace:  testPlacementNamespace,
+				}),
+			}
+			for _, name := range []string{"share-1", "share-2", "share-3", "share-4"} {
+				shareInfos[name].Status = &v1.ShareInfoStatus{InstanceHandle: "assigned"}
+			}
+			instanceInfos := make(map[string]*v1.InstanceInfo)
+			for _, instanceInfo := range []*v1.InstanceInfo{
+				newInstanceInfo("instance-1", 1024*util.Gb, "share-1"),
+				newInstanceInfo("instance-2", 2048*util.Gb, "share-2"),
+				newInstanceInfo("instance-3", 1024*util.Gb, "share-3", "share-4"),
+			} {
+				if _, err := client.MultishareV1().InstanceInfos(util.ManagedFilestoreCSINamespace).Create(context.TODO(), instanceInfo, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("failed to create instanceInfo: %v", err)
+				}
+				instanceInfos[util.InstanceInfoNameToInstanceURI(instanceInfo.Name)] = instanceInfo
+			}
+			if _, err := client.MultishareV1().ShareInfos(util.ManagedFilestoreCSINamespace).Create(context.TODO(), shareInfos["new-share"], metav1.CreateOptions{}); err != nil {
+				t.Fatalf("failed to create shareInfo: %v", err)
+			}
+
+			config := &controllerServerConfig{driver: initTestDriver(t)}
+			config.multiShareController = NewMultishareController(config)
+			recon := &MultishareReconciler{
+				clientset:        client,
+				controllerServer: &controllerServer{config: config},
+			}
+			recon.assignSharesToEligibleOrNewInstances(shareInfos, instanceInfos, map[string][]*file.Share{})
+
+			expectedURI := instanceURI(testProject, testRegion, tc.expected)
+			if shareInfos["new-share"].Status == nil || shareInfos["new-share"].Status.InstanceHandle != expectedURI {
+				t.Fatalf("got shareInfo status %+v, expected instance handle %q", shareInfos["new-share"].Status, expectedURI)
+			}
+			if shareNames := instanceInfos[expectedURI].Status.ShareNames; shareNames[len(shareNames)-1] != "new-share" {
+				t.Errorf("got instanceInfo shares %v, expected new-share to be assigned", shareNames)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -470,6 +471,7 @@ func (recon *MultishareReconciler) generateNewMultishareInstance(instanceInfo *v
 		case ParamReservedIPV4CIDR, ParamReservedIPRange:
 		case cloud.ParameterKeyResourceTags:
 		case ParamMultishareInstanceScLabel, ParameterKeyLabels, ParameterKeyPVCName, ParameterKeyPVCNamespace, ParameterKeyPVName, paramMultishare:
+		case ParamSharePlacementPolicy, ParamWarmPoolSize:
 		case "csiprovisionersecretname", "csiprovisionersecretnamespace":
 		default:
 			klog.Errorf("Ignoring invalid parameter %q", k)
@@ -549,9 +551,12 @@ func (recon *MultishareReconciler) assignSharesToInstances(shareInfos map[string
 
 	recon.assignSharesToEligibleOrNewInstances(shareInfos, instanceInfos, instanceShares)
 
+	warmPools := recon.warmPoolSizes()
+	recon.fillWarmPools(instanceInfos, warmPools)
+
 	// Have to call deleteOrResizeInstances() after assigning shares and/or fixing two way pointers because no resizing were attempted in
 	// assignSharesToEligibleOrNewInstances() or fixTwoWayPointers()
-	recon.deleteOrResizeInstances(instanceInfos)
+	recon.deleteOrResizeInstances(instanceInfos, warmPools)
 }
 
 // fixTwoWayPointers scans over all instanceInfo objects and try to fix the 2 way pointer between instanceInfo and shareInfo objects.
@@ -645,8 +650,13 @@ func (recon *MultishareReconciler) assignSharesToEligibleOrNewInstances(shareInf
 				continue
 			}
 
-			var instanceURI string
-			var err error
+			policy, err := getSharePlacementPolicy(shareInfo.Spec.Parameters)
+			if err != nil {
+				klog.Errorf("Cannot place share %q: %v", shareInfo.Name, err)
+				continue
+			}
+
+			var candidates []*placementCandidate
 			for _, instanceInfo := range instanceInfos {
 				_, ok := instanceShares[util.InstanceInfoNameToInstanceURI(instanceInfo.Name)]
 				if !ok && instanceInfo.Status != nil && instanceInfo.Status.InstanceStatus != "" {
@@ -654,19 +664,25 @@ func (recon *MultishareReconciler) assignSharesToEligibleOrNewInstances(shareInf
 					klog.Warningf("instanceInfo %s has non empty InstanceStatus but underlying instance does not exist. Skip assignment to that instance", instanceInfo.Name)
 				}
 				if recon.instanceFitShare(instanceInfo, shareInfo) {
-					instanceURI = util.InstanceInfoNameToInstanceURI(instanceInfo.Name)
-					instanceInfo, err = recon.assignShareToInstanceInfo(instanceInfo, shareInfo.Name)
-					if err != nil {
-						klog.Errorf("Failed to add share %q to instanceInfo %q: %v", shareInfo.Name, instanceInfo.Name, err)
-						continue
-					}
-					klog.Infof("Share %q is now assigned to instance %q", shareInfo.Name, instanceURI)
-					instanceInfos[instanceURI] = instanceInfo
-					break
+					candidates = append(candidates, newInstanceInfoPlacementCandidate(instanceInfo, shareInfos))
 				}
 			}
-			if err != nil {
-				continue
+
+			var instanceURI string
+			if len(candidates) > 0 {
+				sortPlacementCandidates(candidates)
+				share := &placementShare{
+					capacityBytes: shareInfo.Spec.CapacityBytes,
+					namespace:     shareInfo.Spec.Parameters[ParameterKeyPVCNamespace],
+				}
+				instanceURI = candidates[policy.pick(share, candidates)].name
+				instanceInfo, err := recon.assignShareToInstanceInfo(instanceInfos[instanceURI], shareInfo.Name)
+				if err != nil {
+					klog.Errorf("Failed to add share %q to instanceInfo %q: %v", shareInfo.Name, instanceInfos[instanceURI].Name, err)
+					continue
+				}
+				klog.Infof("Share %q is now assigned to instance %q", shareInfo.Name, instanceURI)
+				instanceInfos[instanceURI] = instanceInfo
 			}
 
 			if instanceURI == "" {
@@ -703,13 +719,24 @@ func (recon *MultishareReconciler) assignSharesToEligibleOrNewInstances(shareInf
 }
 
 // deleteOrResizeInstances takes a map of instanceUri -> instanceInfos and
-// 1) add DeletionTimestamp for any instanceInfo that's empty (doesn't have share assigned to it).
+// 1) add DeletionTimestamp for any instanceInfo that's empty (doesn't have share assigned to it), beyond the warm pool of its storage class.
 // 2) calculates and updates the minimum viable Spec.CapacityBytes for instanceInfos that are not empty.
-func (recon *MultishareReconciler) deleteOrResizeInstances(instanceInfos map[string]*v1.InstanceInfo) {
-	for instanceURI, instanceInfo := range instanceInfos {
+func (recon *MultishareReconciler) deleteOrResizeInstances(instanceInfos map[string]*v1.InstanceInfo, warmPools map[string]int) {
+	instanceURIs := make([]string, 0, len(instanceInfos))
+	for instanceURI := range instanceInfos {
+		instanceURIs = append(instanceURIs, instanceURI)
+	}
+	// Keep the same instances in the warm pools on every round.
+	sort.Strings(instanceURIs)
+	for _, instanceURI := range instanceURIs {
+		instanceInfo := instanceInfos[instanceURI]
 		if instanceInfo.DeletionTimestamp != nil {
 			continue
 		}
+		if instanceUnassigned(instanceInfo) && warmPools[instanceInfo.Labels[ParamMultishareInstanceScLabel]] > 0 {
+			warmPools[instanceInfo.Labels[ParamMultishareInstanceScLabel]]--
+			continue
+		}
 
 		instanceInfoClone := instanceInfo.DeepCopy()
 		var updated bool
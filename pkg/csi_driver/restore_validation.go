@@ -0,0 +1,74 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// restoreTarget describes the instance, or multishare share, a volume is restored to.
+type restoreTarget struct {
+	tier          string
+	protocol      string
+	kmsKeyName    string
+	capacityBytes int64
+}
+
+// validateRestoreSource checks up front that a volume can be restored from the backup, instead
+// of failing later in the instance creation with a less precise error. A backup can only be
+// restored to:
+//   - an instance or share at least as large as its source share,
+//   - a basic tier instance for backups of basic tier instances, and an instance of another
+//     tier for backups of other tiers,
+//   - an instance with the file system protocol of its source instance,
+//   - an instance encrypted with a KMS key, if the backup is.
+func validateRestoreSource(backup *file.Backup, target restoreTarget) error {
+	backupBytes := util.GbToBytes(backup.Backup.CapacityGb)
+	if target.capacityBytes < backupBytes {
+		return status.Errorf(codes.OutOfRange, "requested capacity %d bytes is smaller than the %d GiB capacity of the source share of backup %s, request at least %d bytes", target.capacityBytes, backup.Backup.CapacityGb, backup.Backup.Name, backupBytes)
+	}
+
+	if sourceTier := strings.ToLower(backup.Backup.SourceInstanceTier); sourceTier != "" {
+		if isBasicTier(sourceTier) != isBasicTier(strings.ToLower(target.tier)) {
+			return status.Errorf(codes.InvalidArgument, "backup %s of a %s tier instance cannot be restored to a %s tier instance, backups of basic tier instances (%s, %s, %s, %s) can only be restored to basic tier instances and backups of other tiers to instances of other tiers", backup.Backup.Name, sourceTier, target.tier, defaultTier, premiumTier, basicHDDTier, basicSSDTier)
+		}
+	}
+
+	sourceProtocol := backup.FileSystemProtocl
+	if sourceProtocol == "" {
+		sourceProtocol = backup.Backup.FileSystemProtocol
+	}
+	if sourceProtocol != "" {
+		protocol := target.protocol
+		if protocol == "" {
+			protocol = v3FileProtocol
+		}
+		if !strings.EqualFold(sourceProtocol, protocol) {
+			return status.Errorf(codes.InvalidArgument, "backup %s of an %s instance cannot be restored to an %s instance, set the %q parameter to %q", backup.Backup.Name, sourceProtocol, protocol, paramFileProtocol, sourceProtocol)
+		}
+	}
+
+	if kmsKeyName := backup.Backup.KmsKeyName; kmsKeyName != "" && target.kmsKeyName == "" {
+		return status.Errorf(codes.InvalidArgument, "backup %s is encrypted with KMS key %s and can only be restored to an encrypted instance, set the %q parameter", backup.Backup.Name, kmsKeyName, ParamInstanceEncryptionKmsKey)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	filev1beta1 "google.golang.org/api/file/v1beta1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+func TestValidateRestoreSource(t *testing.T) {
+	testKey := "projects/test-project/locations/us-central1/keyRings/ring/cryptoKeys/key"
+	cases := []struct {
+		name       string
+		backup     filev1beta1.Backup
+		target     restoreTarget
+		expectCode codes.Code
+	}{
+		{
+			name:   "same size",
+			backup: filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "BASIC_HDD"},
+			target: restoreTarget{tier: defaultTier, capacityBytes: 1 * util.Tb},
+		},
+		{
+			name:   "larger volume",
+			backup: filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "ENTERPRISE"},
+			target: restoreTarget{tier: zonalTier, capacityBytes: 2 * util.Tb},
+		},
+		{
+			name:   "unknown source tier and protocol",
+			backup: filev1beta1.Backup{CapacityGb: 1024},
+			target: restoreTarget{tier: enterpriseTier, protocol: v4_1FileProtocol, capacityBytes: 1 * util.Tb},
+		},
+		{
+			name:       "smaller volume",
+			backup:     filev1beta1.Backup{CapacityGb: 2048, SourceInstanceTier: "BASIC_HDD"},
+			target:     restoreTarget{tier: defaultTier, capacityBytes: 1 * util.Tb},
+			expectCode: codes.OutOfRange,
+		},
+		{
+			name:       "basic to enterprise",
+			backup:     filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "BASIC_SSD"},
+			target:     restoreTarget{tier: enterpriseTier, capacityBytes: 1 * util.Tb},
+			expectCode: codes.InvalidArgument,
+		},
+		{
+			name:       "zonal to basic",
+			backup:     filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "ZONAL"},
+			target:     restoreTarget{tier: premiumTier, capacityBytes: 1 * util.Tb},
+			expectCode: codes.InvalidArgument,
+		},
+		{
+			name:   "same protocol",
+			backup: filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "ZONAL", FileSystemProtocol: v4_1FileProtocol},
+			target: restoreTarget{tier: zonalTier, protocol: v4_1FileProtocol, capacityBytes: 1 * util.Tb},
+		},
+		{
+			name:   "default protocol",
+			backup: filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "BASIC_HDD", FileSystemProtocol: v3FileProtocol},
+			target: restoreTarget{tier: defaultTier, capacityBytes: 1 * util.Tb},
+		},
+		{
+			name:       "NFSv4.1 to NFSv3",
+			backup:     filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "ZONAL", FileSystemProtocol: v4_1FileProtocol},
+			target:     restoreTarget{tier: zonalTier, capacityBytes: 1 * util.Tb},
+			expectCode: codes.InvalidArgument,
+		},
+		{
+			name:   "encrypted backup to encrypted instance",
+			backup: filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "ENTERPRISE", KmsKeyName: testKey},
+			target: restoreTarget{tier: enterpriseTier, kmsKeyName: testKey, capacityBytes: 1 * util.Tb},
+		},
+		{
+			name:   "backup to encrypted instance",
+			backup: filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "ENTERPRISE"},
+			target: restoreTarget{tier: enterpriseTier, kmsKeyName: testKey, capacityBytes: 1 * util.Tb},
+		},
+		{
+			name:       "encrypted backup to instance",
+			backup:     filev1beta1.Backup{CapacityGb: 1024, SourceInstanceTier: "ENTERPRISE", KmsKeyName: testKey},
+			target:     restoreTarget{tier: enterpriseTier, capacityBytes: 1 * util.Tb},
+			expectCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			backup := tc.backup
+			backup.Name = "projects/test-project/locations/us-central1/backups/mybackup"
+			err := validateRestoreSource(&file.Backup{Backup: &backup}, tc.target)
+			if status.Code(err) != tc.expectCode {
+				t.Errorf("got error %v, expected code %v", err, tc.expectCode)
+			}
+		})
+	}
+}
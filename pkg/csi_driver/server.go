@@ -31,7 +31,7 @@ import (
 // Defines Non blocking GRPC server interfaces
 type NonBlockingGRPCServer interface {
 	// Start services at the endpoint
-	Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer)
+	Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer)
 	// Waits for the service to stop
 	Wait()
 	// Stops the service gracefully
@@ -50,11 +50,11 @@ type nonBlockingGRPCServer struct {
 	server *grpc.Server
 }
 
-func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer) {
 
 	s.wg.Add(1)
 
-	go s.serve(endpoint, ids, cs, ns)
+	go s.serve(endpoint, ids, cs, gcs, ns)
 
 	return
 }
@@ -71,7 +71,7 @@ func (s *nonBlockingGRPCServer) ForceStop() {
 	s.server.Stop()
 }
 
-func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer) {
 	u, err := url.Parse(endpoint)
 	if err != nil {
 		klog.Fatal(err.Error())
@@ -107,6 +107,9 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, c
 	if cs != nil {
 		csi.RegisterControllerServer(server, cs)
 	}
+	if gcs != nil {
+		csi.RegisterGroupControllerServer(server, gcs)
+	}
 	if ns != nil {
 		csi.RegisterNodeServer(server, ns)
 	}
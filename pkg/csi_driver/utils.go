@@ -19,6 +19,8 @@ package driver
 import (
 	"fmt"
 	"net"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -44,6 +46,20 @@ const (
 	MinDensityLargeCapacity = int64(3000)
 	MaxDensityLargeCapacity = int64(7500)
 	MinIOPS                 = int64(2000)
+
+	// ParamMutableNfsExportOptions is the mutable counterpart of nfs-export-options-on-create.
+	ParamMutableNfsExportOptions = "nfs-export-options"
+
+	// Deletion protection parameters, accepted both as StorageClass and as mutable parameters.
+	ParamDeletionProtectionEnabled = "deletion-protection-enabled"
+	ParamDeletionProtectionReason  = "deletion-protection-reason"
+)
+
+// Defaults applied by Filestore to the NFS export options fields which are not set.
+const (
+	defaultNfsAccessMode = "READ_WRITE"
+	defaultNfsSquashMode = "NO_ROOT_SQUASH"
+	defaultNfsAnonID     = int64(65534)
 )
 
 func NewVolumeCapabilityAccessMode(mode csi.VolumeCapability_AccessMode_Mode) *csi.VolumeCapability_AccessMode {
@@ -96,6 +112,19 @@ func IsCIDR(ipRange string) bool {
 	return err == nil
 }
 
+// validateNetwork verifies that the network is either a network name, or the resource name
+// of a network in a Shared VPC host project: projects/{project}/global/networks/{network}.
+func validateNetwork(network string) error {
+	if !strings.Contains(network, "/") {
+		return nil
+	}
+	tokens := strings.Split(network, "/")
+	if len(tokens) != 5 || tokens[0] != "projects" || tokens[1] == "" || tokens[2] != "global" || tokens[3] != "networks" || tokens[4] == "" {
+		return fmt.Errorf("network %q must be a network name or of the form projects/{project}/global/networks/{network}", network)
+	}
+	return nil
+}
+
 // bytesToTiB converts bytes to TiB with floating point precision
 func bytesToTiB(bytes int64) float64 {
 	return float64(bytes) / (1024.0 * 1024.0 * 1024.0 * 1024.0)
@@ -131,6 +160,100 @@ func getMaxTotalIOPS(tier string) int64 {
 	return MaxIOPSZonal
 }
 
+// parseMutableNfsExportOptions returns the NFS export options set in the mutable parameters,
+// or nil if they are not set. Unlike on create, an empty list is rejected so that access rules
+// can't be dropped by mistake.
+func parseMutableNfsExportOptions(params map[string]string) ([]*file.NfsExportOptions, error) {
+	v, ok := params[ParamMutableNfsExportOptions]
+	if !ok {
+		return nil, nil
+	}
+	options, err := parseNfsExportOptions(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s %s: %v", ParamMutableNfsExportOptions, v, err)
+	}
+	if len(options) == 0 {
+		return nil, fmt.Errorf("%s must contain at least one export option", ParamMutableNfsExportOptions)
+	}
+	return options, nil
+}
+
+// equalNfsExportOptions returns true if the NFS export options are the same once the defaults
+// applied by Filestore are filled in, so that options read back from an instance or share
+// compare equal to the parameters they were set from.
+func equalNfsExportOptions(a, b []*file.NfsExportOptions) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(normalizeNfsExportOptions(a[i]), normalizeNfsExportOptions(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeNfsExportOptions(o *file.NfsExportOptions) file.NfsExportOptions {
+	var normalized file.NfsExportOptions
+	if o != nil {
+		normalized = *o
+	}
+	normalized.IpRanges = slices.Clone(normalized.IpRanges)
+	slices.Sort(normalized.IpRanges)
+	if normalized.AccessMode == "" {
+		normalized.AccessMode = defaultNfsAccessMode
+	}
+	if normalized.SquashMode == "" {
+		normalized.SquashMode = defaultNfsSquashMode
+	}
+	if normalized.SquashMode == defaultNfsSquashMode {
+		// The anonymous ids only apply to root squashing.
+		normalized.AnonUid, normalized.AnonGid = 0, 0
+	} else {
+		if normalized.AnonUid == 0 {
+			normalized.AnonUid = defaultNfsAnonID
+		}
+		if normalized.AnonGid == 0 {
+			normalized.AnonGid = defaultNfsAnonID
+		}
+	}
+	return normalized
+}
+
+// parseDeletionProtection returns the deletion protection set in the parameters, or nil if
+// none of the deletion protection parameters is set.
+func parseDeletionProtection(params map[string]string) (*file.DeletionProtection, error) {
+	var enabledStr, reason string
+	var hasEnabled, hasReason bool
+	for k, v := range params {
+		switch strings.ToLower(k) {
+		case ParamDeletionProtectionEnabled:
+			enabledStr, hasEnabled = v, true
+		case ParamDeletionProtectionReason:
+			reason, hasReason = v, true
+		}
+	}
+	if !hasEnabled && !hasReason {
+		return nil, nil
+	}
+	if !hasEnabled {
+		return nil, fmt.Errorf("%s requires %s to be set", ParamDeletionProtectionReason, ParamDeletionProtectionEnabled)
+	}
+	enabled, err := strconv.ParseBool(enabledStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %v", ParamDeletionProtectionEnabled, enabledStr, err)
+	}
+	if !enabled && reason != "" {
+		return nil, fmt.Errorf("%s can only be set when %s is true", ParamDeletionProtectionReason, ParamDeletionProtectionEnabled)
+	}
+	return &file.DeletionProtection{Enabled: enabled, Reason: reason}, nil
+}
+
+// deletionProtectionEnabled returns true if the instance has deletion protection enabled.
+func deletionProtectionEnabled(instance *file.ServiceInstance) bool {
+	return instance.DeletionProtection != nil && instance.DeletionProtection.Enabled
+}
+
 func validateAndBuildPerformanceConfig(params map[string]string, capacityBytes int64, tier string) (*file.PerformanceConfig, error) {
 	iopsStr, hasIOPS := params[ParamMaxIOPS]
 	densityStr, hasDensity := params[ParamMaxIOPSPerTB]
@@ -18,6 +18,8 @@ package driver
 
 import (
 	"testing"
+
+	file "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
 )
 
 func TestValidateAndBuildPerformanceConfig_NoParams(t *testing.T) {
@@ -472,3 +474,54 @@ func TestValidateAndBuildPerformanceConfig_BothParamsError(t *testing.T) {
 		})
 	}
 }
+
+func TestEqualNfsExportOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  []*file.NfsExportOptions
+		desired  []*file.NfsExportOptions
+		expected bool
+	}{
+		{
+			name:     "defaults filled in by Filestore",
+			current:  []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}, AccessMode: "READ_WRITE", SquashMode: "NO_ROOT_SQUASH"}},
+			desired:  []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}}},
+			expected: true,
+		},
+		{
+			name:     "default anonymous ids with root squashing",
+			current:  []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}, AccessMode: "READ_ONLY", SquashMode: "ROOT_SQUASH", AnonUid: 65534, AnonGid: 65534}},
+			desired:  []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}, AccessMode: "READ_ONLY", SquashMode: "ROOT_SQUASH"}},
+			expected: true,
+		},
+		{
+			name:     "ip ranges in another order",
+			current:  []*file.NfsExportOptions{{IpRanges: []string{"10.0.1.0/24", "10.0.0.0/24"}}},
+			desired:  []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24", "10.0.1.0/24"}}},
+			expected: true,
+		},
+		{
+			name:    "different anonymous uid",
+			current: []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}, SquashMode: "ROOT_SQUASH", AnonUid: 65534, AnonGid: 65534}},
+			desired: []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}, SquashMode: "ROOT_SQUASH", AnonUid: 1000}},
+		},
+		{
+			name:    "different access mode",
+			current: []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}, AccessMode: "READ_WRITE"}},
+			desired: []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}, AccessMode: "READ_ONLY"}},
+		},
+		{
+			name:    "different number of options",
+			current: nil,
+			desired: []*file.NfsExportOptions{{IpRanges: []string{"10.0.0.0/24"}}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := equalNfsExportOptions(tc.current, tc.desired); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// Filestore cannot copy an instance directly, so a single share volume is cloned by taking a
+// transient backup of the source instance and restoring it into the new instance. The backup
+// is named after the CreateVolume request so that retried calls reuse it, and it is deleted
+// once the new instance is ready, or once CreateVolume fails with a final error. The backups
+// of requests that are abandoned while being retried are collected by the backup garbage
+// collector after their retention.
+
+const (
+	cloneBackupPrefix = "clone"
+
+	// cloneBackupRetention is the retention label value of the transient clone backups.
+	cloneBackupRetention = "1d"
+)
+
+// validateCloneSource checks that the clone source is an existing single share volume which
+// fits in the requested capacity.
+func (s *controllerServer) validateCloneSource(ctx context.Context, sourceVolumeID string, capacityBytes int64) error {
+	if isMultishareVolId(sourceVolumeID) || isSharePoolVolumeID(sourceVolumeID) {
+		return status.Errorf(codes.InvalidArgument, "Unsupported volume content source %v, only single share volumes can be cloned", sourceVolumeID)
+	}
+	source, mode, err := s.getFileInstanceFromVolumeID(sourceVolumeID)
+	if err != nil || mode != modeInstance {
+		// An invalid id format is treated as doesn't exist
+		return status.Errorf(codes.NotFound, "source volume %v not found", sourceVolumeID)
+	}
+	source, err = s.config.fileService.GetInstance(ctx, source)
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return status.Errorf(codes.NotFound, "source volume %v not found", sourceVolumeID)
+		}
+		return file.StatusError(err)
+	}
+	if source.Volume.SizeBytes > capacityBytes {
+		return status.Errorf(codes.OutOfRange, "requested capacity %v is smaller than the capacity %v of source volume %v", capacityBytes, source.Volume.SizeBytes, sourceVolumeID)
+	}
+	return nil
+}
+
+// cloneBackupInfo returns the backup info of the transient backup used to clone the source
+// volume into the volume with the given name.
+func (s *controllerServer) cloneBackupInfo(name, sourceVolumeID string) (*file.BackupInfo, error) {
+	backupInfo, err := gatherBackupInfo(fmt.Sprintf("%s-%s", cloneBackupPrefix, name), sourceVolumeID, s.config.cloud.Project)
+	if err != nil {
+		return nil, err
+	}
+	backupURI, region, err := file.CreateBackupURI(backupInfo.Location, backupInfo.Project, backupInfo.Name, "")
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	backupInfo.Location = region
+	backupInfo.BackupURI = backupURI
+	return backupInfo, nil
+}
+
+// prepareCloneBackup creates the transient backup of the source volume, or reuses the one
+// created by a previous attempt of the same request, and returns its URI.
+func (s *controllerServer) prepareCloneBackup(ctx context.Context, name, sourceVolumeID string, params map[string]string) (string, error) {
+	backupInfo, err := s.cloneBackupInfo(name, sourceVolumeID)
+	if err != nil {
+		return "", err
+	}
+
+	// Serialize with snapshots of the source volume.
+	if acquired := s.config.volumeLocks.TryAcquire(sourceVolumeID); !acquired {
+		return "", status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, sourceVolumeID)
+	}
+	defer s.config.volumeLocks.Release(sourceVolumeID)
+
+	existingBackup, err := s.config.fileService.GetBackup(ctx, backupInfo.BackupURI)
+	backupExists, err := file.CheckBackupExists(existingBackup, err)
+	if err != nil {
+		return "", err
+	}
+	if backupExists && existingBackup.Backup.State == "FAILED" {
+		return "", deleteFailedBackup(ctx, s.config.fileService, backupInfo)
+	}
+	if backupExists {
+		snapshot, err := file.ProcessExistingBackup(ctx, existingBackup, sourceVolumeID, modeInstance)
+		if err != nil {
+			return "", err
+		}
+		if !snapshot.ReadyToUse {
+			return "", file.BackupNotReadyError(snapshot)
+		}
+		return backupInfo.BackupURI, nil
+	}
+
+	labels, err := extractLabels(params, s.config.extraVolumeLabels, s.config.driver.config.Name)
+	if err != nil {
+		return "", err
+	}
+	labels[tagKeyCloneName] = name
+	labels[tagKeyBackupRetention] = cloneBackupRetention
+	backupInfo.Labels = labels
+
+	klog.V(4).Infof("Creating transient backup %v of volume %v to clone volume %v", backupInfo.BackupURI, sourceVolumeID, name)
+	if _, err := s.config.fileService.CreateBackup(ctx, backupInfo); err != nil {
+		klog.Errorf("Create clone backup for volume Id %s failed: %v", sourceVolumeID, err.Error())
+		return "", file.StatusError(err)
+	}
+	return backupInfo.BackupURI, nil
+}
+
+// cleanupCloneBackup deletes the transient backup once the cloned instance is ready.
+func (s *controllerServer) cleanupCloneBackup(ctx context.Context, name, sourceVolumeID string) error {
+	backupInfo, err := s.cloneBackupInfo(name, sourceVolumeID)
+	if err != nil {
+		return err
+	}
+	backup, err := s.config.fileService.GetBackup(ctx, backupInfo.BackupURI)
+	if err != nil {
+		if file.IsNotFoundErr(err) {
+			return nil
+		}
+		return file.StatusError(err)
+	}
+	if backup.Backup.State == "DELETING" {
+		return nil
+	}
+	if err := s.config.fileService.DeleteBackup(ctx, backupInfo.BackupURI); err != nil {
+		klog.Errorf("Delete clone backup %s failed: %v", backupInfo.BackupURI, err.Error())
+		return file.StatusError(err)
+	}
+	klog.V(4).Infof("Deleted transient backup %v used to clone volume %v", backupInfo.BackupURI, name)
+	return nil
+}
+
+// abandonCloneBackup deletes the transient backup when CreateVolume fails with a final error,
+// since the request may never be retried. The backup is kept on transient errors so that the
+// retried request reuses it, and on AlreadyExists since the conflicting backup is not the one of
+// this request.
+func (s *controllerServer) abandonCloneBackup(ctx context.Context, name, sourceVolumeID string, err error) {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Unavailable, codes.Aborted, codes.Canceled, codes.AlreadyExists:
+		return
+	}
+	if err := s.cleanupCloneBackup(ctx, name, sourceVolumeID); err != nil {
+		klog.Warningf("Failed to delete transient backup used to clone volume %v after CreateVolume failed, it will be deleted after its retention: %v", name, err)
+	}
+}
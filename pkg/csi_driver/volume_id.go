@@ -38,9 +38,33 @@ const (
 	totalIDElements // Always last
 )
 
+// Ordering of elements in the versioned volume id of instances outside of the driver's project
+// ID is of form {provisioningMode}/v2/{project}/{location}/{instanceName}/{volume}
+const (
+	idV2ProvisioningMode = iota
+	idV2Version
+	idV2Project
+	idV2Location
+	idV2Instance
+	idV2Volume
+	totalIDV2Elements // Always last
+)
+
 // getVolumeIDFromFileInstance generates an id to uniquely identify the GCFS volume.
-// This id is used for volume deletion.
-func getVolumeIDFromFileInstance(obj *file.ServiceInstance, mode string) string {
+// This id is used for volume deletion. Instances outside of the default project get a
+// versioned id that includes their project.
+func getVolumeIDFromFileInstance(obj *file.ServiceInstance, mode, defaultProject string) string {
+	if obj.Project != "" && obj.Project != defaultProject {
+		idElements := make([]string, totalIDV2Elements)
+		idElements[idV2ProvisioningMode] = mode
+		idElements[idV2Version] = util.VolumeIDVersionWithProject
+		idElements[idV2Project] = obj.Project
+		idElements[idV2Location] = obj.Location
+		idElements[idV2Instance] = obj.Name
+		idElements[idV2Volume] = obj.Volume.Name
+		return strings.Join(idElements, "/")
+	}
+
 	idElements := make([]string, totalIDElements)
 	idElements[idProvisioningMode] = mode
 	idElements[idLocation] = obj.Location
@@ -49,12 +73,17 @@ func getVolumeIDFromFileInstance(obj *file.ServiceInstance, mode string) string
 	return strings.Join(idElements, "/")
 }
 
+// gatherBackupInfo returns the backup info for a backup of the given volume. The backup is
+// created in the project of the volume, or in the given project for legacy volume ids.
 func gatherBackupInfo(name string, id string, project string) (*file.BackupInfo, error) {
 	filer, _, err := getFileInstanceFromID(id)
 	if err != nil {
 		klog.Errorf("Failed to get instance for volumeID %v snapshot, error: %v", id, err.Error())
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	if filer.Project != "" {
+		project = filer.Project
+	}
 	backupInfo := &file.BackupInfo{
 		Name:               name,
 		SourceVolumeId:     id,
@@ -66,9 +95,21 @@ func gatherBackupInfo(name string, id string, project string) (*file.BackupInfo,
 	return backupInfo, nil
 }
 
-// getFileInstanceFromID generates a GCFS Instance object from the volume id
+// getFileInstanceFromID generates a GCFS Instance object from the volume id. The project is
+// only set for versioned volume ids.
 func getFileInstanceFromID(id string) (*file.ServiceInstance, string, error) {
 	tokens := strings.Split(id, "/")
+	if len(tokens) == totalIDV2Elements && tokens[idV2Version] == util.VolumeIDVersionWithProject {
+		if tokens[idV2ProvisioningMode] != modeInstance || tokens[idV2Project] == "" {
+			return nil, "", fmt.Errorf("volume id %q unexpected format", id)
+		}
+		return &file.ServiceInstance{
+			Project:  tokens[idV2Project],
+			Location: tokens[idV2Location],
+			Name:     tokens[idV2Instance],
+			Volume:   file.Volume{Name: tokens[idV2Volume]},
+		}, tokens[idV2ProvisioningMode], nil
+	}
 	if len(tokens) != totalIDElements {
 		return nil, "", fmt.Errorf("volume id %q unexpected format: got %v tokens", id, len(tokens))
 	}
@@ -80,6 +121,19 @@ func getFileInstanceFromID(id string) (*file.ServiceInstance, string, error) {
 	}, tokens[idProvisioningMode], nil
 }
 
+// getFileInstanceFromVolumeID generates a GCFS Instance object from the volume id, using the
+// project of the driver for legacy volume ids.
+func (s *controllerServer) getFileInstanceFromVolumeID(id string) (*file.ServiceInstance, string, error) {
+	filer, mode, err := getFileInstanceFromID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if filer.Project == "" {
+		filer.Project = s.config.cloud.Project
+	}
+	return filer, mode, nil
+}
+
 func generateMultishareVolumeIdFromShare(instancePrefix string, s *file.Share) (string, error) {
 	if instancePrefix == "" {
 		return "", fmt.Errorf("invalid instance prefix")
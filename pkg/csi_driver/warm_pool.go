@@ -0,0 +1,353 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	v1 "sigs.k8s.io/gcp-filestore-csi-driver/pkg/apis/multishare/v1"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// A multishare StorageClass can keep a warm pool of empty instances ahead of demand with the
+// warm-pool-size parameter, so that a CreateVolume of the StorageClass does not wait for an
+// instance to be created. New shares are placed on the pool instances like on any other eligible
+// instance, and the pool is topped up with new instances. Empty instances beyond the pool size,
+// e.g. once the pool size is lowered, are deleted.
+//
+// Without the stateful controller, pool instances are labeled storage_gke_io_warm-pool, and only
+// labeled instances are deleted when the pool shrinks. With the stateful controller, the
+// reconciler keeps the pool as InstanceInfo objects without assigned shares.
+
+const (
+	// ParamWarmPoolSize is the number of empty instances kept for a multishare StorageClass.
+	ParamWarmPoolSize = "warm-pool-size"
+
+	tagKeyWarmPool = "storage_gke_io_warm-pool"
+
+	// warmPoolSyncTimeout bounds a warm pool sync.
+	warmPoolSyncTimeout = 10 * time.Minute
+)
+
+// parseWarmPoolSize returns the warm pool size of a StorageClass with the given parameters, zero if not set.
+func parseWarmPoolSize(params map[string]string) (int, error) {
+	v, ok := params[ParamWarmPoolSize]
+	if !ok {
+		return 0, nil
+	}
+	size, err := strconv.Atoi(v)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid %q parameter %q, must be a non-negative integer", ParamWarmPoolSize, v)
+	}
+	return size, nil
+}
+
+// warmPoolStorageClasses returns the multishare StorageClasses of the driver with a warm pool, by instance StorageClass label.
+func warmPoolStorageClasses(storageClasses []*storagev1.StorageClass, driverName string) map[string]*storagev1.StorageClass {
+	pools := make(map[string]*storagev1.StorageClass)
+	for _, sc := range storageClasses {
+		if sc.Provisioner != driverName || strings.ToLower(sc.Parameters[paramMultishare]) != "true" {
+			continue
+		}
+		scLabel := sc.Parameters[ParamMultishareInstanceScLabel]
+		if scLabel == "" {
+			continue
+		}
+		size, err := parseWarmPoolSize(sc.Parameters)
+		if err != nil {
+			klog.Warningf("Ignoring warm pool of StorageClass %q: %v", sc.Name, err)
+			continue
+		}
+		if size > 0 {
+			pools[scLabel] = sc
+		}
+	}
+	return pools
+}
+
+// multishareWarmPool keeps the warm pools of the multishare StorageClasses, without the stateful controller.
+type multishareWarmPool struct {
+	mc         *MultishareController
+	cluster    clusterInstances
+	kubeClient kubernetes.Interface
+	config     *FeatureMultishareWarmPool
+}
+
+func newMultishareWarmPool(config *controllerServerConfig, mc *MultishareController) *multishareWarmPool {
+	return &multishareWarmPool{
+		mc:         mc,
+		cluster:    newClusterInstances(config),
+		kubeClient: config.features.FeatureMultishareWarmPool.KubeClient,
+		config:     config.features.FeatureMultishareWarmPool,
+	}
+}
+
+func (p *multishareWarmPool) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting multishare warm pool, sync period %v", p.config.SyncPeriod)
+	wait.Until(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), warmPoolSyncTimeout)
+		defer cancel()
+		p.sync(ctx)
+	}, p.config.SyncPeriod, stopCh)
+}
+
+// warmPoolTarget is the pool of a StorageClass: the instance new pool instances are generated
+// like, and the request matching instances are checked against.
+type warmPoolTarget struct {
+	sc       *storagev1.StorageClass
+	size     int
+	req      *csi.CreateVolumeRequest
+	template *file.MultishareInstance
+	members  []*file.MultishareInstance
+}
+
+// sync creates the missing pool instances, and deletes the labeled pool instances beyond the pool sizes.
+func (p *multishareWarmPool) sync(ctx context.Context) error {
+	scList, err := p.kubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf("Warm pool failed to list StorageClasses: %v", err)
+		return err
+	}
+	var storageClasses []*storagev1.StorageClass
+	for i := range scList.Items {
+		storageClasses = append(storageClasses, &scList.Items[i])
+	}
+	targets := make(map[string]*warmPoolTarget)
+	for scLabel, sc := range warmPoolStorageClasses(storageClasses, p.mc.driver.config.Name) {
+		target, err := p.newTarget(sc)
+		if err != nil {
+			klog.Errorf("Warm pool skipping StorageClass %q: %v", sc.Name, err)
+			continue
+		}
+		targets[scLabel] = target
+	}
+
+	instances, err := p.mc.fileService.ListMultishareInstances(ctx, &file.ListFilter{Project: p.mc.cloud.Project, Location: "-"})
+	if err != nil {
+		klog.Errorf("Warm pool failed to list instances: %v", err)
+		return err
+	}
+	ops, err := p.mc.opsManager.listMultishareResourceRunningOps(ctx)
+	if err != nil {
+		klog.Errorf("Warm pool failed to list operations: %v", err)
+		return err
+	}
+
+	var errs []error
+	// Labeled pool instances of StorageClasses without a pool, or which no longer match their StorageClass.
+	var orphans []*file.MultishareInstance
+	for _, instance := range instances {
+		if !p.cluster.owns(instance) {
+			continue
+		}
+		empty, err := p.isEmpty(ctx, instance)
+		if err != nil {
+			klog.Errorf("Warm pool failed to check instance %s: %v", instance.String(), err)
+			errs = append(errs, err)
+			continue
+		}
+		if !empty {
+			continue
+		}
+		target, ok := targets[instance.Labels[util.ParamMultishareInstanceScLabelKey]]
+		if ok {
+			matched, err := isMatchedInstance(instance, target.template, target.req)
+			if err == nil && matched {
+				target.members = append(target.members, instance)
+				continue
+			}
+		}
+		if instance.Labels[tagKeyWarmPool] != "" {
+			orphans = append(orphans, instance)
+		}
+	}
+
+	for _, target := range targets {
+		for i := len(target.members); i < target.size; i++ {
+			if err := p.createInstance(ctx, target); err != nil {
+				klog.Errorf("Warm pool failed to create instance for StorageClass %q: %v", target.sc.Name, err)
+				errs = append(errs, err)
+				break
+			}
+		}
+		if len(target.members) > target.size {
+			orphans = append(orphans, excessPoolInstances(target.members, target.size)...)
+		}
+	}
+
+	for _, instance := range orphans {
+		if op, err := containsOpWithInstanceTargetPrefix(instance, ops); err != nil || op != nil || instance.State != "READY" {
+			continue
+		}
+		if _, err := p.mc.opsManager.checkAndStartEmptyInstanceDeleteWorkflow(ctx, instance); err != nil {
+			klog.Errorf("Warm pool failed to delete instance %s: %v", instance.String(), err)
+			errs = append(errs, err)
+			continue
+		}
+		klog.Infof("Warm pool started deletion of instance %s", instance.String())
+	}
+	return errors.Join(errs...)
+}
+
+func (p *multishareWarmPool) newTarget(sc *storagev1.StorageClass) (*warmPoolTarget, error) {
+	size, err := parseWarmPoolSize(sc.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	req := &csi.CreateVolumeRequest{Name: sc.Name, Parameters: sc.Parameters}
+	template, err := p.newInstance(req)
+	if err != nil {
+		return nil, err
+	}
+	return &warmPoolTarget{sc: sc, size: size, req: req, template: template}, nil
+}
+
+func (p *multishareWarmPool) newInstance(req *csi.CreateVolumeRequest) (*file.MultishareInstance, error) {
+	maxSharesPerInstance, _, err := p.mc.parseMaxVolumeSizeParam(req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+	instance, err := p.mc.generateNewMultishareInstance(util.NewMultishareInstancePrefix+string(uuid.NewUUID()), req, maxSharesPerInstance)
+	if err != nil {
+		return nil, err
+	}
+	instance.Labels[tagKeyWarmPool] = "true"
+	return instance, nil
+}
+
+func (p *multishareWarmPool) createInstance(ctx context.Context, target *warmPoolTarget) error {
+	instance, err := p.newInstance(target.req)
+	if err != nil {
+		return err
+	}
+	if _, err := p.mc.opsManager.startNewInstanceWorkflowSafe(ctx, instance, target.req.GetParameters()); err != nil {
+		return err
+	}
+	klog.Infof("Warm pool started creation of instance %s for StorageClass %q", instance.String(), target.sc.Name)
+	target.members = append(target.members, instance)
+	return nil
+}
+
+// isEmpty returns true if the instance is being created, or is ready without shares.
+func (p *multishareWarmPool) isEmpty(ctx context.Context, instance *file.MultishareInstance) (bool, error) {
+	switch instance.State {
+	case "CREATING":
+		return true, nil
+	case "READY":
+	default:
+		return false, nil
+	}
+	shares, err := p.mc.fileService.ListShares(ctx, &file.ListFilter{Project: instance.Project, Location: instance.Location, InstanceName: instance.Name})
+	if err != nil {
+		return false, err
+	}
+	return len(shares) == 0, nil
+}
+
+// excessPoolInstances returns the labeled members of a pool beyond its size, in name order.
+func excessPoolInstances(members []*file.MultishareInstance, size int) []*file.MultishareInstance {
+	var labeled []*file.MultishareInstance
+	for _, instance := range members {
+		if instance.Labels[tagKeyWarmPool] != "" {
+			labeled = append(labeled, instance)
+		}
+	}
+	sort.Slice(labeled, func(i, j int) bool {
+		return labeled[i].Name < labeled[j].Name
+	})
+	excess := len(members) - size
+	if excess > len(labeled) {
+		excess = len(labeled)
+	}
+	return labeled[:excess]
+}
+
+// warmPoolSizes returns the warm pool sizes of the multishare StorageClasses, by instance StorageClass label.
+func (recon *MultishareReconciler) warmPoolSizes() map[string]int {
+	sizes := make(map[string]int)
+	if recon.config.FeatureOptions == nil || recon.config.FeatureOptions.FeatureMultishareWarmPool == nil || !recon.config.FeatureOptions.FeatureMultishareWarmPool.Enabled {
+		return sizes
+	}
+	storageClasses, err := recon.scLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list StorageClasses for warm pools: %v", err)
+		return sizes
+	}
+	for scLabel, sc := range warmPoolStorageClasses(storageClasses, recon.config.Name) {
+		sizes[scLabel], _ = parseWarmPoolSize(sc.Parameters)
+	}
+	return sizes
+}
+
+// fillWarmPools generates the instanceInfos missing from the warm pools, in the region of the cluster.
+func (recon *MultishareReconciler) fillWarmPools(instanceInfos map[string]*v1.InstanceInfo, warmPools map[string]int) {
+	if len(warmPools) == 0 {
+		return
+	}
+	members := make(map[string]int)
+	for _, instanceInfo := range instanceInfos {
+		if instanceInfo.DeletionTimestamp == nil && instanceUnassigned(instanceInfo) {
+			members[instanceInfo.Labels[ParamMultishareInstanceScLabel]]++
+		}
+	}
+	region, err := util.GetRegionFromZone(recon.cloud.Zone)
+	if err != nil {
+		klog.Errorf("Failed to get the region of the cluster for warm pools: %v", err)
+		return
+	}
+	for scLabel, size := range warmPools {
+		for i := members[scLabel]; i < size; i++ {
+			storageClass, err := recon.storageClassFromTag(scLabel)
+			if err != nil {
+				klog.Errorf("Failed to get StorageClass of warm pool %q: %v", scLabel, err)
+				break
+			}
+			instanceURI, _ := file.GenerateMultishareInstanceURI(&file.MultishareInstance{
+				Project:  recon.cloud.Project,
+				Location: region,
+				Name:     util.NewMultishareInstancePrefix + string(uuid.NewUUID()),
+			})
+			instanceInfo, err := recon.generateInstanceInfo(instanceURI, scLabel, storageClass.Parameters)
+			if err != nil {
+				klog.Errorf("Failed to create instanceInfo %q for warm pool %q: %v", instanceURI, scLabel, err)
+				break
+			}
+			klog.Infof("Generated instanceInfo %q for warm pool %q", instanceInfo.Name, scLabel)
+			instanceInfos[instanceURI] = instanceInfo
+		}
+	}
+}
+
+// instanceUnassigned returns true if no share is assigned to the instanceInfo, including instanceInfos without status yet.
+func instanceUnassigned(instanceInfo *v1.InstanceInfo) bool {
+	return instanceInfo.Status == nil || len(instanceInfo.Status.ShareNames) == 0
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	v1 "sigs.k8s.io/gcp-filestore-csi-driver/pkg/apis/multishare/v1"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/client/clientset/versioned/fake"
+	multishareinformers "sigs.k8s.io/gcp-filestore-csi-driver/pkg/client/informers/externalversions"
+	cloud "sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/cloud_provider/file"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+func newWarmPoolStorageClass(size string) *storagev1.StorageClass {
+	params := map[string]string{
+		paramMultishare:                "true",
+		ParamMultishareInstanceScLabel: testInstanceScPrefix,
+	}
+	if size != "" {
+		params[ParamWarmPoolSize] = size
+	}
+	return &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "test-sc"},
+		Provisioner: "test-driver",
+		Parameters:  params,
+	}
+}
+
+func TestParseWarmPoolSize(t *testing.T) {
+	cases := []struct {
+		name        string
+		params      map[string]string
+		expected    int
+		expectError bool
+	}{
+		{
+			name:   "not set",
+			params: map[string]string{},
+		},
+		{
+			name:     "valid size",
+			params:   map[string]string{ParamWarmPoolSize: "3"},
+			expected: 3,
+		},
+		{
+			name:   "zero",
+			params: map[string]string{ParamWarmPoolSize: "0"},
+		},
+		{
+			name:        "negative size",
+			params:      map[string]string{ParamWarmPoolSize: "-1"},
+			expectError: true,
+		},
+		{
+			name:        "not a number",
+			params:      map[string]string{ParamWarmPoolSize: "two"},
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := parseWarmPoolSize(tc.params)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if size != tc.expected {
+				t.Errorf("got size %d, expected %d", size, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMultishareWarmPoolSync(t *testing.T) {
+	type poolInstance struct {
+		name    string
+		labeled bool
+		share   bool
+	}
+	cases := []struct {
+		name      string
+		size      string
+		instances []poolInstance
+		// expectedEmpty are the names of the empty instances left after the sync, "new" standing for a created one.
+		expectedEmpty []string
+	}{
+		{
+			name:          "fill empty pool",
+			size:          "2",
+			expectedEmpty: []string{"new", "new"},
+		},
+		{
+			name: "empty instances count towards the pool",
+			size: "2",
+			instances: []poolInstance{
+				{name: "instance-1"},
+				{name: "instance-2", share: true},
+			},
+			expectedEmpty: []string{"instance-1", "new"},
+		},
+		{
+			name: "pool is full",
+			size: "1",
+			instances: []poolInstance{
+				{name: "instance-1", labeled: true},
+			},
+			expectedEmpty: []string{"instance-1"},
+		},
+		{
+			name: "shrink pool",
+			size: "1",
+			instances: []poolInstance{
+				{name: "instance-1", labeled: true},
+				{name: "instance-2", labeled: true},
+				{name: "instance-3", labeled: true},
+			},
+			expectedEmpty: []string{"instance-3"},
+		},
+		{
+			name: "shrink pool keeps unlabeled instances",
+			size: "1",
+			instances: []poolInstance{
+				{name: "instance-1"},
+				{name: "instance-2", labeled: true},
+			},
+			expectedEmpty: []string{"instance-1"},
+		},
+		{
+			name: "pool removed",
+			instances: []poolInstance{
+				{name: "instance-1"},
+				{name: "instance-2", labeled: true},
+			},
+			expectedEmpty: []string{"instance-1"},
+		},
+		{
+			name: "invalid pool size",
+			size: "-1",
+			instances: []poolInstance{
+				{name: "instance-1", labeled: true},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			newController := func(s file.Service) *MultishareController {
+				cloudProvider, _ := cloud.NewFakeCloud()
+				cloudProvider.File = s
+				return NewMultishareController(&controllerServerConfig{
+					driver:      initTestDriver(t),
+					fileService: s,
+					cloud:       cloudProvider,
+					clusterName: testClusterName,
+					features: &GCFSDriverFeatureOptions{
+						FeatureMultishareWarmPool: &FeatureMultishareWarmPool{
+							Enabled:    true,
+							SyncPeriod: time.Minute,
+							KubeClient: k8sfake.NewSimpleClientset(newWarmPoolStorageClass(tc.size)),
+						},
+					},
+				})
+			}
+
+			// The instances are generated like the pool instances of a valid StorageClass.
+			target, err := newController(nil).warmPool.newTarget(newWarmPoolStorageClass("1"))
+			if err != nil {
+				t.Fatalf("failed to generate pool instance: %v", err)
+			}
+			var instances []*file.MultishareInstance
+			var shares []*file.Share
+			for _, i := range tc.instances {
+				instance := *target.template
+				instance.Name = i.name
+				instance.State = "READY"
+				instance.Labels = make(map[string]string)
+				for k, v := range target.template.Labels {
+					instance.Labels[k] = v
+				}
+				if !i.labeled {
+					delete(instance.Labels, tagKeyWarmPool)
+				}
+				instances = append(instances, &instance)
+				if i.share {
+					shares = append(shares, &file.Share{Name: "test-share", Parent: &instance, CapacityBytes: 100 * util.Gb})
+				}
+			}
+			s, err := file.NewFakeServiceForMultishare(instances, shares, nil)
+			if err != nil {
+				t.Fatalf("failed to fake service: %v", err)
+			}
+			mcs := newController(s)
+			if mcs.warmPool == nil {
+				t.Fatalf("expected warm pool to be enabled")
+			}
+
+			if err := mcs.warmPool.sync(context.TODO()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			existing := make(map[string]bool)
+			for _, i := range tc.instances {
+				existing[i.name] = true
+			}
+			instances, _ = s.ListMultishareInstances(context.TODO(), &file.ListFilter{Project: testProject, Location: "-"})
+			var empty []string
+			for _, instance := range instances {
+				shares, _ := s.ListShares(context.TODO(), &file.ListFilter{InstanceName: instance.Name})
+				if len(shares) > 0 {
+					continue
+				}
+				if !existing[instance.Name] {
+					if instance.Labels[tagKeyWarmPool] == "" {
+						t.Errorf("expected created instance %q to be labeled", instance.Name)
+					}
+					empty = append(empty, "new")
+					continue
+				}
+				empty = append(empty, instance.Name)
+			}
+			sort.Strings(empty)
+			if fmt.Sprint(empty) != fmt.Sprint(tc.expectedEmpty) {
+				t.Errorf("got empty instances %v, expected %v", empty, tc.expectedEmpty)
+			}
+		})
+	}
+}
+
+func TestReconcilerWarmPool(t *testing.T) {
+	newInstanceInfo := func(name string, shareNames ...string) *v1.InstanceInfo {
+		instanceInfo := &v1.InstanceInfo{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       util.InstanceURIToInstanceInfoName(instanceURI(testProject, testRegion, name)),
+				Finalizers: []string{util.FilestoreResourceCleanupFinalizer},
+				Labels:     map[string]string{ParamMultishareInstanceScLabel: testInstanceScPrefix},
+			},
+			Spec: v1.InstanceInfoSpec{
+				CapacityBytes:    util.MinMultishareInstanceSizeBytes,
+				StorageClassName: "test-sc",
+			},
+			Status: &v1.InstanceInfoStatus{
+				ShareNames: shareNames,
+			},
+		}
+		return instanceInfo
+	}
+
+	cases := []struct {
+		name          string
+		size          string
+		instanceInfos []*v1.InstanceInfo
+		// expectedUnassigned is the number of unassigned instanceInfos left.
+		expectedUnassigned int
+		expectedDeleted    []string
+	}{
+		{
+			name:               "fill empty pool",
+			size:               "2",
+			expectedUnassigned: 2,
+		},
+		{
+			name: "assigned instances do not count towards the pool",
+			size: "1",
+			instanceInfos: []*v1.InstanceInfo{
+				newInstanceInfo("instance-1", "share-1"),
+			},
+			expectedUnassigned: 1,
+		},
+		{
+			name: "pool is full",
+			size: "1",
+			instanceInfos: []*v1.InstanceInfo{
+				newInstanceInfo("instance-1"),
+			},
+			expectedUnassigned: 1,
+		},
+		{
+			name: "shrink pool",
+			size: "1",
+			instanceInfos: []*v1.InstanceInfo{
+				newInstanceInfo("instance-1"),
+				newInstanceInfo("instance-2"),
+				newInstanceInfo("instance-3"),
+			},
+			expectedUnassigned: 1,
+			expectedDeleted:    []string{"instance-2", "instance-3"},
+		},
+		{
+			name: "pool removed",
+			instanceInfos: []*v1.InstanceInfo{
+				newInstanceInfo("instance-1"),
+			},
+			expectedDeleted: []string{"instance-1"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			instanceInfos := make(map[string]*v1.InstanceInfo)
+			for _, instanceInfo := range tc.instanceInfos {
+				if _, err := client.MultishareV1().InstanceInfos(util.ManagedFilestoreCSINamespace).Create(context.TODO(), instanceInfo, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("failed to create instanceInfo: %v", err)
+				}
+				instanceInfos[util.InstanceInfoNameToInstanceURI(instanceInfo.Name)] = instanceInfo
+			}
+			scInformer := informers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), 0).Storage().V1().StorageClasses()
+			if err := scInformer.Informer().GetIndexer().Add(newWarmPoolStorageClass(tc.size)); err != nil {
+				t.Fatalf("failed to add StorageClass: %v", err)
+			}
+			cloudProvider, _ := cloud.NewFakeCloud()
+			recon := &MultishareReconciler{
+				clientset: client,
+				cloud:     cloudProvider,
+				config: &GCFSDriverConfig{
+					Name: "test-driver",
+					FeatureOptions: &GCFSDriverFeatureOptions{
+						FeatureMultishareWarmPool: &FeatureMultishareWarmPool{Enabled: true},
+					},
+				},
+				shareLister: multishareinformers.NewSharedInformerFactory(client, 0).Multishare().V1().ShareInfos().Lister(),
+				scLister:    scInformer.Lister(),
+			}
+
+			warmPools := recon.warmPoolSizes()
+			recon.fillWarmPools(instanceInfos, warmPools)
+			recon.deleteOrResizeInstances(instanceInfos, warmPools)
+
+			list, err := client.MultishareV1().InstanceInfos(util.ManagedFilestoreCSINamespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to list instanceInfos: %v", err)
+			}
+			left := make(map[string]bool)
+			unassigned := 0
+			for _, instanceInfo := range list.Items {
+				left[instanceInfo.Name] = true
+				if instanceUnassigned(&instanceInfo) {
+					unassigned++
+				}
+			}
+			if unassigned != tc.expectedUnassigned {
+				t.Errorf("got %d unassigned instanceInfos, expected %d", unassigned, tc.expectedUnassigned)
+			}
+			for _, name := range tc.expectedDeleted {
+				if left[util.InstanceURIToInstanceInfoName(instanceURI(testProject, testRegion, name))] {
+					t.Errorf("expected instanceInfo of %q to be deleted", name)
+				}
+			}
+		})
+	}
+}
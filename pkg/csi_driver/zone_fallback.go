@@ -0,0 +1,156 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// When a location runs out of capacity for a new instance, CreateVolume falls back to the
+// next location allowed by the topology requirement instead of failing on the same location
+// until the request is given up on. This matters most for WaitForFirstConsumer volumes, where
+// the preferred zone is the zone of the selected node but any requisite zone is acceptable.
+
+// zoneFallbackTTL is how long the exhausted locations of a volume are remembered after the
+// last attempt. Requests which are not retried, e.g. because the PVC was deleted, would
+// otherwise leave their records behind forever.
+const zoneFallbackTTL = time.Hour
+
+// zoneFallback records the locations that ran out of capacity per CreateVolume request.
+type zoneFallback struct {
+	mu        sync.Mutex
+	exhausted map[string]*exhaustedLocations
+	ttl       time.Duration
+}
+
+// exhaustedLocations are the locations that ran out of capacity for a volume.
+type exhaustedLocations struct {
+	locations []string
+	updated   time.Time
+}
+
+func newZoneFallback() *zoneFallback {
+	return &zoneFallback{exhausted: make(map[string]*exhaustedLocations), ttl: zoneFallbackTTL}
+}
+
+// get returns the record of the named volume, or nil if there is none or it expired. The
+// caller holds the lock.
+func (z *zoneFallback) get(name string) *exhaustedLocations {
+	e, ok := z.exhausted[name]
+	if !ok {
+		return nil
+	}
+	if time.Since(e.updated) > z.ttl {
+		delete(z.exhausted, name)
+		return nil
+	}
+	return e
+}
+
+// markExhausted records that the location has no capacity for the named volume.
+func (z *zoneFallback) markExhausted(name, location string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	// Drop the records of abandoned requests.
+	for n := range z.exhausted {
+		z.get(n)
+	}
+	e := z.get(name)
+	if e == nil {
+		e = &exhaustedLocations{}
+		z.exhausted[name] = e
+	}
+	e.updated = time.Now()
+	if !slices.Contains(e.locations, location) {
+		e.locations = append(e.locations, location)
+	}
+}
+
+// exhaustedLocations returns the locations attempted for the named volume that had no capacity.
+func (z *zoneFallback) exhaustedLocations(name string) []string {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	e := z.get(name)
+	if e == nil {
+		return []string{}
+	}
+	return append([]string{}, e.locations...)
+}
+
+// nextLocation returns the first candidate location not yet exhausted for the named volume,
+// or an empty string if all of them are.
+func (z *zoneFallback) nextLocation(name string, candidates []string) string {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	var exhausted []string
+	if e := z.get(name); e != nil {
+		exhausted = e.locations
+	}
+	for _, c := range candidates {
+		if !slices.Contains(exhausted, c) {
+			return c
+		}
+	}
+	return ""
+}
+
+// forget drops the record of the named volume.
+func (z *zoneFallback) forget(name string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	delete(z.exhausted, name)
+}
+
+// candidateLocations returns the locations a new instance of the given tier may be created in,
+// in order of preference. Regional and enterprise instances are created in the region of the
+// zones.
+func (s *controllerServer) candidateLocations(top *csi.TopologyRequirement, tier string) ([]string, error) {
+	zones := []string{s.config.cloud.Zone}
+	if top != nil {
+		var err error
+		zones, err = listZonesFromTopology(top)
+		if err != nil {
+			return nil, err
+		}
+		if len(zones) == 0 {
+			return nil, fmt.Errorf("both requisite and preferred topology list empty")
+		}
+	}
+	if tier != enterpriseTier && tier != regionalTier {
+		return zones, nil
+	}
+
+	regions := []string{}
+	seen := make(map[string]bool)
+	for _, zone := range zones {
+		region, err := util.GetRegionFromZone(zone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get region from zone %s: %w", zone, err)
+		}
+		if !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	return regions, nil
+}
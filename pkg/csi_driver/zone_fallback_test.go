@@ -0,0 +1,46 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestZoneFallbackExpiry(t *testing.T) {
+	z := newZoneFallback()
+	z.ttl = 50 * time.Millisecond
+	candidates := []string{"us-central1-a", "us-central1-b"}
+
+	z.markExhausted("abandoned", "us-central1-a")
+	if got := z.nextLocation("abandoned", candidates); got != "us-central1-b" {
+		t.Fatalf("got next location %q, expected %q", got, "us-central1-b")
+	}
+
+	time.Sleep(2 * z.ttl)
+	// The record of a request which was not retried is dropped once it expired.
+	z.markExhausted("other", "us-central1-a")
+	if _, ok := z.exhausted["abandoned"]; ok {
+		t.Errorf("expected expired record to be dropped")
+	}
+	if got := z.nextLocation("abandoned", candidates); got != "us-central1-a" {
+		t.Errorf("got next location %q after expiry, expected %q", got, "us-central1-a")
+	}
+	if got := z.exhaustedLocations("other"); len(got) != 1 {
+		t.Errorf("got exhausted locations %v, expected one", got)
+	}
+}
@@ -64,6 +64,26 @@ const (
 	ReconcilerOpSource  = "lock_release_reconciler"
 	// Label status_code indicates whether the lock release rpc call succeeds or not.
 	labelLockReleaseStatusCode = "status_code"
+
+	// Filestore inventory cache metrics.
+	inventoryLookupCountMetricName  = "inventory_cache_lookup_count"
+	inventoryListDurationMetricName = "inventory_list_duration_seconds"
+	labelInventoryResource          = "resource"
+	labelInventoryLookupResult      = "result"
+	inventoryLookupHit              = "hit"
+	inventoryLookupMiss             = "miss"
+
+	// Backup garbage collection metrics.
+	backupGCDeletionCountMetricName = "backup_gc_deletion_count"
+	backupGCRunDurationMetricName   = "backup_gc_run_duration_seconds"
+	labelBackupGCReason             = "reason"
+	labelBackupGCResult             = "result"
+	backupGCResultDryRun            = "dry_run"
+
+	// Multishare janitor metrics.
+	multishareJanitorDeletionCountMetricName = "multishare_janitor_deletion_count"
+	multishareJanitorRunDurationMetricName   = "multishare_janitor_run_duration_seconds"
+	labelMultishareJanitorResult             = "result"
 )
 
 var (
@@ -103,6 +123,63 @@ var (
 		},
 		[]string{labelOpStatusCode, labelResourceType, labelOpType, labelOpSource},
 	)
+
+	inventoryLookupCount = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem: subSystem,
+			Name:      inventoryLookupCountMetricName,
+			Help:      "Metric to expose count of Filestore list lookups served from or missing the inventory cache.",
+		},
+		[]string{labelInventoryResource, labelInventoryLookupResult},
+	)
+
+	inventoryListDurationSeconds = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem: subSystem,
+			Name:      inventoryListDurationMetricName,
+			Buckets:   metricBuckets,
+			Help:      "Metric to expose duration of Filestore lists made by the inventory cache.",
+		},
+		[]string{labelOpStatusCode, labelInventoryResource},
+	)
+
+	backupGCDeletionCount = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem: subSystem,
+			Name:      backupGCDeletionCountMetricName,
+			Help:      "Metric to expose count of backups deleted, or which would be deleted in dry-run mode, by the backup garbage collector.",
+		},
+		[]string{labelBackupGCReason, labelBackupGCResult},
+	)
+
+	backupGCRunDurationSeconds = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem: subSystem,
+			Name:      backupGCRunDurationMetricName,
+			Buckets:   metricBuckets,
+			Help:      "Metric to expose duration of backup garbage collection runs.",
+		},
+		[]string{labelOpStatusCode},
+	)
+
+	multishareJanitorDeletionCount = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem: subSystem,
+			Name:      multishareJanitorDeletionCountMetricName,
+			Help:      "Metric to expose count of empty multishare instances deleted, or which would be deleted in dry-run mode, by the multishare janitor.",
+		},
+		[]string{labelMultishareJanitorResult},
+	)
+
+	multishareJanitorRunDurationSeconds = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem: subSystem,
+			Name:      multishareJanitorRunDurationMetricName,
+			Buckets:   metricBuckets,
+			Help:      "Metric to expose duration of multishare janitor runs.",
+		},
+		[]string{labelOpStatusCode},
+	)
 )
 
 type MetricsManager struct {
@@ -133,6 +210,21 @@ func (mm *MetricsManager) RegisterKubeAPIDurationMetric() {
 	mm.registry.MustRegister(kubeAPIDurationMilliseconds)
 }
 
+func (mm *MetricsManager) RegisterInventoryMetrics() {
+	mm.registry.MustRegister(inventoryLookupCount)
+	mm.registry.MustRegister(inventoryListDurationSeconds)
+}
+
+func (mm *MetricsManager) RegisterBackupGCMetrics() {
+	mm.registry.MustRegister(backupGCDeletionCount)
+	mm.registry.MustRegister(backupGCRunDurationSeconds)
+}
+
+func (mm *MetricsManager) RegisterMultishareJanitorMetrics() {
+	mm.registry.MustRegister(multishareJanitorDeletionCount)
+	mm.registry.MustRegister(multishareJanitorRunDurationSeconds)
+}
+
 func (mm *MetricsManager) registerComponentVersionMetric() {
 	mm.registry.MustRegister(gkeComponentVersion)
 }
@@ -173,6 +265,64 @@ func (mm *MetricsManager) RecordLockReleaseMetrics(opErr error) {
 	lockReleaseCount.WithLabelValues(statusCode).Inc()
 }
 
+func (mm *MetricsManager) RecordInventoryLookup(resource string, hit bool) {
+	result := inventoryLookupMiss
+	if hit {
+		result = inventoryLookupHit
+	}
+	inventoryLookupCount.WithLabelValues(resource, result).Inc()
+}
+
+func (mm *MetricsManager) RecordInventoryList(resource string, err error, duration time.Duration) {
+	statusCode := successStatusCode
+	if err != nil {
+		statusCode = failureStatusCode
+	}
+	inventoryListDurationSeconds.WithLabelValues(statusCode, resource).Observe(duration.Seconds())
+}
+
+// RecordBackupGCDeletion records the deletion of a backup by the backup garbage collector,
+// for the given reason. A nil error with dryRun set means the deletion was skipped.
+func (mm *MetricsManager) RecordBackupGCDeletion(reason string, dryRun bool, err error) {
+	result := successStatusCode
+	switch {
+	case dryRun:
+		result = backupGCResultDryRun
+	case err != nil:
+		result = failureStatusCode
+	}
+	backupGCDeletionCount.WithLabelValues(reason, result).Inc()
+}
+
+func (mm *MetricsManager) RecordBackupGCRun(err error, duration time.Duration) {
+	statusCode := successStatusCode
+	if err != nil {
+		statusCode = failureStatusCode
+	}
+	backupGCRunDurationSeconds.WithLabelValues(statusCode).Observe(duration.Seconds())
+}
+
+// RecordMultishareJanitorDeletion records the deletion of an empty instance by the multishare
+// janitor. A nil error with dryRun set means the deletion was skipped.
+func (mm *MetricsManager) RecordMultishareJanitorDeletion(dryRun bool, err error) {
+	result := successStatusCode
+	switch {
+	case dryRun:
+		result = backupGCResultDryRun
+	case err != nil:
+		result = failureStatusCode
+	}
+	multishareJanitorDeletionCount.WithLabelValues(result).Inc()
+}
+
+func (mm *MetricsManager) RecordMultishareJanitorRun(err error, duration time.Duration) {
+	statusCode := successStatusCode
+	if err != nil {
+		statusCode = failureStatusCode
+	}
+	multishareJanitorRunDurationSeconds.WithLabelValues(statusCode).Observe(duration.Seconds())
+}
+
 func getErrorCode(err error) string {
 	if err == nil {
 		return codes.OK.String()
@@ -0,0 +1,176 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronDescriptors are the supported shorthands for cron expressions.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField is the range of a cron expression field.
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// CronSchedule is a parsed cron expression. Each field is a bitset of the matching values.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// If both the day of month and the day of week are restricted, i.e. do not start with
+	// "*", a day matches if either of them matches.
+	domRestricted, dowRestricted bool
+}
+
+// ParseCronSchedule parses a cron expression in the standard five field format, "minute hour
+// day-of-month month day-of-week", where each field is a comma separated list of "*", values,
+// ranges "a-b" and steps "*/n" or "a-b/n", or one of the @yearly, @monthly, @weekly, @daily
+// and @hourly shorthands. Month and day names are not supported.
+func ParseCronSchedule(spec string) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expr, ok := cronDescriptors[spec]; ok {
+		spec = expr
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields, got %d", spec, len(cronFields), len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		var err error
+		if bits[i], err = parseCronField(field, cronFields[i]); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+		}
+	}
+	// Sunday is both 0 and 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return &CronSchedule{
+		minute:        bits[0],
+		hour:          bits[1],
+		dom:           bits[2],
+		month:         bits[3],
+		dow:           bits[4],
+		domRestricted: !strings.HasPrefix(fields[2], "*"),
+		dowRestricted: !strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, r cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", r.name, part)
+			}
+		}
+
+		start, end := r.min, r.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			start, err1 = strconv.Atoi(bounds[0])
+			end, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range in %s field %q", r.name, part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field %q", r.name, part)
+			}
+			start, end = value, value
+			if step != 1 {
+				// "a/n" means from a to the end of the range.
+				end = r.max
+			}
+		}
+		if start < r.min || end > r.max || start > end {
+			return 0, fmt.Errorf("%s field %q out of range [%d, %d]", r.name, part, r.min, r.max)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// cronSearchLimit bounds the search of the next matching time, e.g. for "0 0 30 2 *".
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// Next returns the first time strictly after t matching the schedule, evaluated in UTC, or
+// the zero time if there is none within five years.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
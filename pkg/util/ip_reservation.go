@@ -17,10 +17,14 @@ limitations under the License.
 package util
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net"
 	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
 )
 
 const (
@@ -36,6 +40,12 @@ type IPAllocator struct {
 
 	// pendingIPRangesMutex is used to synchronize access to the pendingIPRanges set to prevent data races
 	pendingIPRangesMutex sync.Mutex
+
+	// store persists the reservations in place of the pendingIPRanges set, if set
+	store IPReservationStore
+
+	// reservationTTL is how long a persisted reservation is kept if it is never released
+	reservationTTL time.Duration
 }
 
 // NewIPAllocator is the constructor to initialize the IPAllocator object
@@ -51,6 +61,17 @@ func NewIPAllocator(pendingIPRanges map[string]bool) *IPAllocator {
 	}
 }
 
+// NewPersistentIPAllocator is the constructor to initialize an IPAllocator which persists the IP ranges
+// reserved by service instances in the given store instead of holding them in memory. Reservations
+// which are not released expire after the ttl.
+func NewPersistentIPAllocator(store IPReservationStore, ttl time.Duration) *IPAllocator {
+	return &IPAllocator{
+		pendingIPRanges: make(map[string]bool),
+		store:           store,
+		reservationTTL:  ttl,
+	}
+}
+
 // holdIPRange adds a particular IP range in the pendingIPRanges set
 // Argument ipRange string is an IPV4 range which needs put in pendingIPRanges
 func (ipAllocator *IPAllocator) holdIPRange(ipRange string) {
@@ -59,10 +80,74 @@ func (ipAllocator *IPAllocator) holdIPRange(ipRange string) {
 
 // ReleaseIPRange releases the pending IPRange
 // Argument ipRange string is an IPV4 range which needs to be released
+// A persisted reservation which fails to be released is left to expire
 func (ipAllocator *IPAllocator) ReleaseIPRange(ipRange string) {
 	ipAllocator.pendingIPRangesMutex.Lock()
 	defer ipAllocator.pendingIPRangesMutex.Unlock()
 	delete(ipAllocator.pendingIPRanges, ipRange)
+	if ipAllocator.store == nil || ipRange == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := ipAllocator.store.Update(ctx, func(reservations map[string]IPReservation) (map[string]IPReservation, error) {
+		delete(reservations, ipRange)
+		return reservations, nil
+	})
+	if err != nil {
+		klog.Errorf("Failed to release IP range %s, it will be released once its reservation expires: %v", ipRange, err)
+	}
+}
+
+// ReserveIPRange returns an unreserved IP block like GetUnreservedIPRange and reserves it for the owner.
+// If the IPAllocator persists its reservations, the reservation is recorded in the store. Expired
+// reservations, reservations of IP ranges already used by cloud instances and earlier reservations of
+// the owner are dropped from the store at the same time, which reconciles the store with the cloud
+// instances.
+func (ipAllocator *IPAllocator) ReserveIPRange(ctx context.Context, cidr string, ipRangeSize int, cloudInstancesReservedIPRanges map[string]bool, owner string) (string, error) {
+	if ipAllocator.store == nil {
+		return ipAllocator.GetUnreservedIPRange(cidr, ipRangeSize, cloudInstancesReservedIPRanges)
+	}
+	ip, ipnet, err := ipAllocator.parseCIDR(cidr, ipRangeSize)
+	if err != nil {
+		return "", err
+	}
+
+	// Serialize the reservations of this controller to avoid conflicting updates of the store
+	ipAllocator.pendingIPRangesMutex.Lock()
+	defer ipAllocator.pendingIPRangesMutex.Unlock()
+
+	var ipRange string
+	err = ipAllocator.store.Update(ctx, func(reservations map[string]IPReservation) (map[string]IPReservation, error) {
+		now := time.Now()
+		reservedIPRanges := make(map[string]bool)
+		for cloudInstancesReservedIPRange := range cloudInstancesReservedIPRanges {
+			reservedIPRanges[cloudInstancesReservedIPRange] = true
+		}
+		for reservedIPRange, reservation := range reservations {
+			if now.After(reservation.Expires) || cloudInstancesReservedIPRanges[reservedIPRange] || reservation.Owner == owner {
+				delete(reservations, reservedIPRange)
+				continue
+			}
+			reservedIPRanges[reservedIPRange] = true
+		}
+
+		var err error
+		ipRange, err = findUnreservedIPRange(cidr, ip, ipnet, ipRangeSize, reservedIPRanges)
+		if err != nil {
+			return nil, err
+		}
+		reservations[ipRange] = IPReservation{
+			Owner:   owner,
+			Expires: now.Add(ipAllocator.reservationTTL),
+		}
+		return reservations, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return ipRange, nil
 }
 
 // GetUnreservedIPRange returns an unreserved IP block.
@@ -93,6 +178,18 @@ func (ipAllocator *IPAllocator) GetUnreservedIPRange(cidr string, ipRangeSize in
 		reservedIPRanges[reservedIPRange] = true
 	}
 
+	ipRange, err := findUnreservedIPRange(cidr, ip, ipnet, ipRangeSize, reservedIPRanges)
+	if err != nil {
+		return "", err
+	}
+	ipAllocator.holdIPRange(ipRange)
+	return ipRange, nil
+}
+
+// findUnreservedIPRange returns the first IP range of the given size in the cidr which does not overlap
+// with any of the reserved IP ranges
+func findUnreservedIPRange(cidr string, ip net.IP, ipnet *net.IPNet, ipRangeSize int, reservedIPRanges map[string]bool) (string, error) {
+	var err error
 	incrementStepIPRange := (uint32)(math.Exp2(float64(ipV4Bits - ipRangeSize)))
 	for cidrIP := cloneIP(ip.Mask(ipnet.Mask)); ipnet.Contains(cidrIP) && err == nil; cidrIP, err = incrementIP(cidrIP, incrementStepIPRange) {
 		overLap := false
@@ -119,9 +216,7 @@ func (ipAllocator *IPAllocator) GetUnreservedIPRange(cidr string, ipRangeSize in
 			}
 		}
 		if !overLap {
-			ipRange := fmt.Sprint(cidrIP.String(), "/", ipRangeSize)
-			ipAllocator.holdIPRange(ipRange)
-			return ipRange, nil
+			return fmt.Sprint(cidrIP.String(), "/", ipRangeSize), nil
 		}
 	}
 
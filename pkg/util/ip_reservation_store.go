@@ -0,0 +1,146 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiError "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// IPReservationConfigMapName is the name of the configmap holding the IP ranges reserved
+	// by instances being created.
+	IPReservationConfigMapName = "filestore-csi-ip-reservations"
+
+	// DefaultIPReservationTTL is how long a reservation is kept if it is never released, e.g.
+	// because the controller holding it restarted.
+	DefaultIPReservationTTL = 10 * time.Minute
+)
+
+// IPReservation is a persisted reservation of an IP range.
+type IPReservation struct {
+	// Owner is the name of the instance the range is reserved for.
+	Owner string `json:"owner"`
+	// Expires is the time after which the reservation is dropped.
+	Expires time.Time `json:"expires"`
+}
+
+// IPReservationStore persists the IP ranges reserved by instances being created, so that
+// the reservations survive controller restarts and are shared between controller replicas.
+type IPReservationStore interface {
+	// Update calls fn with the current reservations, keyed by IP range, and persists the
+	// reservations it returns. fn may be called more than once if the reservations were
+	// updated concurrently.
+	Update(ctx context.Context, fn func(reservations map[string]IPReservation) (map[string]IPReservation, error)) error
+}
+
+// ConfigMapIPReservationStore is an IPReservationStore backed by a configmap. Each key of the
+// configmap is a reserved IP range with the "/" replaced by "_", e.g. 10.0.0.0_29, and its
+// value is the JSON encoded IPReservation. Concurrent updates are detected through the
+// resource version of the configmap.
+type ConfigMapIPReservationStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapIPReservationStore returns a store persisting the reservations in the named
+// configmap, which is created on first use.
+func NewConfigMapIPReservationStore(client kubernetes.Interface, namespace, name string) *ConfigMapIPReservationStore {
+	return &ConfigMapIPReservationStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (s *ConfigMapIPReservationStore) Update(ctx context.Context, fn func(reservations map[string]IPReservation) (map[string]IPReservation, error)) error {
+	retriable := func(err error) bool {
+		return apiError.IsConflict(err) || apiError.IsAlreadyExists(err)
+	}
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		create := false
+		if err != nil {
+			if !apiError.IsNotFound(err) {
+				return fmt.Errorf("failed to get configmap %s/%s: %w", s.namespace, s.name, err)
+			}
+			create = true
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      s.name,
+					Namespace: s.namespace,
+				},
+			}
+		}
+
+		reservations, err := decodeIPReservations(cm.Data)
+		if err != nil {
+			return fmt.Errorf("invalid configmap %s/%s: %w", s.namespace, s.name, err)
+		}
+		reservations, err = fn(reservations)
+		if err != nil {
+			return err
+		}
+		if cm.Data, err = encodeIPReservations(reservations); err != nil {
+			return err
+		}
+
+		if create {
+			_, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+		} else {
+			_, err = s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		}
+		if err != nil && !retriable(err) {
+			return fmt.Errorf("failed to update configmap %s/%s: %w", s.namespace, s.name, err)
+		}
+		return err
+	})
+}
+
+func decodeIPReservations(data map[string]string) (map[string]IPReservation, error) {
+	reservations := make(map[string]IPReservation)
+	for key, value := range data {
+		var reservation IPReservation
+		if err := json.Unmarshal([]byte(value), &reservation); err != nil {
+			return nil, fmt.Errorf("invalid reservation %s: %w", key, err)
+		}
+		reservations[strings.ReplaceAll(key, "_", "/")] = reservation
+	}
+	return reservations, nil
+}
+
+func encodeIPReservations(reservations map[string]IPReservation) (map[string]string, error) {
+	data := make(map[string]string)
+	for ipRange, reservation := range reservations {
+		value, err := json.Marshal(reservation)
+		if err != nil {
+			return nil, err
+		}
+		data[strings.ReplaceAll(ipRange, "/", "_")] = string(value)
+	}
+	return data, nil
+}
@@ -17,10 +17,16 @@ limitations under the License.
 package util
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net"
 	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func initTestIPAllocator() *IPAllocator {
@@ -371,6 +377,153 @@ func TestGetUnReservedIPRange(t *testing.T) {
 	}
 }
 
+func TestReserveIPRangePersistent(t *testing.T) {
+	testNamespace := "test-namespace"
+	now := time.Now()
+	reservation := func(owner string, expires time.Time) string {
+		return fmt.Sprintf(`{"owner":%q,"expires":%q}`, owner, expires.Format(time.RFC3339Nano))
+	}
+	cases := []struct {
+		name                          string
+		existing                      map[string]string
+		cloudProviderReservedIPRanges map[string]bool
+		owner                         string
+		expected                      string
+		expectedKeys                  []string
+		errorExpected                 bool
+	}{
+		{
+			name:         "no configmap",
+			owner:        "instance-a",
+			expected:     "192.168.92.0/29",
+			expectedKeys: []string{"192.168.92.0_29"},
+		},
+		{
+			name: "range reserved by another owner",
+			existing: map[string]string{
+				"192.168.92.0_29": reservation("instance-b", now.Add(time.Minute)),
+			},
+			owner:        "instance-a",
+			expected:     "192.168.92.8/29",
+			expectedKeys: []string{"192.168.92.0_29", "192.168.92.8_29"},
+		},
+		{
+			name: "expired reservation is dropped",
+			existing: map[string]string{
+				"192.168.92.0_29": reservation("instance-b", now.Add(-time.Minute)),
+			},
+			owner:        "instance-a",
+			expected:     "192.168.92.0/29",
+			expectedKeys: []string{"192.168.92.0_29"},
+		},
+		{
+			name: "reservation of a range used by a cloud instance is dropped",
+			existing: map[string]string{
+				"192.168.92.0_29": reservation("instance-b", now.Add(time.Minute)),
+			},
+			cloudProviderReservedIPRanges: map[string]bool{"192.168.92.0/29": true},
+			owner:                         "instance-a",
+			expected:                      "192.168.92.8/29",
+			expectedKeys:                  []string{"192.168.92.8_29"},
+		},
+		{
+			name: "earlier reservation of the owner is dropped",
+			existing: map[string]string{
+				"192.168.92.8_29": reservation("instance-a", now.Add(time.Minute)),
+			},
+			owner:        "instance-a",
+			expected:     "192.168.92.0/29",
+			expectedKeys: []string{"192.168.92.0_29"},
+		},
+		{
+			name: "all ranges reserved",
+			existing: map[string]string{
+				"192.168.92.0_29": reservation("instance-b", now.Add(time.Minute)),
+				"192.168.92.8_29": reservation("instance-c", now.Add(time.Minute)),
+			},
+			cloudProviderReservedIPRanges: map[string]bool{"192.168.92.16/28": true},
+			owner:                         "instance-a",
+			expectedKeys:                  []string{"192.168.92.0_29", "192.168.92.8_29"},
+			errorExpected:                 true,
+		},
+		{
+			name: "invalid reservation",
+			existing: map[string]string{
+				"192.168.92.0_29": "invalid",
+			},
+			owner:         "instance-a",
+			expectedKeys:  []string{"192.168.92.0_29"},
+			errorExpected: true,
+		},
+	}
+
+	for _, test := range cases {
+		client := fake.NewSimpleClientset()
+		if test.existing != nil {
+			client = fake.NewSimpleClientset(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: IPReservationConfigMapName, Namespace: testNamespace},
+				Data:       test.existing,
+			})
+		}
+		ipAllocator := NewPersistentIPAllocator(NewConfigMapIPReservationStore(client, testNamespace, IPReservationConfigMapName), DefaultIPReservationTTL)
+		ipRange, err := ipAllocator.ReserveIPRange(context.Background(), "192.168.92.0/27", IpRangeSize, test.cloudProviderReservedIPRanges, test.owner)
+		if err != nil && !test.errorExpected {
+			t.Errorf("test %q failed: got error %s, expected %s", test.name, err.Error(), test.expected)
+		} else if err == nil && test.errorExpected {
+			t.Errorf("test %q failed: got reserved IP range %s, expected error", test.name, ipRange)
+		} else if ipRange != test.expected {
+			t.Errorf("test %q failed: got reserved IP range %s, expected %s", test.name, ipRange, test.expected)
+		}
+
+		cm, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), IPReservationConfigMapName, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("test %q failed: failed to get configmap: %v", test.name, err)
+			continue
+		}
+		if len(cm.Data) != len(test.expectedKeys) {
+			t.Errorf("test %q failed: got reservations %v, expected %v", test.name, cm.Data, test.expectedKeys)
+		}
+		for _, key := range test.expectedKeys {
+			if _, ok := cm.Data[key]; !ok {
+				t.Errorf("test %q failed: got reservations %v, expected %v", test.name, cm.Data, test.expectedKeys)
+			}
+		}
+	}
+}
+
+func TestReleaseIPRangePersistent(t *testing.T) {
+	testNamespace := "test-namespace"
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapIPReservationStore(client, testNamespace, IPReservationConfigMapName)
+
+	// Two allocators sharing the store stand for two controller replicas, or a controller before and after a restart.
+	first := NewPersistentIPAllocator(store, DefaultIPReservationTTL)
+	second := NewPersistentIPAllocator(store, DefaultIPReservationTTL)
+	ipRange, err := first.ReserveIPRange(context.Background(), "192.168.92.0/28", IpRangeSize, nil, "instance-a")
+	if err != nil {
+		t.Fatalf("failed to reserve IP range: %v", err)
+	}
+	otherIPRange, err := second.ReserveIPRange(context.Background(), "192.168.92.0/28", IpRangeSize, nil, "instance-b")
+	if err != nil {
+		t.Fatalf("failed to reserve IP range: %v", err)
+	}
+	if ipRange == otherIPRange {
+		t.Fatalf("IP range %s reserved twice", ipRange)
+	}
+	if _, err := second.ReserveIPRange(context.Background(), "192.168.92.0/28", IpRangeSize, nil, "instance-c"); err == nil {
+		t.Fatalf("expected error reserving IP range from a fully reserved cidr")
+	}
+
+	first.ReleaseIPRange(ipRange)
+	releasedIPRange, err := second.ReserveIPRange(context.Background(), "192.168.92.0/28", IpRangeSize, nil, "instance-c")
+	if err != nil {
+		t.Fatalf("failed to reserve released IP range: %v", err)
+	}
+	if releasedIPRange != ipRange {
+		t.Errorf("got reserved IP range %s, expected released IP range %s", releasedIPRange, ipRange)
+	}
+}
+
 func getIPRanges(cidr string, ipRangesCount int, ipRangeSize int, t *testing.T) map[string]bool {
 	ip, ipnet, err := net.ParseCIDR(cidr)
 	ipRangeMask := net.CIDRMask(ipRangeSize, ipV4Bits)
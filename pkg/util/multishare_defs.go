@@ -41,7 +41,8 @@ const (
 	ErrRetention = 15 * time.Minute
 
 	// configurable max shares consts
-	ConfigurablePackMinShareSizeBytes int64 = 10 * Gb
+	ConfigurablePackMinShareSizeBytes    int64 = 10 * Gb
+	ConfigurablePackMaxSharesPerInstance       = 80
 )
 
 type OperationType int
@@ -88,6 +89,8 @@ const (
 	OpVerbCreate = "create"
 	OpVerbDelete = "delete"
 	OpVerbUpdate = "update"
+	// OpVerbRestore is the verb of the operations restoring a single share instance.
+	OpVerbRestore = "restore"
 )
 
 func ConvertInstanceOpVerbToType(v string) OperationType {
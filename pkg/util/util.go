@@ -40,13 +40,18 @@ const (
 	VolumeSnapshotLocationKey  = "location"
 	VolumeSnapshotTypeSnapshot = "snapshot"
 	VolumeSnapshotTypeBackup   = "backup"
+	// VolumeSnapshotCopyLocationKey is the region of the copy of a backup, if any.
+	VolumeSnapshotCopyLocationKey = "copy-location"
 
-	SnapshotHandleBackupKey = "backups"
+	SnapshotHandleBackupKey   = "backups"
+	SnapshotHandleSnapshotKey = "snapshots"
 
 	// number of elements in a snapshot Id.
 	// For backups: projects/{project name}/locations/{region}/backups/{name}
 	// For snapshot: projects/{project name}/locations/{zone}/snapshots/{name}
 	snapshotTotalElements = 6
+	// number of elements in an instance snapshot Id e.g. projects/{project name}/locations/{zone|region}/instances/{instance}/snapshots/{name}
+	instanceSnapshotTotalElements = 8
 
 	// number of elements in backup Volume sources e.g. projects/{project name}/locations/{zone}/instances/{name}
 	singleShareVolumeTotalElements = 6
@@ -177,13 +182,40 @@ func ParseTimestamp(timestamp string) (*timestamppb.Timestamp, error) {
 }
 
 func IsBackupHandle(handle string) (bool, error) {
+	if IsInstanceSnapshotHandle(handle) {
+		return false, nil
+	}
 	splitId := strings.Split(handle, "/")
 	if len(splitId) != snapshotTotalElements {
-		return false, fmt.Errorf("failed to get id components. Expected 'projects/{project}/location/{zone|region}/[snapshots|backups]/{name}'. Got: %s", handle)
+		return false, fmt.Errorf("failed to get id components. Expected 'projects/{project}/location/{zone|region}/[snapshots|backups]/{name}' or 'projects/{project}/location/{zone|region}/instances/{instance}/snapshots/{name}'. Got: %s", handle)
 	}
 	return splitId[4] == SnapshotHandleBackupKey, nil
 }
 
+// IsInstanceSnapshotHandle returns true if the handle is the URI of an in-place Filestore instance snapshot.
+func IsInstanceSnapshotHandle(handle string) bool {
+	_, _, _, _, err := ParseInstanceSnapshotURI(handle)
+	return err == nil
+}
+
+func ParseInstanceSnapshotURI(snapshotURI string) (string, string, string, string, error) {
+	// Expected snapshot URI projects/<project-name>/locations/<location-name>/instances/<instance-name>/snapshots/<snapshot-name>
+	splitStr := strings.Split(snapshotURI, "/")
+	if len(splitStr) != instanceSnapshotTotalElements || splitStr[0] != "projects" || splitStr[2] != "locations" || splitStr[4] != "instances" || splitStr[6] != SnapshotHandleSnapshotKey {
+		return "", "", "", "", fmt.Errorf("unknown instance snapshot URI format %q", snapshotURI)
+	}
+
+	project := splitStr[1]
+	location := splitStr[3]
+	instanceName := splitStr[5]
+	snapshotName := splitStr[7]
+	if project == "" || location == "" || instanceName == "" || snapshotName == "" {
+		return "", "", "", "", fmt.Errorf("unknown instance snapshot URI format %q", snapshotURI)
+	}
+
+	return project, location, instanceName, snapshotName, nil
+}
+
 func IsSnapshotTypeSupported(params map[string]string) (bool, error) {
 	if params == nil {
 		return false, fmt.Errorf("empty parameters in VolumeSnapshot")
@@ -192,12 +224,20 @@ func IsSnapshotTypeSupported(params map[string]string) (bool, error) {
 	if !ok {
 		return false, fmt.Errorf("volume snapshot type is missing")
 	}
-	if snapType != VolumeSnapshotTypeBackup {
+	if snapType != VolumeSnapshotTypeBackup && snapType != VolumeSnapshotTypeSnapshot {
 		return false, fmt.Errorf("volume snapshot type %q not supported", snapType)
 	}
 	return true, nil
 }
 
+// GetSnapshotType returns the VolumeSnapshot type, defaulting to backup when it is not set.
+func GetSnapshotType(params map[string]string) string {
+	if snapType, ok := params[VolumeSnapshotTypeKey]; ok {
+		return snapType
+	}
+	return VolumeSnapshotTypeBackup
+}
+
 func GetBackupLocation(params map[string]string) string {
 	location := ""
 	if params == nil {
@@ -208,6 +248,12 @@ func GetBackupLocation(params map[string]string) string {
 	return location
 }
 
+// GetBackupCopyLocation returns the region of the copy of the backup, or an empty string if
+// the backup is not copied.
+func GetBackupCopyLocation(params map[string]string) string {
+	return params[VolumeSnapshotCopyLocationKey]
+}
+
 func BackupVolumeSourceToCSIVolumeHandle(mode, sourceInstance, sourceShare string) (string, error) {
 	splitId := strings.Split(sourceInstance, "/")
 	if mode == "modeInstance" {
@@ -222,6 +268,21 @@ func BackupVolumeSourceToCSIVolumeHandle(mode, sourceInstance, sourceShare strin
 	return fmt.Sprintf("%s/%s/%s/%s", mode, splitId[3], splitId[5], sourceShare), nil
 }
 
+// VolumeIDVersionWithProject marks single share volume handles that include the project of
+// the instance: modeInstance/v2/{project}/{location}/{instance}/{share}. They are used for
+// instances outside of the project of the driver.
+const VolumeIDVersionWithProject = "v2"
+
+// BackupVolumeSourceToProjectCSIVolumeHandle returns the single share volume handle that
+// includes the project of the backup source instance.
+func BackupVolumeSourceToProjectCSIVolumeHandle(sourceInstance, sourceShare string) (string, error) {
+	splitId := strings.Split(sourceInstance, "/")
+	if len(splitId) != singleShareVolumeTotalElements {
+		return "", fmt.Errorf("failed to get id components. Expected 'projects/{project}/location/{zone}/instances/{name}'. Got: %s", sourceInstance)
+	}
+	return fmt.Sprintf("modeInstance/%s/%s/%s/%s/%s", VolumeIDVersionWithProject, splitId[1], splitId[3], splitId[5], sourceShare), nil
+}
+
 // Multishare util functions.
 
 func ConvertVolToShareName(csiVolName string) string {
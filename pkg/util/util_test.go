@@ -20,6 +20,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -408,6 +409,58 @@ func TestParseShareURI(t *testing.T) {
 
 }
 
+func TestIsBackupHandle(t *testing.T) {
+	tests := []struct {
+		name           string
+		handle         string
+		expectErr      bool
+		expectBackup   bool
+		expectSnapshot bool
+	}{
+		{
+			name:         "backup handle",
+			handle:       "projects/" + testProject + "/locations/" + testRegion + "/backups/mybackup",
+			expectBackup: true,
+		},
+		{
+			name:   "legacy snapshot handle",
+			handle: "projects/" + testProject + "/locations/us-central1-c/snapshots/mysnapshot",
+		},
+		{
+			name:           "instance snapshot handle",
+			handle:         "projects/" + testProject + "/locations/us-central1-c/instances/" + testInstanceName + "/snapshots/mysnapshot",
+			expectSnapshot: true,
+		},
+		{
+			name:      "share uri",
+			handle:    "projects/" + testProject + "/locations/" + testRegion + "/instances/" + testInstanceName + "/shares/" + testShareName,
+			expectErr: true,
+		},
+		{
+			name:      "invalid handle",
+			handle:    "none-exist-id",
+			expectErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isBackup, err := IsBackupHandle(tc.handle)
+			if !tc.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.expectErr && err == nil {
+				t.Error("expected error, got none")
+			}
+			if isBackup != tc.expectBackup {
+				t.Errorf("expected backup handle %v, got %v", tc.expectBackup, isBackup)
+			}
+			if isSnapshot := IsInstanceSnapshotHandle(tc.handle); isSnapshot != tc.expectSnapshot {
+				t.Errorf("expected instance snapshot handle %v, got %v", tc.expectSnapshot, isSnapshot)
+			}
+		})
+	}
+}
+
 func TestAlignBytes(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -659,3 +712,91 @@ func TestIINameToInstanceURI(t *testing.T) {
 		})
 	}
 }
+
+func TestCronSchedule(t *testing.T) {
+	from := time.Date(2026, 3, 14, 10, 30, 45, 0, time.UTC) // A Saturday.
+	cases := []struct {
+		name      string
+		spec      string
+		expected  time.Time
+		expectErr bool
+	}{
+		{
+			name:     "every minute",
+			spec:     "* * * * *",
+			expected: time.Date(2026, 3, 14, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily",
+			spec:     "@daily",
+			expected: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "step and list",
+			spec:     "*/20 9,11 * * *",
+			expected: time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "range of weekdays",
+			spec:     "0 2 * * 1-5",
+			expected: time.Date(2026, 3, 16, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "sunday as 7",
+			spec:     "0 0 * * 7",
+			expected: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week",
+			spec:     "0 0 20 * 1",
+			expected: time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly crosses the year",
+			spec:     "0 0 1 1 *",
+			expected: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "never matches",
+			spec:     "0 0 30 2 *",
+			expected: time.Time{},
+		},
+		{
+			name:      "too few fields",
+			spec:      "0 0 * *",
+			expectErr: true,
+		},
+		{
+			name:      "out of range",
+			spec:      "60 * * * *",
+			expectErr: true,
+		},
+		{
+			name:      "invalid step",
+			spec:      "*/0 * * * *",
+			expectErr: true,
+		},
+		{
+			name:      "names are not supported",
+			spec:      "0 0 * * MON",
+			expectErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := ParseCronSchedule(tc.spec)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tc.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if next := schedule.Next(from); !next.Equal(tc.expected) {
+				t.Errorf("got next time %v, expected %v", next, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validation validates the StorageClass parameters checked by both the controller and the
+// StorageClass webhook, so that they accept the same values.
+package validation
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+// MultishareLimits are the limits of the multishare instances with a configurable share count, which
+// the allowed max-volume-size values and the number of shares per instance are derived from.
+type MultishareLimits struct {
+	// MaxShareCount is the maximum number of shares of an instance.
+	MaxShareCount int
+	// CapacityStepSizeGb is the step the capacity of an instance grows by.
+	CapacityStepSizeGb int64
+	MinShareSizeBytes  int64
+	MaxShareSizeBytes  int64
+	// MaxInstanceSizeBytes is the maximum capacity of an instance.
+	MaxInstanceSizeBytes int64
+}
+
+// DefaultMultishareLimits are the limits of the enterprise multishare instances.
+var DefaultMultishareLimits = MultishareLimits{
+	MaxShareCount:        util.ConfigurablePackMaxSharesPerInstance,
+	CapacityStepSizeGb:   util.DefaultStepSizeGb,
+	MinShareSizeBytes:    util.ConfigurablePackMinShareSizeBytes,
+	MaxShareSizeBytes:    util.MaxShareSizeBytes,
+	MaxInstanceSizeBytes: util.MaxMultishareInstanceSizeBytes,
+}
+
+// ParseMaxVolumeSize parses a max-volume-size StorageClass parameter, and returns the max volume size
+// in bytes and the number of shares of that size an instance holds.
+func (l MultishareLimits) ParseMaxVolumeSize(v string) (int64, int, error) {
+	if v == "" {
+		return 0, 0, fmt.Errorf("max volume size is empty")
+	}
+	q, err := resource.ParseQuantity(v)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid max volume size %q: %w", v, err)
+	}
+	sizeBytes := q.Value()
+	sharesPerInstance, err := l.SharesPerInstance(sizeBytes)
+	if err != nil {
+		return 0, 0, err
+	}
+	return sizeBytes, sharesPerInstance, nil
+}
+
+// ValidateMaxVolumeSize checks that shares of the given max volume size can be created on an instance.
+func (l MultishareLimits) ValidateMaxVolumeSize(sizeBytes int64) error {
+	if sizeBytes < l.MinShareSizeBytes || sizeBytes > l.MaxShareSizeBytes {
+		return fmt.Errorf("unsupported max volume size %s, must be between %s and %s", formatBytes(sizeBytes), formatBytes(l.MinShareSizeBytes), formatBytes(l.MaxShareSizeBytes))
+	}
+	if !util.IsAligned(sizeBytes, util.Gb) {
+		return fmt.Errorf("unsupported max volume size %s, must be a multiple of 1Gi", formatBytes(sizeBytes))
+	}
+	return nil
+}
+
+// SharesPerInstance returns the number of shares of the given max volume size an instance holds: as
+// many as fit the largest instance capacity reachable by capacity steps, up to the max share count.
+func (l MultishareLimits) SharesPerInstance(sizeBytes int64) (int, error) {
+	if err := l.ValidateMaxVolumeSize(sizeBytes); err != nil {
+		return 0, err
+	}
+	capacityBytes := l.MaxInstanceSizeBytes
+	if stepBytes := util.GbToBytes(l.CapacityStepSizeGb); stepBytes > 0 {
+		capacityBytes -= capacityBytes % stepBytes
+	}
+	sharesPerInstance := capacityBytes / sizeBytes
+	if sharesPerInstance > int64(l.MaxShareCount) {
+		sharesPerInstance = int64(l.MaxShareCount)
+	}
+	if sharesPerInstance < 1 {
+		return 0, fmt.Errorf("unsupported max volume size %s, larger than the instance capacity %s", formatBytes(sizeBytes), formatBytes(capacityBytes))
+	}
+	return int(sharesPerInstance), nil
+}
+
+func formatBytes(b int64) string {
+	return resource.NewQuantity(b, resource.BinarySI).String()
+}
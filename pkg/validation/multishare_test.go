@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"testing"
+
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/util"
+)
+
+func TestParseMaxVolumeSize(t *testing.T) {
+	tests := []struct {
+		name                      string
+		limits                    *MultishareLimits
+		value                     string
+		expectedSizeBytes         int64
+		expectedSharesPerInstance int
+		expectErr                 bool
+	}{
+		{
+			name:      "empty value",
+			value:     "",
+			expectErr: true,
+		},
+		{
+			name:      "invalid quantity",
+			value:     "12i",
+			expectErr: true,
+		},
+		{
+			name:      "negative value",
+			value:     "-128Gi",
+			expectErr: true,
+		},
+		{
+			name:      "zero",
+			value:     "0",
+			expectErr: true,
+		},
+		{
+			name:      "below min share size",
+			value:     "9Gi",
+			expectErr: true,
+		},
+		{
+			name:      "above max share size",
+			value:     "1025Gi",
+			expectErr: true,
+		},
+		{
+			name:      "not aligned to Gi",
+			value:     "131073Mi",
+			expectErr: true,
+		},
+		{
+			name:      "not aligned to Gi, in bytes",
+			value:     "131073",
+			expectErr: true,
+		},
+		{
+			name:                      "min share size, share count capped",
+			value:                     "10Gi",
+			expectedSizeBytes:         10 * util.Gb,
+			expectedSharesPerInstance: 80,
+		},
+		{
+			name:                      "share count capped",
+			value:                     "100Gi",
+			expectedSizeBytes:         100 * util.Gb,
+			expectedSharesPerInstance: 80,
+		},
+		{
+			name:                      "128Gi",
+			value:                     "128Gi",
+			expectedSizeBytes:         128 * util.Gb,
+			expectedSharesPerInstance: 80,
+		},
+		{
+			name:                      "200Gi",
+			value:                     "200Gi",
+			expectedSizeBytes:         200 * util.Gb,
+			expectedSharesPerInstance: 51,
+		},
+		{
+			name:                      "256Gi",
+			value:                     "256Gi",
+			expectedSizeBytes:         256 * util.Gb,
+			expectedSharesPerInstance: 40,
+		},
+		{
+			name:                      "in Mi",
+			value:                     "131072Mi",
+			expectedSizeBytes:         128 * util.Gb,
+			expectedSharesPerInstance: 80,
+		},
+		{
+			name:                      "in bytes",
+			value:                     "549755813888",
+			expectedSizeBytes:         512 * util.Gb,
+			expectedSharesPerInstance: 20,
+		},
+		{
+			name:                      "max share size",
+			value:                     "1Ti",
+			expectedSizeBytes:         1 * util.Tb,
+			expectedSharesPerInstance: 10,
+		},
+		{
+			name: "instance capacity aligned to the step size",
+			limits: &MultishareLimits{
+				MaxShareCount:        80,
+				CapacityStepSizeGb:   256,
+				MinShareSizeBytes:    10 * util.Gb,
+				MaxShareSizeBytes:    1 * util.Tb,
+				MaxInstanceSizeBytes: 1000 * util.Gb,
+			},
+			value:                     "100Gi",
+			expectedSizeBytes:         100 * util.Gb,
+			expectedSharesPerInstance: 7,
+		},
+		{
+			name: "larger than the instance capacity",
+			limits: &MultishareLimits{
+				MaxShareCount:        80,
+				CapacityStepSizeGb:   256,
+				MinShareSizeBytes:    10 * util.Gb,
+				MaxShareSizeBytes:    1 * util.Tb,
+				MaxInstanceSizeBytes: 1000 * util.Gb,
+			},
+			value:     "800Gi",
+			expectErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limits := DefaultMultishareLimits
+			if tc.limits != nil {
+				limits = *tc.limits
+			}
+			sizeBytes, sharesPerInstance, err := limits.ParseMaxVolumeSize(tc.value)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error for %q, got size %d and %d shares per instance", tc.value, sizeBytes, sharesPerInstance)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", tc.value, err)
+			}
+			if sizeBytes != tc.expectedSizeBytes {
+				t.Errorf("got size %d, expected %d", sizeBytes, tc.expectedSizeBytes)
+			}
+			if sharesPerInstance != tc.expectedSharesPerInstance {
+				t.Errorf("got %d shares per instance, expected %d", sharesPerInstance, tc.expectedSharesPerInstance)
+			}
+		})
+	}
+}
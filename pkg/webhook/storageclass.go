@@ -23,9 +23,9 @@ import (
 
 	v1 "k8s.io/api/admission/v1"
 	storagev1 "k8s.io/api/storage/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-filestore-csi-driver/pkg/validation"
 )
 
 const (
@@ -90,22 +90,10 @@ func validateMaxVolumeSizeParam(sc *storagev1.StorageClass) error {
 		return fmt.Errorf("'max-volume-size' parameter is not supported")
 	}
 
-	val, err := resource.ParseQuantity(v)
-	if err != nil {
-		return err
-	}
-	valBytes := val.Value()
-	switch valBytes {
-	case 128 * Gi:
-		return nil
-	case 256 * Gi:
-		return nil
-	case 512 * Gi:
-		return nil
-	case 1024 * Gi:
-		return nil
+	if _, _, err := validation.DefaultMultishareLimits.ParseMaxVolumeSize(v); err != nil {
+		return fmt.Errorf("invalid 'max-volume-size' %s: %v", v, err)
 	}
-	return fmt.Errorf("invalid 'max-volume-size' %s, allowed sizes are '128Gi', '256Gi', '512Gi', '1Ti'", v)
+	return nil
 }
 
 func applyV1StorageClassPatch(sc *storagev1.StorageClass) *v1.AdmissionResponse {
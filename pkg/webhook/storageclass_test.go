@@ -310,12 +310,34 @@ func TestValidateMaxVolumeSize(t *testing.T) {
 			errExpected: true,
 		},
 		{
-			name: "max-volume-size key set, value invalid - test2",
+			name: "max-volume-size key set, value below min share size",
 			sc: &storagev1.StorageClass{
 				ObjectMeta:  metav1.ObjectMeta{Name: storageClassName},
 				Provisioner: FilestoreCSIDriver,
 				Parameters: map[string]string{
-					"max-volume-size": "100Gi",
+					"max-volume-size": "5Gi",
+				},
+			},
+			errExpected: true,
+		},
+		{
+			name: "max-volume-size key set, value above max share size",
+			sc: &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: storageClassName},
+				Provisioner: FilestoreCSIDriver,
+				Parameters: map[string]string{
+					"max-volume-size": "2Ti",
+				},
+			},
+			errExpected: true,
+		},
+		{
+			name: "max-volume-size key set, value not aligned to Gi",
+			sc: &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: storageClassName},
+				Provisioner: FilestoreCSIDriver,
+				Parameters: map[string]string{
+					"max-volume-size": "1500Mi",
 				},
 			},
 			errExpected: true,
@@ -421,6 +443,36 @@ func TestValidateMaxVolumeSize(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "max-volume-size key set, arbitrary value valid - test11",
+			sc: &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: storageClassName},
+				Provisioner: FilestoreCSIDriver,
+				Parameters: map[string]string{
+					"max-volume-size": "100Gi",
+				},
+			},
+		},
+		{
+			name: "max-volume-size key set, arbitrary value valid - test12",
+			sc: &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: storageClassName},
+				Provisioner: FilestoreCSIDriver,
+				Parameters: map[string]string{
+					"max-volume-size": "200Gi",
+				},
+			},
+		},
+		{
+			name: "max-volume-size key set, min share size valid - test13",
+			sc: &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: storageClassName},
+				Provisioner: FilestoreCSIDriver,
+				Parameters: map[string]string{
+					"max-volume-size": "10Gi",
+				},
+			},
+		},
 	}
 	originalfeatureValue := featureMaxSharesPerInstance
 	featureMaxSharesPerInstance = true